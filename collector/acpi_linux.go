@@ -0,0 +1,120 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noacpi
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const acpiSubsystem = "acpi"
+
+type acpiCollector struct {
+	acOnline *prometheus.Desc
+	lidOpen  *prometheus.Desc
+	logger   log.Logger
+}
+
+func init() {
+	registerCollector(acpiSubsystem, defaultDisabled, NewACPICollector)
+}
+
+// NewACPICollector returns a new Collector exposing the AC adapter and
+// laptop lid state.
+func NewACPICollector(logger log.Logger) (Collector, error) {
+	return &acpiCollector{
+		acOnline: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, acpiSubsystem, "ac_online"),
+			"Whether the AC adapter is plugged in.",
+			[]string{"adapter"}, nil,
+		),
+		lidOpen: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, acpiSubsystem, "lid_open"),
+			"Whether the laptop lid is open.",
+			[]string{"lid"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *acpiCollector) Update(ch chan<- prometheus.Metric) error {
+	found := false
+
+	supplies, err := filepath.Glob(sysFilePath("class/power_supply/*"))
+	if err != nil {
+		return err
+	}
+	for _, supply := range supplies {
+		// AC adapters are of type Mains, as opposed to batteries and USB
+		// ports.
+		typ, err := ioutil.ReadFile(filepath.Join(supply, "type"))
+		if err != nil || strings.TrimSpace(string(typ)) != "Mains" {
+			continue
+		}
+		online, err := readUintFromFile(filepath.Join(supply, "online"))
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read AC adapter state", "adapter", supply, "err", err)
+			continue
+		}
+		found = true
+		ch <- prometheus.MustNewConstMetric(c.acOnline, prometheus.GaugeValue, float64(online), filepath.Base(supply))
+	}
+
+	lids, err := filepath.Glob(procFilePath("acpi/button/lid/*/state"))
+	if err != nil {
+		return err
+	}
+	for _, lid := range lids {
+		name := filepath.Base(filepath.Dir(lid))
+		open, err := readACPILidState(lid)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read lid state", "lid", name, "err", err)
+			continue
+		}
+		found = true
+		ch <- prometheus.MustNewConstMetric(c.lidOpen, prometheus.GaugeValue, open, name)
+	}
+
+	if !found {
+		return ErrNoData
+	}
+	return nil
+}
+
+// readACPILidState parses a lid state file like "state:      open".
+func readACPILidState(path string) (float64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] != "state:" {
+		return 0, fmt.Errorf("unexpected lid state %q", strings.TrimSpace(string(data)))
+	}
+	switch fields[1] {
+	case "open":
+		return 1, nil
+	case "closed":
+		return 0, nil
+	}
+	return 0, fmt.Errorf("unknown lid state %q", fields[1])
+}
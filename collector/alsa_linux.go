@@ -0,0 +1,146 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noalsa
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const alsaSubsystem = "alsa"
+
+var (
+	// Card lines of /proc/asound/cards look like
+	// " 0 [PCH            ]: HDA-Intel - HDA Intel PCH".
+	alsaCardRE = regexp.MustCompile(`^\s*(\d+)\s+\[(\S+)\s*\]: (\S+) - (.*)$`)
+	alsaPCMRE  = regexp.MustCompile(`card(\d+)/pcm(\d+)([pc])/sub(\d+)/status$`)
+)
+
+type alsaCollector struct {
+	cardInfo   *prometheus.Desc
+	pcmOpen    *prometheus.Desc
+	pcmRunning *prometheus.Desc
+	logger     log.Logger
+}
+
+func init() {
+	registerCollector(alsaSubsystem, defaultDisabled, NewALSACollector)
+}
+
+// NewALSACollector returns a new Collector exposing sound cards and the state
+// of their PCM streams.
+func NewALSACollector(logger log.Logger) (Collector, error) {
+	pcmLabels := []string{"card", "device", "subdevice", "stream"}
+	return &alsaCollector{
+		cardInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, alsaSubsystem, "card_info"),
+			"Sound card present on the system, value is always 1.",
+			[]string{"card", "id", "driver", "name"}, nil,
+		),
+		pcmOpen: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, alsaSubsystem, "pcm_open"),
+			"Whether the PCM substream is opened by an application.",
+			pcmLabels, nil,
+		),
+		pcmRunning: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, alsaSubsystem, "pcm_running"),
+			"Whether the PCM substream is running, i.e. playing or capturing audio.",
+			pcmLabels, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *alsaCollector) Update(ch chan<- prometheus.Metric) error {
+	f, err := os.Open(procFilePath("asound/cards"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "ALSA not available")
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to read sound cards: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Each card is followed by an indented line with its long name.
+		if m := alsaCardRE.FindStringSubmatch(scanner.Text()); m != nil {
+			ch <- prometheus.MustNewConstMetric(c.cardInfo, prometheus.GaugeValue, 1, m[1], m[2], m[3], strings.TrimSpace(m[4]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read sound cards: %w", err)
+	}
+
+	substreams, err := filepath.Glob(procFilePath("asound/card[0-9]*/pcm[0-9]*[pc]/sub[0-9]*/status"))
+	if err != nil {
+		return err
+	}
+	for _, path := range substreams {
+		m := alsaPCMRE.FindStringSubmatch(path)
+		if m == nil {
+			continue
+		}
+		stream := "playback"
+		if m[3] == "c" {
+			stream = "capture"
+		}
+		state, err := readALSAPCMState(path)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read PCM state", "path", path, "err", err)
+			continue
+		}
+		open, running := 1.0, 0.0
+		switch state {
+		case "closed":
+			open = 0
+		case "RUNNING":
+			running = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.pcmOpen, prometheus.GaugeValue, open, m[1], m[2], m[4], stream)
+		ch <- prometheus.MustNewConstMetric(c.pcmRunning, prometheus.GaugeValue, running, m[1], m[2], m[4], stream)
+	}
+	return nil
+}
+
+// readALSAPCMState returns the state of a PCM substream, "closed" or a
+// state like "RUNNING" or "PREPARED" from the "state:" line of its status.
+func readALSAPCMState(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	status := strings.TrimSpace(string(data))
+	if status == "closed" {
+		return status, nil
+	}
+	for _, line := range strings.Split(status, "\n") {
+		if state := strings.TrimPrefix(line, "state:"); state != line {
+			return strings.TrimSpace(state), nil
+		}
+	}
+	return "", fmt.Errorf("no state in PCM status")
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noapcupsd
+
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const apcupsdSubsystem = "apcupsd"
+
+var (
+	apcupsdAddress = kingpin.Flag("collector.apcupsd.address", "Address of the apcupsd network information server.").Default("localhost:3551").String()
+	apcupsdTimeout = kingpin.Flag("collector.apcupsd.timeout", "Timeout for querying apcupsd.").Default("2s").Duration()
+)
+
+// apcupsdFields are the numeric status fields exported as metrics.
+var apcupsdFields = []struct {
+	field, name, help string
+	valueType         prometheus.ValueType
+}{
+	{"LINEV", "line_voltage_volts", "Input line voltage of the UPS.", prometheus.GaugeValue},
+	{"OUTPUTV", "output_voltage_volts", "Output voltage of the UPS.", prometheus.GaugeValue},
+	{"LOADPCT", "load_ratio", "Load of the UPS relative to its capacity.", prometheus.GaugeValue},
+	{"NOMPOWER", "nominal_power_watts", "Nominal output power of the UPS.", prometheus.GaugeValue},
+	{"BCHARGE", "battery_charge_ratio", "Charge level of the UPS battery.", prometheus.GaugeValue},
+	{"BATTV", "battery_voltage_volts", "Voltage of the UPS battery.", prometheus.GaugeValue},
+	{"TIMELEFT", "battery_time_left_seconds", "Estimated remaining runtime of the UPS on battery.", prometheus.GaugeValue},
+	{"TONBATT", "time_on_battery_seconds", "Time the UPS has been on battery in the current outage.", prometheus.GaugeValue},
+	{"CUMONBATT", "time_on_battery_seconds_total", "Total time the UPS has been on battery since apcupsd started.", prometheus.CounterValue},
+	{"NUMXFERS", "transfers_total", "Number of transfers to battery since apcupsd started.", prometheus.CounterValue},
+}
+
+type apcupsdCollector struct {
+	info   *prometheus.Desc
+	fields map[string]*prometheus.Desc
+	logger log.Logger
+}
+
+func init() {
+	registerCollector("apcupsd", defaultDisabled, NewApcupsdCollector)
+}
+
+// NewApcupsdCollector returns a new Collector exposing the UPS status
+// reported by apcupsd.
+func NewApcupsdCollector(logger log.Logger) (Collector, error) {
+	c := &apcupsdCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, apcupsdSubsystem, "info"),
+			"UPS monitored by apcupsd with its status, e.g. ONLINE or ONBATT, value is always 1.",
+			[]string{"ups", "model", "serial", "status", "last_transfer_reason"}, nil,
+		),
+		fields: map[string]*prometheus.Desc{},
+		logger: logger,
+	}
+	for _, f := range apcupsdFields {
+		c.fields[f.field] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, apcupsdSubsystem, f.name),
+			f.help, []string{"ups"}, nil,
+		)
+	}
+	return c, nil
+}
+
+func (c *apcupsdCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := net.DialTimeout("tcp", *apcupsdAddress, *apcupsdTimeout)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to apcupsd: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(*apcupsdTimeout)); err != nil {
+		return err
+	}
+
+	status, err := readApcupsdStatus(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't read apcupsd status: %w", err)
+	}
+
+	ups := status["UPSNAME"]
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+		ups, status["MODEL"], status["SERIALNO"], status["STATUS"], status["LASTXFER"])
+	for _, f := range apcupsdFields {
+		raw, ok := status[f.field]
+		if !ok {
+			continue
+		}
+		value, err := parseApcupsdValue(raw)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "invalid apcupsd status value", "field", f.field, "value", raw, "err", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.fields[f.field], f.valueType, value, ups)
+	}
+	return nil
+}
+
+// readApcupsdStatus sends the status command to the apcupsd network
+// information server and returns the fields of the reply. Messages in both
+// directions are prefixed with their 16 bit length, an empty message ends
+// the reply.
+func readApcupsdStatus(rw io.ReadWriter) (map[string]string, error) {
+	cmd := []byte("\x00\x06status")
+	if _, err := rw.Write(cmd); err != nil {
+		return nil, err
+	}
+
+	status := map[string]string{}
+	for {
+		var size uint16
+		if err := binary.Read(rw, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return status, nil
+		}
+		line := make([]byte, size)
+		if _, err := io.ReadFull(rw, line); err != nil {
+			return nil, err
+		}
+		// Lines are formatted as "KEY      : value".
+		parts := strings.SplitN(string(line), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		status[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+}
+
+// parseApcupsdValue parses a value like "13.0 Percent" or "64.0 Minutes" into
+// base units.
+func parseApcupsdValue(s string) (float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty value")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "Percent":
+			value /= 100
+		case "Minutes":
+			value *= 60
+		case "Hours":
+			value *= 3600
+		}
+	}
+	return value, nil
+}
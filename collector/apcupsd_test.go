@@ -0,0 +1,108 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noapcupsd
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// apcupsdConn reads a canned reply and records the command sent.
+type apcupsdConn struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (c *apcupsdConn) Read(p []byte) (int, error) {
+	return c.Reader.Read(p)
+}
+
+// apcupsdRecords frames lines like the network information server does.
+func apcupsdRecords(lines ...string) []byte {
+	var b bytes.Buffer
+	for _, line := range lines {
+		binary.Write(&b, binary.BigEndian, uint16(len(line)))
+		b.WriteString(line)
+	}
+	b.Write([]byte{0, 0})
+	return b.Bytes()
+}
+
+func TestReadApcupsdStatus(t *testing.T) {
+	conn := &apcupsdConn{Reader: bytes.NewReader(apcupsdRecords(
+		"APC      : 001,036,0879\n",
+		"UPSNAME  : rack1\n",
+		"STATUS   : ONLINE \n",
+		"LOADPCT  : 13.0 Percent\n",
+		"TIMELEFT : 64.5 Minutes\n",
+		"TONBATT  : 0 Seconds\n",
+		"END APC  : 2021-09-08 10:00:00 +0000  \n",
+	))}
+
+	status, err := readApcupsdStatus(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte("\x00\x06status"); !bytes.Equal(conn.Bytes(), want) {
+		t.Errorf("want command %q, got %q", want, conn.Bytes())
+	}
+	want := map[string]string{
+		"APC":      "001,036,0879",
+		"UPSNAME":  "rack1",
+		"STATUS":   "ONLINE",
+		"LOADPCT":  "13.0 Percent",
+		"TIMELEFT": "64.5 Minutes",
+		"TONBATT":  "0 Seconds",
+		"END APC":  "2021-09-08 10:00:00 +0000",
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("want %q, got %q", want, status)
+	}
+
+	// A reply cut off before the terminating empty record.
+	truncated := apcupsdRecords("UPSNAME  : rack1\n")
+	if _, err := readApcupsdStatus(&apcupsdConn{Reader: bytes.NewReader(truncated[:len(truncated)-2])}); err == nil {
+		t.Error("expected error for a truncated reply")
+	}
+}
+
+func TestParseApcupsdValue(t *testing.T) {
+	for raw, want := range map[string]float64{
+		"13.0 Percent": 0.13,
+		"64.5 Minutes": 3870,
+		"2.0 Hours":    7200,
+		"45 Seconds":   45,
+		"230.0 Volts":  230,
+		"3":            3,
+	} {
+		got, err := parseApcupsdValue(raw)
+		if err != nil {
+			t.Errorf("%q: %v", raw, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: want %v, got %v", raw, want, got)
+		}
+	}
+	for _, raw := range []string{"", "N/A"} {
+		if _, err := parseApcupsdValue(raw); err == nil {
+			t.Errorf("%q: expected error", raw)
+		}
+	}
+}
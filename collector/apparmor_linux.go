@@ -0,0 +1,93 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noapparmor
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const apparmorSubsystem = "apparmor"
+
+type apparmorCollector struct {
+	profiles    *prometheus.Desc
+	profileInfo *prometheus.Desc
+	logger      log.Logger
+}
+
+func init() {
+	registerCollector(apparmorSubsystem, defaultDisabled, NewAppArmorCollector)
+}
+
+// NewAppArmorCollector returns a new Collector exposing the loaded AppArmor
+// profiles.
+func NewAppArmorCollector(logger log.Logger) (Collector, error) {
+	return &apparmorCollector{
+		profiles: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, apparmorSubsystem, "profiles"),
+			"Number of loaded AppArmor profiles by mode.",
+			[]string{"mode"}, nil,
+		),
+		profileInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, apparmorSubsystem, "profile_info"),
+			"Loaded AppArmor profile, value is always 1.",
+			[]string{"profile", "mode"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *apparmorCollector) Update(ch chan<- prometheus.Metric) error {
+	f, err := os.Open(sysFilePath("kernel/security/apparmor/profiles"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "AppArmor not enabled or securityfs not mounted")
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to read AppArmor profiles: %w", err)
+	}
+	defer f.Close()
+
+	counts := map[string]float64{"enforce": 0, "complain": 0}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines look like "/usr/sbin/cupsd (enforce)", profile names may
+		// contain spaces.
+		line := scanner.Text()
+		i := strings.LastIndex(line, " (")
+		if i < 0 || !strings.HasSuffix(line, ")") {
+			level.Debug(c.logger).Log("msg", "invalid AppArmor profile line", "line", line)
+			continue
+		}
+		profile, mode := line[:i], line[i+2:len(line)-1]
+		counts[mode]++
+		ch <- prometheus.MustNewConstMetric(c.profileInfo, prometheus.GaugeValue, 1, profile, mode)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read AppArmor profiles: %w", err)
+	}
+
+	for mode, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.profiles, prometheus.GaugeValue, count, mode)
+	}
+	return nil
+}
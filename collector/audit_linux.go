@@ -0,0 +1,141 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noaudit
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	auditSubsystem = "audit"
+
+	// AUDIT_GET, see /usr/include/linux/audit.h.
+	auditGet = 1000
+)
+
+// auditStatus holds the fields of struct audit_status used by the collector.
+type auditStatus struct {
+	Enabled      uint32
+	Failure      uint32
+	PID          uint32
+	RateLimit    uint32
+	BacklogLimit uint32
+	Lost         uint32
+	Backlog      uint32
+}
+
+type auditCollector struct {
+	enabled          *prometheus.Desc
+	failureMode      *prometheus.Desc
+	daemonRegistered *prometheus.Desc
+	rateLimit        *prometheus.Desc
+	backlog          *prometheus.Desc
+	backlogLimit     *prometheus.Desc
+	lost             *prometheus.Desc
+	logger           log.Logger
+}
+
+func init() {
+	registerCollector(auditSubsystem, defaultDisabled, NewAuditCollector)
+}
+
+// NewAuditCollector returns a new Collector exposing the status of the kernel
+// audit subsystem.
+func NewAuditCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, auditSubsystem, name), help, nil, nil)
+	}
+	return &auditCollector{
+		enabled:          desc("enabled", "Whether auditing is enabled, 2 if the configuration is locked."),
+		failureMode:      desc("failure_mode", "Action on critical audit errors, 0 is silent, 1 is printk and 2 is panic."),
+		daemonRegistered: desc("daemon_registered", "Whether an audit daemon is registered to receive audit events."),
+		rateLimit:        desc("rate_limit_messages_per_second", "Maximum number of audit messages per second, 0 if unlimited."),
+		backlog:          desc("backlog", "Number of audit events waiting to be read by the audit daemon."),
+		backlogLimit:     desc("backlog_limit", "Maximum number of audit events waiting to be read by the audit daemon."),
+		lost:             desc("lost_events_total", "Number of audit events lost due to the backlog or rate limit."),
+		logger:           logger,
+	}, nil
+}
+
+func (c *auditCollector) Update(ch chan<- prometheus.Metric) error {
+	status, err := getAuditStatus()
+	if err != nil {
+		return fmt.Errorf("couldn't get audit status: %w", err)
+	}
+
+	daemonRegistered := 0.0
+	if status.PID != 0 {
+		daemonRegistered = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, float64(status.Enabled))
+	ch <- prometheus.MustNewConstMetric(c.failureMode, prometheus.GaugeValue, float64(status.Failure))
+	ch <- prometheus.MustNewConstMetric(c.daemonRegistered, prometheus.GaugeValue, daemonRegistered)
+	ch <- prometheus.MustNewConstMetric(c.rateLimit, prometheus.GaugeValue, float64(status.RateLimit))
+	ch <- prometheus.MustNewConstMetric(c.backlog, prometheus.GaugeValue, float64(status.Backlog))
+	ch <- prometheus.MustNewConstMetric(c.backlogLimit, prometheus.GaugeValue, float64(status.BacklogLimit))
+	ch <- prometheus.MustNewConstMetric(c.lost, prometheus.CounterValue, float64(status.Lost))
+	return nil
+}
+
+// getAuditStatus requests the audit status from the kernel. This requires
+// CAP_AUDIT_CONTROL.
+func getAuditStatus() (*auditStatus, error) {
+	c, err := netlink.Dial(unix.NETLINK_AUDIT, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	msgs, err := c.Execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  auditGet,
+			Flags: netlink.Request,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		if msg.Header.Type == auditGet {
+			return parseAuditStatus(msg.Data)
+		}
+	}
+	return nil, fmt.Errorf("no audit status in netlink response")
+}
+
+// parseAuditStatus parses struct audit_status, which starts with a mask of the
+// fields to set followed by the status fields.
+func parseAuditStatus(data []byte) (*auditStatus, error) {
+	if len(data) < 32 {
+		return nil, fmt.Errorf("audit status too short: %d bytes", len(data))
+	}
+	field := func(i int) uint32 { return nlenc.Uint32(data[i*4 : i*4+4]) }
+	return &auditStatus{
+		Enabled:      field(1),
+		Failure:      field(2),
+		PID:          field(3),
+		RateLimit:    field(4),
+		BacklogLimit: field(5),
+		Lost:         field(6),
+		Backlog:      field(7),
+	}, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	backgroundInterval = kingpin.Flag("collector.background.interval", "Collect on this interval in the background and serve the latest snapshot with its timestamp on scrape, 0 collects on every scrape.").Default("0s").Duration()
+	backgroundMaxAge   = kingpin.Flag("collector.background.max-age", "Age after which a background snapshot is stale and no longer served, 0 uses twice the interval.").Default("0s").Duration()
+)
+
+// backgroundCollector serves the snapshot of the last collection of a
+// NodeCollector, the samples carry the time of the collection so that
+// Prometheus honoring timestamps stores them as collected.
+type backgroundCollector struct {
+	nc     *NodeCollector
+	maxAge time.Duration
+	logger log.Logger
+
+	mtx       sync.RWMutex
+	metrics   []prometheus.Metric
+	timestamp time.Time
+}
+
+// BackgroundCollection returns a collector serving snapshots of nc collected
+// every --collector.background.interval, or nc itself if the interval is not
+// set.
+func BackgroundCollection(nc *NodeCollector, logger log.Logger) prometheus.Collector {
+	if *backgroundInterval <= 0 {
+		return nc
+	}
+	maxAge := *backgroundMaxAge
+	if maxAge <= 0 {
+		maxAge = 2 * *backgroundInterval
+	}
+	b := &backgroundCollector{nc: nc, maxAge: maxAge, logger: logger}
+	go b.run(*backgroundInterval)
+	return b
+}
+
+func (b *backgroundCollector) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		b.collect()
+		<-ticker.C
+	}
+}
+
+// collect runs the collectors and replaces the snapshot.
+func (b *backgroundCollector) collect() {
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	begin := time.Now()
+	var metrics []prometheus.Metric
+	go func() {
+		for m := range ch {
+			metrics = append(metrics, prometheus.NewMetricWithTimestamp(begin, m))
+		}
+		close(done)
+	}()
+	b.nc.Collect(ch)
+	close(ch)
+	<-done
+	level.Debug(b.logger).Log("msg", "background collection finished", "metrics", len(metrics), "duration_seconds", time.Since(begin).Seconds())
+
+	b.mtx.Lock()
+	b.metrics = metrics
+	b.timestamp = begin
+	b.mtx.Unlock()
+}
+
+// Describe implements the prometheus.Collector interface.
+func (b *backgroundCollector) Describe(ch chan<- *prometheus.Desc) {
+	b.nc.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface.
+func (b *backgroundCollector) Collect(ch chan<- prometheus.Metric) {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	if b.timestamp.IsZero() {
+		level.Debug(b.logger).Log("msg", "no background snapshot yet")
+		return
+	}
+	if age := time.Since(b.timestamp); age > b.maxAge {
+		// Serving nothing lets the series go stale instead of repeating
+		// old samples.
+		level.Warn(b.logger).Log("msg", "background snapshot is stale", "age_seconds", age.Seconds())
+		return
+	}
+	for _, m := range b.metrics {
+		ch <- m
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestBackgroundCollector(t *testing.T) {
+	b := &backgroundCollector{
+		nc: &NodeCollector{
+			Collectors: map[string]Collector{"sleep": sleepCollector{}},
+			logger:     log.NewNopLogger(),
+		},
+		maxAge: time.Hour,
+		logger: log.NewNopLogger(),
+	}
+
+	collect := func() []prometheus.Metric {
+		ch := make(chan prometheus.Metric, 16)
+		b.Collect(ch)
+		close(ch)
+		var metrics []prometheus.Metric
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		return metrics
+	}
+
+	if metrics := collect(); len(metrics) != 0 {
+		t.Fatalf("expected no metrics before the first collection, got %d", len(metrics))
+	}
+
+	b.collect()
+	metrics := collect()
+	// Two metrics of the collector, its duration, success and queue wait.
+	if len(metrics) != 5 {
+		t.Fatalf("expected 5 metrics, got %d", len(metrics))
+	}
+	var metric dto.Metric
+	if err := metrics[0].Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := b.timestamp.UnixNano()/int64(time.Millisecond), metric.GetTimestampMs(); want != got {
+		t.Errorf("expected timestamp %d, got %d", want, got)
+	}
+
+	b.maxAge = 0
+	if metrics := collect(); len(metrics) != 0 {
+		t.Errorf("expected no metrics of a stale snapshot, got %d", len(metrics))
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nobacklight
+
+package collector
+
+import (
+	"path/filepath"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const backlightSubsystem = "backlight"
+
+type backlightCollector struct {
+	brightness       *prometheus.Desc
+	actualBrightness *prometheus.Desc
+	maxBrightness    *prometheus.Desc
+	logger           log.Logger
+}
+
+func init() {
+	registerCollector(backlightSubsystem, defaultDisabled, NewBacklightCollector)
+}
+
+// NewBacklightCollector returns a new Collector exposing display backlight
+// brightness.
+func NewBacklightCollector(logger log.Logger) (Collector, error) {
+	return &backlightCollector{
+		brightness: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, backlightSubsystem, "brightness"),
+			"Brightness of the backlight requested by userspace.",
+			[]string{"device"}, nil,
+		),
+		actualBrightness: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, backlightSubsystem, "actual_brightness"),
+			"Brightness of the backlight reported by the hardware.",
+			[]string{"device"}, nil,
+		),
+		maxBrightness: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, backlightSubsystem, "max_brightness"),
+			"Maximum brightness of the backlight.",
+			[]string{"device"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *backlightCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("class/backlight/*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		level.Debug(c.logger).Log("msg", "no backlight devices found")
+		return ErrNoData
+	}
+
+	for _, path := range devices {
+		device := filepath.Base(path)
+		for desc, file := range map[*prometheus.Desc]string{
+			c.brightness:       "brightness",
+			c.actualBrightness: "actual_brightness",
+			c.maxBrightness:    "max_brightness",
+		} {
+			value, err := readUintFromFile(filepath.Join(path, file))
+			if err != nil {
+				level.Debug(c.logger).Log("msg", "failed to read backlight attribute", "device", device, "file", file, "err", err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), device)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nobluetooth
+
+package collector
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/godbus/dbus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	bluetoothSubsystem = "bluetooth"
+	bluezDbusObject    = "org.bluez"
+)
+
+type bluetoothCollector struct {
+	adapterInfo         *prometheus.Desc
+	adapterPowered      *prometheus.Desc
+	adapterDiscoverable *prometheus.Desc
+	adapterDiscovering  *prometheus.Desc
+	devicesPaired       *prometheus.Desc
+	devicesConnected    *prometheus.Desc
+	logger              log.Logger
+}
+
+type bluetoothAdapter struct {
+	name         string
+	address      string
+	alias        string
+	powered      bool
+	discoverable bool
+	discovering  bool
+	paired       int
+	connected    int
+}
+
+func init() {
+	registerCollector(bluetoothSubsystem, defaultDisabled, NewBluetoothCollector)
+}
+
+// NewBluetoothCollector returns a new Collector exposing the state of the
+// Bluetooth adapters managed by BlueZ.
+func NewBluetoothCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bluetoothSubsystem, name),
+			help, append([]string{"adapter"}, labels...), nil,
+		)
+	}
+	return &bluetoothCollector{
+		adapterInfo:         desc("adapter_info", "Bluetooth adapter managed by BlueZ, value is always 1.", "address", "alias"),
+		adapterPowered:      desc("adapter_powered", "Whether the Bluetooth adapter is powered on."),
+		adapterDiscoverable: desc("adapter_discoverable", "Whether the Bluetooth adapter is discoverable by other devices."),
+		adapterDiscovering:  desc("adapter_discovering", "Whether the Bluetooth adapter is scanning for devices."),
+		devicesPaired:       desc("devices_paired", "Number of devices paired with the Bluetooth adapter."),
+		devicesConnected:    desc("devices_connected", "Number of devices connected to the Bluetooth adapter."),
+		logger:              logger,
+	}, nil
+}
+
+func (c *bluetoothCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := newSystemBusPrivate()
+	if err != nil {
+		return fmt.Errorf("unable to connect to dbus: %w", err)
+	}
+	defer conn.Close()
+
+	var objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	err = conn.Object(bluezDbusObject, "/").Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&objects)
+	if err != nil {
+		return fmt.Errorf("unable to get BlueZ objects: %w", err)
+	}
+
+	value := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	for _, adapter := range parseBluezObjects(objects) {
+		ch <- prometheus.MustNewConstMetric(c.adapterInfo, prometheus.GaugeValue, 1, adapter.name, adapter.address, adapter.alias)
+		ch <- prometheus.MustNewConstMetric(c.adapterPowered, prometheus.GaugeValue, value(adapter.powered), adapter.name)
+		ch <- prometheus.MustNewConstMetric(c.adapterDiscoverable, prometheus.GaugeValue, value(adapter.discoverable), adapter.name)
+		ch <- prometheus.MustNewConstMetric(c.adapterDiscovering, prometheus.GaugeValue, value(adapter.discovering), adapter.name)
+		ch <- prometheus.MustNewConstMetric(c.devicesPaired, prometheus.GaugeValue, float64(adapter.paired), adapter.name)
+		ch <- prometheus.MustNewConstMetric(c.devicesConnected, prometheus.GaugeValue, float64(adapter.connected), adapter.name)
+	}
+	return nil
+}
+
+// parseBluezObjects returns the adapters among the objects managed by BlueZ,
+// along with the number of paired and connected devices of each.
+func parseBluezObjects(objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant) []bluetoothAdapter {
+	boolProperty := func(props map[string]dbus.Variant, name string) bool {
+		v, _ := props[name].Value().(bool)
+		return v
+	}
+	stringProperty := func(props map[string]dbus.Variant, name string) string {
+		v, _ := props[name].Value().(string)
+		return v
+	}
+
+	adapters := map[dbus.ObjectPath]*bluetoothAdapter{}
+	for objectPath, ifaces := range objects {
+		props, ok := ifaces[bluezDbusObject+".Adapter1"]
+		if !ok {
+			continue
+		}
+		adapters[objectPath] = &bluetoothAdapter{
+			// Adapters are exported as /org/bluez/hciN.
+			name:         path.Base(string(objectPath)),
+			address:      stringProperty(props, "Address"),
+			alias:        stringProperty(props, "Alias"),
+			powered:      boolProperty(props, "Powered"),
+			discoverable: boolProperty(props, "Discoverable"),
+			discovering:  boolProperty(props, "Discovering"),
+		}
+	}
+	for _, ifaces := range objects {
+		props, ok := ifaces[bluezDbusObject+".Device1"]
+		if !ok {
+			continue
+		}
+		adapterPath, _ := props["Adapter"].Value().(dbus.ObjectPath)
+		adapter, ok := adapters[adapterPath]
+		if !ok {
+			continue
+		}
+		if boolProperty(props, "Paired") {
+			adapter.paired++
+		}
+		if boolProperty(props, "Connected") {
+			adapter.connected++
+		}
+	}
+
+	result := make([]bluetoothAdapter, 0, len(adapters))
+	for _, adapter := range adapters {
+		result = append(result, *adapter)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nobluetooth
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestParseBluezObjects(t *testing.T) {
+	device := func(adapter string, paired, connected bool) map[string]map[string]dbus.Variant {
+		return map[string]map[string]dbus.Variant{
+			"org.bluez.Device1": {
+				"Adapter":   dbus.MakeVariant(dbus.ObjectPath(adapter)),
+				"Paired":    dbus.MakeVariant(paired),
+				"Connected": dbus.MakeVariant(connected),
+			},
+		}
+	}
+	objects := map[dbus.ObjectPath]map[string]map[string]dbus.Variant{
+		"/": {"org.freedesktop.DBus.ObjectManager": {}},
+		"/org/bluez/hci0": {
+			"org.bluez.Adapter1": {
+				"Address":      dbus.MakeVariant("00:1A:7D:DA:71:13"),
+				"Alias":        dbus.MakeVariant("kiosk-01"),
+				"Powered":      dbus.MakeVariant(true),
+				"Discoverable": dbus.MakeVariant(false),
+				"Discovering":  dbus.MakeVariant(true),
+			},
+		},
+		"/org/bluez/hci1": {
+			"org.bluez.Adapter1": {
+				"Address": dbus.MakeVariant("5C:F3:70:8B:12:0E"),
+				"Powered": dbus.MakeVariant(false),
+			},
+		},
+		"/org/bluez/hci0/dev_C8_7B_23_11_22_33": device("/org/bluez/hci0", true, true),
+		"/org/bluez/hci0/dev_DC_2C_26_44_55_66": device("/org/bluez/hci0", true, false),
+		"/org/bluez/hci0/dev_F0_99_B6_77_88_99": device("/org/bluez/hci0", false, false),
+	}
+
+	want := []bluetoothAdapter{
+		{name: "hci0", address: "00:1A:7D:DA:71:13", alias: "kiosk-01", powered: true, discovering: true, paired: 2, connected: 1},
+		{name: "hci1", address: "5C:F3:70:8B:12:0E"},
+	}
+	if got := parseBluezObjects(objects); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
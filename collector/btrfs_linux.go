@@ -16,16 +16,81 @@
 package collector
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
 	"github.com/prometheus/procfs/btrfs"
+	"golang.org/x/sys/unix"
 )
 
+// Ioctls of linux/btrfs.h.
+const (
+	btrfsIocScrubProgress   = 0xc400941d
+	btrfsIocFSInfo          = 0x8400941f
+	btrfsIocBalanceProgress = 0x84009422
+
+	btrfsBalanceStateRunning = 1 << 0
+)
+
+// btrfsIoctlFSInfoArgs is the beginning of struct btrfs_ioctl_fs_info_args.
+type btrfsIoctlFSInfoArgs struct {
+	MaxID      uint64
+	NumDevices uint64
+	FSID       [16]byte
+	_          [1024 - 32]byte
+}
+
+// btrfsIoctlScrubArgs is struct btrfs_ioctl_scrub_args.
+type btrfsIoctlScrubArgs struct {
+	DevID               uint64
+	Start               uint64
+	End                 uint64
+	Flags               uint64
+	DataExtentsScrubbed uint64
+	TreeExtentsScrubbed uint64
+	DataBytesScrubbed   uint64
+	TreeBytesScrubbed   uint64
+	ReadErrors          uint64
+	CsumErrors          uint64
+	VerifyErrors        uint64
+	NoCsum              uint64
+	CsumDiscards        uint64
+	SuperErrors         uint64
+	MallocErrors        uint64
+	UncorrectableErrors uint64
+	CorrectedErrors     uint64
+	LastPhysical        uint64
+	UnverifiedErrors    uint64
+	_                   [1024 - 32 - 15*8]byte
+}
+
+// btrfsIoctlBalanceArgs is struct btrfs_ioctl_balance_args without the
+// filters of the block group types, which are 136 bytes each.
+type btrfsIoctlBalanceArgs struct {
+	Flags      uint64
+	State      uint64
+	_          [3 * 136]byte
+	Expected   uint64
+	Considered uint64
+	Completed  uint64
+	_          [1024 - 424 - 24]byte
+}
+
 // A btrfsCollector is a Collector which gathers metrics from Btrfs filesystems.
 type btrfsCollector struct {
 	fs     btrfs.FS
+	procFS procfs.FS
 	logger log.Logger
 }
 
@@ -39,9 +104,14 @@ func NewBtrfsCollector(logger log.Logger) (Collector, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open sysfs: %w", err)
 	}
+	procFS, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
 
 	return &btrfsCollector{
 		fs:     fs,
+		procFS: procFS,
 		logger: logger,
 	}, nil
 }
@@ -54,8 +124,30 @@ func (c *btrfsCollector) Update(ch chan<- prometheus.Metric) error {
 		return fmt.Errorf("failed to retrieve Btrfs stats: %w", err)
 	}
 
+	// Scrubs and balances can only be queried with ioctls on a mount point.
+	mountPoints, err := c.mountPoints()
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to find Btrfs mount points", "err", err)
+	}
+
 	for _, s := range stats {
-		c.updateBtrfsStats(ch, s)
+		metrics := c.getMetrics(s)
+
+		deviceErrors, err := c.getDeviceErrorMetrics(s.UUID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve Btrfs device errors of %s: %w", s.UUID, err)
+		}
+		metrics = append(metrics, deviceErrors...)
+
+		if mountPoint, ok := mountPoints[s.UUID]; ok {
+			progress, err := c.getProgressMetrics(mountPoint)
+			if err != nil {
+				level.Debug(c.logger).Log("msg", "failed to retrieve Btrfs scrub and balance progress", "mountpoint", mountPoint, "err", err)
+			}
+			metrics = append(metrics, progress...)
+		}
+
+		c.updateBtrfsStats(ch, s.UUID, metrics)
 	}
 
 	return nil
@@ -66,20 +158,18 @@ type btrfsMetric struct {
 	name            string
 	desc            string
 	value           float64
+	counter         bool
 	extraLabel      []string
 	extraLabelValue []string
 }
 
-// updateBtrfsStats collects statistics for one bcache ID.
-func (c *btrfsCollector) updateBtrfsStats(ch chan<- prometheus.Metric, s *btrfs.Stats) {
+// updateBtrfsStats exports the metrics of one Btrfs filesystem.
+func (c *btrfsCollector) updateBtrfsStats(ch chan<- prometheus.Metric, uuid string, metrics []btrfsMetric) {
 	const subsystem = "btrfs"
 
 	// Basic information about the filesystem.
 	devLabels := []string{"uuid"}
 
-	// Retrieve the metrics.
-	metrics := c.getMetrics(s)
-
 	// Convert all gathered metrics to Prometheus Metrics and add to channel.
 	for _, m := range metrics {
 		labels := append(devLabels, m.extraLabel...)
@@ -91,14 +181,19 @@ func (c *btrfsCollector) updateBtrfsStats(ch chan<- prometheus.Metric, s *btrfs.
 			nil,
 		)
 
-		labelValues := []string{s.UUID}
+		labelValues := []string{uuid}
 		if len(m.extraLabelValue) > 0 {
 			labelValues = append(labelValues, m.extraLabelValue...)
 		}
 
+		valueType := prometheus.GaugeValue
+		if m.counter {
+			valueType = prometheus.CounterValue
+		}
+
 		ch <- prometheus.MustNewConstMetric(
 			desc,
-			prometheus.GaugeValue,
+			valueType,
 			m.value,
 			labelValues...,
 		)
@@ -187,3 +282,199 @@ func (c *btrfsCollector) getLayoutStats(a, l string, s *btrfs.LayoutUsage) []btr
 		},
 	}
 }
+
+// getDeviceErrorMetrics returns the error counters of the devices of a Btrfs
+// filesystem, which are only available in sysfs since Linux 5.14.
+func (c *btrfsCollector) getDeviceErrorMetrics(uuid string) ([]btrfsMetric, error) {
+	paths, err := filepath.Glob(sysFilePath(filepath.Join("fs/btrfs", uuid, "devinfo/*/error_stats")))
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []btrfsMetric
+	for _, path := range paths {
+		devID := filepath.Base(filepath.Dir(path))
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		errorStats, err := parseBtrfsErrorStats(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for typ, value := range errorStats {
+			metrics = append(metrics, btrfsMetric{
+				name:            "device_errors_total",
+				desc:            "Number of errors of a device that is part of the filesystem by type, e.g. read or corruption.",
+				value:           float64(value),
+				counter:         true,
+				extraLabel:      []string{"devid", "type"},
+				extraLabelValue: []string{devID, typ},
+			})
+		}
+	}
+	return metrics, nil
+}
+
+// parseBtrfsErrorStats parses the error stats of a device, which have a line
+// like "write_errs 0" per type.
+func parseBtrfsErrorStats(r io.Reader) (map[string]uint64, error) {
+	errorStats := map[string]uint64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed line %q", scanner.Text())
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed line %q: %w", scanner.Text(), err)
+		}
+		errorStats[strings.TrimSuffix(fields[0], "_errs")] = value
+	}
+	return errorStats, scanner.Err()
+}
+
+// mountPoints returns a mount point of each mounted Btrfs filesystem by UUID.
+func (c *btrfsCollector) mountPoints() (map[string]string, error) {
+	p, err := c.procFS.Proc(1)
+	if err != nil {
+		// Fall back to the mounts of the exporter if hidepid hides init.
+		if p, err = c.procFS.Self(); err != nil {
+			return nil, err
+		}
+	}
+	mounts, err := p.MountInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	mountPoints := map[string]string{}
+	for _, m := range mounts {
+		if m.FSType != "btrfs" {
+			continue
+		}
+		mountPoint := rootfsFilePath(rootfsStripPrefix(m.MountPoint))
+		var args btrfsIoctlFSInfoArgs
+		if err := btrfsIoctl(mountPoint, btrfsIocFSInfo, unsafe.Pointer(&args)); err != nil {
+			level.Debug(c.logger).Log("msg", "failed to get Btrfs filesystem info", "mountpoint", mountPoint, "err", err)
+			continue
+		}
+		f := args.FSID
+		uuid := fmt.Sprintf("%x-%x-%x-%x-%x", f[0:4], f[4:6], f[6:8], f[8:10], f[10:16])
+		if _, ok := mountPoints[uuid]; !ok {
+			mountPoints[uuid] = mountPoint
+		}
+	}
+	return mountPoints, nil
+}
+
+// getProgressMetrics returns the state of running scrubs and balances of a
+// Btrfs filesystem, which needs CAP_SYS_ADMIN.
+func (c *btrfsCollector) getProgressMetrics(mountPoint string) ([]btrfsMetric, error) {
+	var info btrfsIoctlFSInfoArgs
+	if err := btrfsIoctl(mountPoint, btrfsIocFSInfo, unsafe.Pointer(&info)); err != nil {
+		return nil, err
+	}
+
+	var metrics []btrfsMetric
+	// The device ids have holes after devices were removed.
+	for devID := uint64(1); devID <= info.MaxID; devID++ {
+		args := btrfsIoctlScrubArgs{DevID: devID}
+		err := btrfsIoctl(mountPoint, btrfsIocScrubProgress, unsafe.Pointer(&args))
+		if errors.Is(err, unix.ENODEV) {
+			continue
+		}
+		running := 1.0
+		if errors.Is(err, unix.ENOTCONN) {
+			running = 0
+		} else if err != nil {
+			return metrics, err
+		}
+
+		devLabel := strconv.FormatUint(devID, 10)
+		metrics = append(metrics, btrfsMetric{
+			name:            "scrub_running",
+			desc:            "Whether a scrub of a device that is part of the filesystem is running.",
+			value:           running,
+			extraLabel:      []string{"devid"},
+			extraLabelValue: []string{devLabel},
+		})
+		if running == 0 {
+			continue
+		}
+		metrics = append(metrics, btrfsMetric{
+			name:            "scrub_scrubbed_bytes",
+			desc:            "Number of bytes checked by the running scrub of a device.",
+			value:           float64(args.DataBytesScrubbed + args.TreeBytesScrubbed),
+			extraLabel:      []string{"devid"},
+			extraLabelValue: []string{devLabel},
+		})
+		for typ, value := range map[string]uint64{
+			"read":          args.ReadErrors,
+			"csum":          args.CsumErrors,
+			"verify":        args.VerifyErrors,
+			"super":         args.SuperErrors,
+			"corrected":     args.CorrectedErrors,
+			"uncorrectable": args.UncorrectableErrors,
+		} {
+			metrics = append(metrics, btrfsMetric{
+				name:            "scrub_errors",
+				desc:            "Number of errors found by the running scrub of a device by type.",
+				value:           float64(value),
+				extraLabel:      []string{"devid", "type"},
+				extraLabelValue: []string{devLabel, typ},
+			})
+		}
+	}
+
+	var args btrfsIoctlBalanceArgs
+	err := btrfsIoctl(mountPoint, btrfsIocBalanceProgress, unsafe.Pointer(&args))
+	if err != nil && !errors.Is(err, unix.ENOTCONN) {
+		return metrics, err
+	}
+	running := 0.0
+	if err == nil && args.State&btrfsBalanceStateRunning != 0 {
+		running = 1
+	}
+	metrics = append(metrics, btrfsMetric{
+		name:  "balance_running",
+		desc:  "Whether a balance of the filesystem is running.",
+		value: running,
+	})
+	// A paused balance keeps its progress.
+	if err == nil {
+		metrics = append(metrics,
+			btrfsMetric{
+				name:  "balance_expected_chunks",
+				desc:  "Estimated number of chunks the current balance has to relocate.",
+				value: float64(args.Expected),
+			},
+			btrfsMetric{
+				name:  "balance_considered_chunks",
+				desc:  "Number of chunks the current balance has considered for relocation.",
+				value: float64(args.Considered),
+			},
+			btrfsMetric{
+				name:  "balance_completed_chunks",
+				desc:  "Number of chunks the current balance has relocated.",
+				value: float64(args.Completed),
+			},
+		)
+	}
+	return metrics, nil
+}
+
+// btrfsIoctl calls an ioctl on the mount point of a Btrfs filesystem.
+func btrfsIoctl(mountPoint string, req uintptr, arg unsafe.Pointer) error {
+	fd, err := unix.Open(mountPoint, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -116,3 +116,29 @@ func TestBtrfs(t *testing.T) {
 		}
 	}
 }
+
+func TestBtrfsDeviceErrors(t *testing.T) {
+	*sysPath = "fixtures/sys"
+	collector := &btrfsCollector{}
+
+	metrics, err := collector.getDeviceErrorMetrics("0abb23a9-579b-43e6-ad30-227ef47fcb9d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, m := range metrics {
+		got[strings.Join(m.extraLabelValue, "/")] = m.value
+	}
+	want := map[string]float64{
+		"1/write": 0, "1/read": 0, "1/flush": 0, "1/corruption": 0, "1/generation": 0,
+		"2/write": 12, "2/read": 4, "2/flush": 1, "2/corruption": 37, "2/generation": 0,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d device error metrics, got %v", len(want), got)
+	}
+	for labels, value := range want {
+		if got[labels] != value {
+			t.Errorf("%s: expected %v, got %v", labels, value, got[labels])
+		}
+	}
+}
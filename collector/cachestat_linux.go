@@ -0,0 +1,384 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocachestat
+
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const cachestatSubsystem = "cachestat"
+
+var cachestatCgroups = kingpin.Flag("collector.cachestat.cgroups", "Count page cache events by top level cgroup, requires Linux 5.7 or newer.").Bool()
+
+// cachestatProbes are the kernel functions counted by the collector, in the
+// order of the counters in the map values. Functions were renamed with the
+// folio conversion, the first one found is used.
+var cachestatProbes = []struct {
+	name      string
+	help      string
+	functions []string
+}{
+	{"page_accesses_total", "Number of page cache accesses, including buffer dirties.", []string{"folio_mark_accessed", "mark_page_accessed"}},
+	{"buffer_dirties_total", "Number of buffers marked dirty.", []string{"mark_buffer_dirty"}},
+	{"page_additions_total", "Number of pages added to the page cache, including pages dirtied.", []string{"filemap_add_folio", "add_to_page_cache_lru"}},
+	{"page_dirties_total", "Number of page cache pages dirtied.", []string{"folio_account_dirtied", "account_page_dirtied"}},
+}
+
+type cachestatCollector struct {
+	descs  []*prometheus.Desc
+	mapFD  int
+	logger log.Logger
+}
+
+func init() {
+	registerCollector(cachestatSubsystem, defaultDisabled, NewCachestatCollector)
+}
+
+// NewCachestatCollector returns a new Collector counting page cache accesses
+// and misses with eBPF kprobes, like the cachestat tool of BCC. Misses are
+// page additions minus page dirties, accesses are page accesses minus buffer
+// dirties.
+func NewCachestatCollector(logger log.Logger) (Collector, error) {
+	c := &cachestatCollector{logger: logger}
+	var labels []string
+	if *cachestatCgroups {
+		labels = []string{"cgroup"}
+	}
+	for _, probe := range cachestatProbes {
+		c.descs = append(c.descs, prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cachestatSubsystem, probe.name),
+			probe.help, labels, nil,
+		))
+	}
+
+	var err error
+	c.mapFD, err = bpfCreateMap(unix.BPF_MAP_TYPE_HASH, 8, uint32(8*len(cachestatProbes)), 1024)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create eBPF map: %w", err)
+	}
+	for i, probe := range cachestatProbes {
+		progFD, err := bpfLoadKprobe(cachestatProgram(c.mapFD, i, *cachestatCgroups))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load eBPF program for %s: %w", probe.name, err)
+		}
+		var function string
+		for _, f := range probe.functions {
+			if err = attachKprobe(f, progFD); err == nil {
+				function = f
+				break
+			}
+		}
+		if function == "" {
+			return nil, fmt.Errorf("failed to attach kprobe for %s: %w", probe.name, err)
+		}
+		level.Debug(logger).Log("msg", "attached kprobe", "function", function)
+	}
+	return c, nil
+}
+
+func (c *cachestatCollector) Update(ch chan<- prometheus.Metric) error {
+	names := map[uint64]string{0: "/"}
+	if *cachestatCgroups {
+		// The cgroup ID is the inode number of its directory.
+		dirs, err := ioutil.ReadDir(sysFilePath("fs/cgroup"))
+		if err != nil {
+			return fmt.Errorf("failed to list cgroups: %w", err)
+		}
+		for _, dir := range dirs {
+			if st, ok := dir.Sys().(*syscall.Stat_t); ok && dir.IsDir() {
+				names[st.Ino] = dir.Name()
+			}
+		}
+	}
+
+	key := make([]byte, 8)
+	next := make([]byte, 8)
+	value := make([]byte, 8*len(cachestatProbes))
+	first := true
+	for {
+		if err := bpfMapNextKey(c.mapFD, key, next, first); err != nil {
+			if errors.Is(err, unix.ENOENT) {
+				return nil
+			}
+			return fmt.Errorf("failed to iterate eBPF map: %w", err)
+		}
+		first = false
+		copy(key, next)
+		if err := bpfMapLookup(c.mapFD, key, value); err != nil {
+			if errors.Is(err, unix.ENOENT) {
+				continue
+			}
+			return fmt.Errorf("failed to read eBPF map: %w", err)
+		}
+
+		var labels []string
+		if *cachestatCgroups {
+			name, ok := names[nativeEndian.Uint64(key)]
+			if !ok {
+				// Removed cgroup or one not in the cgroup v2 hierarchy.
+				continue
+			}
+			labels = []string{name}
+		}
+		for i, desc := range c.descs {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(nativeEndian.Uint64(value[i*8:])), labels...)
+		}
+	}
+}
+
+// nativeEndian is the byte order of eBPF instructions and map keys and values.
+var nativeEndian = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// bpfInsn is an encoded eBPF instruction, see /usr/include/linux/bpf.h.
+type bpfInsn [8]byte
+
+func newBPFInsn(code, dst, src uint8, off int16, imm int32) bpfInsn {
+	var insn bpfInsn
+	insn[0] = code
+	// The register nibbles follow the bit field order of the host.
+	if nativeEndian == binary.LittleEndian {
+		insn[1] = dst | src<<4
+	} else {
+		insn[1] = dst<<4 | src
+	}
+	nativeEndian.PutUint16(insn[2:], uint16(off))
+	nativeEndian.PutUint32(insn[4:], uint32(imm))
+	return insn
+}
+
+// cachestatProgram returns an eBPF program incrementing the counter at index
+// in the map value of the cgroup of the current task, or of key 0.
+func cachestatProgram(mapFD, index int, cgroups bool) []bpfInsn {
+	const (
+		r0, r1, r2, r3, r4, r10 = 0, 1, 2, 3, 4, 10
+
+		movImm   = 0xb7
+		movReg   = 0xbf
+		addImm   = 0x07
+		stImm    = 0x7a
+		stxReg   = 0x7b
+		xadd     = 0xdb
+		ldImm64  = 0x18
+		call     = 0x85
+		jeqImm   = 0x15
+		jneImm   = 0x55
+		exit     = 0x95
+		pseudoFD = 1
+
+		mapLookupElem           = 1
+		mapUpdateElem           = 2
+		getCurrentAncestorCgrID = 123
+	)
+	loadMap := []bpfInsn{
+		newBPFInsn(ldImm64, r1, pseudoFD, 0, int32(mapFD)),
+		{},
+	}
+	// The key is stored at r10-8, a zeroed value at r10-40.
+	var prog []bpfInsn
+	if cgroups {
+		prog = append(prog,
+			newBPFInsn(movImm, r1, 0, 0, 1),
+			newBPFInsn(call, 0, 0, 0, getCurrentAncestorCgrID),
+			newBPFInsn(stxReg, r10, r0, -8, 0),
+		)
+	} else {
+		prog = append(prog, newBPFInsn(stImm, r10, 0, -8, 0))
+	}
+	prog = append(prog, loadMap...)
+	prog = append(prog,
+		newBPFInsn(movReg, r2, r10, 0, 0),
+		newBPFInsn(addImm, r2, 0, 0, -8),
+		newBPFInsn(call, 0, 0, 0, mapLookupElem),
+		// Skip creating the entry if it exists.
+		newBPFInsn(jneImm, r0, 0, 18, 0),
+		newBPFInsn(stImm, r10, 0, -40, 0),
+		newBPFInsn(stImm, r10, 0, -32, 0),
+		newBPFInsn(stImm, r10, 0, -24, 0),
+		newBPFInsn(stImm, r10, 0, -16, 0),
+	)
+	prog = append(prog, loadMap...)
+	prog = append(prog,
+		newBPFInsn(movReg, r2, r10, 0, 0),
+		newBPFInsn(addImm, r2, 0, 0, -8),
+		newBPFInsn(movReg, r3, r10, 0, 0),
+		newBPFInsn(addImm, r3, 0, 0, -40),
+		newBPFInsn(movImm, r4, 0, 0, unix.BPF_NOEXIST),
+		newBPFInsn(call, 0, 0, 0, mapUpdateElem),
+	)
+	prog = append(prog, loadMap...)
+	prog = append(prog,
+		newBPFInsn(movReg, r2, r10, 0, 0),
+		newBPFInsn(addImm, r2, 0, 0, -8),
+		newBPFInsn(call, 0, 0, 0, mapLookupElem),
+		newBPFInsn(jeqImm, r0, 0, 2, 0),
+		newBPFInsn(movImm, r1, 0, 0, 1),
+		newBPFInsn(xadd, r0, r1, int16(index*8), 0),
+		newBPFInsn(movImm, r0, 0, 0, 0),
+		newBPFInsn(exit, 0, 0, 0, 0),
+	)
+	return prog
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(fd), nil
+}
+
+func bpfCreateMap(mapType, keySize, valueSize, maxEntries uint32) (int, error) {
+	attr := struct {
+		mapType, keySize, valueSize, maxEntries, flags uint32
+	}{mapType, keySize, valueSize, maxEntries, 0}
+	return bpf(unix.BPF_MAP_CREATE, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+}
+
+func bpfMapNextKey(mapFD int, key, next []byte, first bool) error {
+	attr := struct {
+		mapFD     uint32
+		_         uint32
+		key, next uint64
+	}{mapFD: uint32(mapFD), next: uint64(uintptr(unsafe.Pointer(&next[0])))}
+	if !first {
+		attr.key = uint64(uintptr(unsafe.Pointer(&key[0])))
+	}
+	_, err := bpf(unix.BPF_MAP_GET_NEXT_KEY, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(next)
+	return err
+}
+
+func bpfMapLookup(mapFD int, key, value []byte) error {
+	attr := struct {
+		mapFD      uint32
+		_          uint32
+		key, value uint64
+		flags      uint64
+	}{
+		mapFD: uint32(mapFD),
+		key:   uint64(uintptr(unsafe.Pointer(&key[0]))),
+		value: uint64(uintptr(unsafe.Pointer(&value[0]))),
+	}
+	_, err := bpf(unix.BPF_MAP_LOOKUP_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(value)
+	return err
+}
+
+func bpfLoadKprobe(insns []bpfInsn) (int, error) {
+	license := []byte("Dual MIT/GPL\x00")
+	logBuf := make([]byte, 65536)
+	attr := struct {
+		progType    uint32
+		insnCnt     uint32
+		insns       uint64
+		license     uint64
+		logLevel    uint32
+		logSize     uint32
+		logBuf      uint64
+		kernVersion uint32
+		_           uint32
+	}{
+		progType:    unix.BPF_PROG_TYPE_KPROBE,
+		insnCnt:     uint32(len(insns)),
+		insns:       uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		license:     uint64(uintptr(unsafe.Pointer(&license[0]))),
+		logLevel:    1,
+		logSize:     uint32(len(logBuf)),
+		logBuf:      uint64(uintptr(unsafe.Pointer(&logBuf[0]))),
+		kernVersion: kernelVersionCode(),
+	}
+	fd, err := bpf(unix.BPF_PROG_LOAD, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+	if err != nil {
+		if log := strings.TrimRight(string(logBuf), "\x00"); log != "" {
+			return 0, fmt.Errorf("%w: %s", err, log)
+		}
+		return 0, err
+	}
+	return fd, nil
+}
+
+// attachKprobe attaches an eBPF program to a kernel function with the kprobe
+// PMU, available since Linux 4.17.
+func attachKprobe(function string, progFD int) error {
+	pmuType, err := readUintFromFile(sysFilePath("bus/event_source/devices/kprobe/type"))
+	if err != nil {
+		return fmt.Errorf("kprobe PMU not available: %w", err)
+	}
+	name := append([]byte(function), 0)
+	attr := unix.PerfEventAttr{
+		Type: uint32(pmuType),
+		Ext1: uint64(uintptr(unsafe.Pointer(&name[0]))),
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+	// Kprobes fire on all CPUs regardless of the CPU of the perf event.
+	fd, err := unix.PerfEventOpen(&attr, -1, 0, -1, unix.PERF_FLAG_FD_CLOEXEC)
+	runtime.KeepAlive(name)
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_SET_BPF, progFD); err != nil {
+		unix.Close(fd)
+		return err
+	}
+	if err := unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_ENABLE, 0); err != nil {
+		unix.Close(fd)
+		return err
+	}
+	return nil
+}
+
+// kernelVersionCode returns the running kernel version in the format of
+// LINUX_VERSION_CODE, which kprobe programs had to pass before Linux 5.0.
+func kernelVersionCode() uint32 {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return 0
+	}
+	release := strings.SplitN(bytesToString(uts.Release[:]), "-", 2)[0]
+	var version [3]uint32
+	for i, part := range strings.SplitN(release, ".", 3) {
+		v, _ := strconv.ParseUint(part, 10, 32)
+		if v > 255 {
+			v = 255
+		}
+		version[i] = uint32(v)
+	}
+	return version[0]<<16 | version[1]<<8 | version[2]
+}
@@ -0,0 +1,306 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocarp
+
+package collector
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	IPPROTO_CARP    = 112
+	IPPROTO_PFSYNC  = 240
+	CARPCTL_STATS   = 4
+	PFSYNCCTL_STATS = 1
+
+	// SIOCGVH is _IOWR('i', 246, struct ifreq).
+	SIOCGVH = 0xc02069f6
+	// SIOCGIFGATTR is _IOWR('i', 139, struct ifgroupreq).
+	SIOCGIFGATTR = 0xc028698b
+
+	CARP_MAXNODES = 32
+)
+
+// carpStates are the values of carpr_states.
+var carpStates = []string{"init", "backup", "master"}
+
+type carpreq struct {
+	State    int32
+	Carpdev  [16]byte
+	Vhids    [CARP_MAXNODES]uint8
+	Advskews [CARP_MAXNODES]uint8
+	States   [CARP_MAXNODES]uint8
+	Advbase  int32
+	Key      [20]byte
+	Peer     [4]byte
+	Peer6    [16]byte
+}
+
+type ifreqData struct {
+	Name [unix.IFNAMSIZ]byte
+	Data uintptr
+	_    [8]byte
+}
+
+type ifgroupreq struct {
+	Name    [unix.IFNAMSIZ]byte
+	Len     uint32
+	_       [4]byte
+	Demoted int32
+	_       [12]byte
+}
+
+// carpstats and pfsyncstats consist of uint64 counters only.
+type carpstats struct {
+	Ipackets  uint64
+	Ipackets6 uint64
+	Badif     uint64
+	Badttl    uint64
+	Hdrops    uint64
+	Badsum    uint64
+	Badver    uint64
+	Badlen    uint64
+	Badauth   uint64
+	Badvhid   uint64
+	Badaddrs  uint64
+	Opackets  uint64
+	Opackets6 uint64
+	Onomem    uint64
+	Preempt   uint64
+}
+
+type pfsyncstats struct {
+	Ipackets  uint64
+	Ipackets6 uint64
+	Badif     uint64
+	Badttl    uint64
+	Hdrops    uint64
+	Badver    uint64
+	Badact    uint64
+	Badlen    uint64
+	Badauth   uint64
+	Stale     uint64
+	Badval    uint64
+	Badstate  uint64
+	Opackets  uint64
+	Opackets6 uint64
+	Onomem    uint64
+	Oerrors   uint64
+}
+
+type carpCollector struct {
+	state         *prometheus.Desc
+	advskew       *prometheus.Desc
+	advbase       *prometheus.Desc
+	demotion      *prometheus.Desc
+	carpPackets   *prometheus.Desc
+	carpDropped   *prometheus.Desc
+	carpPreempt   *prometheus.Desc
+	pfsyncPackets *prometheus.Desc
+	pfsyncDropped *prometheus.Desc
+	pfsyncErrors  *prometheus.Desc
+	logger        log.Logger
+}
+
+func init() {
+	registerCollector("carp", defaultDisabled, NewCarpCollector)
+}
+
+// NewCarpCollector returns a new Collector exposing the state of CARP
+// interfaces, the demotion counter of the carp interface group and the
+// packet statistics of CARP and pfsync.
+func NewCarpCollector(logger log.Logger) (Collector, error) {
+	return &carpCollector{
+		state: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "carp", "state"),
+			"State of the virtual host of the CARP interface.",
+			[]string{"device", "vhid", "state"}, nil,
+		),
+		advskew: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "carp", "advskew"),
+			"Skew of the advertisements of the virtual host of the CARP interface.",
+			[]string{"device", "vhid"}, nil,
+		),
+		advbase: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "carp", "advbase_seconds"),
+			"Interval of the advertisements of the CARP interface.",
+			[]string{"device"}, nil,
+		),
+		demotion: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "carp", "demotion"),
+			"Demotion counter of the carp interface group, hosts with a higher counter do not become master.",
+			nil, nil,
+		),
+		carpPackets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "carp", "packets_total"),
+			"Total number of CARP advertisements.",
+			[]string{"direction", "family"}, nil,
+		),
+		carpDropped: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "carp", "dropped_packets_total"),
+			"Total number of received CARP advertisements dropped by reason.",
+			[]string{"reason"}, nil,
+		),
+		carpPreempt: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "carp", "preemptions_total"),
+			"Total number of transitions to master.",
+			nil, nil,
+		),
+		pfsyncPackets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pfsync", "packets_total"),
+			"Total number of pfsync packets.",
+			[]string{"direction", "family"}, nil,
+		),
+		pfsyncDropped: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pfsync", "dropped_packets_total"),
+			"Total number of received pfsync packets dropped by reason.",
+			[]string{"reason"}, nil,
+		),
+		pfsyncErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pfsync", "send_errors_total"),
+			"Total number of pfsync packets which could not be sent.",
+			nil, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *carpCollector) Update(ch chan<- prometheus.Metric) error {
+	if err := c.updateInterfaces(ch); err != nil {
+		return err
+	}
+
+	buf, err := sysctl([]_C_int{unix.CTL_NET, unix.AF_INET, IPPROTO_CARP, CARPCTL_STATS})
+	if err != nil {
+		return fmt.Errorf("failed to get CARP statistics: %w", err)
+	}
+	if len(buf) < int(unsafe.Sizeof(carpstats{})) {
+		return fmt.Errorf("invalid size %d of CARP statistics", len(buf))
+	}
+	carp := *(*carpstats)(unsafe.Pointer(&buf[0]))
+	ch <- prometheus.MustNewConstMetric(c.carpPackets, prometheus.CounterValue, float64(carp.Ipackets), "receive", "inet")
+	ch <- prometheus.MustNewConstMetric(c.carpPackets, prometheus.CounterValue, float64(carp.Ipackets6), "receive", "inet6")
+	ch <- prometheus.MustNewConstMetric(c.carpPackets, prometheus.CounterValue, float64(carp.Opackets), "transmit", "inet")
+	ch <- prometheus.MustNewConstMetric(c.carpPackets, prometheus.CounterValue, float64(carp.Opackets6), "transmit", "inet6")
+	for reason, value := range map[string]uint64{
+		"bad_interface": carp.Badif,
+		"bad_ttl":       carp.Badttl,
+		"short":         carp.Hdrops,
+		"bad_checksum":  carp.Badsum,
+		"bad_version":   carp.Badver,
+		"bad_length":    carp.Badlen,
+		"bad_auth":      carp.Badauth,
+		"bad_vhid":      carp.Badvhid,
+		"bad_address":   carp.Badaddrs,
+	} {
+		ch <- prometheus.MustNewConstMetric(c.carpDropped, prometheus.CounterValue, float64(value), reason)
+	}
+	ch <- prometheus.MustNewConstMetric(c.carpPreempt, prometheus.CounterValue, float64(carp.Preempt))
+
+	buf, err = sysctl([]_C_int{unix.CTL_NET, unix.AF_INET, IPPROTO_PFSYNC, PFSYNCCTL_STATS})
+	if err != nil {
+		return fmt.Errorf("failed to get pfsync statistics: %w", err)
+	}
+	if len(buf) < int(unsafe.Sizeof(pfsyncstats{})) {
+		return fmt.Errorf("invalid size %d of pfsync statistics", len(buf))
+	}
+	pfsync := *(*pfsyncstats)(unsafe.Pointer(&buf[0]))
+	ch <- prometheus.MustNewConstMetric(c.pfsyncPackets, prometheus.CounterValue, float64(pfsync.Ipackets), "receive", "inet")
+	ch <- prometheus.MustNewConstMetric(c.pfsyncPackets, prometheus.CounterValue, float64(pfsync.Ipackets6), "receive", "inet6")
+	ch <- prometheus.MustNewConstMetric(c.pfsyncPackets, prometheus.CounterValue, float64(pfsync.Opackets), "transmit", "inet")
+	ch <- prometheus.MustNewConstMetric(c.pfsyncPackets, prometheus.CounterValue, float64(pfsync.Opackets6), "transmit", "inet6")
+	for reason, value := range map[string]uint64{
+		"bad_interface": pfsync.Badif,
+		"bad_ttl":       pfsync.Badttl,
+		"short":         pfsync.Hdrops,
+		"bad_version":   pfsync.Badver,
+		"bad_action":    pfsync.Badact,
+		"bad_length":    pfsync.Badlen,
+		"bad_auth":      pfsync.Badauth,
+		"stale":         pfsync.Stale,
+		"bad_value":     pfsync.Badval,
+		"bad_state":     pfsync.Badstate,
+	} {
+		ch <- prometheus.MustNewConstMetric(c.pfsyncDropped, prometheus.CounterValue, float64(value), reason)
+	}
+	ch <- prometheus.MustNewConstMetric(c.pfsyncErrors, prometheus.CounterValue, float64(pfsync.Onomem+pfsync.Oerrors))
+	return nil
+}
+
+func (c *carpCollector) updateInterfaces(ch chan<- prometheus.Metric) error {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return fmt.Errorf("net.Interfaces() failed: %w", err)
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	for _, iface := range ifaces {
+		if !strings.HasPrefix(iface.Name, "carp") {
+			continue
+		}
+		var carpr carpreq
+		var ifr ifreqData
+		copy(ifr.Name[:], iface.Name)
+		ifr.Data = uintptr(unsafe.Pointer(&carpr))
+		if err := carpIoctl(fd, SIOCGVH, unsafe.Pointer(&ifr)); err != nil {
+			return fmt.Errorf("SIOCGVH failed for %s: %w", iface.Name, err)
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.advbase, prometheus.GaugeValue, float64(carpr.Advbase), iface.Name)
+		// The list of virtual hosts, used for load balancing, ends with 0.
+		for i := 0; i < CARP_MAXNODES && carpr.Vhids[i] != 0; i++ {
+			vhid := strconv.Itoa(int(carpr.Vhids[i]))
+			for state, name := range carpStates {
+				var value float64
+				if int(carpr.States[i]) == state {
+					value = 1
+				}
+				ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, value, iface.Name, vhid, name)
+			}
+			ch <- prometheus.MustNewConstMetric(c.advskew, prometheus.GaugeValue, float64(carpr.Advskews[i]), iface.Name, vhid)
+		}
+	}
+
+	var ifgr ifgroupreq
+	copy(ifgr.Name[:], "carp")
+	if err := carpIoctl(fd, SIOCGIFGATTR, unsafe.Pointer(&ifgr)); err != nil {
+		// The group only exists while CARP interfaces are configured.
+		if err == unix.ENOENT {
+			return nil
+		}
+		return fmt.Errorf("SIOCGIFGATTR failed: %w", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.demotion, prometheus.GaugeValue, float64(ifgr.Demoted))
+	return nil
+}
+
+func carpIoctl(fd int, req uint, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocertificate
+
+package collector
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const certificateSubsystem = "certificate"
+
+var certificatePaths = kingpin.Flag("collector.certificate.path", "Glob of PEM encoded certificate files to export the validity of, can be repeated.").Strings()
+
+type certificateCollector struct {
+	notBefore  *prometheus.Desc
+	notAfter   *prometheus.Desc
+	parseError *prometheus.Desc
+	logger     log.Logger
+}
+
+func init() {
+	registerCollector(certificateSubsystem, defaultDisabled, NewCertificateCollector)
+}
+
+// NewCertificateCollector returns a new Collector exposing the validity of
+// certificate files.
+func NewCertificateCollector(logger log.Logger) (Collector, error) {
+	for _, pattern := range *certificatePaths {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid certificate path %q: %w", pattern, err)
+		}
+	}
+	labels := []string{"path", "subject", "issuer", "serial"}
+	return &certificateCollector{
+		notBefore: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, certificateSubsystem, "not_before_timestamp_seconds"),
+			"Time from which the certificate is valid.",
+			labels, nil,
+		),
+		notAfter: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, certificateSubsystem, "not_after_timestamp_seconds"),
+			"Time until which the certificate is valid.",
+			labels, nil,
+		),
+		parseError: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, certificateSubsystem, "parse_error"),
+			"Whether the file could not be read or holds invalid certificates.",
+			[]string{"path"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *certificateCollector) Update(ch chan<- prometheus.Metric) error {
+	if len(*certificatePaths) == 0 {
+		level.Debug(c.logger).Log("msg", "no certificate paths configured")
+		return ErrNoData
+	}
+
+	seen := map[string]bool{}
+	for _, pattern := range *certificatePaths {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+
+			certs, err := readCertificates(path)
+			if err != nil {
+				level.Debug(c.logger).Log("msg", "failed to read certificates", "path", path, "err", err)
+				ch <- prometheus.MustNewConstMetric(c.parseError, prometheus.GaugeValue, 1, path)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.parseError, prometheus.GaugeValue, 0, path)
+			for _, cert := range certs {
+				labels := []string{path, cert.Subject.String(), cert.Issuer.String(), cert.SerialNumber.String()}
+				ch <- prometheus.MustNewConstMetric(c.notBefore, prometheus.GaugeValue, float64(cert.NotBefore.Unix()), labels...)
+				ch <- prometheus.MustNewConstMetric(c.notAfter, prometheus.GaugeValue, float64(cert.NotAfter.Unix()), labels...)
+			}
+		}
+	}
+	return nil
+}
+
+// readCertificates returns the certificates of a PEM file, e.g. a chain.
+// Other blocks like private keys are skipped.
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
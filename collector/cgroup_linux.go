@@ -0,0 +1,112 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocgroup
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const cgroupSubsystem = "cgroup"
+
+type cgroupCollector struct {
+	memoryEvents *prometheus.Desc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector(cgroupSubsystem, defaultDisabled, NewCgroupCollector)
+}
+
+// NewCgroupCollector returns a new Collector exposing statistics of the top
+// level cgroups of the cgroup v2 hierarchy.
+func NewCgroupCollector(logger log.Logger) (Collector, error) {
+	return &cgroupCollector{
+		memoryEvents: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cgroupSubsystem, "memory_events_total"),
+			"Number of memory events of the cgroup and its descendants from memory.events, e.g. oom_kill.",
+			[]string{"cgroup", "event"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *cgroupCollector) Update(ch chan<- prometheus.Metric) error {
+	root := sysFilePath("fs/cgroup")
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "cgroup v2 hierarchy not mounted", "path", root)
+			return ErrNoData
+		}
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return fmt.Errorf("failed to list cgroups: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		events, err := parseCgroupKeyValues(filepath.Join(root, entry.Name(), "memory.events"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// The memory controller isn't enabled for this cgroup.
+				continue
+			}
+			return fmt.Errorf("failed to read memory events of cgroup %q: %w", entry.Name(), err)
+		}
+		for event, value := range events {
+			ch <- prometheus.MustNewConstMetric(c.memoryEvents, prometheus.CounterValue, float64(value), entry.Name(), event)
+		}
+	}
+	return nil
+}
+
+// parseCgroupKeyValues parses a flat keyed cgroup file like memory.events
+// with lines of the form "<key> <value>".
+func parseCgroupKeyValues(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in line %q: %w", scanner.Text(), err)
+		}
+		values[fields[0]] = value
+	}
+	return values, scanner.Err()
+}
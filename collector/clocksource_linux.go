@@ -0,0 +1,140 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noclocksource
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const clocksourceSubsystem = "clocksource"
+
+type clocksourceCollector struct {
+	available *prometheus.Desc
+	current   *prometheus.Desc
+	changes   *prometheus.Desc
+	offset    *prometheus.Desc
+	logger    log.Logger
+
+	mtx         sync.Mutex
+	lastCurrent map[string]string
+	changeCount map[string]uint64
+}
+
+func init() {
+	registerCollector(clocksourceSubsystem, defaultDisabled, NewClocksourceCollector)
+}
+
+// NewClocksourceCollector returns a new Collector exposing the clocksources of
+// the kernel timekeeping and the offset between the realtime and the
+// monotonic clock.
+func NewClocksourceCollector(logger log.Logger) (Collector, error) {
+	return &clocksourceCollector{
+		available: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clocksourceSubsystem, "available_info"),
+			"Clocksources the kernel can use for timekeeping.",
+			[]string{"device", "clocksource"}, nil,
+		),
+		current: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clocksourceSubsystem, "current_info"),
+			"Clocksource the kernel uses for timekeeping.",
+			[]string{"device", "clocksource"}, nil,
+		),
+		changes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clocksourceSubsystem, "changes_total"),
+			"Number of times the current clocksource was seen changing since the exporter started, e.g. the kernel falling back from an unstable tsc.",
+			[]string{"device"}, nil,
+		),
+		offset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, clocksourceSubsystem, "realtime_monotonic_offset_seconds"),
+			"Offset of CLOCK_REALTIME to CLOCK_MONOTONIC, changes are steps or slewing of the realtime clock.",
+			nil, nil,
+		),
+		logger:      logger,
+		lastCurrent: map[string]string{},
+		changeCount: map[string]uint64{},
+	}, nil
+}
+
+func (c *clocksourceCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("devices/system/clocksource/clocksource[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		level.Debug(c.logger).Log("msg", "no clocksource devices found")
+		return ErrNoData
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, device := range devices {
+		name := filepath.Base(device)
+		available, err := ioutil.ReadFile(filepath.Join(device, "available_clocksource"))
+		if err != nil {
+			return fmt.Errorf("failed to read available clocksources of %s: %w", name, err)
+		}
+		current, err := ioutil.ReadFile(filepath.Join(device, "current_clocksource"))
+		if err != nil {
+			return fmt.Errorf("failed to read current clocksource of %s: %w", name, err)
+		}
+
+		for _, cs := range strings.Fields(string(available)) {
+			ch <- prometheus.MustNewConstMetric(c.available, prometheus.GaugeValue, 1, name, cs)
+		}
+		cs := strings.TrimSpace(string(current))
+		ch <- prometheus.MustNewConstMetric(c.current, prometheus.GaugeValue, 1, name, cs)
+
+		if last, ok := c.lastCurrent[name]; ok && last != cs {
+			c.changeCount[name]++
+		}
+		c.lastCurrent[name] = cs
+		ch <- prometheus.MustNewConstMetric(c.changes, prometheus.CounterValue, float64(c.changeCount[name]), name)
+	}
+
+	offset, err := clockRealtimeMonotonicOffset()
+	if err != nil {
+		return fmt.Errorf("failed to read clocks: %w", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.offset, prometheus.GaugeValue, offset)
+	return nil
+}
+
+// clockRealtimeMonotonicOffset returns the offset of the realtime clock to the
+// monotonic clock, which is read before and after to cancel out the time
+// between the reads.
+func clockRealtimeMonotonicOffset() (float64, error) {
+	var before, realtime, after unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &before); err != nil {
+		return 0, err
+	}
+	if err := unix.ClockGettime(unix.CLOCK_REALTIME, &realtime); err != nil {
+		return 0, err
+	}
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &after); err != nil {
+		return 0, err
+	}
+	monotonic := (before.Nano() + after.Nano()) / 2
+	return float64(realtime.Nano()-monotonic) / 1e9, nil
+}
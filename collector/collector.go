@@ -17,6 +17,7 @@ package collector
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -42,8 +43,23 @@ var (
 		[]string{"collector"},
 		nil,
 	)
+	scrapeQueueWaitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_queue_wait_seconds"),
+		"node_exporter: Time a collector waited for a free worker before its scrape started.",
+		[]string{"collector"},
+		nil,
+	)
 )
 
+var (
+	collectorMaxParallel = kingpin.Flag("collector.max-parallel", "Maximum number of collectors running in parallel during a scrape, 0 runs all of them at once.").Default("0").Int()
+	collectorTimeout     = kingpin.Flag("collector.timeout", "Duration after which a collector is marked as failed and its remaining metrics are dropped from the scrape, 0 disables the timeout.").Default("0s").Duration()
+)
+
+// errCollectorTimeout indicates the collector did not finish within the
+// --collector.timeout.
+var errCollectorTimeout = errors.New("collector timed out")
+
 const (
 	defaultEnabled  = true
 	defaultDisabled = false
@@ -79,6 +95,7 @@ func registerCollector(collector string, isDefaultEnabled bool, factory func(log
 type NodeCollector struct {
 	Collectors map[string]Collector
 	logger     log.Logger
+	optional   map[string]bool // skipped under memory pressure
 }
 
 // DisableDefaultCollectors sets the collector state to false for all collectors which
@@ -134,31 +151,78 @@ func NewNodeCollector(logger log.Logger, filters ...string) (*NodeCollector, err
 			initiatedCollectors[key] = collector
 		}
 	}
-	return &NodeCollector{Collectors: collectors, logger: logger}, nil
+	return &NodeCollector{Collectors: collectors, logger: logger, optional: optionalCollectors()}, nil
 }
 
 // Describe implements the prometheus.Collector interface.
 func (n NodeCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- scrapeDurationDesc
 	ch <- scrapeSuccessDesc
+	ch <- scrapeQueueWaitDesc
+	ch <- scrapeCPUDesc
+	ch <- scrapeAllocatedDesc
+	ch <- scrapeMemoryDegradedDesc
 }
 
-// Collect implements the prometheus.Collector interface.
+// Collect implements the prometheus.Collector interface. The collectors are
+// run by a pool of --collector.max-parallel workers, the time a collector
+// waits for a worker is exposed separately from its duration. Close to the
+// memory limit the optional collectors are skipped for the scrape.
 func (n NodeCollector) Collect(ch chan<- prometheus.Metric) {
+	names := make([]string, 0, len(n.Collectors))
+	limited, degraded := memoryDegraded()
+	var skipped []string
+	for name := range n.Collectors {
+		if degraded && n.optional[name] {
+			skipped = append(skipped, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	if limited {
+		var value float64
+		if degraded {
+			value = 1
+			level.Warn(n.logger).Log("msg", "memory usage close to the limit, skipping optional collectors", "skipped", strings.Join(skipped, ","))
+		}
+		ch <- prometheus.MustNewConstMetric(scrapeMemoryDegradedDesc, prometheus.GaugeValue, value)
+	}
+
+	workers := *collectorMaxParallel
+	if workers <= 0 || workers > len(names) {
+		workers = len(names)
+	}
+
+	begin := time.Now()
+	queue := make(chan string)
 	wg := sync.WaitGroup{}
-	wg.Add(len(n.Collectors))
-	for name, c := range n.Collectors {
-		go func(name string, c Collector) {
-			execute(name, c, ch, n.logger)
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for name := range queue {
+				execute(name, n.Collectors[name], ch, n.logger, time.Since(begin), *collectorTimeout)
+			}
 			wg.Done()
-		}(name, c)
+		}()
+	}
+	for _, name := range names {
+		queue <- name
 	}
+	close(queue)
 	wg.Wait()
 }
 
-func execute(name string, c Collector, ch chan<- prometheus.Metric, logger log.Logger) {
+func execute(name string, c Collector, ch chan<- prometheus.Metric, logger log.Logger, wait, timeout time.Duration) {
+	if *selfProfileEnabled {
+		c = profiledCollector{name: name, c: c}
+	}
 	begin := time.Now()
-	err := c.Update(ch)
+	var err error
+	if timeout > 0 {
+		err = updateWithTimeout(c, ch, timeout)
+	} else {
+		err = c.Update(ch)
+	}
 	duration := time.Since(begin)
 	var success float64
 
@@ -166,7 +230,7 @@ func execute(name string, c Collector, ch chan<- prometheus.Metric, logger log.L
 		if IsNoDataError(err) {
 			level.Debug(logger).Log("msg", "collector returned no data", "name", name, "duration_seconds", duration.Seconds(), "err", err)
 		} else {
-			level.Error(logger).Log("msg", "collector failed", "name", name, "duration_seconds", duration.Seconds(), "err", err)
+			level.Error(logger).Log("msg", "collector failed", "name", name, "duration_seconds", duration.Seconds(), "queue_wait_seconds", wait.Seconds(), "err", err)
 		}
 		success = 0
 	} else {
@@ -175,6 +239,41 @@ func execute(name string, c Collector, ch chan<- prometheus.Metric, logger log.L
 	}
 	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), name)
 	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, name)
+	ch <- prometheus.MustNewConstMetric(scrapeQueueWaitDesc, prometheus.GaugeValue, wait.Seconds(), name)
+	if *selfProfileEnabled {
+		collectProfile(name, ch)
+	}
+}
+
+// updateWithTimeout forwards the metrics of the collector until it finishes or
+// the timeout expires. A collector which times out keeps running in the
+// background, its remaining metrics are discarded as they must not be sent
+// after Collect returned.
+func updateWithTimeout(c Collector, ch chan<- prometheus.Metric, timeout time.Duration) error {
+	metrics := make(chan prometheus.Metric)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Update(metrics)
+		close(metrics)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case m, ok := <-metrics:
+			if !ok {
+				return <-done
+			}
+			ch <- m
+		case <-timer.C:
+			go func() {
+				for range metrics {
+				}
+			}()
+			return errCollectorTimeout
+		}
+	}
 }
 
 // Collector is the interface a collector has to implement.
@@ -0,0 +1,168 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type sleepCollector struct {
+	sleep time.Duration
+}
+
+func (c sleepCollector) Update(ch chan<- prometheus.Metric) error {
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, 1, "before")
+	time.Sleep(c.sleep)
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, 1, "after")
+	return nil
+}
+
+func TestUpdateWithTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		sleep   time.Duration
+		err     error
+		metrics int
+	}{
+		{sleep: 0, err: nil, metrics: 2},
+		{sleep: time.Second, err: errCollectorTimeout, metrics: 1},
+	} {
+		ch := make(chan prometheus.Metric, 2)
+		err := updateWithTimeout(sleepCollector{tc.sleep}, ch, 100*time.Millisecond)
+		if err != tc.err {
+			t.Errorf("sleep %s: expected error %v, got %v", tc.sleep, tc.err, err)
+		}
+		close(ch)
+		if n := len(ch); n != tc.metrics {
+			t.Errorf("sleep %s: expected %d metrics, got %d", tc.sleep, tc.metrics, n)
+		}
+	}
+}
+
+func TestCollectMaxParallel(t *testing.T) {
+	*collectorMaxParallel = 1
+	defer func() { *collectorMaxParallel = 0 }()
+
+	n := NodeCollector{Collectors: map[string]Collector{
+		"a": sleepCollector{50 * time.Millisecond},
+		"b": sleepCollector{50 * time.Millisecond},
+	}, logger: log.NewNopLogger()}
+	ch := make(chan prometheus.Metric, 16)
+	n.Collect(ch)
+	close(ch)
+
+	var waited bool
+	for m := range ch {
+		if m.Desc() != scrapeQueueWaitDesc {
+			continue
+		}
+		var metric dto.Metric
+		if err := m.Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		if metric.GetGauge().GetValue() >= 0.05 {
+			waited = true
+		}
+	}
+	if !waited {
+		t.Error("expected one collector to wait for the other with a single worker")
+	}
+}
+
+func TestSelfProfile(t *testing.T) {
+	enabled := *selfProfileEnabled
+	*selfProfileEnabled = true
+	defer func() { *selfProfileEnabled = enabled }()
+
+	ch := make(chan prometheus.Metric, 16)
+	execute("profiled", sleepCollector{10 * time.Millisecond}, ch, log.NewNopLogger(), 0, 0)
+	close(ch)
+
+	var allocated bool
+	for m := range ch {
+		if m.Desc() == scrapeAllocatedDesc {
+			allocated = true
+		}
+	}
+	if !allocated {
+		t.Error("expected the allocated bytes of the collector")
+	}
+}
+
+func TestCollectMemoryDegraded(t *testing.T) {
+	limit, limitSet := os.LookupEnv("GOMEMLIMIT")
+	os.Setenv("GOMEMLIMIT", "1KiB")
+	defer func() {
+		if limitSet {
+			os.Setenv("GOMEMLIMIT", limit)
+		} else {
+			os.Unsetenv("GOMEMLIMIT")
+		}
+	}()
+	threshold := *memoryLimitThreshold
+	*memoryLimitThreshold = 0.9
+	defer func() { *memoryLimitThreshold = threshold }()
+
+	n := NodeCollector{Collectors: map[string]Collector{
+		"optional": sleepCollector{},
+		"required": sleepCollector{},
+	}, logger: log.NewNopLogger(), optional: map[string]bool{"optional": true}}
+	ch := make(chan prometheus.Metric, 16)
+	n.Collect(ch)
+	close(ch)
+
+	var degraded, collected float64
+	for m := range ch {
+		var metric dto.Metric
+		if err := m.Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		switch m.Desc() {
+		case scrapeMemoryDegradedDesc:
+			degraded = metric.GetGauge().GetValue()
+		case scrapeDurationDesc:
+			collected++
+		}
+	}
+	if degraded != 1 {
+		t.Errorf("expected the scrape to be degraded, got %v", degraded)
+	}
+	if collected != 1 {
+		t.Errorf("expected only the required collector to run, got %v collectors", collected)
+	}
+}
+
+func TestParseMemoryLimit(t *testing.T) {
+	for value, expected := range map[string]uint64{
+		"":           0,
+		"off":        0,
+		"1048576":    1 << 20,
+		"512MiB":     512 << 20,
+		"2GiB":       2 << 30,
+		"100B":       100,
+		"1.5GiB":     0,
+		"-1":         0,
+		"9999999TiB": 0,
+	} {
+		limit, _ := parseMemoryLimit(value)
+		if limit != expected {
+			t.Errorf("%q: expected %d, got %d", value, expected, limit)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocompaction
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const compactionSubsystem = "compaction"
+
+type compactionCollector struct {
+	events        *prometheus.Desc
+	fragmentation *prometheus.Desc
+	logger        log.Logger
+}
+
+type extfragIndex struct {
+	node    string
+	zone    string
+	indexes []float64
+}
+
+func init() {
+	registerCollector(compactionSubsystem, defaultDisabled, NewCompactionCollector)
+}
+
+// NewCompactionCollector returns a new Collector exposing memory compaction
+// statistics and the external fragmentation index of memory zones.
+func NewCompactionCollector(logger log.Logger) (Collector, error) {
+	return &compactionCollector{
+		events: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, compactionSubsystem, "events_total"),
+			"Number of memory compaction events and scanned pages from /proc/vmstat, e.g. stall or fail.",
+			[]string{"event"}, nil,
+		),
+		fragmentation: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, compactionSubsystem, "fragmentation_index"),
+			"External fragmentation index of the zone for allocations of the order, towards 0 failures are due to lack of memory, towards 1 due to fragmentation, -1 if the allocation would succeed.",
+			[]string{"node", "zone", "order"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *compactionCollector) Update(ch chan<- prometheus.Metric) error {
+	events, err := readVmstatFields("compact_")
+	if err != nil {
+		return fmt.Errorf("failed to read vmstat: %w", err)
+	}
+	for event, value := range events {
+		ch <- prometheus.MustNewConstMetric(c.events, prometheus.CounterValue, float64(value), event)
+	}
+
+	// The fragmentation index is only available in debugfs.
+	f, err := os.Open(sysFilePath("kernel/debug/extfrag/extfrag_index"))
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "fragmentation index not available, debugfs not mounted or not readable", "err", err)
+		return nil
+	}
+	defer f.Close()
+
+	zones, err := parseExtfragIndex(f)
+	if err != nil {
+		return fmt.Errorf("failed to parse fragmentation index: %w", err)
+	}
+	for _, zone := range zones {
+		for order, index := range zone.indexes {
+			ch <- prometheus.MustNewConstMetric(c.fragmentation, prometheus.GaugeValue, index, zone.node, zone.zone, strconv.Itoa(order))
+		}
+	}
+	return nil
+}
+
+// parseExtfragIndex parses lines like
+// "Node 0, zone   Normal -1.000 -1.000 0.923 0.961" with an index per order.
+func parseExtfragIndex(r io.Reader) ([]extfragIndex, error) {
+	var zones []extfragIndex
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] != "Node" || fields[2] != "zone" {
+			return nil, fmt.Errorf("invalid line %q", scanner.Text())
+		}
+		zone := extfragIndex{
+			node: strings.TrimSuffix(fields[1], ","),
+			zone: fields[3],
+		}
+		for _, field := range fields[4:] {
+			index, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index in line %q: %w", scanner.Text(), err)
+			}
+			zone.indexes = append(zone.indexes, index)
+		}
+		zones = append(zones, zone)
+	}
+	return zones, scanner.Err()
+}
@@ -16,14 +16,24 @@
 package collector
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	conntrackPerCPU         = kingpin.Flag("collector.conntrack.per-cpu", "Expose the found, invalid and drop conntrack statistics per CPU.").Bool()
+	conntrackEntriesByState = kingpin.Flag("collector.conntrack.entries-by-state", "Expose the number of conntrack entries by protocol and state from /proc/net/nf_conntrack. This is expensive for large tables.").Bool()
 )
 
 type conntrackCollector struct {
@@ -37,6 +47,10 @@ type conntrackCollector struct {
 	drop          *prometheus.Desc
 	earlyDrop     *prometheus.Desc
 	searchRestart *prometheus.Desc
+	cpuFound      *prometheus.Desc
+	cpuInvalid    *prometheus.Desc
+	cpuDrop       *prometheus.Desc
+	stateEntries  *prometheus.Desc
 	logger        log.Logger
 }
 
@@ -108,6 +122,26 @@ func NewConntrackCollector(logger log.Logger) (Collector, error) {
 			"Number of conntrack table lookups which had to be restarted due to hashtable resizes.",
 			nil, nil,
 		),
+		cpuFound: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "nf_conntrack_stat_cpu_found"),
+			"Number of searched entries which were successful, per CPU.",
+			[]string{"cpu"}, nil,
+		),
+		cpuInvalid: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "nf_conntrack_stat_cpu_invalid"),
+			"Number of packets seen which can not be tracked, per CPU.",
+			[]string{"cpu"}, nil,
+		),
+		cpuDrop: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "nf_conntrack_stat_cpu_drop"),
+			"Number of packets dropped due to conntrack failure, per CPU.",
+			[]string{"cpu"}, nil,
+		),
+		stateEntries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "nf_conntrack_protocol_entries"),
+			"Number of connection tracking entries by protocol and state.",
+			[]string{"protocol", "state"}, nil,
+		),
 		logger: logger,
 	}, nil
 }
@@ -127,7 +161,7 @@ func (c *conntrackCollector) Update(ch chan<- prometheus.Metric) error {
 	ch <- prometheus.MustNewConstMetric(
 		c.limit, prometheus.GaugeValue, float64(value))
 
-	conntrackStats, err := getConntrackStatistics()
+	conntrackStats, cpuStats, err := getConntrackStatistics()
 	if err != nil {
 		return c.handleErr(err)
 	}
@@ -148,6 +182,30 @@ func (c *conntrackCollector) Update(ch chan<- prometheus.Metric) error {
 		c.earlyDrop, prometheus.GaugeValue, float64(conntrackStats.earlyDrop))
 	ch <- prometheus.MustNewConstMetric(
 		c.searchRestart, prometheus.GaugeValue, float64(conntrackStats.searchRestart))
+
+	if *conntrackPerCPU {
+		// The kernel lists one line per possible CPU, in order.
+		for i, cpuStat := range cpuStats {
+			cpu := strconv.Itoa(i)
+			ch <- prometheus.MustNewConstMetric(
+				c.cpuFound, prometheus.GaugeValue, float64(cpuStat.Found), cpu)
+			ch <- prometheus.MustNewConstMetric(
+				c.cpuInvalid, prometheus.GaugeValue, float64(cpuStat.Invalid), cpu)
+			ch <- prometheus.MustNewConstMetric(
+				c.cpuDrop, prometheus.GaugeValue, float64(cpuStat.Drop), cpu)
+		}
+	}
+
+	if *conntrackEntriesByState {
+		entries, err := getConntrackEntriesByState(procFilePath("net/nf_conntrack"))
+		if err != nil {
+			return c.handleErr(err)
+		}
+		for key, count := range entries {
+			ch <- prometheus.MustNewConstMetric(
+				c.stateEntries, prometheus.GaugeValue, float64(count), key.protocol, key.state)
+		}
+	}
 	return nil
 }
 
@@ -159,17 +217,17 @@ func (c *conntrackCollector) handleErr(err error) error {
 	return fmt.Errorf("failed to retrieve conntrack stats: %w", err)
 }
 
-func getConntrackStatistics() (*conntrackStatistics, error) {
+func getConntrackStatistics() (*conntrackStatistics, []procfs.ConntrackStatEntry, error) {
 	c := conntrackStatistics{}
 
 	fs, err := procfs.NewFS(*procPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open procfs: %w", err)
+		return nil, nil, fmt.Errorf("failed to open procfs: %w", err)
 	}
 
 	connStats, err := fs.ConntrackStat()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, connStat := range connStats {
@@ -183,5 +241,42 @@ func getConntrackStatistics() (*conntrackStatistics, error) {
 		c.searchRestart += connStat.SearchRestart
 	}
 
-	return &c, nil
+	return &c, connStats, nil
+}
+
+type conntrackStateKey struct {
+	protocol string
+	state    string
+}
+
+func getConntrackEntriesByState(fileName string) (map[conntrackStateKey]uint64, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseConntrackEntriesByState(file)
+}
+
+// parseConntrackEntriesByState counts the entries of /proc/net/nf_conntrack by
+// the protocol name in the third field. Only connection oriented protocols
+// have a state, which follows the timeout in the sixth field.
+func parseConntrackEntriesByState(r io.Reader) (map[conntrackStateKey]uint64, error) {
+	entries := map[conntrackStateKey]uint64{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("invalid line in nf_conntrack: %q", scanner.Text())
+		}
+		key := conntrackStateKey{protocol: fields[2]}
+		if !strings.Contains(fields[5], "=") {
+			key.state = strings.ToLower(fields[5])
+		}
+		entries[key]++
+	}
+
+	return entries, scanner.Err()
 }
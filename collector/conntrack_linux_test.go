@@ -0,0 +1,43 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noconntrack
+
+package collector
+
+import (
+	"testing"
+)
+
+func TestConntrackEntriesByState(t *testing.T) {
+	entries, err := getConntrackEntriesByState("fixtures/proc/net/nf_conntrack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[conntrackStateKey]uint64{
+		{protocol: "tcp", state: "established"}: 2,
+		{protocol: "tcp", state: "time_wait"}:   1,
+		{protocol: "tcp", state: "syn_sent"}:    1,
+		{protocol: "udp"}:                       2,
+		{protocol: "icmp"}:                      1,
+	} {
+		if got := entries[key]; want != got {
+			t.Errorf("want %+v entries to be %d, got %d", key, want, got)
+		}
+	}
+
+	if want, got := 5, len(entries); want != got {
+		t.Errorf("want %d protocol/state combinations, got %d", want, got)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nocpu
+// +build cgo
+
+package collector
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+#cgo LDFLAGS: -lperfstat
+#include <libperfstat.h>
+#include <unistd.h>
+*/
+import "C"
+
+type cpuCollector struct {
+	cpu    typedDesc
+	logger log.Logger
+}
+
+func init() {
+	registerCollector("cpu", defaultEnabled, NewCPUCollector)
+}
+
+// NewCPUCollector returns a new Collector exposing CPU stats of the logical
+// processors from perfstat.
+func NewCPUCollector(logger log.Logger) (Collector, error) {
+	return &cpuCollector{
+		cpu:    typedDesc{nodeCPUSecondsDesc, prometheus.CounterValue},
+		logger: logger,
+	}, nil
+}
+
+func (c *cpuCollector) Update(ch chan<- prometheus.Metric) error {
+	n := C.perfstat_cpu(nil, nil, C.sizeof_perfstat_cpu_t, 0)
+	if n <= 0 {
+		return errors.New("perfstat_cpu failed")
+	}
+	cpus := make([]C.perfstat_cpu_t, n)
+	// The zero value of the name is FIRST_CPU.
+	var first C.perfstat_id_t
+	n = C.perfstat_cpu(&first, &cpus[0], C.sizeof_perfstat_cpu_t, n)
+	if n <= 0 {
+		return errors.New("perfstat_cpu failed")
+	}
+
+	hz := float64(C.sysconf(C._SC_CLK_TCK))
+	for i, cpu := range cpus[:n] {
+		lcpu := strconv.Itoa(i)
+		ch <- c.cpu.mustNewConstMetric(float64(cpu.user)/hz, lcpu, "user")
+		ch <- c.cpu.mustNewConstMetric(float64(cpu.sys)/hz, lcpu, "system")
+		ch <- c.cpu.mustNewConstMetric(float64(cpu.idle)/hz, lcpu, "idle")
+		ch <- c.cpu.mustNewConstMetric(float64(cpu.wait)/hz, lcpu, "iowait")
+	}
+	return nil
+}
@@ -23,10 +23,13 @@ import (
 	"encoding/binary"
 	"fmt"
 	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
 )
 
 /*
@@ -50,8 +53,9 @@ import "C"
 const ClocksPerSec = float64(C.CLK_TCK)
 
 type statCollector struct {
-	cpu    *prometheus.Desc
-	logger log.Logger
+	cpu       *prometheus.Desc
+	perflevel *prometheus.Desc
+	logger    log.Logger
 }
 
 func init() {
@@ -61,7 +65,12 @@ func init() {
 // NewCPUCollector returns a new Collector exposing CPU stats.
 func NewCPUCollector(logger log.Logger) (Collector, error) {
 	return &statCollector{
-		cpu:    nodeCPUSecondsDesc,
+		cpu: nodeCPUSecondsDesc,
+		perflevel: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "perflevel_info"),
+			"Performance level of the CPU on machines with performance and efficiency cores, value is always 1.",
+			[]string{"cpu", "perflevel"}, nil,
+		),
 		logger: logger,
 	}, nil
 }
@@ -114,5 +123,36 @@ func (c *statCollector) Update(ch chan<- prometheus.Metric) error {
 			ch <- prometheus.MustNewConstMetric(c.cpu, prometheus.CounterValue, float64(cpuTicks[v])/ClocksPerSec, strconv.Itoa(i), k)
 		}
 	}
+
+	if err := c.updatePerflevels(ch); err != nil {
+		level.Debug(c.logger).Log("msg", "failed to get CPU performance levels", "err", err)
+	}
+	return nil
+}
+
+// updatePerflevels exports the performance level of each CPU of Apple Silicon
+// machines, hw.perflevel0 are the performance cores. The efficiency cores are
+// numbered first. Machines with a single level, e.g. Intel, are skipped. The
+// frequencies of the clusters are only available from private frameworks.
+func (c *statCollector) updatePerflevels(ch chan<- prometheus.Metric) error {
+	levels, err := unix.SysctlUint32("hw.nperflevels")
+	if err != nil || levels < 2 {
+		return err
+	}
+	cpu := 0
+	for l := int(levels) - 1; l >= 0; l-- {
+		name, err := unix.Sysctl(fmt.Sprintf("hw.perflevel%d.name", l))
+		if err != nil {
+			return err
+		}
+		cpus, err := unix.SysctlUint32(fmt.Sprintf("hw.perflevel%d.logicalcpu", l))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(cpus); i++ {
+			ch <- prometheus.MustNewConstMetric(c.perflevel, prometheus.GaugeValue, 1, strconv.Itoa(cpu), strings.ToLower(name))
+			cpu++
+		}
+	}
 	return nil
 }
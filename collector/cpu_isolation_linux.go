@@ -0,0 +1,118 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/procfs"
+)
+
+// parseCPUList parses a list of CPUs like "0-3,8,10-15:2" as used by sysfs
+// and the kernel command line.
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return cpus, nil
+	}
+	for _, r := range strings.Split(list, ",") {
+		stride := 1
+		if i := strings.Index(r, ":"); i >= 0 {
+			s, err := strconv.Atoi(r[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid stride in CPU list %q", list)
+			}
+			stride = s
+			r = r[:i]
+		}
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list %q: %w", list, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid CPU list %q: %w", list, err)
+			}
+		}
+		for cpu := first; cpu <= last; cpu += stride {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// isolatedCPUs returns the CPUs of the isolcpus, nohz_full and rcu_nocbs sets
+// by set. The sets are read from sysfs if the kernel exposes them, otherwise
+// from the kernel command line. Sets which are not configured are missing.
+func isolatedCPUs(fs procfs.FS) (map[string][]int, error) {
+	sets := map[string][]int{}
+	for set, file := range map[string]string{"isolcpus": "isolated", "nohz_full": "nohz_full"} {
+		data, err := ioutil.ReadFile(sysFilePath("devices/system/cpu/" + file))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		cpus, err := parseCPUList(string(data))
+		if err != nil {
+			return nil, err
+		}
+		if len(cpus) > 0 {
+			sets[set] = cpus
+		}
+	}
+
+	cmdline, err := fs.CmdLine()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sets, nil
+		}
+		return nil, err
+	}
+	for _, param := range cmdline {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		set := kv[0]
+		if set != "isolcpus" && set != "nohz_full" && set != "rcu_nocbs" {
+			continue
+		}
+		// Flags of isolcpus like "nohz,domain," precede the CPU list.
+		fields := strings.Split(kv[1], ",")
+		for len(fields) > 0 && (fields[0] == "" || fields[0][0] < '0' || fields[0][0] > '9') {
+			fields = fields[1:]
+		}
+		value := strings.Join(fields, ",")
+		if _, ok := sets[set]; ok {
+			continue
+		}
+		cpus, err := parseCPUList(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s parameter: %w", set, err)
+		}
+		if len(cpus) > 0 {
+			sets[set] = cpus
+		}
+	}
+	return sets, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/procfs"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for list, want := range map[string][]int{
+		"":          nil,
+		"3\n":       {3},
+		"0-3,8":     {0, 1, 2, 3, 8},
+		"1-7:3,10":  {1, 4, 7, 10},
+		"2,4-5,7-7": {2, 4, 5, 7},
+	} {
+		got, err := parseCPUList(list)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", list, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: want %v, got %v", list, want, got)
+		}
+	}
+
+	for _, list := range []string{"a", "1-b", "1-3:0"} {
+		if _, err := parseCPUList(list); err == nil {
+			t.Errorf("%q: expected error", list)
+		}
+	}
+}
+
+func TestIsolatedCPUs(t *testing.T) {
+	if _, err := kingpin.CommandLine.Parse([]string{"--path.sysfs", "fixtures/sys"}); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := procfs.NewFS("fixtures/proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := isolatedCPUs(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]int{
+		"isolcpus":  {1},
+		"nohz_full": {1},
+		"rcu_nocbs": {1, 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
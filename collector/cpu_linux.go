@@ -17,9 +17,11 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/go-kit/log"
@@ -38,6 +40,8 @@ type cpuCollector struct {
 	cpuGuest           *prometheus.Desc
 	cpuCoreThrottle    *prometheus.Desc
 	cpuPackageThrottle *prometheus.Desc
+	cpuMicrocode       *prometheus.Desc
+	cpuIsolation       *prometheus.Desc
 	logger             log.Logger
 	cpuStats           []procfs.CPUStat
 	cpuStatsMutex      sync.Mutex
@@ -100,6 +104,16 @@ func NewCPUCollector(logger log.Logger) (Collector, error) {
 			"Number of times this CPU package has been throttled.",
 			[]string{"package"}, nil,
 		),
+		cpuMicrocode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "microcode_info"),
+			"Microcode revision loaded on the CPU, value is always 1.",
+			[]string{"cpu", "version"}, nil,
+		),
+		cpuIsolation: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "isolation_info"),
+			"CPUs of the isolcpus, nohz_full and rcu_nocbs sets of the kernel, value is always 1.",
+			[]string{"cpu", "set"}, nil,
+		),
 		logger: logger,
 	}
 	err = c.compileIncludeFlags(flagsInclude, bugsInclude)
@@ -144,6 +158,12 @@ func (c *cpuCollector) Update(ch chan<- prometheus.Metric) error {
 	if err := c.updateThermalThrottle(ch); err != nil {
 		return err
 	}
+	if err := c.updateMicrocode(ch); err != nil {
+		return err
+	}
+	if err := c.updateIsolation(ch); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -216,12 +236,12 @@ func (c *cpuCollector) updateThermalThrottle(ch chan<- prometheus.Metric) error
 		var physicalPackageID, coreID uint64
 
 		// topology/physical_package_id
-		if physicalPackageID, err = readUintFromFile(filepath.Join(cpu, "topology", "physical_package_id")); err != nil {
+		if physicalPackageID, err = readStaticUint("cpu", filepath.Join(cpu, "topology", "physical_package_id")); err != nil {
 			level.Debug(c.logger).Log("msg", "CPU is missing physical_package_id", "cpu", cpu)
 			continue
 		}
 		// topology/core_id
-		if coreID, err = readUintFromFile(filepath.Join(cpu, "topology", "core_id")); err != nil {
+		if coreID, err = readStaticUint("cpu", filepath.Join(cpu, "topology", "core_id")); err != nil {
 			level.Debug(c.logger).Log("msg", "CPU is missing core_id", "cpu", cpu)
 			continue
 		}
@@ -274,6 +294,42 @@ func (c *cpuCollector) updateThermalThrottle(ch chan<- prometheus.Metric) error
 	return nil
 }
 
+// updateMicrocode reads /sys/devices/system/cpu/cpu*/microcode/version, the
+// same revision as reported by /proc/cpuinfo without having to parse it.
+// Only x86 CPUs expose it.
+func (c *cpuCollector) updateMicrocode(ch chan<- prometheus.Metric) error {
+	versions, err := filepath.Glob(sysFilePath("devices/system/cpu/cpu[0-9]*/microcode/version"))
+	if err != nil {
+		return err
+	}
+	for _, path := range versions {
+		// Not cached, a late load of microcode sends no uevent.
+		version, err := ioutil.ReadFile(path)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read microcode version", "path", path, "err", err)
+			continue
+		}
+		cpu := strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(path))), "cpu")
+		ch <- prometheus.MustNewConstMetric(c.cpuMicrocode, prometheus.GaugeValue, 1, cpu, strings.TrimSpace(string(version)))
+	}
+	return nil
+}
+
+// updateIsolation exports the CPUs isolated from the scheduler, the timer tick
+// and RCU callbacks for latency sensitive workloads.
+func (c *cpuCollector) updateIsolation(ch chan<- prometheus.Metric) error {
+	sets, err := isolatedCPUs(c.fs)
+	if err != nil {
+		return fmt.Errorf("failed to get isolated CPUs: %w", err)
+	}
+	for set, cpus := range sets {
+		for _, cpu := range cpus {
+			ch <- prometheus.MustNewConstMetric(c.cpuIsolation, prometheus.GaugeValue, 1, strconv.Itoa(cpu), set)
+		}
+	}
+	return nil
+}
+
 // updateStat reads /proc/stat through procfs and exports CPU-related metrics.
 func (c *cpuCollector) updateStat(ch chan<- prometheus.Metric) error {
 	stats, err := c.fs.Stat()
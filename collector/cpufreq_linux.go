@@ -17,6 +17,10 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
@@ -31,6 +35,8 @@ type cpuFreqCollector struct {
 	scalingFreq    *prometheus.Desc
 	scalingFreqMin *prometheus.Desc
 	scalingFreqMax *prometheus.Desc
+	policyInfo     *prometheus.Desc
+	boost          *prometheus.Desc
 	logger         log.Logger
 }
 
@@ -77,6 +83,16 @@ func NewCPUFreqCollector(logger log.Logger) (Collector, error) {
 			"Maximum scaled CPU thread frequency in hertz.",
 			[]string{"cpu"}, nil,
 		),
+		policyInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "frequency_policy_info"),
+			"CPU frequency scaling policy with its driver, governor and energy performance preference, value is always 1.",
+			[]string{"policy", "related_cpus", "driver", "governor", "energy_performance_preference"}, nil,
+		),
+		boost: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "frequency_boost_enabled"),
+			"Whether frequency boost (turbo) is enabled for the CPU frequency scaling policy.",
+			[]string{"policy"}, nil,
+		),
 		logger: logger,
 	}, nil
 }
@@ -140,5 +156,49 @@ func (c *cpuFreqCollector) Update(ch chan<- prometheus.Metric) error {
 			)
 		}
 	}
+	return c.updatePolicies(ch)
+}
+
+// updatePolicies exposes the settings of the cpufreq policies, each of which
+// covers the CPUs sharing a clock.
+func (c *cpuFreqCollector) updatePolicies(ch chan<- prometheus.Metric) error {
+	policies, err := filepath.Glob(sysFilePath("devices/system/cpu/cpufreq/policy[0-9]*"))
+	if err != nil {
+		return err
+	}
+	globalBoost, hasGlobalBoost := readCPUFreqBoost()
+
+	for _, policy := range policies {
+		name := strings.TrimPrefix(filepath.Base(policy), "policy")
+		attr := func(file string) string {
+			value, err := ioutil.ReadFile(filepath.Join(policy, file))
+			if err != nil {
+				return ""
+			}
+			return strings.TrimSpace(string(value))
+		}
+		ch <- prometheus.MustNewConstMetric(c.policyInfo, prometheus.GaugeValue, 1, name,
+			attr("related_cpus"), attr("scaling_driver"), attr("scaling_governor"), attr("energy_performance_preference"))
+
+		// Newer kernels allow toggling boost per policy.
+		if boost, err := strconv.ParseFloat(attr("boost"), 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.boost, prometheus.GaugeValue, boost, name)
+		} else if hasGlobalBoost {
+			ch <- prometheus.MustNewConstMetric(c.boost, prometheus.GaugeValue, globalBoost, name)
+		}
+	}
 	return nil
 }
+
+// readCPUFreqBoost returns the system wide boost setting of the cpufreq
+// driver, if it has one.
+func readCPUFreqBoost() (float64, bool) {
+	if boost, err := readUintFromFile(sysFilePath("devices/system/cpu/cpufreq/boost")); err == nil {
+		return float64(boost), true
+	}
+	// intel_pstate doesn't implement the generic boost attribute.
+	if noTurbo, err := readUintFromFile(sysFilePath("devices/system/cpu/intel_pstate/no_turbo")); err == nil {
+		return 1 - float64(noTurbo), true
+	}
+	return 0, false
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// globPrefix selects comma separated shell patterns instead of a regular
+// expression, e.g. "glob:veth*,docker?".
+const globPrefix = "glob:"
+
+// deviceMatcher matches device names against the pattern of a flag, compiled
+// once when the collector is created. Regular expressions, which only consist
+// of anchored literals like "^(lo|eth0)$" or "^veth", are matched without the
+// regexp engine as these are evaluated for every device on every scrape.
+type deviceMatcher struct {
+	exact    map[string]struct{}
+	prefixes []string
+	globs    []string
+	pattern  *regexp.Regexp
+}
+
+// newDeviceMatcher compiles the regular expression or globs of pattern.
+func newDeviceMatcher(pattern string) (*deviceMatcher, error) {
+	if strings.HasPrefix(pattern, globPrefix) {
+		globs := strings.Split(strings.TrimPrefix(pattern, globPrefix), ",")
+		for _, glob := range globs {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+			}
+		}
+		return &deviceMatcher{globs: globs}, nil
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	if m := literalMatcher(re.Simplify()); m != nil {
+		return m, nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &deviceMatcher{pattern: compiled}, nil
+}
+
+// mustNewDeviceMatcher is newDeviceMatcher panicking on invalid patterns like
+// regexp.MustCompile.
+func mustNewDeviceMatcher(pattern string) *deviceMatcher {
+	m, err := newDeviceMatcher(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("invalid device pattern %q: %s", pattern, err))
+	}
+	return m
+}
+
+// literalMatcher returns a matcher for regular expressions starting with ^
+// followed by literals or an alternation of literals, optionally ending with
+// $, and nil for all others.
+func literalMatcher(re *syntax.Regexp) *deviceMatcher {
+	if re.Op != syntax.OpConcat || len(re.Sub) < 1 || re.Sub[0].Op != syntax.OpBeginText {
+		return nil
+	}
+	sub := re.Sub[1:]
+	anchored := len(sub) > 0 && sub[len(sub)-1].Op == syntax.OpEndText
+	if anchored {
+		sub = sub[:len(sub)-1]
+	}
+
+	literals := []string{""}
+	switch {
+	case len(sub) == 0:
+	case len(sub) == 1:
+		var ok bool
+		if literals, ok = alternateLiterals(sub[0]); !ok {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	m := &deviceMatcher{}
+	if !anchored {
+		m.prefixes = literals
+		return m
+	}
+	m.exact = make(map[string]struct{}, len(literals))
+	for _, l := range literals {
+		m.exact[l] = struct{}{}
+	}
+	return m
+}
+
+// alternateLiterals returns the strings matched by a literal or an
+// alternation of literals, which may be captured.
+func alternateLiterals(re *syntax.Regexp) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpCapture:
+		return alternateLiterals(re.Sub[0])
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		return []string{string(re.Rune)}, true
+	case syntax.OpAlternate:
+		var literals []string
+		for _, sub := range re.Sub {
+			l, ok := alternateLiterals(sub)
+			if !ok {
+				return nil, false
+			}
+			literals = append(literals, l...)
+		}
+		return literals, true
+	}
+	return nil, false
+}
+
+// matches returns whether the name matches the pattern.
+func (m *deviceMatcher) matches(name string) bool {
+	switch {
+	case m.pattern != nil:
+		return m.pattern.MatchString(name)
+	case m.exact != nil:
+		_, ok := m.exact[name]
+		return ok
+	case m.globs != nil:
+		for _, glob := range m.globs {
+			if ok, _ := path.Match(glob, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
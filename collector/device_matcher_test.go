@@ -0,0 +1,67 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestDeviceMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		literal bool
+		names   map[string]bool
+	}{
+		{"^$", true, map[string]bool{"": true, "eth0": false}},
+		{"^(lo|eth0)$", true, map[string]bool{"lo": true, "eth0": true, "eth01": false, "veth0": false}},
+		{"^veth", true, map[string]bool{"veth0": true, "eth0": false}},
+		{"^(veth|docker)", true, map[string]bool{"veth0": true, "docker1": true, "br0": false}},
+		{"^(?i)lo$", false, map[string]bool{"LO": true, "lo": true}},
+		{"eth", false, map[string]bool{"veth0": true, "lo": false}},
+		{"", false, map[string]bool{"lo": true}},
+		{"^(ram|loop|fd|(h|s|v|xv)d[a-z]|nvme\\d+n\\d+p)\\d+$", false, map[string]bool{"sda1": true, "sda": false, "nvme0n1": false}},
+	}
+
+	for _, test := range tests {
+		m, err := newDeviceMatcher(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if literal := m.pattern == nil; literal != test.literal {
+			t.Errorf("pattern %q: expected literal=%v, got %v", test.pattern, test.literal, literal)
+		}
+		re := regexp.MustCompile(test.pattern)
+		for name, want := range test.names {
+			if got := m.matches(name); got != want || got != re.MatchString(name) {
+				t.Errorf("pattern %q: expected %q to match=%v, got %v", test.pattern, name, want, got)
+			}
+		}
+	}
+}
+
+func TestDeviceMatcherGlob(t *testing.T) {
+	m, err := newDeviceMatcher("glob:veth*,docker?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"veth12ab": true, "docker0": true, "docker10": false, "eth0": false} {
+		if got := m.matches(name); got != want {
+			t.Errorf("expected %q to match=%v, got %v", name, want, got)
+		}
+	}
+	if _, err := newDeviceMatcher("glob:[veth"); err == nil {
+		t.Error("expected error for invalid glob")
+	}
+}
@@ -38,6 +38,7 @@ int _get_stats(struct devinfo *info, Stats **stats) {
 		long double duration_other, duration_read, duration_write, duration_free;
 		long double busy_time;
 		uint64_t blocks;
+		uint64_t queue_length;
 
 		strcpy(p[i].device, current.dinfo->devices[i].device_name);
 		p[i].unit = current.dinfo->devices[i].unit_number;
@@ -57,6 +58,7 @@ int _get_stats(struct devinfo *info, Stats **stats) {
 				DSM_TOTAL_DURATION_FREE, &duration_free,
 				DSM_TOTAL_BUSY_TIME, &busy_time,
 				DSM_TOTAL_BLOCKS, &blocks,
+				DSM_QUEUE_LENGTH, &queue_length,
 				DSM_NONE);
 
 		p[i].bytes.read = bytes_read;
@@ -72,6 +74,7 @@ int _get_stats(struct devinfo *info, Stats **stats) {
 		p[i].duration.free = duration_free;
 		p[i].busyTime = busy_time;
 		p[i].blocks = blocks;
+		p[i].queueLength = queue_length;
 	}
 
 	*stats = p;
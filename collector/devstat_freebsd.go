@@ -42,6 +42,7 @@ type devstatCollector struct {
 	duration  typedDesc
 	busyTime  typedDesc
 	blocks    typedDesc
+	queue     typedDesc
 	logger    log.Logger
 }
 
@@ -49,7 +50,9 @@ func init() {
 	registerCollector("devstat", defaultDisabled, NewDevstatCollector)
 }
 
-// NewDevstatCollector returns a new Collector exposing Device stats.
+// NewDevstatCollector returns a new Collector exposing Device stats. Free
+// transactions are BIO_DELETE requests like TRIM, devstat keeps no
+// distribution of their latency, only the total duration per type.
 func NewDevstatCollector(logger log.Logger) (Collector, error) {
 	return &devstatCollector{
 		devinfo: &C.struct_devinfo{},
@@ -78,6 +81,11 @@ func NewDevstatCollector(logger log.Logger) (Collector, error) {
 			"The total number of blocks transferred.",
 			[]string{"device"}, nil,
 		), prometheus.CounterValue},
+		queue: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, devstatSubsystem, "queue_length"),
+			"The number of transactions outstanding.",
+			[]string{"device"}, nil,
+		), prometheus.GaugeValue},
 		logger: logger,
 	}, nil
 }
@@ -100,14 +108,18 @@ func (c *devstatCollector) Update(ch chan<- prometheus.Metric) error {
 		device := fmt.Sprintf("%s%d", C.GoString(&stat.device[0]), stat.unit)
 		ch <- c.bytes.mustNewConstMetric(float64(stat.bytes.read), device, "read")
 		ch <- c.bytes.mustNewConstMetric(float64(stat.bytes.write), device, "write")
+		ch <- c.bytes.mustNewConstMetric(float64(stat.bytes.free), device, "free")
 		ch <- c.transfers.mustNewConstMetric(float64(stat.transfers.other), device, "other")
 		ch <- c.transfers.mustNewConstMetric(float64(stat.transfers.read), device, "read")
 		ch <- c.transfers.mustNewConstMetric(float64(stat.transfers.write), device, "write")
+		ch <- c.transfers.mustNewConstMetric(float64(stat.transfers.free), device, "free")
 		ch <- c.duration.mustNewConstMetric(float64(stat.duration.other), device, "other")
 		ch <- c.duration.mustNewConstMetric(float64(stat.duration.read), device, "read")
 		ch <- c.duration.mustNewConstMetric(float64(stat.duration.write), device, "write")
+		ch <- c.duration.mustNewConstMetric(float64(stat.duration.free), device, "free")
 		ch <- c.busyTime.mustNewConstMetric(float64(stat.busyTime), device)
 		ch <- c.blocks.mustNewConstMetric(float64(stat.blocks), device)
+		ch <- c.queue.mustNewConstMetric(float64(stat.queueLength), device)
 	}
 	C.free(unsafe.Pointer(stats))
 	return nil
@@ -45,8 +45,9 @@ typedef struct {
 	Bytes		bytes;
 	Transfers	transfers;
 	Duration	duration;
-	long		busyTime;
+	double		busyTime;
 	uint64_t	blocks;
+	uint64_t	queueLength;
 } Stats;
 
 
@@ -0,0 +1,172 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nodirsize
+
+package collector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const dirsizeSubsystem = "dirsize"
+
+var (
+	dirsizePaths    = kingpin.Flag("collector.dirsize.path", "Directory to export the size of, can be repeated.").Strings()
+	dirsizeInterval = kingpin.Flag("collector.dirsize.interval", "Interval between walks of the directories.").Default("5m").Duration()
+	dirsizeMaxFiles = kingpin.Flag("collector.dirsize.max-files", "Maximum number of files to walk per directory, larger directories are only walked partially.").Default("100000").Int()
+)
+
+// errDirsizeMaxFiles stops the walk of a directory with too many files.
+var errDirsizeMaxFiles = errors.New("too many files")
+
+type dirsizeCollector struct {
+	bytes         *prometheus.Desc
+	files         *prometheus.Desc
+	oldestFile    *prometheus.Desc
+	truncated     *prometheus.Desc
+	success       *prometheus.Desc
+	walkTimestamp *prometheus.Desc
+	walkDuration  *prometheus.Desc
+	logger        log.Logger
+
+	mtx   sync.Mutex
+	walks map[string]dirsizeWalk
+}
+
+// dirsizeWalk is the result of the last walk of a directory.
+type dirsizeWalk struct {
+	bytes, files int64
+	oldestFile   time.Time
+	truncated    bool
+	err          error
+	time         time.Time
+	duration     time.Duration
+}
+
+func init() {
+	registerCollector(dirsizeSubsystem, defaultDisabled, NewDirsizeCollector)
+}
+
+// NewDirsizeCollector returns a new Collector exposing the size and number of
+// files of directories. The directories are walked in the background as
+// walking large directories on each scrape is too expensive.
+func NewDirsizeCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, dirsizeSubsystem, name), help, []string{"path"}, nil)
+	}
+	c := &dirsizeCollector{
+		bytes:         desc("bytes", "Size of the regular files below the directory."),
+		files:         desc("files", "Number of regular files below the directory."),
+		oldestFile:    desc("oldest_file_timestamp_seconds", "Modification time of the oldest regular file below the directory."),
+		truncated:     desc("truncated", "Whether the walk of the directory stopped at the maximum number of files."),
+		success:       desc("walk_success", "Whether the last walk of the directory succeeded."),
+		walkTimestamp: desc("walk_timestamp_seconds", "Time of the last walk of the directory."),
+		walkDuration:  desc("walk_duration_seconds", "Duration of the last walk of the directory."),
+		logger:        logger,
+		walks:         map[string]dirsizeWalk{},
+	}
+	if len(*dirsizePaths) > 0 {
+		go c.run()
+	}
+	return c, nil
+}
+
+func (c *dirsizeCollector) run() {
+	ticker := time.NewTicker(*dirsizeInterval)
+	defer ticker.Stop()
+	for {
+		for _, path := range *dirsizePaths {
+			w := walkDirsize(path, *dirsizeMaxFiles)
+			if w.err != nil {
+				level.Error(c.logger).Log("msg", "failed to walk directory", "path", path, "err", w.err)
+			}
+			c.mtx.Lock()
+			c.walks[path] = w
+			c.mtx.Unlock()
+		}
+		<-ticker.C
+	}
+}
+
+func (c *dirsizeCollector) Update(ch chan<- prometheus.Metric) error {
+	if len(*dirsizePaths) == 0 {
+		level.Debug(c.logger).Log("msg", "no directories configured")
+		return ErrNoData
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for path, w := range c.walks {
+		ch <- prometheus.MustNewConstMetric(c.walkTimestamp, prometheus.GaugeValue, float64(w.time.UnixNano())/1e9, path)
+		ch <- prometheus.MustNewConstMetric(c.walkDuration, prometheus.GaugeValue, w.duration.Seconds(), path)
+		if w.err != nil {
+			ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, 0, path)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, 1, path)
+		ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(w.bytes), path)
+		ch <- prometheus.MustNewConstMetric(c.files, prometheus.GaugeValue, float64(w.files), path)
+		truncated := 0.0
+		if w.truncated {
+			truncated = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.truncated, prometheus.GaugeValue, truncated, path)
+		if w.files > 0 {
+			ch <- prometheus.MustNewConstMetric(c.oldestFile, prometheus.GaugeValue, float64(w.oldestFile.UnixNano())/1e9, path)
+		}
+	}
+	return nil
+}
+
+// walkDirsize walks a directory without following symlinks, files removed
+// while walking are skipped.
+func walkDirsize(dir string, maxFiles int) dirsizeWalk {
+	w := dirsizeWalk{time: time.Now()}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != dir {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if w.files >= int64(maxFiles) {
+			return errDirsizeMaxFiles
+		}
+		w.files++
+		w.bytes += info.Size()
+		if w.oldestFile.IsZero() || info.ModTime().Before(w.oldestFile) {
+			w.oldestFile = info.ModTime()
+		}
+		return nil
+	})
+	if err == errDirsizeMaxFiles {
+		w.truncated = true
+		err = nil
+	}
+	w.err = err
+	w.duration = time.Since(w.time)
+	return w
+}
@@ -63,7 +63,7 @@ func NewDiskstatsCollector(logger log.Logger) (Collector, error) {
 					valueType: prometheus.CounterValue,
 				},
 				value: func(stat *iostat.DriveStats) float64 {
-					return float64(stat.NumRead) / float64(stat.BlockSize)
+					return float64(stat.BytesRead) / float64(stat.BlockSize)
 				},
 			},
 			{
@@ -95,7 +95,7 @@ func NewDiskstatsCollector(logger log.Logger) (Collector, error) {
 					valueType: prometheus.CounterValue,
 				},
 				value: func(stat *iostat.DriveStats) float64 {
-					return float64(stat.NumWrite) / float64(stat.BlockSize)
+					return float64(stat.BytesWritten) / float64(stat.BlockSize)
 				},
 			},
 			{
@@ -107,6 +107,34 @@ func NewDiskstatsCollector(logger log.Logger) (Collector, error) {
 					return stat.TotalWriteTime.Seconds()
 				},
 			},
+			{
+				typedDesc: typedDesc{
+					desc: prometheus.NewDesc(
+						prometheus.BuildFQName(namespace, diskSubsystem, "read_latency_seconds_total"),
+						"The total latency of reads in seconds as reported by the block storage driver.",
+						diskLabelNames,
+						nil,
+					),
+					valueType: prometheus.CounterValue,
+				},
+				value: func(stat *iostat.DriveStats) float64 {
+					return stat.ReadLatency.Seconds()
+				},
+			},
+			{
+				typedDesc: typedDesc{
+					desc: prometheus.NewDesc(
+						prometheus.BuildFQName(namespace, diskSubsystem, "write_latency_seconds_total"),
+						"The total latency of writes in seconds as reported by the block storage driver.",
+						diskLabelNames,
+						nil,
+					),
+					valueType: prometheus.CounterValue,
+				},
+				value: func(stat *iostat.DriveStats) float64 {
+					return stat.WriteLatency.Seconds()
+				},
+			},
 			{
 				typedDesc: typedDesc{
 					desc:      readBytesDesc,
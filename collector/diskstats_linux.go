@@ -16,13 +16,9 @@
 package collector
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
-	"strconv"
-	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -36,7 +32,7 @@ const (
 )
 
 var (
-	ignoredDevices = kingpin.Flag("collector.diskstats.ignored-devices", "Regexp of devices to ignore for diskstats.").Default("^(ram|loop|fd|(h|s|v|xv)d[a-z]|nvme\\d+n\\d+p)\\d+$").String()
+	ignoredDevices = kingpin.Flag("collector.diskstats.ignored-devices", "Regexp, or comma separated globs prefixed with glob:, of devices to ignore for diskstats.").Default("^(ram|loop|fd|(h|s|v|xv)d[a-z]|nvme\\d+n\\d+p)\\d+$").String()
 )
 
 type typedFactorDesc struct {
@@ -53,7 +49,7 @@ func (d *typedFactorDesc) mustNewConstMetric(value float64, labels ...string) pr
 }
 
 type diskstatsCollector struct {
-	ignoredDevicesPattern *regexp.Regexp
+	ignoredDevicesPattern *deviceMatcher
 	descs                 []typedFactorDesc
 	logger                log.Logger
 }
@@ -68,7 +64,7 @@ func NewDiskstatsCollector(logger log.Logger) (Collector, error) {
 	var diskLabelNames = []string{"device"}
 
 	return &diskstatsCollector{
-		ignoredDevicesPattern: regexp.MustCompile(*ignoredDevices),
+		ignoredDevicesPattern: mustNewDeviceMatcher(*ignoredDevices),
 		descs: []typedFactorDesc{
 			{
 				desc: readsCompletedDesc, valueType: prometheus.CounterValue,
@@ -191,7 +187,7 @@ func (c *diskstatsCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	for dev, stats := range diskStats {
-		if c.ignoredDevicesPattern.MatchString(dev) {
+		if c.ignoredDevicesPattern.matches(dev) {
 			level.Debug(c.logger).Log("msg", "Ignoring device", "device", dev)
 			continue
 		}
@@ -201,17 +197,13 @@ func (c *diskstatsCollector) Update(ch chan<- prometheus.Metric) error {
 			if i >= len(c.descs) {
 				break
 			}
-			v, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return fmt.Errorf("invalid value %s in diskstats: %w", value, err)
-			}
-			ch <- c.descs[i].mustNewConstMetric(v, dev)
+			ch <- c.descs[i].mustNewConstMetric(value, dev)
 		}
 	}
 	return nil
 }
 
-func getDiskStats() (map[string][]string, error) {
+func getDiskStats() (map[string][]float64, error) {
 	file, err := os.Open(procFilePath(diskstatsFilename))
 	if err != nil {
 		return nil, err
@@ -221,19 +213,26 @@ func getDiskStats() (map[string][]string, error) {
 	return parseDiskStats(file)
 }
 
-func parseDiskStats(r io.Reader) (map[string][]string, error) {
-	var (
-		diskStats = map[string][]string{}
-		scanner   = bufio.NewScanner(r)
-	)
+func parseDiskStats(r io.Reader) (map[string][]float64, error) {
+	diskStats := map[string][]float64{}
+	scanner, release := newPooledScanner(r)
+	defer release()
 
+	var parts [][]byte
 	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
+		parts = appendFields(parts[:0], scanner.Bytes())
 		if len(parts) < 4 { // we strip major, minor and dev
 			return nil, fmt.Errorf("invalid line in %s: %s", procFilePath(diskstatsFilename), scanner.Text())
 		}
-		dev := parts[2]
-		diskStats[dev] = parts[3:]
+		stats := make([]float64, len(parts)-3)
+		for i, value := range parts[3:] {
+			v, err := parseFloatBytes(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %s in diskstats: %w", value, err)
+			}
+			stats[i] = v
+		}
+		diskStats[internedStrings.get(parts[2])] = stats
 	}
 
 	return diskStats, scanner.Err()
@@ -30,23 +30,23 @@ func TestDiskStats(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if want, got := "25353629", diskStats["sda4"][0]; want != got {
-		t.Errorf("want diskstats sda4 %s, got %s", want, got)
+	if want, got := 25353629.0, diskStats["sda4"][0]; want != got {
+		t.Errorf("want diskstats sda4 %f, got %f", want, got)
 	}
 
-	if want, got := "68", diskStats["mmcblk0p2"][10]; want != got {
-		t.Errorf("want diskstats mmcblk0p2 %s, got %s", want, got)
+	if want, got := 68.0, diskStats["mmcblk0p2"][10]; want != got {
+		t.Errorf("want diskstats mmcblk0p2 %f, got %f", want, got)
 	}
 
-	if want, got := "11130", diskStats["sdb"][14]; want != got {
-		t.Errorf("want diskstats sdb %s, got %s", want, got)
+	if want, got := 11130.0, diskStats["sdb"][14]; want != got {
+		t.Errorf("want diskstats sdb %f, got %f", want, got)
 	}
 
-	if want, got := "1555", diskStats["sdc"][15]; want != got {
-		t.Errorf("want diskstats sdc %s, got %s", want, got)
+	if want, got := 1555.0, diskStats["sdc"][15]; want != got {
+		t.Errorf("want diskstats sdc %f, got %f", want, got)
 	}
 
-	if want, got := "1944", diskStats["sdc"][16]; want != got {
-		t.Errorf("want diskstats sdc %s, got %s", want, got)
+	if want, got := 1944.0, diskStats["sdc"][16]; want != got {
+		t.Errorf("want diskstats sdc %f, got %f", want, got)
 	}
 }
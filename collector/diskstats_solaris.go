@@ -0,0 +1,103 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nodiskstats
+
+package collector
+
+import (
+	"github.com/go-kit/log"
+	"github.com/illumos/go-kstat"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type diskstatsCollector struct {
+	rxfer        typedDesc
+	rbytes       typedDesc
+	wxfer        typedDesc
+	wbytes       typedDesc
+	ioNow        typedDesc
+	time         typedDesc
+	weightedTime typedDesc
+	waitTime     typedDesc
+	weightedWait typedDesc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector("diskstats", defaultEnabled, NewDiskstatsCollector)
+}
+
+// NewDiskstatsCollector returns a new Collector exposing disk device stats
+// from the I/O kstats of class disk, partitions are skipped.
+func NewDiskstatsCollector(logger log.Logger) (Collector, error) {
+	return &diskstatsCollector{
+		rxfer:  typedDesc{readsCompletedDesc, prometheus.CounterValue},
+		rbytes: typedDesc{readBytesDesc, prometheus.CounterValue},
+		wxfer:  typedDesc{writesCompletedDesc, prometheus.CounterValue},
+		wbytes: typedDesc{writtenBytesDesc, prometheus.CounterValue},
+		ioNow: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskSubsystem, "io_now"),
+			"The number of I/Os currently in progress.",
+			diskLabelNames, nil,
+		), prometheus.GaugeValue},
+		time: typedDesc{ioTimeSecondsDesc, prometheus.CounterValue},
+		weightedTime: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskSubsystem, "io_time_weighted_seconds_total"),
+			"The weighted # of seconds spent doing I/Os.",
+			diskLabelNames, nil,
+		), prometheus.CounterValue},
+		waitTime: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskSubsystem, "wait_time_seconds_total"),
+			"Total seconds I/Os were queued before being issued to the device.",
+			diskLabelNames, nil,
+		), prometheus.CounterValue},
+		weightedWait: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskSubsystem, "wait_time_weighted_seconds_total"),
+			"The weighted # of seconds I/Os were queued before being issued to the device.",
+			diskLabelNames, nil,
+		), prometheus.CounterValue},
+		logger: logger,
+	}, nil
+}
+
+func (c *diskstatsCollector) Update(ch chan<- prometheus.Metric) error {
+	tok, err := kstat.Open()
+	if err != nil {
+		return err
+	}
+	defer tok.Close()
+
+	for _, ks := range tok.All() {
+		if ks.Class != "disk" || ks.Type != kstat.IoStat {
+			continue
+		}
+		io, err := ks.GetIO()
+		if err != nil {
+			return err
+		}
+
+		// The run queue holds the I/Os issued to the device, the wait
+		// queue those held back by the driver. Times are in nanoseconds.
+		ch <- c.rxfer.mustNewConstMetric(float64(io.Reads), ks.Name)
+		ch <- c.rbytes.mustNewConstMetric(float64(io.Nread), ks.Name)
+		ch <- c.wxfer.mustNewConstMetric(float64(io.Writes), ks.Name)
+		ch <- c.wbytes.mustNewConstMetric(float64(io.Nwritten), ks.Name)
+		ch <- c.ioNow.mustNewConstMetric(float64(io.Rcnt+io.Wcnt), ks.Name)
+		ch <- c.time.mustNewConstMetric(float64(io.Rtime)/1e9, ks.Name)
+		ch <- c.weightedTime.mustNewConstMetric(float64(io.Rlentime)/1e9, ks.Name)
+		ch <- c.waitTime.mustNewConstMetric(float64(io.Wtime)/1e9, ks.Name)
+		ch <- c.weightedWait.mustNewConstMetric(float64(io.Wlentime)/1e9, ks.Name)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nodmi
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const dmiSubsystem = "dmi"
+
+// dmiInfoFiles maps the world readable attributes of /sys/class/dmi/id to
+// the labels of the info metric.
+var dmiInfoFiles = []struct{ file, label string }{
+	{"bios_date", "bios_date"},
+	{"bios_release", "bios_release"},
+	{"bios_vendor", "bios_vendor"},
+	{"bios_version", "bios_version"},
+	{"board_name", "board_name"},
+	{"board_vendor", "board_vendor"},
+	{"board_version", "board_version"},
+	{"chassis_vendor", "chassis_vendor"},
+	{"chassis_version", "chassis_version"},
+	{"product_family", "product_family"},
+	{"product_name", "product_name"},
+	{"product_sku", "product_sku"},
+	{"product_version", "product_version"},
+	{"sys_vendor", "system_vendor"},
+}
+
+// dmiChassisTypes are the names of the chassis types of the SMBIOS
+// specification.
+var dmiChassisTypes = []string{
+	1: "Other", 2: "Unknown", 3: "Desktop", 4: "Low Profile Desktop",
+	5: "Pizza Box", 6: "Mini Tower", 7: "Tower", 8: "Portable", 9: "Laptop",
+	10: "Notebook", 11: "Hand Held", 12: "Docking Station", 13: "All in One",
+	14: "Sub Notebook", 15: "Space-saving", 16: "Lunch Box",
+	17: "Main Server Chassis", 18: "Expansion Chassis", 19: "SubChassis",
+	20: "Bus Expansion Chassis", 21: "Peripheral Chassis", 22: "RAID Chassis",
+	23: "Rack Mount Chassis", 24: "Sealed-case PC", 25: "Multi-system Chassis",
+	26: "Compact PCI", 27: "Advanced TCA", 28: "Blade", 29: "Blade Enclosure",
+	30: "Tablet", 31: "Convertible", 32: "Detachable", 33: "IoT Gateway",
+	34: "Embedded PC", 35: "Mini PC", 36: "Stick PC",
+}
+
+type dmiCollector struct {
+	info                    *prometheus.Desc
+	chassisInfo             *prometheus.Desc
+	memoryDeviceInfo        *prometheus.Desc
+	memoryDeviceSize        *prometheus.Desc
+	memoryDeviceSpeed       *prometheus.Desc
+	memoryDeviceConfigSpeed *prometheus.Desc
+	logger                  log.Logger
+}
+
+func init() {
+	registerCollector(dmiSubsystem, defaultDisabled, NewDMICollector)
+}
+
+// NewDMICollector returns a new Collector exposing the system's DMI/SMBIOS
+// hardware inventory.
+func NewDMICollector(logger log.Logger) (Collector, error) {
+	labels := make([]string, 0, len(dmiInfoFiles))
+	for _, f := range dmiInfoFiles {
+		labels = append(labels, f.label)
+	}
+	memoryDesc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dmiSubsystem, "memory_device_"+name),
+			help, append([]string{"locator", "bank_locator"}, labels...), nil,
+		)
+	}
+	return &dmiCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dmiSubsystem, "info"),
+			"A metric with a constant '1' value labeled by BIOS, board, chassis and product information from DMI.",
+			labels, nil,
+		),
+		chassisInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dmiSubsystem, "chassis_info"),
+			"A metric with a constant '1' value labeled by the chassis type, asset tag and service tag, the serial number of the system, from DMI.",
+			[]string{"chassis_type", "asset_tag", "service_tag"}, nil,
+		),
+		memoryDeviceInfo: memoryDesc("info",
+			"Memory slot from the SMBIOS memory device table, value is 1 if a module is installed.",
+			"type", "manufacturer", "part_number", "serial_number"),
+		memoryDeviceSize:        memoryDesc("size_bytes", "Size of the memory module, 0 if the slot is empty."),
+		memoryDeviceSpeed:       memoryDesc("speed_transfers_per_second", "Maximum speed of the memory module."),
+		memoryDeviceConfigSpeed: memoryDesc("configured_speed_transfers_per_second", "Speed the memory module is configured to run at."),
+		logger:                  logger,
+	}, nil
+}
+
+func (c *dmiCollector) Update(ch chan<- prometheus.Metric) error {
+	dir := sysFilePath("class/dmi/id")
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "DMI information not available", "path", dir)
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to access DMI information: %w", err)
+	}
+
+	values := make([]string, 0, len(dmiInfoFiles))
+	for _, f := range dmiInfoFiles {
+		value, err := readStaticFile(dmiSubsystem, filepath.Join(dir, f.file))
+		if err != nil && !os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "failed to read DMI attribute", "file", f.file, "err", err)
+		}
+		values = append(values, strings.TrimSpace(string(value)))
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, values...)
+
+	chassisType := readDMIAttribute(dir, "chassis_type")
+	if i, err := strconv.Atoi(chassisType); err == nil && i > 0 && i < len(dmiChassisTypes) {
+		chassisType = dmiChassisTypes[i]
+	}
+	// The serial numbers are only readable by root, vendors like Dell use the
+	// one of the system as service tag.
+	serviceTag := readDMIAttribute(dir, "product_serial")
+	if serviceTag == "" {
+		serviceTag = readDMIAttribute(dir, "chassis_serial")
+	}
+	ch <- prometheus.MustNewConstMetric(c.chassisInfo, prometheus.GaugeValue, 1, chassisType, readDMIAttribute(dir, "chassis_asset_tag"), serviceTag)
+
+	// The raw SMBIOS tables are only readable by root.
+	devices, err := readSMBIOSMemoryDevices()
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to read SMBIOS memory devices", "err", err)
+		return nil
+	}
+	for _, device := range devices {
+		installed := 0.0
+		if device.Installed {
+			installed = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.memoryDeviceInfo, prometheus.GaugeValue, installed,
+			device.Locator, device.BankLocator, device.Type, device.Manufacturer, device.PartNumber, device.SerialNumber)
+		if !device.Installed {
+			ch <- prometheus.MustNewConstMetric(c.memoryDeviceSize, prometheus.GaugeValue, 0, device.Locator, device.BankLocator)
+			continue
+		}
+		if device.Size > 0 {
+			ch <- prometheus.MustNewConstMetric(c.memoryDeviceSize, prometheus.GaugeValue, float64(device.Size), device.Locator, device.BankLocator)
+		}
+		if device.Speed > 0 {
+			ch <- prometheus.MustNewConstMetric(c.memoryDeviceSpeed, prometheus.GaugeValue, float64(device.Speed)*1e6, device.Locator, device.BankLocator)
+		}
+		if device.ConfiguredSpeed > 0 {
+			ch <- prometheus.MustNewConstMetric(c.memoryDeviceConfigSpeed, prometheus.GaugeValue, float64(device.ConfiguredSpeed)*1e6, device.Locator, device.BankLocator)
+		}
+	}
+	return nil
+}
+
+// readDMIAttribute returns an attribute of /sys/class/dmi/id, which is empty
+// if the firmware does not provide it or it is not readable.
+func readDMIAttribute(dir, file string) string {
+	value, err := readStaticFile(dmiSubsystem, filepath.Join(dir, file))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(value))
+}
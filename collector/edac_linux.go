@@ -17,10 +17,16 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -31,13 +37,24 @@ const (
 var (
 	edacMemControllerRE = regexp.MustCompile(`.*devices/system/edac/mc/mc([0-9]*)`)
 	edacMemCsrowRE      = regexp.MustCompile(`.*devices/system/edac/mc/mc[0-9]*/csrow([0-9]*)`)
+	edacMemDimmRE       = regexp.MustCompile(`.*devices/system/edac/mc/mc[0-9]*/(?:dimm|rank)([0-9]*)`)
 )
 
+// edacDimm is a memory module of a memory controller. Controllers that
+// report errors per chip select row expose ranks instead of DIMMs.
+type edacDimm struct {
+	controller, dimm int
+	label, location  string
+	ceCount, ueCount uint64
+}
+
 type edacCollector struct {
 	ceCount      *prometheus.Desc
 	ueCount      *prometheus.Desc
 	csRowCECount *prometheus.Desc
 	csRowUECount *prometheus.Desc
+	dimmCECount  *prometheus.Desc
+	dimmUECount  *prometheus.Desc
 	logger       log.Logger
 }
 
@@ -68,6 +85,16 @@ func NewEdacCollector(logger log.Logger) (Collector, error) {
 			"Total uncorrectable memory errors for this csrow.",
 			[]string{"controller", "csrow"}, nil,
 		),
+		dimmCECount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, edacSubsystem, "dimm_correctable_errors_total"),
+			"Total correctable memory errors for this DIMM.",
+			[]string{"controller", "dimm", "label", "location", "locator"}, nil,
+		),
+		dimmUECount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, edacSubsystem, "dimm_uncorrectable_errors_total"),
+			"Total uncorrectable memory errors for this DIMM.",
+			[]string{"controller", "dimm", "label", "location", "locator"}, nil,
+		),
 		logger: logger,
 	}, nil
 }
@@ -77,6 +104,7 @@ func (c *edacCollector) Update(ch chan<- prometheus.Metric) error {
 	if err != nil {
 		return err
 	}
+	var dimms []edacDimm
 	for _, controller := range memControllers {
 		controllerMatch := edacMemControllerRE.FindStringSubmatch(controller)
 		if controllerMatch == nil {
@@ -138,7 +166,109 @@ func (c *edacCollector) Update(ch chan<- prometheus.Metric) error {
 			ch <- prometheus.MustNewConstMetric(
 				c.csRowUECount, prometheus.CounterValue, float64(value), controllerNumber, csrowNumber)
 		}
+
+		controllerDimms, err := readEdacDimms(controller, controllerNumber)
+		if err != nil {
+			return fmt.Errorf("couldn't get DIMMs for controller %s: %w", controllerNumber, err)
+		}
+		dimms = append(dimms, controllerDimms...)
+	}
+
+	if len(dimms) == 0 {
+		return nil
+	}
+	sort.Slice(dimms, func(i, j int) bool {
+		if dimms[i].controller != dimms[j].controller {
+			return dimms[i].controller < dimms[j].controller
+		}
+		return dimms[i].dimm < dimms[j].dimm
+	})
+	memDevices, err := readSMBIOSMemoryDevices()
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "couldn't read SMBIOS memory devices, DIMM locators unavailable", "err", err)
+	}
+	locators := edacDimmLocators(dimms, memDevices)
+	for i, dimm := range dimms {
+		labels := []string{strconv.Itoa(dimm.controller), strconv.Itoa(dimm.dimm), dimm.label, dimm.location, locators[i]}
+		ch <- prometheus.MustNewConstMetric(c.dimmCECount, prometheus.CounterValue, float64(dimm.ceCount), labels...)
+		ch <- prometheus.MustNewConstMetric(c.dimmUECount, prometheus.CounterValue, float64(dimm.ueCount), labels...)
+	}
+
+	return nil
+}
+
+// readEdacDimms returns the DIMMs of a memory controller.
+func readEdacDimms(controller, controllerNumber string) ([]edacDimm, error) {
+	number, err := strconv.Atoi(controllerNumber)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(controller + "/dimm[0-9]*")
+	if err != nil {
+		return nil, err
 	}
+	ranks, err := filepath.Glob(controller + "/rank[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+
+	var dimms []edacDimm
+	for _, path := range append(paths, ranks...) {
+		dimmMatch := edacMemDimmRE.FindStringSubmatch(path)
+		if dimmMatch == nil {
+			return nil, fmt.Errorf("dimm string didn't match regexp: %s", path)
+		}
+		dimm := edacDimm{controller: number}
+		if dimm.dimm, err = strconv.Atoi(dimmMatch[1]); err != nil {
+			return nil, err
+		}
+		if dimm.ceCount, err = readUintFromFile(filepath.Join(path, "dimm_ce_count")); err != nil {
+			// Older kernels lack the per DIMM counters.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if dimm.ueCount, err = readUintFromFile(filepath.Join(path, "dimm_ue_count")); err != nil {
+			return nil, err
+		}
+		if label, err := ioutil.ReadFile(filepath.Join(path, "dimm_label")); err == nil {
+			dimm.label = strings.TrimSpace(string(label))
+		}
+		if location, err := ioutil.ReadFile(filepath.Join(path, "dimm_location")); err == nil {
+			dimm.location = strings.Join(strings.Fields(string(location)), " ")
+		}
+		dimms = append(dimms, dimm)
+	}
+	return dimms, nil
+}
+
+// edacDimmLocators returns the SMBIOS device locator of each DIMM, or an
+// empty string if it's unknown.
+//
+// Some EDAC drivers label DIMMs after their SMBIOS bank and device locators,
+// and labels can be set from userspace, so a DIMM whose label or location
+// contains a device locator is matched with it. DIMMs are not matched by
+// their position, as the order of EDAC and of the SMBIOS table can differ and
+// a wrong locator would point to the wrong slot.
+func edacDimmLocators(dimms []edacDimm, memDevices []smbiosMemoryDevice) []string {
+	locators := make([]string, len(dimms))
+	for i, dimm := range dimms {
+		for _, device := range memDevices {
+			if !device.Installed || device.Locator == "" {
+				continue
+			}
+			if edacNameMatches(dimm.label, device.Locator) || edacNameMatches(dimm.location, device.Locator) {
+				locators[i] = device.Locator
+				break
+			}
+		}
+	}
+	return locators
+}
 
-	return err
+// edacNameMatches returns whether the label or location is the locator, or
+// ends with it like "NODE 1 CPU1_DIMM_A1".
+func edacNameMatches(name, locator string) bool {
+	return name == locator || strings.HasSuffix(name, " "+locator)
 }
@@ -0,0 +1,111 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noedac
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadSMBIOSMemoryDevices(t *testing.T) {
+	*sysPath = "fixtures/sys"
+
+	devices, err := readSMBIOSMemoryDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []smbiosMemoryDevice{
+		{
+			Locator: "CPU1_DIMM_A1", BankLocator: "NODE 1", Installed: true, Type: "DDR4",
+			Manufacturer: "Samsung", SerialNumber: "0x1A2B3C4D", PartNumber: "M393A2K43BB1-CTD",
+			Size: 16 << 30, Speed: 2666, ConfiguredSpeed: 2666,
+		},
+		{
+			Locator: "CPU1_DIMM_A2", BankLocator: "NODE 1", Type: "Unknown",
+			Manufacturer: "NO DIMM", SerialNumber: "NO DIMM", PartNumber: "NO DIMM",
+		},
+		{
+			Locator: "CPU1_DIMM_B1", BankLocator: "NODE 1", Installed: true, Type: "DDR4",
+			Manufacturer: "Samsung", SerialNumber: "0x5E6F7A8B", PartNumber: "M393A4K40CB2-CTD",
+			Size: 32 << 30, Speed: 2666, ConfiguredSpeed: 2666,
+		},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("want %+v, got %+v", want, devices)
+	}
+}
+
+func TestEdacDimmLocators(t *testing.T) {
+	memDevices := []smbiosMemoryDevice{
+		{Locator: "CPU1_DIMM_A1", BankLocator: "NODE 1", Installed: true},
+		{Locator: "CPU1_DIMM_A2", BankLocator: "NODE 1"},
+		{Locator: "CPU1_DIMM_B1", BankLocator: "NODE 1", Installed: true},
+		{Locator: "CPU2_DIMM_A1", BankLocator: "NODE 2", Installed: true},
+	}
+
+	for _, tt := range []struct {
+		name  string
+		dimms []edacDimm
+		want  []string
+	}{
+		{
+			name: "by label",
+			dimms: []edacDimm{
+				{controller: 0, dimm: 0, label: "NODE 1 CPU1_DIMM_B1"},
+				{controller: 1, dimm: 0, label: "CPU2_DIMM_A1"},
+			},
+			want: []string{"CPU1_DIMM_B1", "CPU2_DIMM_A1"},
+		},
+		{
+			name: "by location",
+			dimms: []edacDimm{
+				{controller: 0, dimm: 0, label: "mc#0csrow#0channel#0", location: "CPU1_DIMM_A1"},
+			},
+			want: []string{"CPU1_DIMM_A1"},
+		},
+		{
+			// The order of the SMBIOS table is not the one of EDAC.
+			name: "not by position",
+			dimms: []edacDimm{
+				{controller: 0, dimm: 0, label: "CPU_SrcID#0_MC#0_Chan#0_DIMM#0"},
+				{controller: 0, dimm: 1, label: "CPU_SrcID#0_MC#0_Chan#1_DIMM#0"},
+				{controller: 1, dimm: 0, label: "CPU_SrcID#1_MC#0_Chan#0_DIMM#0"},
+			},
+			want: []string{"", "", ""},
+		},
+		{
+			name: "partial match",
+			dimms: []edacDimm{
+				{controller: 0, dimm: 0, label: "CPU_SrcID#0_MC#0_Chan#0_DIMM#0"},
+				{controller: 0, dimm: 1, label: "CPU2_DIMM_A1"},
+			},
+			want: []string{"", "CPU2_DIMM_A1"},
+		},
+		{
+			name: "not installed",
+			dimms: []edacDimm{
+				{controller: 0, dimm: 0, label: "CPU1_DIMM_A2"},
+			},
+			want: []string{""},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := edacDimmLocators(tt.dimms, memDevices); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -17,17 +17,26 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
 )
 
 type entropyCollector struct {
-	fs              procfs.FS
-	entropyAvail    *prometheus.Desc
-	entropyPoolSize *prometheus.Desc
-	logger          log.Logger
+	fs                   procfs.FS
+	entropyAvail         *prometheus.Desc
+	entropyPoolSize      *prometheus.Desc
+	hwrngSource          *prometheus.Desc
+	hwrngQuality         *prometheus.Desc
+	rngdRunning          *prometheus.Desc
+	jitterentropyPresent *prometheus.Desc
+	logger               log.Logger
 }
 
 func init() {
@@ -53,6 +62,26 @@ func NewEntropyCollector(logger log.Logger) (Collector, error) {
 			"Bits of entropy pool.",
 			nil, nil,
 		),
+		hwrngSource: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "hwrng_source"),
+			"Available hardware random number generators, the current one has a value of 1.",
+			[]string{"rng"}, nil,
+		),
+		hwrngQuality: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "hwrng_quality"),
+			"Estimated bits of entropy per 1024 bits of the current hardware random number generator, which only feeds the entropy pool if it is above zero.",
+			nil, nil,
+		),
+		rngdRunning: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "rngd_running"),
+			"Whether rngd is running to feed the entropy pool from user space.",
+			nil, nil,
+		),
+		jitterentropyPresent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "entropy", "jitterentropy_available"),
+			"Whether the CPU jitter random number generator of the kernel crypto API is available.",
+			nil, nil,
+		),
 		logger: logger,
 	}, nil
 }
@@ -75,5 +104,70 @@ func (c *entropyCollector) Update(ch chan<- prometheus.Metric) error {
 	ch <- prometheus.MustNewConstMetric(
 		c.entropyPoolSize, prometheus.GaugeValue, float64(*stats.PoolSize))
 
+	if err := c.updateHWRNG(ch); err != nil {
+		return fmt.Errorf("couldn't get hardware random number generators: %w", err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.rngdRunning, prometheus.GaugeValue, c.rngdRunningValue())
+
+	// The crypto API has no jitterentropy if it is not built or loaded.
+	jitterentropy := 0.0
+	if algs, err := c.fs.Crypto(); err != nil {
+		level.Debug(c.logger).Log("msg", "couldn't get crypto algorithms", "err", err)
+	} else {
+		for _, alg := range algs {
+			if alg.Name == "jitterentropy_rng" {
+				jitterentropy = 1
+			}
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.jitterentropyPresent, prometheus.GaugeValue, jitterentropy)
+
 	return nil
 }
+
+func (c *entropyCollector) updateHWRNG(ch chan<- prometheus.Metric) error {
+	attrs := map[string]string{}
+	for _, name := range []string{"rng_available", "rng_current", "rng_quality"} {
+		value, err := ioutil.ReadFile(sysFilePath("class/misc/hw_random/" + name))
+		if err != nil {
+			// Older kernels miss rng_quality, all are missing without rng-core.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		attrs[name] = strings.TrimSpace(string(value))
+	}
+	if _, ok := attrs["rng_available"]; !ok {
+		level.Debug(c.logger).Log("msg", "no hardware random number generators found")
+		return nil
+	}
+
+	for _, rng := range strings.Fields(attrs["rng_available"]) {
+		current := 0.0
+		if rng == attrs["rng_current"] {
+			current = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.hwrngSource, prometheus.GaugeValue, current, rng)
+	}
+	if quality, err := strconv.ParseFloat(attrs["rng_quality"], 64); err == nil && attrs["rng_current"] != "none" {
+		ch <- prometheus.MustNewConstMetric(c.hwrngQuality, prometheus.GaugeValue, quality)
+	}
+	return nil
+}
+
+func (c *entropyCollector) rngdRunningValue() float64 {
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "couldn't list processes", "err", err)
+		return 0
+	}
+	for _, p := range procs {
+		// Processes can vanish while reading them.
+		if comm, err := p.Comm(); err == nil && comm == "rngd" {
+			return 1
+		}
+	}
+	return 0
+}
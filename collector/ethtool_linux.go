@@ -38,7 +38,7 @@ import (
 )
 
 var (
-	ethtoolIgnoredDevices  = kingpin.Flag("collector.ethtool.ignored-devices", "Regexp of net devices to ignore for ethtool collector.").Default("^$").String()
+	ethtoolIgnoredDevices  = kingpin.Flag("collector.ethtool.ignored-devices", "Regexp, or comma separated globs prefixed with glob:, of net devices to ignore for ethtool collector.").Default("^$").String()
 	ethtoolIncludedMetrics = kingpin.Flag("collector.ethtool.metrics-include", "Regexp of ethtool stats to include.").Default(".*").String()
 	metricNameRegex        = regexp.MustCompile(`_*[^0-9A-Za-z_]+_*`)
 	receivedRegex          = regexp.MustCompile(`(^|_)rx(_|$)`)
@@ -66,7 +66,7 @@ type ethtoolCollector struct {
 	fs                    sysfs.FS
 	entries               map[string]*prometheus.Desc
 	ethtool               Ethtool
-	ignoredDevicesPattern *regexp.Regexp
+	ignoredDevicesPattern *deviceMatcher
 	infoDesc              *prometheus.Desc
 	metricsPattern        *regexp.Regexp
 	logger                log.Logger
@@ -90,7 +90,7 @@ func makeEthtoolCollector(logger log.Logger) (*ethtoolCollector, error) {
 	return &ethtoolCollector{
 		fs:                    fs,
 		ethtool:               &ethtoolLibrary{e},
-		ignoredDevicesPattern: regexp.MustCompile(*ethtoolIgnoredDevices),
+		ignoredDevicesPattern: mustNewDeviceMatcher(*ethtoolIgnoredDevices),
 		metricsPattern:        regexp.MustCompile(*ethtoolIncludedMetrics),
 		logger:                logger,
 		entries: map[string]*prometheus.Desc{
@@ -188,7 +188,7 @@ func (c *ethtoolCollector) Update(ch chan<- prometheus.Metric) error {
 		var stats map[string]uint64
 		var err error
 
-		if c.ignoredDevicesPattern.MatchString(device) {
+		if c.ignoredDevicesPattern.matches(device) {
 			continue
 		}
 
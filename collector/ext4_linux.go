@@ -0,0 +1,93 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noext4
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const ext4Subsystem = "ext4"
+
+type ext4Collector struct {
+	errors         *prometheus.Desc
+	firstErrorTime *prometheus.Desc
+	lastErrorTime  *prometheus.Desc
+	written        *prometheus.Desc
+	logger         log.Logger
+}
+
+func init() {
+	registerCollector(ext4Subsystem, defaultDisabled, NewExt4Collector)
+}
+
+// NewExt4Collector returns a new Collector exposing the error counters and
+// lifetime writes of mounted ext4 filesystems.
+func NewExt4Collector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, ext4Subsystem, name), help, []string{"device"}, nil)
+	}
+	return &ext4Collector{
+		errors:         desc("errors_total", "Number of errors recorded in the superblock of the filesystem, a filesystem with errors is checked on the next mount."),
+		firstErrorTime: desc("first_error_time_seconds", "Time of the first error recorded in the superblock, zero if there was none."),
+		lastErrorTime:  desc("last_error_time_seconds", "Time of the last error recorded in the superblock, zero if there was none."),
+		written:        desc("lifetime_written_bytes_total", "Number of bytes written to the filesystem over its lifetime, counted in KiB."),
+		logger:         logger,
+	}, nil
+}
+
+func (c *ext4Collector) Update(ch chan<- prometheus.Metric) error {
+	// Besides the devices the directory holds the supported features.
+	devices, err := filepath.Glob(sysFilePath("fs/ext4/*/errors_count"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		level.Debug(c.logger).Log("msg", "no mounted ext4 filesystems found")
+		return ErrNoData
+	}
+
+	for _, path := range devices {
+		dir := filepath.Dir(path)
+		device := filepath.Base(dir)
+		for name, m := range map[string]struct {
+			desc      *prometheus.Desc
+			valueType prometheus.ValueType
+			scale     float64
+		}{
+			"errors_count":          {c.errors, prometheus.CounterValue, 1},
+			"first_error_time":      {c.firstErrorTime, prometheus.GaugeValue, 1},
+			"last_error_time":       {c.lastErrorTime, prometheus.GaugeValue, 1},
+			"lifetime_write_kbytes": {c.written, prometheus.CounterValue, 1024},
+		} {
+			value, err := readUintFromFile(filepath.Join(dir, name))
+			if err != nil {
+				// Older kernels miss some of the attributes.
+				if os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("failed to read %s of ext4 filesystem %s: %w", name, device, err)
+			}
+			ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, float64(value)*m.scale, device)
+		}
+	}
+	return nil
+}
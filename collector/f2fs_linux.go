@@ -0,0 +1,174 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nof2fs
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	f2fsSubsystem = "f2fs"
+
+	// f2fsBlocksPerSegment is the number of blocks of a segment, which is
+	// fixed to 2 MiB of 4 KiB blocks.
+	f2fsBlocksPerSegment = 512
+)
+
+type f2fsCollector struct {
+	dirtySegments *prometheus.Desc
+	freeSegments  *prometheus.Desc
+	ovpSegments   *prometheus.Desc
+	segments      *prometheus.Desc
+	utilization   *prometheus.Desc
+	gcCalls       *prometheus.Desc
+	gcMovedBlocks *prometheus.Desc
+	written       *prometheus.Desc
+	logger        log.Logger
+}
+
+func init() {
+	registerCollector(f2fsSubsystem, defaultDisabled, NewF2FSCollector)
+}
+
+// NewF2FSCollector returns a new Collector exposing the segment usage and
+// garbage collection statistics of mounted f2fs filesystems.
+func NewF2FSCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, f2fsSubsystem, name), help, append([]string{"device"}, labels...), nil)
+	}
+	return &f2fsCollector{
+		dirtySegments: desc("dirty_segments", "Number of segments with both valid and invalid blocks, which garbage collection can reclaim."),
+		freeSegments:  desc("free_segments", "Number of free segments."),
+		ovpSegments:   desc("overprovision_segments", "Number of segments reserved for garbage collection."),
+		segments:      desc("segments", "Number of segments of the main area."),
+		utilization:   desc("utilization_ratio", "Ratio of valid blocks in the main area."),
+		gcCalls:       desc("gc_calls_total", "Number of garbage collection runs by type, foreground runs block writes.", "type"),
+		gcMovedBlocks: desc("gc_moved_blocks_total", "Number of blocks moved by garbage collection by type.", "type"),
+		written:       desc("lifetime_written_bytes_total", "Number of bytes written to the filesystem over its lifetime, counted in KiB."),
+		logger:        logger,
+	}, nil
+}
+
+func (c *f2fsCollector) Update(ch chan<- prometheus.Metric) error {
+	// Besides the devices the directory holds the supported features.
+	devices, err := filepath.Glob(sysFilePath("fs/f2fs/*/dirty_segments"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		level.Debug(c.logger).Log("msg", "no mounted f2fs filesystems found")
+		return ErrNoData
+	}
+
+	for _, path := range devices {
+		dir := filepath.Dir(path)
+		device := filepath.Base(dir)
+		for _, m := range []struct {
+			name      string
+			desc      *prometheus.Desc
+			valueType prometheus.ValueType
+			scale     float64
+			labels    []string
+		}{
+			{"dirty_segments", c.dirtySegments, prometheus.GaugeValue, 1, nil},
+			{"free_segments", c.freeSegments, prometheus.GaugeValue, 1, nil},
+			{"ovp_segments", c.ovpSegments, prometheus.GaugeValue, 1, nil},
+			{"gc_foreground_calls", c.gcCalls, prometheus.CounterValue, 1, []string{"foreground"}},
+			{"gc_background_calls", c.gcCalls, prometheus.CounterValue, 1, []string{"background"}},
+			{"moved_blocks_foreground", c.gcMovedBlocks, prometheus.CounterValue, 1, []string{"foreground"}},
+			{"moved_blocks_background", c.gcMovedBlocks, prometheus.CounterValue, 1, []string{"background"}},
+			{"lifetime_write_kbytes", c.written, prometheus.CounterValue, 1024, nil},
+		} {
+			value, err := readUintFromFile(filepath.Join(dir, m.name))
+			if err != nil {
+				// Older kernels miss some of the attributes.
+				if os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("failed to read %s of f2fs filesystem %s: %w", m.name, device, err)
+			}
+			ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, float64(value)*m.scale, append([]string{device}, m.labels...)...)
+		}
+
+		if err := c.updateSegmentInfo(ch, device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *f2fsCollector) updateSegmentInfo(ch chan<- prometheus.Metric, device string) error {
+	f, err := os.Open(procFilePath(filepath.Join("fs/f2fs", device, "segment_info")))
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "no f2fs segment info found", "device", device)
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	segments, validBlocks, err := parseF2FSSegmentInfo(f)
+	if err != nil {
+		return fmt.Errorf("failed to parse segment info of f2fs filesystem %s: %w", device, err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.segments, prometheus.GaugeValue, float64(segments), device)
+	if segments > 0 {
+		ch <- prometheus.MustNewConstMetric(c.utilization, prometheus.GaugeValue, float64(validBlocks)/float64(segments*f2fsBlocksPerSegment), device)
+	}
+	return nil
+}
+
+// parseF2FSSegmentInfo returns the number of segments and valid blocks from
+// the segment info of a filesystem, which lists "type|valid_blocks" of ten
+// segments per line after their first segment number.
+func parseF2FSSegmentInfo(r io.Reader) (uint64, uint64, error) {
+	var segments, validBlocks uint64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		// Skip the description of the format.
+		if _, err := strconv.ParseUint(fields[0], 10, 64); err != nil {
+			continue
+		}
+		for _, field := range fields[1:] {
+			parts := strings.SplitN(field, "|", 2)
+			if len(parts) != 2 {
+				return 0, 0, fmt.Errorf("malformed segment %q", field)
+			}
+			blocks, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("malformed segment %q: %w", field, err)
+			}
+			segments++
+			validBlocks += blocks
+		}
+	}
+	return segments, validBlocks, scanner.Err()
+}
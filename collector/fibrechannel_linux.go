@@ -18,7 +18,11 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -28,9 +32,23 @@ import (
 
 const maxUint64 = ^uint64(0)
 
+// fibrechannelExtraStatistics maps fc_host statistics files not parsed by
+// procfs to their metric names.
+var fibrechannelExtraStatistics = map[string]string{
+	"lip_count":                   "lip_total",
+	"prim_seq_protocol_err_count": "prim_seq_protocol_errors_total",
+	"fcp_input_requests":          "fcp_input_requests_total",
+	"fcp_output_requests":         "fcp_output_requests_total",
+	"fcp_control_requests":        "fcp_control_requests_total",
+	"fcp_input_megabytes":         "fcp_input_bytes_total",
+	"fcp_output_megabytes":        "fcp_output_bytes_total",
+}
+
 type fibrechannelCollector struct {
 	fs          sysfs.FS
 	metricDescs map[string]*prometheus.Desc
+	rportInfo   *prometheus.Desc
+	rportOnline *prometheus.Desc
 	logger      log.Logger
 	subsystem   string
 }
@@ -66,6 +84,13 @@ func NewFibreChannelCollector(logger log.Logger) (Collector, error) {
 		"rx_words_total":                 "Number of words received by host port",
 		"tx_frames_total":                "Number of frames transmitted by host port",
 		"link_failure_total":             "Number of times the host port link has failed",
+		"lip_total":                      "Number of loop initialization primitives",
+		"prim_seq_protocol_errors_total": "Number of primitive sequence protocol errors",
+		"fcp_input_requests_total":       "Number of FCP read requests",
+		"fcp_output_requests_total":      "Number of FCP write requests",
+		"fcp_control_requests_total":     "Number of FCP control requests",
+		"fcp_input_bytes_total":          "Number of bytes read by FCP requests, in MiB resolution",
+		"fcp_output_bytes_total":         "Number of bytes written by FCP requests, in MiB resolution",
 		"name":                           "Name of Fibre Channel HBA",
 		"speed":                          "Current operating speed",
 		"port_state":                     "Current port state",
@@ -92,6 +117,19 @@ func NewFibreChannelCollector(logger log.Logger) (Collector, error) {
 		)
 	}
 
+	i.rportInfo = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, i.subsystem, "rport_info"),
+		"Non-numeric data from /sys/class/fc_remote_ports/<rport>, value is always 1.",
+		[]string{"fc_host", "rport", "port_state", "port_name", "node_name", "port_id", "roles"},
+		nil,
+	)
+	i.rportOnline = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, i.subsystem, "rport_online"),
+		"Whether the remote port is in the Online state.",
+		[]string{"fc_host", "rport"},
+		nil,
+	)
+
 	return &i, nil
 }
 
@@ -143,7 +181,64 @@ func (c *fibrechannelCollector) Update(ch chan<- prometheus.Metric) error {
 		c.pushCounter(ch, "loss_of_signal_total", host.Counters.LossOfSignalCount, host.Name)
 		c.pushCounter(ch, "nos_total", host.Counters.NosCount, host.Name)
 		c.pushCounter(ch, "fcp_packet_aborts_total", host.Counters.FCPPacketAborts, host.Name)
+
+		c.updateExtraStatistics(ch, host.Name)
+	}
+
+	return c.updateRemotePorts(ch)
+}
+
+// updateExtraStatistics pushes the counters of statistics files only some
+// HBA drivers provide.
+func (c *fibrechannelCollector) updateExtraStatistics(ch chan<- prometheus.Metric, host string) {
+	for file, name := range fibrechannelExtraStatistics {
+		value, err := readFibreChannelHex(sysFilePath(filepath.Join("class/fc_host", host, "statistics", file)))
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(file, "_megabytes") && value != maxUint64 {
+			value <<= 20
+		}
+		c.pushCounter(ch, name, value, host)
+	}
+}
+
+func (c *fibrechannelCollector) updateRemotePorts(ch chan<- prometheus.Metric) error {
+	rports, err := filepath.Glob(sysFilePath("class/fc_remote_ports/rport-*"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range rports {
+		rport := filepath.Base(path)
+		// Remote ports are named rport-<host>:<channel>-<number>.
+		hostNum := strings.SplitN(strings.TrimPrefix(rport, "rport-"), ":", 2)[0]
+		host := "host" + hostNum
+
+		attrs := map[string]string{}
+		for _, attr := range []string{"port_state", "port_name", "node_name", "port_id", "roles"} {
+			value, err := ioutil.ReadFile(filepath.Join(path, attr))
+			if err != nil {
+				level.Debug(c.logger).Log("msg", "failed to read remote port attribute", "rport", rport, "attr", attr, "err", err)
+			}
+			attrs[attr] = strings.TrimSpace(string(value))
+		}
+
+		online := 0.0
+		if attrs["port_state"] == "Online" {
+			online = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.rportInfo, prometheus.GaugeValue, 1, host, rport, attrs["port_state"], attrs["port_name"], attrs["node_name"], attrs["port_id"], attrs["roles"])
+		ch <- prometheus.MustNewConstMetric(c.rportOnline, prometheus.GaugeValue, online, host, rport)
 	}
 
 	return nil
 }
+
+func readFibreChannelHex(path string) (uint64, error) {
+	value, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(value)), 0, 64)
+}
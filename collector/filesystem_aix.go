@@ -0,0 +1,135 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nofilesystem
+// +build cgo
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/go-kit/log/level"
+)
+
+/*
+#include <stdlib.h>
+#include <sys/mntctl.h>
+#include <sys/statfs.h>
+#include <sys/vmount.h>
+
+static char *vmt_object(struct vmount *vmt) { return vmt2dataptr(vmt, VMT_OBJECT); }
+static char *vmt_stub(struct vmount *vmt) { return vmt2dataptr(vmt, VMT_STUB); }
+static char *vmt_host(struct vmount *vmt) { return vmt2dataptr(vmt, VMT_HOSTNAME); }
+*/
+import "C"
+
+const (
+	defMountPointsExcluded = "^/(dev|proc)($|/)"
+	defFSTypesExcluded     = "^(namefs|procfs)$"
+)
+
+// vfsTypes are the names of the virtual file system types of vmounts as
+// listed in /etc/vfs.
+var vfsTypes = map[C.int]string{
+	C.MNT_J2:     "jfs2",
+	C.MNT_NAMEFS: "namefs",
+	C.MNT_NFS:    "nfs",
+	C.MNT_JFS:    "jfs",
+	C.MNT_CDROM:  "cdrfs",
+	C.MNT_PROCFS: "procfs",
+	C.MNT_NFS3:   "nfs3",
+	C.MNT_AUTOFS: "autofs",
+	C.MNT_NFS4:   "nfs4",
+	C.MNT_CIFS:   "cifs",
+}
+
+func (c *filesystemCollector) GetStats() (stats []filesystemStats, err error) {
+	buf, n, err := queryMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	stats = []filesystemStats{}
+	for off, i := 0, 0; i < n; i++ {
+		vmt := (*C.struct_vmount)(unsafe.Pointer(&buf[off]))
+		off += int(vmt.vmt_length)
+
+		mountpoint := C.GoString(C.vmt_stub(vmt))
+		if c.excludedMountPointsPattern.MatchString(mountpoint) {
+			level.Debug(c.logger).Log("msg", "Ignoring mount point", "mountpoint", mountpoint)
+			continue
+		}
+
+		fstype, ok := vfsTypes[vmt.vmt_gfstype]
+		if !ok {
+			fstype = fmt.Sprintf("vfs%d", vmt.vmt_gfstype)
+		}
+		if c.excludedFSTypesPattern.MatchString(fstype) {
+			level.Debug(c.logger).Log("msg", "Ignoring fs type", "type", fstype)
+			continue
+		}
+
+		device := C.GoString(C.vmt_object(vmt))
+		// Local file systems have "-" as host.
+		if host := C.GoString(C.vmt_host(vmt)); host != "" && host != "-" {
+			device = host + ":" + device
+		}
+
+		var ro float64
+		if vmt.vmt_flags&C.MNT_READONLY != 0 {
+			ro = 1
+		}
+
+		labels := filesystemLabels{
+			device:     device,
+			mountPoint: mountpoint,
+			fsType:     fstype,
+		}
+		var fs C.struct_statfs
+		if C.statfs(C.vmt_stub(vmt), &fs) != 0 {
+			level.Debug(c.logger).Log("msg", "Error on statfs() system call", "mountpoint", mountpoint)
+			stats = append(stats, filesystemStats{labels: labels, deviceError: 1})
+			continue
+		}
+		stats = append(stats, filesystemStats{
+			labels:    labels,
+			size:      float64(fs.f_blocks) * float64(fs.f_bsize),
+			free:      float64(fs.f_bfree) * float64(fs.f_bsize),
+			avail:     float64(fs.f_bavail) * float64(fs.f_bsize),
+			files:     float64(fs.f_files),
+			filesFree: float64(fs.f_ffree),
+			ro:        ro,
+		})
+	}
+	return stats, nil
+}
+
+// queryMounts returns the struct vmount of the mounted file systems and their
+// number. mntctl returns 0 and the required size in the first word if the
+// buffer is too small.
+func queryMounts() ([]byte, int, error) {
+	buf := make([]byte, 4096)
+	for {
+		n := C.mntctl(C.MCTL_QUERY, C.int(len(buf)), (*C.char)(unsafe.Pointer(&buf[0])))
+		if n < 0 {
+			return nil, 0, errors.New("mntctl failed")
+		}
+		if n > 0 {
+			return buf, int(n), nil
+		}
+		buf = make([]byte, *(*C.int)(unsafe.Pointer(&buf[0])))
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nogpsd
+
+package collector
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const gpsSubsystem = "gps"
+
+var (
+	gpsdAddress = kingpin.Flag("collector.gpsd.address", "Address of the gpsd daemon.").Default("localhost:2947").String()
+	gpsdTimeout = kingpin.Flag("collector.gpsd.timeout", "Timeout for a gpsd query.").Default("2s").Duration()
+	gpsdPPS     = kingpin.Flag("collector.gpsd.pps", "Wait for a PPS report from gpsd, which can delay the scrape by up to a second.").Default("false").Bool()
+)
+
+type gpsdCollector struct {
+	devicesActive     *prometheus.Desc
+	fixMode           *prometheus.Desc
+	satellitesVisible *prometheus.Desc
+	satellitesUsed    *prometheus.Desc
+	ppsOffset         *prometheus.Desc
+	ppsJitter         *prometheus.Desc
+	logger            log.Logger
+
+	mtx        sync.Mutex
+	ppsOffsets map[string][]float64 // the last gpsdJitterSamples by device
+}
+
+// gpsdJitterSamples is the number of PPS offsets of consecutive scrapes the
+// jitter is computed from, the size of the clock filter of ntpd.
+const gpsdJitterSamples = 8
+
+// gpsdMessage holds the fields of the gpsd JSON reports used by the
+// collector, see gpsd_json(5).
+type gpsdMessage struct {
+	Class  string `json:"class"`
+	Device string `json:"device"`
+
+	// TPV
+	Mode int `json:"mode"`
+
+	// SKY, nSat and uSat are only reported by gpsd 3.23 and later.
+	NSat       *int `json:"nSat"`
+	USat       *int `json:"uSat"`
+	Satellites []struct {
+		Used bool `json:"used"`
+	} `json:"satellites"`
+
+	// PPS
+	RealSec   int64 `json:"real_sec"`
+	RealNsec  int64 `json:"real_nsec"`
+	ClockSec  int64 `json:"clock_sec"`
+	ClockNsec int64 `json:"clock_nsec"`
+
+	// POLL
+	Active int           `json:"active"`
+	TPV    []gpsdMessage `json:"tpv"`
+	Sky    []gpsdMessage `json:"sky"`
+}
+
+// gpsdReport is the state reported by a single gpsd POLL, optionally
+// followed by PPS reports.
+type gpsdReport struct {
+	active int
+	tpv    []gpsdMessage
+	sky    []gpsdMessage
+	pps    map[string]float64
+}
+
+func init() {
+	registerCollector("gpsd", defaultDisabled, NewGpsdCollector)
+}
+
+// NewGpsdCollector returns a new Collector exposing GPS fix and PPS state
+// from gpsd.
+func NewGpsdCollector(logger log.Logger) (Collector, error) {
+	return &gpsdCollector{
+		devicesActive: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpsSubsystem, "devices_active"),
+			"Number of GPS devices active in gpsd.",
+			nil, nil,
+		),
+		fixMode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpsSubsystem, "fix_mode"),
+			"NMEA mode of the GPS fix: 0 unknown, 1 no fix, 2 2D fix, 3 3D fix.",
+			[]string{"device"}, nil,
+		),
+		satellitesVisible: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpsSubsystem, "satellites_visible"),
+			"Number of satellites visible to the GPS device.",
+			[]string{"device"}, nil,
+		),
+		satellitesUsed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpsSubsystem, "satellites_used"),
+			"Number of satellites used in the GPS fix.",
+			[]string{"device"}, nil,
+		),
+		ppsOffset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpsSubsystem, "pps_offset_seconds"),
+			"Offset of the system clock from the last PPS pulse.",
+			[]string{"device"}, nil,
+		),
+		ppsJitter: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, gpsSubsystem, "pps_jitter_seconds"),
+			"Jitter of the PPS offset, the RMS of the differences between the offsets of the last scrapes.",
+			[]string{"device"}, nil,
+		),
+		logger:     logger,
+		ppsOffsets: map[string][]float64{},
+	}, nil
+}
+
+func (c *gpsdCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := net.DialTimeout("tcp", *gpsdAddress, *gpsdTimeout)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to gpsd: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(*gpsdTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(conn, `?WATCH={"enable":true,"json":true,"pps":true};?POLL;`); err != nil {
+		return fmt.Errorf("couldn't query gpsd: %w", err)
+	}
+	report, err := readGpsdReport(conn, *gpsdPPS)
+	if err != nil {
+		return fmt.Errorf("couldn't read gpsd report: %w", err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.devicesActive, prometheus.GaugeValue, float64(report.active))
+	for _, tpv := range report.tpv {
+		ch <- prometheus.MustNewConstMetric(c.fixMode, prometheus.GaugeValue, float64(tpv.Mode), tpv.Device)
+	}
+	for _, sky := range report.sky {
+		visible, used := len(sky.Satellites), 0
+		for _, sat := range sky.Satellites {
+			if sat.Used {
+				used++
+			}
+		}
+		if sky.NSat != nil && sky.USat != nil {
+			visible, used = *sky.NSat, *sky.USat
+		}
+		ch <- prometheus.MustNewConstMetric(c.satellitesVisible, prometheus.GaugeValue, float64(visible), sky.Device)
+		ch <- prometheus.MustNewConstMetric(c.satellitesUsed, prometheus.GaugeValue, float64(used), sky.Device)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for device, offset := range report.pps {
+		ch <- prometheus.MustNewConstMetric(c.ppsOffset, prometheus.GaugeValue, offset, device)
+
+		// gpsd reports no jitter, it is derived from the offsets like ntpd
+		// does.
+		offsets := append(c.ppsOffsets[device], offset)
+		if len(offsets) > gpsdJitterSamples {
+			offsets = offsets[len(offsets)-gpsdJitterSamples:]
+		}
+		c.ppsOffsets[device] = offsets
+		if len(offsets) > 1 {
+			ch <- prometheus.MustNewConstMetric(c.ppsJitter, prometheus.GaugeValue, ppsJitter(offsets), device)
+		}
+	}
+	if *gpsdPPS && len(report.pps) == 0 {
+		level.Debug(c.logger).Log("msg", "no PPS report received from gpsd")
+	}
+
+	return nil
+}
+
+// ppsJitter returns the root mean square of the differences between
+// consecutive offsets.
+func ppsJitter(offsets []float64) float64 {
+	var sum float64
+	for i := 1; i < len(offsets); i++ {
+		d := offsets[i] - offsets[i-1]
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(offsets)-1))
+}
+
+// readGpsdReport reads gpsd reports up to the response to ?POLL. If waitPPS
+// is set, it keeps reading until a PPS report arrives or the connection
+// deadline expires.
+func readGpsdReport(r io.Reader, waitPPS bool) (*gpsdReport, error) {
+	report := &gpsdReport{pps: map[string]float64{}}
+	polled := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var msg gpsdMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, err
+		}
+		switch msg.Class {
+		case "ERROR":
+			return nil, fmt.Errorf("gpsd returned an error: %s", scanner.Text())
+		case "POLL":
+			polled = true
+			report.active = msg.Active
+			report.tpv = msg.TPV
+			report.sky = msg.Sky
+		case "PPS":
+			report.pps[msg.Device] = float64(msg.RealSec-msg.ClockSec) + float64(msg.RealNsec-msg.ClockNsec)/1e9
+		}
+		if polled && (!waitPPS || len(report.pps) > 0) {
+			return report, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ne, ok := err.(net.Error); polled && ok && ne.Timeout() {
+			// No PPS within the timeout, the POLL response is still valid.
+			return report, nil
+		}
+		return nil, err
+	}
+	if !polled {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return report, nil
+}
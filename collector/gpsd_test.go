@@ -0,0 +1,134 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nogpsd
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const gpsdPollResponse = `{"class":"VERSION","release":"3.22","rev":"3.22","proto_major":3,"proto_minor":14}
+{"class":"DEVICES","devices":[{"class":"DEVICE","path":"/dev/ttyAMA0","driver":"u-blox","activated":"2021-09-08T10:00:00.000Z"}]}
+{"class":"WATCH","enable":true,"json":true,"pps":true}
+{"class":"POLL","time":"2021-09-08T10:00:01.000Z","active":1,"tpv":[{"class":"TPV","device":"/dev/ttyAMA0","mode":3,"lat":52.1,"lon":4.3}],"gst":[],"sky":[{"class":"SKY","device":"/dev/ttyAMA0","satellites":[{"PRN":1,"used":true},{"PRN":3,"used":true},{"PRN":8,"used":false}]}]}
+`
+
+func gpsdPPSReport(device string, offset time.Duration) string {
+	clock := time.Unix(1631095202, 0).Add(-offset)
+	return fmt.Sprintf(`{"class":"PPS","device":%q,"real_sec":1631095202,"real_nsec":0,"clock_sec":%d,"clock_nsec":%d,"precision":-20,"qErr":-2154}`+"\n",
+		device, clock.Unix(), clock.Nanosecond())
+}
+
+func TestReadGpsdReport(t *testing.T) {
+	report, err := readGpsdReport(strings.NewReader(gpsdPollResponse), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.active != 1 {
+		t.Errorf("expected 1 active device, got %d", report.active)
+	}
+	if len(report.tpv) != 1 || report.tpv[0].Mode != 3 || report.tpv[0].Device != "/dev/ttyAMA0" {
+		t.Errorf("unexpected TPV %+v", report.tpv)
+	}
+	if len(report.sky) != 1 || len(report.sky[0].Satellites) != 3 {
+		t.Errorf("unexpected SKY %+v", report.sky)
+	}
+
+	report, err = readGpsdReport(strings.NewReader(gpsdPollResponse+gpsdPPSReport("/dev/pps0", 1500*time.Microsecond)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset := report.pps["/dev/pps0"]; math.Abs(offset-0.0015) > 1e-9 {
+		t.Errorf("expected PPS offset 0.0015, got %v", offset)
+	}
+
+	if _, err := readGpsdReport(strings.NewReader(`{"class":"ERROR","message":"Unrecognized request"}`+"\n"), false); err == nil {
+		t.Error("expected error for a gpsd error")
+	}
+	if _, err := readGpsdReport(strings.NewReader(`{"class":"VERSION"}`+"\n"), false); err == nil {
+		t.Error("expected error without a POLL response")
+	}
+}
+
+func TestGpsdCollector(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	offsets := []time.Duration{1000 * time.Microsecond, 1300 * time.Microsecond, 900 * time.Microsecond}
+	go func() {
+		for _, offset := range offsets {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// Answer ?WATCH and ?POLL once they arrived.
+			bufio.NewReader(conn).ReadString(';')
+			fmt.Fprint(conn, gpsdPollResponse+gpsdPPSReport("/dev/pps0", offset))
+			conn.Close()
+		}
+	}()
+
+	address, timeout, pps := *gpsdAddress, *gpsdTimeout, *gpsdPPS
+	*gpsdAddress, *gpsdTimeout, *gpsdPPS = listener.Addr().String(), time.Second, true
+	defer func() { *gpsdAddress, *gpsdTimeout, *gpsdPPS = address, timeout, pps }()
+
+	c, err := NewGpsdCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values map[string]float64
+	for range offsets {
+		ch := make(chan prometheus.Metric, 16)
+		if err := c.Update(ch); err != nil {
+			t.Fatal(err)
+		}
+		close(ch)
+		values = map[string]float64{}
+		for m := range ch {
+			var metric dto.Metric
+			if err := m.Write(&metric); err != nil {
+				t.Fatal(err)
+			}
+			name := strings.SplitN(strings.TrimPrefix(m.Desc().String(), `Desc{fqName: "`), `"`, 2)[0]
+			values[name] = metric.GetGauge().GetValue()
+		}
+	}
+
+	for name, want := range map[string]float64{
+		"node_gps_devices_active":     1,
+		"node_gps_fix_mode":           3,
+		"node_gps_satellites_visible": 3,
+		"node_gps_satellites_used":    2,
+		"node_gps_pps_offset_seconds": 0.0009,
+		// sqrt((0.0003² + 0.0004²) / 2)
+		"node_gps_pps_jitter_seconds": math.Sqrt((0.0003*0.0003 + 0.0004*0.0004) / 2),
+	} {
+		if got, ok := values[name]; !ok || math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v (found %t)", name, want, got, ok)
+		}
+	}
+}
@@ -14,10 +14,14 @@
 package collector
 
 import (
+	"bufio"
 	"bytes"
+	"io"
 	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 func readUintFromFile(path string) (uint64, error) {
@@ -44,3 +48,125 @@ func bytesToString(byteArray []byte) string {
 	}
 	return string(byteArray[:n])
 }
+
+// readVmstatFields returns the fields of /proc/vmstat starting with prefix,
+// with the prefix removed from their names.
+func readVmstatFields(prefix string) (map[string]uint64, error) {
+	file, err := os.Open(procFilePath("vmstat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fields := map[string]uint64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], prefix) {
+			continue
+		}
+		value, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		fields[strings.TrimPrefix(parts[0], prefix)] = value
+	}
+	return fields, scanner.Err()
+}
+
+// scanBufferPool holds the buffers of the scanners of files parsed on every
+// scrape, the lines of /proc/net/netstat exceed the default 4 KiB.
+var scanBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 16*1024)
+		return &b
+	},
+}
+
+// newPooledScanner returns a scanner of r with a buffer from the pool, which
+// has to be given back with release once scanning is done.
+func newPooledScanner(r io.Reader) (scanner *bufio.Scanner, release func()) {
+	buf := scanBufferPool.Get().(*[]byte)
+	scanner = bufio.NewScanner(r)
+	scanner.Buffer(*buf, bufio.MaxScanTokenSize)
+	return scanner, func() { scanBufferPool.Put(buf) }
+}
+
+// appendFields appends the space separated fields of b to dst like
+// bytes.Fields, reusing the slice between lines.
+func appendFields(dst [][]byte, b []byte) [][]byte {
+	start := -1
+	for i, c := range b {
+		if c == ' ' || c == '\t' {
+			if start >= 0 {
+				dst = append(dst, b[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		dst = append(dst, b[start:])
+	}
+	return dst
+}
+
+// parseFloatBytes parses the decimal integers of proc files without
+// allocating, other numbers are parsed by strconv.ParseFloat.
+func parseFloatBytes(b []byte) (float64, error) {
+	digits := b
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 || len(digits) > 19 {
+		return strconv.ParseFloat(string(b), 64)
+	}
+	var v uint64
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return strconv.ParseFloat(string(b), 64)
+		}
+		v = v*10 + uint64(c-'0')
+	}
+	if len(digits) < len(b) {
+		return -float64(v), nil
+	}
+	return float64(v), nil
+}
+
+// stringCache returns the strings of byte slices, e.g. the keys of proc
+// files, which are converted once instead of on every scrape.
+type stringCache struct {
+	mtx     sync.Mutex
+	strings map[string]string
+	convert func([]byte) string
+}
+
+// stringCacheSize bounds the cache, which e.g. holds the names of devices
+// coming and going.
+const stringCacheSize = 4096
+
+func newStringCache(convert func([]byte) string) *stringCache {
+	return &stringCache{strings: map[string]string{}, convert: convert}
+}
+
+func (c *stringCache) get(b []byte) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	// The conversion in the index expression does not allocate.
+	if s, ok := c.strings[string(b)]; ok {
+		return s
+	}
+	if len(c.strings) >= stringCacheSize {
+		c.strings = map[string]string{}
+	}
+	s := c.convert(b)
+	c.strings[string(b)] = s
+	return s
+}
+
+// internedStrings caches the plain conversion of byte slices.
+var internedStrings = newStringCache(func(b []byte) string { return string(b) })
@@ -61,3 +61,45 @@ func TestBytesToString(t *testing.T) {
 		}
 	}
 }
+
+func TestAppendFields(t *testing.T) {
+	fields := appendFields(nil, []byte("  8       0 sda\t25353629 "))
+	expected := []string{"8", "0", "sda", "25353629"}
+	if len(fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %d", len(expected), len(fields))
+	}
+	for i, field := range fields {
+		if string(field) != expected[i] {
+			t.Errorf("expected field %d to be %q, got %q", i, expected[i], field)
+		}
+	}
+}
+
+func TestParseFloatBytes(t *testing.T) {
+	for in, expected := range map[string]float64{
+		"0":                    0,
+		"25353629":             25353629,
+		"-1":                   -1,
+		"18446744073709551615": 18446744073709551615,
+		"0.5":                  0.5,
+	} {
+		got, err := parseFloatBytes([]byte(in))
+		if err != nil {
+			t.Errorf("parseFloatBytes(%q): %s", in, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("parseFloatBytes(%q): expected %f, got %f", in, expected, got)
+		}
+	}
+	for _, in := range []string{"", "-", "12a"} {
+		if _, err := parseFloatBytes([]byte(in)); err == nil {
+			t.Errorf("parseFloatBytes(%q): expected error", in)
+		}
+	}
+
+	b := []byte("25353629")
+	if allocs := testing.AllocsPerRun(100, func() { parseFloatBytes(b) }); allocs != 0 {
+		t.Errorf("expected no allocations, got %f", allocs)
+	}
+}
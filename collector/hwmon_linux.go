@@ -17,6 +17,8 @@ package collector
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -28,9 +30,12 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/yaml.v2"
 )
 
 var (
+	hwmonSensorNamesConfig  = kingpin.Flag("collector.hwmon.sensor-names-config", "Path to a file mapping chips and sensors to names and locations, which are added as sensor_name and location labels.").Default("").String()
 	hwmonInvalidMetricChars = regexp.MustCompile("[^a-z0-9:_]")
 	hwmonFilenameFormat     = regexp.MustCompile(`^(?P<type>[^0-9]+)(?P<id>[0-9]*)?(_(?P<property>.+))?$`)
 	hwmonLabelDesc          = []string{"chip", "sensor"}
@@ -42,18 +47,118 @@ var (
 	}
 )
 
+var (
+	hwmonDriveTempDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "disk", "temperature_celsius"),
+		"Temperature of the disk as reported by the drivetemp driver.",
+		[]string{"device"}, nil,
+	)
+	hwmonK10TempDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cpu", "temperature_celsius"),
+		"Temperature of the CPU package as reported by the k10temp driver, e.g. tctl or tccd1 for the first CCD.",
+		[]string{"package", "sensor"}, nil,
+	)
+	hwmonCoreEnergyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cpu", "core_energy_joules_total"),
+		"Energy consumed by the CPU core as reported by the amd_energy driver.",
+		[]string{"core"}, nil,
+	)
+	hwmonPackageEnergyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "cpu", "package_energy_joules_total"),
+		"Energy consumed by the CPU package as reported by the amd_energy driver.",
+		[]string{"package"}, nil,
+	)
+)
+
 func init() {
 	registerCollector("hwmon", defaultEnabled, NewHwMonCollector)
 }
 
 type hwMonCollector struct {
-	logger log.Logger
+	logger      log.Logger
+	sensorNames []hwmonSensorName
+}
+
+// hwmonSensorName maps sensors to a name and location, e.g.
+//
+//	sensors:
+//	- chip: nct6775
+//	  sensor: temp7
+//	  name: ambient_inlet
+//	  location: front
+//
+// The chip is a regular expression matching the chip or chip name, any chip
+// if unset. The sensor either matches the sensor or, if the label is set, the
+// sensor label regular expression. The first matching entry is used.
+type hwmonSensorName struct {
+	Chip     string `yaml:"chip"`
+	Sensor   string `yaml:"sensor"`
+	Label    string `yaml:"label"`
+	Name     string `yaml:"name"`
+	Location string `yaml:"location"`
+
+	chip  *regexp.Regexp
+	label *regexp.Regexp
 }
 
 // NewHwMonCollector returns a new Collector exposing /sys/class/hwmon stats
 // (similar to lm-sensors).
 func NewHwMonCollector(logger log.Logger) (Collector, error) {
-	return &hwMonCollector{logger}, nil
+	c := &hwMonCollector{logger: logger}
+	if *hwmonSensorNamesConfig != "" {
+		f, err := os.Open(*hwmonSensorNamesConfig)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		c.sensorNames, err = parseHwmonSensorNames(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hwmon sensor names config %s: %w", *hwmonSensorNamesConfig, err)
+		}
+	}
+	return c, nil
+}
+
+// parseHwmonSensorNames parses and validates a sensor names configuration.
+func parseHwmonSensorNames(r io.Reader) ([]hwmonSensorName, error) {
+	var config struct {
+		Sensors []hwmonSensorName `yaml:"sensors"`
+	}
+	if err := yaml.NewDecoder(r).Decode(&config); err != nil {
+		return nil, err
+	}
+	for i, s := range config.Sensors {
+		if s.Name == "" {
+			return nil, fmt.Errorf("sensor %d has no name", i)
+		}
+		if (s.Sensor == "") == (s.Label == "") {
+			return nil, fmt.Errorf("sensor %q needs either a sensor or a label", s.Name)
+		}
+		if s.Chip == "" {
+			s.Chip = ".*"
+		}
+		var err error
+		if config.Sensors[i].chip, err = regexp.Compile("^(?:" + s.Chip + ")$"); err != nil {
+			return nil, fmt.Errorf("invalid chip of sensor %q: %w", s.Name, err)
+		}
+		if config.Sensors[i].label, err = regexp.Compile("^(?:" + s.Label + ")$"); err != nil {
+			return nil, fmt.Errorf("invalid label of sensor %q: %w", s.Name, err)
+		}
+	}
+	return config.Sensors, nil
+}
+
+// sensorName returns the configured name and location of the sensor.
+func (c *hwMonCollector) sensorName(chip, chipName, sensor, label string) (string, string) {
+	for _, s := range c.sensorNames {
+		if !s.chip.MatchString(chip) && !s.chip.MatchString(chipName) {
+			continue
+		}
+		if s.Sensor == sensor || (s.Label != "" && s.label.MatchString(label)) {
+			return s.Name, s.Location
+		}
+	}
+	return "", ""
 }
 
 func cleanMetricName(name string) string {
@@ -63,12 +168,8 @@ func cleanMetricName(name string) string {
 	return cleaned
 }
 
-func addValueFile(data map[string]map[string]string, sensor string, prop string, file string) {
-	raw, err := sysReadFile(file)
-	if err != nil {
-		return
-	}
-	value := strings.Trim(string(raw), "\n")
+func addValue(data map[string]map[string]string, sensor string, prop string, raw string) {
+	value := strings.Trim(raw, "\n")
 
 	if _, ok := data[sensor]; !ok {
 		data[sensor] = make(map[string]string)
@@ -126,13 +227,15 @@ func explodeSensorFilename(filename string) (ok bool, sensorType string, sensorN
 	return true, sensorType, sensorNum, sensorProperty
 }
 
-func collectSensorData(dir string, data map[string]map[string]string) error {
-	sensorFiles, dirError := ioutil.ReadDir(dir)
+// collectSensorData reads the attributes of all sensors of the directory in
+// one batch, see --collector.io-uring.
+func collectSensorData(dir *sysfsDir, data map[string]map[string]string) error {
+	sensorFiles, dirError := dir.names()
 	if dirError != nil {
 		return dirError
 	}
-	for _, file := range sensorFiles {
-		filename := file.Name()
+	var files, sensors, props []string
+	for _, filename := range sensorFiles {
 		ok, sensorType, sensorNum, sensorProperty := explodeSensorFilename(filename)
 		if !ok {
 			continue
@@ -140,11 +243,20 @@ func collectSensorData(dir string, data map[string]map[string]string) error {
 
 		for _, t := range hwmonSensorTypes {
 			if t == sensorType {
-				addValueFile(data, sensorType+strconv.Itoa(sensorNum), sensorProperty, filepath.Join(dir, file.Name()))
+				files = append(files, filename)
+				sensors = append(sensors, sensorType+strconv.Itoa(sensorNum))
+				props = append(props, sensorProperty)
 				break
 			}
 		}
 	}
+	values, errs := dir.readStrings(files)
+	for i := range files {
+		if errs[i] != nil {
+			continue
+		}
+		addValue(data, sensors[i], props[i], values[i])
+	}
 	return nil
 }
 
@@ -154,13 +266,20 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 		return err
 	}
 
+	d, err := openSysfsDir(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
 	data := make(map[string]map[string]string)
-	err = collectSensorData(dir, data)
+	err = collectSensorData(d, data)
 	if err != nil {
 		return err
 	}
-	if _, err := os.Stat(filepath.Join(dir, "device")); err == nil {
-		err := collectSensorData(filepath.Join(dir, "device"), data)
+	if device, err := d.openDir("device"); err == nil {
+		err := collectSensorData(device, data)
+		device.Close()
 		if err != nil {
 			return err
 		}
@@ -185,12 +304,26 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 		)
 	}
 
+	switch hwmonChipName {
+	case "drivetemp":
+		c.updateDriveTemp(ch, dir)
+	case "k10temp":
+		c.updateK10Temp(ch, dir, data)
+	case "amd_energy":
+		c.updateAMDEnergy(ch, data)
+	}
+
 	// Format all sensors.
 	for sensor, sensorData := range data {
 
 		_, sensorType, _, _ := explodeSensorFilename(sensor)
 
-		labels := []string{hwmonName, sensor}
+		labelNames, labels := hwmonLabelDesc, []string{hwmonName, sensor}
+		if c.sensorNames != nil {
+			name, location := c.sensorName(hwmonName, hwmonChipName, sensor, sensorData["label"])
+			labelNames = append(append([]string{}, hwmonLabelDesc...), "sensor_name", "location")
+			labels = append(labels, name, location)
+		}
 		if labelText, ok := sensorData["label"]; ok {
 			label := cleanMetricName(labelText)
 			if label != "" {
@@ -206,7 +339,7 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 				value = 1.0
 			}
 			metricName := "node_hwmon_beep_enabled"
-			desc := prometheus.NewDesc(metricName, "Hardware beep enabled", hwmonLabelDesc, nil)
+			desc := prometheus.NewDesc(metricName, "Hardware beep enabled", labelNames, nil)
 			ch <- prometheus.MustNewConstMetric(
 				desc, prometheus.GaugeValue, value, labels...)
 			continue
@@ -217,7 +350,7 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 				continue
 			}
 			metricName := "node_hwmon_voltage_regulator_version"
-			desc := prometheus.NewDesc(metricName, "Hardware voltage regulator", hwmonLabelDesc, nil)
+			desc := prometheus.NewDesc(metricName, "Hardware voltage regulator", labelNames, nil)
 			ch <- prometheus.MustNewConstMetric(
 				desc, prometheus.GaugeValue, parsedValue, labels...)
 			continue
@@ -228,7 +361,7 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 				continue
 			}
 			metricName := "node_hwmon_update_interval_seconds"
-			desc := prometheus.NewDesc(metricName, "Hardware monitor update interval", hwmonLabelDesc, nil)
+			desc := prometheus.NewDesc(metricName, "Hardware monitor update interval", labelNames, nil)
 			ch <- prometheus.MustNewConstMetric(
 				desc, prometheus.GaugeValue, parsedValue*0.001, labels...)
 			continue
@@ -258,19 +391,19 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 
 			// special elements, fault, alarm & beep should be handed out without units
 			if element == "fault" || element == "alarm" {
-				desc := prometheus.NewDesc(name, "Hardware sensor "+element+" status ("+sensorType+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name, "Hardware sensor "+element+" status ("+sensorType+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, parsedValue, labels...)
 				continue
 			}
 			if element == "beep" {
-				desc := prometheus.NewDesc(name+"_enabled", "Hardware monitor sensor has beeping enabled", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name+"_enabled", "Hardware monitor sensor has beeping enabled", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, parsedValue, labels...)
 				continue
 			}
 
 			// everything else should get a unit
 			if sensorType == "in" || sensorType == "cpu" {
-				desc := prometheus.NewDesc(name+"_volts", "Hardware monitor for voltage ("+element+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name+"_volts", "Hardware monitor for voltage ("+element+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.GaugeValue, parsedValue*0.001, labels...)
 				continue
@@ -279,51 +412,51 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 				if element == "" {
 					element = "input"
 				}
-				desc := prometheus.NewDesc(name+"_celsius", "Hardware monitor for temperature ("+element+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name+"_celsius", "Hardware monitor for temperature ("+element+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.GaugeValue, parsedValue*0.001, labels...)
 				continue
 			}
 			if sensorType == "curr" {
-				desc := prometheus.NewDesc(name+"_amps", "Hardware monitor for current ("+element+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name+"_amps", "Hardware monitor for current ("+element+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.GaugeValue, parsedValue*0.001, labels...)
 				continue
 			}
 			if sensorType == "energy" {
-				desc := prometheus.NewDesc(name+"_joule_total", "Hardware monitor for joules used so far ("+element+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name+"_joule_total", "Hardware monitor for joules used so far ("+element+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.CounterValue, parsedValue/1000000.0, labels...)
 				continue
 			}
 			if sensorType == "power" && element == "accuracy" {
-				desc := prometheus.NewDesc(name, "Hardware monitor power meter accuracy, as a ratio", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name, "Hardware monitor power meter accuracy, as a ratio", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.GaugeValue, parsedValue/1000000.0, labels...)
 				continue
 			}
 			if sensorType == "power" && (element == "average_interval" || element == "average_interval_min" || element == "average_interval_max") {
-				desc := prometheus.NewDesc(name+"_seconds", "Hardware monitor power usage update interval ("+element+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name+"_seconds", "Hardware monitor power usage update interval ("+element+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.GaugeValue, parsedValue*0.001, labels...)
 				continue
 			}
 			if sensorType == "power" {
-				desc := prometheus.NewDesc(name+"_watt", "Hardware monitor for power usage in watts ("+element+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name+"_watt", "Hardware monitor for power usage in watts ("+element+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.GaugeValue, parsedValue/1000000.0, labels...)
 				continue
 			}
 
 			if sensorType == "humidity" {
-				desc := prometheus.NewDesc(name, "Hardware monitor for humidity, as a ratio (multiply with 100.0 to get the humidity as a percentage) ("+element+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name, "Hardware monitor for humidity, as a ratio (multiply with 100.0 to get the humidity as a percentage) ("+element+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.GaugeValue, parsedValue/1000000.0, labels...)
 				continue
 			}
 
 			if sensorType == "fan" && (element == "input" || element == "min" || element == "max" || element == "target") {
-				desc := prometheus.NewDesc(name+"_rpm", "Hardware monitor for fan revolutions per minute ("+element+")", hwmonLabelDesc, nil)
+				desc := prometheus.NewDesc(name+"_rpm", "Hardware monitor for fan revolutions per minute ("+element+")", labelNames, nil)
 				ch <- prometheus.MustNewConstMetric(
 					desc, prometheus.GaugeValue, parsedValue, labels...)
 				continue
@@ -331,7 +464,7 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 
 			// fallback, just dump the metric as is
 
-			desc := prometheus.NewDesc(name, "Hardware monitor "+sensorType+" element "+element, hwmonLabelDesc, nil)
+			desc := prometheus.NewDesc(name, "Hardware monitor "+sensorType+" element "+element, labelNames, nil)
 			ch <- prometheus.MustNewConstMetric(
 				desc, prometheus.GaugeValue, parsedValue, labels...)
 		}
@@ -398,6 +531,86 @@ func (c *hwMonCollector) hwmonName(dir string) (string, error) {
 	return "", errors.New("Could not derive a monitoring name for " + dir)
 }
 
+// updateDriveTemp exposes the temperature of disks monitored by the drivetemp
+// driver by their block device name, so they can be joined with diskstats.
+// The hwmon device is registered below the SCSI device of the disk.
+func (c *hwMonCollector) updateDriveTemp(ch chan<- prometheus.Metric, dir string) {
+	blockDevices, err := ioutil.ReadDir(filepath.Join(dir, "device", "block"))
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to find block device of drivetemp sensor", "dir", dir, "err", err)
+		return
+	}
+	raw, err := sysReadFile(filepath.Join(dir, "temp1_input"))
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to read drivetemp sensor", "dir", dir, "err", err)
+		return
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(raw)), 64)
+	if err != nil {
+		return
+	}
+
+	for _, blockDevice := range blockDevices {
+		ch <- prometheus.MustNewConstMetric(hwmonDriveTempDesc, prometheus.GaugeValue, value*0.001, blockDevice.Name())
+	}
+}
+
+// updateK10Temp exposes the control and CCD temperatures of AMD CPUs by
+// package and sensor label, as the sensor numbers differ between CPU
+// generations and the chip name doesn't tell the package.
+func (c *hwMonCollector) updateK10Temp(ch chan<- prometheus.Metric, dir string, data map[string]map[string]string) {
+	// The sensors belong to function 3 of the data fabric of the node, which
+	// is device 18h plus the node id on bus 0.
+	devicePath, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to find PCI device of k10temp sensor", "dir", dir, "err", err)
+		return
+	}
+	address := filepath.Base(devicePath)
+	slot, err := strconv.ParseUint(strings.TrimSuffix(address[strings.LastIndex(address, ":")+1:], ".3"), 16, 8)
+	if err != nil || slot < 0x18 {
+		level.Debug(c.logger).Log("msg", "failed to derive package of k10temp sensor", "device", address)
+		return
+	}
+	pkg := strconv.FormatUint(slot-0x18, 10)
+
+	for _, sensorData := range data {
+		label := cleanMetricName(sensorData["label"])
+		if label == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(sensorData["input"], 64)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(hwmonK10TempDesc, prometheus.GaugeValue, value*0.001, pkg, label)
+	}
+}
+
+// updateAMDEnergy exposes the per core and package energy counters of AMD
+// CPUs reported by the amd_energy driver, identified by their labels.
+func (c *hwMonCollector) updateAMDEnergy(ch chan<- prometheus.Metric, data map[string]map[string]string) {
+	for _, sensorData := range data {
+		value, err := strconv.ParseFloat(sensorData["input"], 64)
+		if err != nil {
+			continue
+		}
+		// The labels are Ecore000 for the first core and Esocket0 for the
+		// first package.
+		label := sensorData["label"]
+		for prefix, desc := range map[string]*prometheus.Desc{"Ecore": hwmonCoreEnergyDesc, "Esocket": hwmonPackageEnergyDesc} {
+			if !strings.HasPrefix(label, prefix) {
+				continue
+			}
+			id, err := strconv.Atoi(strings.TrimPrefix(label, prefix))
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value/1000000.0, strconv.Itoa(id))
+		}
+	}
+}
+
 // hwmonHumanReadableChipName is similar to the methods in hwmonName, but with
 // different precedences -- we can allow duplicates here.
 func (c *hwMonCollector) hwmonHumanReadableChipName(dir string) (string, error) {
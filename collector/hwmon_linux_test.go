@@ -0,0 +1,72 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nohwmon
+
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHwmonSensorName(t *testing.T) {
+	sensorNames, err := parseHwmonSensorNames(strings.NewReader(`
+sensors:
+- chip: platform_coretemp_1
+  label: Physical id \d+
+  name: cpu_package
+  location: socket1
+- chip: nct6779
+  sensor: fan2
+  name: front_intake
+  location: front
+- sensor: temp1
+  name: board
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &hwMonCollector{sensorNames: sensorNames}
+
+	for _, tt := range []struct {
+		chip, chipName, sensor, label string
+		name, location                string
+	}{
+		{"platform_coretemp_1", "coretemp", "temp1", "Physical id 1", "cpu_package", "socket1"},
+		{"platform_coretemp_1", "coretemp", "temp2", "Core 0", "", ""},
+		{"nct6779", "nct6779", "fan2", "", "front_intake", "front"},
+		{"nct6779", "nct6779", "fan1", "", "", ""},
+		{"platform_coretemp_0", "coretemp", "temp1", "Physical id 0", "board", ""},
+	} {
+		name, location := c.sensorName(tt.chip, tt.chipName, tt.sensor, tt.label)
+		if name != tt.name || location != tt.location {
+			t.Errorf("%s %s: want %q %q, got %q %q", tt.chip, tt.sensor, tt.name, tt.location, name, location)
+		}
+	}
+}
+
+func TestParseHwmonSensorNamesInvalid(t *testing.T) {
+	for config, want := range map[string]string{
+		"sensors: [{sensor: temp1}]":                            "sensor 0 has no name",
+		"sensors: [{name: a}]":                                  `sensor "a" needs either a sensor or a label`,
+		"sensors: [{name: a, sensor: temp1, label: Core}]":      `sensor "a" needs either a sensor or a label`,
+		"sensors: [{name: a, chip: '(', sensor: temp1}]":        "invalid chip of sensor",
+		"sensors: [{name: a, chip: coretemp, label: 'Core ('}]": "invalid label of sensor",
+	} {
+		_, err := parseHwmonSensorNames(strings.NewReader(config))
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("%s: want error %q, got %v", config, want, err)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nohwmon
+
+package collector
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+// plistref is the argument of proplib ioctls, the kernel maps the externalized
+// dictionary into the address space of the process.
+type plistref struct {
+	plist unsafe.Pointer
+	len   uintptr
+}
+
+// ENVSYS_GETDICTIONARY is _IOWR('E', 0, struct plistref).
+var ENVSYS_GETDICTIONARY = 0xc0000000 | uint((unsafe.Sizeof(plistref{})&0x1fff)<<16) | uint('E')<<8
+
+// envsysSensorTypes maps the types of envsys sensors to the metrics of the
+// Linux hwmon collector and of the maximum if any, values are scaled to base
+// units.
+var envsysSensorTypes = map[string]struct {
+	metric string
+	max    string
+	help   string
+	scale  func(int64) float64
+}{
+	"Temperature":    {"temp_celsius", "", "Hardware monitor for temperature.", func(v int64) float64 { return float64(v-273150000) / 1e6 }},
+	"Fan":            {"fan_rpm", "", "Hardware monitor for fan revolutions per minute.", func(v int64) float64 { return float64(v) }},
+	"Voltage DC":     {"in_volts", "", "Hardware monitor for DC voltage.", func(v int64) float64 { return float64(v) / 1e6 }},
+	"Voltage AC":     {"in_ac_volts", "", "Hardware monitor for AC voltage.", func(v int64) float64 { return float64(v) / 1e6 }},
+	"Watts":          {"power_watt", "", "Hardware monitor for power usage in watts.", func(v int64) float64 { return float64(v) / 1e6 }},
+	"Ampere":         {"curr_amps", "", "Hardware monitor for current.", func(v int64) float64 { return float64(v) / 1e6 }},
+	"Watt hour":      {"energy_joules", "energy_max_joules", "Hardware monitor for energy, e.g. the charge of batteries.", func(v int64) float64 { return float64(v) * 3600 / 1e6 }},
+	"Ampere hour":    {"charge_coulombs", "charge_max_coulombs", "Hardware monitor for electric charge, e.g. of batteries.", func(v int64) float64 { return float64(v) * 3600 / 1e6 }},
+	"Indicator":      {"indicator", "", "Hardware monitor for boolean indicators, e.g. whether the battery is charging.", func(v int64) float64 { return float64(v) }},
+	"Battery charge": {"indicator", "", "Hardware monitor for boolean indicators, e.g. whether the battery is charging.", func(v int64) float64 { return float64(v) }},
+}
+
+// envsysDriveStates are the values of drive sensors, starting with
+// ENVSYS_DRIVE_EMPTY at 1.
+var envsysDriveStates = []string{"unknown", "empty", "ready", "powering_up", "online", "idle", "active", "rebuilding", "powering_down", "failed", "predictive_fail", "migrating", "offline", "building", "checking"}
+
+// envsysBatteryCapacities are the values of battery capacity sensors,
+// starting with ENVSYS_BATTERY_CAPACITY_NORMAL at 1.
+var envsysBatteryCapacities = []string{"none", "normal", "warning", "critical", "low", "high"}
+
+// envsysStates are the states of valid sensors decided by the driver or the
+// limits configured with envstat(8).
+var envsysStates = []string{"valid", "critical", "critical-under", "critical-over", "warning-under", "warning-over"}
+
+type hwMonCollector struct {
+	driveState      *prometheus.Desc
+	batteryCapacity *prometheus.Desc
+	status          *prometheus.Desc
+	label           *prometheus.Desc
+	logger          log.Logger
+}
+
+func init() {
+	registerCollector("hwmon", defaultEnabled, NewHwMonCollector)
+}
+
+// NewHwMonCollector returns a new Collector exposing the sensors of the envsys
+// framework as shown by envstat(8).
+func NewHwMonCollector(logger log.Logger) (Collector, error) {
+	return &hwMonCollector{
+		driveState: prometheus.NewDesc(
+			"node_hwmon_drive_state",
+			"State of the drive, e.g. of RAID volumes, as reported by the sensor.",
+			[]string{"chip", "sensor", "state"}, nil,
+		),
+		batteryCapacity: prometheus.NewDesc(
+			"node_hwmon_battery_capacity_state",
+			"Capacity of the battery as decided by the driver.",
+			[]string{"chip", "sensor", "state"}, nil,
+		),
+		status: prometheus.NewDesc(
+			"node_hwmon_sensor_status",
+			"Status of the sensor as decided by the driver or its configured limits.",
+			[]string{"chip", "sensor", "status"}, nil,
+		),
+		label: prometheus.NewDesc(
+			"node_hwmon_sensor_label",
+			"Label for given chip and sensor",
+			[]string{"chip", "sensor", "label"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *hwMonCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(rootfsFilePath("/dev/sysmon"))
+	if err != nil {
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			level.Debug(c.logger).Log("msg", "envsys not available", "err", err)
+			return ErrNoData
+		}
+		return err
+	}
+	defer file.Close()
+
+	var ref plistref
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, file.Fd(), uintptr(ENVSYS_GETDICTIONARY), uintptr(unsafe.Pointer(&ref))); errno != 0 {
+		return fmt.Errorf("ENVSYS_GETDICTIONARY failed: %w", errno)
+	}
+	plist := make([]byte, ref.len)
+	copy(plist, (*[1 << 30]byte)(ref.plist)[:ref.len:ref.len])
+	if _, _, errno := unix.Syscall(unix.SYS_MUNMAP, uintptr(ref.plist), ref.len, 0); errno != 0 {
+		return fmt.Errorf("failed to unmap dictionary: %w", errno)
+	}
+
+	devices, err := parseEnvsysPlist(bytes.NewReader(plist))
+	if err != nil {
+		return fmt.Errorf("failed to parse envsys dictionary: %w", err)
+	}
+	for chip, sensors := range devices {
+		for _, sensor := range sensors {
+			c.updateSensor(ch, chip, sensor)
+		}
+	}
+	return nil
+}
+
+func (c *hwMonCollector) updateSensor(ch chan<- prometheus.Metric, chip string, sensor map[string]interface{}) {
+	index, _ := sensor["index"].(string)
+	typ, _ := sensor["type"].(string)
+	state, _ := sensor["state"].(string)
+	value, ok := sensor["cur-value"].(int64)
+	// The dictionary with the device-properties has no index.
+	if index == "" || !ok || state == "invalid" {
+		return
+	}
+
+	oneHot := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	switch typ {
+	case "Drive":
+		for i, s := range envsysDriveStates {
+			ch <- prometheus.MustNewConstMetric(c.driveState, prometheus.GaugeValue, oneHot(int64(i) == value), chip, index, s)
+		}
+	case "Battery capacity":
+		for i, s := range envsysBatteryCapacities {
+			ch <- prometheus.MustNewConstMetric(c.batteryCapacity, prometheus.GaugeValue, oneHot(int64(i) == value), chip, index, s)
+		}
+	default:
+		t, ok := envsysSensorTypes[typ]
+		if !ok {
+			level.Debug(c.logger).Log("msg", "unsupported sensor type", "chip", chip, "sensor", index, "type", typ)
+			return
+		}
+		desc := prometheus.NewDesc("node_hwmon_"+t.metric, t.help, []string{"chip", "sensor"}, nil)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, t.scale(value), chip, index)
+		// Batteries report the charge when full as maximum.
+		if max, ok := sensor["max-value"].(int64); ok && t.max != "" {
+			desc := prometheus.NewDesc("node_hwmon_"+t.max, t.help+" (max)", []string{"chip", "sensor"}, nil)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, t.scale(max), chip, index)
+		}
+	}
+
+	if description, ok := sensor["description"].(string); ok {
+		ch <- prometheus.MustNewConstMetric(c.label, prometheus.GaugeValue, 1, chip, index, description)
+	}
+	for _, s := range envsysStates {
+		ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, oneHot(s == state), chip, index, s)
+	}
+}
+
+// parseEnvsysPlist parses the XML property list of envsys, a dictionary with
+// an array of sensor dictionaries per device.
+func parseEnvsysPlist(r io.Reader) (map[string][]map[string]interface{}, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "dict" {
+			continue
+		}
+		root, err := parsePlistValue(decoder, start)
+		if err != nil {
+			return nil, err
+		}
+		devices := map[string][]map[string]interface{}{}
+		for chip, value := range root.(map[string]interface{}) {
+			array, _ := value.([]interface{})
+			for _, v := range array {
+				if sensor, ok := v.(map[string]interface{}); ok {
+					devices[chip] = append(devices[chip], sensor)
+				}
+			}
+		}
+		return devices, nil
+	}
+}
+
+// parsePlistValue parses the value started by start into maps, slices,
+// strings, int64 and bool.
+func parsePlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]interface{}{}
+		var key string
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := parsePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				if t.Name.Local == "key" {
+					key, _ = value.(string)
+				} else {
+					dict[key] = value
+				}
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+	case "array":
+		var array []interface{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := parsePlistValue(decoder, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := decoder.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+	if start.Name.Local == "integer" {
+		// Unsigned numbers are externalized in hexadecimal.
+		return strconv.ParseInt(text, 0, 64)
+	}
+	return text, nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nohwmon
+
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	HW_SENSORS = 11
+
+	SENSOR_TEMP      = 0
+	SENSOR_FANRPM    = 1
+	SENSOR_VOLTS_DC  = 2
+	SENSOR_VOLTS_AC  = 3
+	SENSOR_WATTS     = 5
+	SENSOR_AMPS      = 6
+	SENSOR_INDICATOR = 9
+	SENSOR_PERCENT   = 11
+	SENSOR_DRIVE     = 13
+	SENSOR_TIMEDELTA = 14
+	SENSOR_HUMIDITY  = 15
+
+	SENSOR_FINVALID = 0x0001
+)
+
+// openbsdSensorTypes maps the sensor types to the names used by sysctl(8) and
+// the metrics of the Linux hwmon collector, values are scaled to base units.
+var openbsdSensorTypes = map[int32]struct {
+	sensor string
+	metric string
+	help   string
+	value  func(int64) float64
+}{
+	SENSOR_TEMP:      {"temp", "temp_celsius", "Hardware monitor for temperature.", func(v int64) float64 { return float64(v-273150000) / 1e6 }},
+	SENSOR_FANRPM:    {"fan", "fan_rpm", "Hardware monitor for fan revolutions per minute.", func(v int64) float64 { return float64(v) }},
+	SENSOR_VOLTS_DC:  {"volt", "in_volts", "Hardware monitor for DC voltage.", func(v int64) float64 { return float64(v) / 1e6 }},
+	SENSOR_VOLTS_AC:  {"acvolt", "in_ac_volts", "Hardware monitor for AC voltage.", func(v int64) float64 { return float64(v) / 1e6 }},
+	SENSOR_WATTS:     {"power", "power_watt", "Hardware monitor for power usage in watts.", func(v int64) float64 { return float64(v) / 1e6 }},
+	SENSOR_AMPS:      {"current", "curr_amps", "Hardware monitor for current.", func(v int64) float64 { return float64(v) / 1e6 }},
+	SENSOR_INDICATOR: {"indicator", "indicator", "Hardware monitor for boolean indicators.", func(v int64) float64 { return float64(v) }},
+	SENSOR_PERCENT:   {"percent", "percent", "Hardware monitor for percentages, as a ratio.", func(v int64) float64 { return float64(v) / 1e5 }},
+	SENSOR_TIMEDELTA: {"timedelta", "timedelta_seconds", "Hardware monitor for the error of the system time.", func(v int64) float64 { return float64(v) / 1e9 }},
+	SENSOR_HUMIDITY:  {"humidity", "humidity", "Hardware monitor for humidity, as a ratio.", func(v int64) float64 { return float64(v) / 1e5 }},
+}
+
+// openbsdSensorStatus are the values of enum sensor_status.
+var openbsdSensorStatus = []string{"unspecified", "ok", "warning", "critical", "unknown"}
+
+// openbsdDriveStates are the values of drive sensors, starting with
+// SENSOR_DRIVE_EMPTY at 1.
+var openbsdDriveStates = []string{"unknown", "empty", "ready", "powering_up", "online", "idle", "active", "rebuilding", "powering_down", "failed", "predictive_fail"}
+
+type hwMonCollector struct {
+	driveState *prometheus.Desc
+	status     *prometheus.Desc
+	label      *prometheus.Desc
+	logger     log.Logger
+}
+
+func init() {
+	registerCollector("hwmon", defaultEnabled, NewHwMonCollector)
+}
+
+// NewHwMonCollector returns a new Collector exposing the sensors framework
+// available as hw.sensors sysctl tree.
+func NewHwMonCollector(logger log.Logger) (Collector, error) {
+	return &hwMonCollector{
+		driveState: prometheus.NewDesc(
+			"node_hwmon_drive_state",
+			"State of the drive, e.g. of RAID volumes, as reported by the sensor.",
+			[]string{"chip", "sensor", "state"}, nil,
+		),
+		status: prometheus.NewDesc(
+			"node_hwmon_sensor_status",
+			"Status of the sensor as decided by the driver.",
+			[]string{"chip", "sensor", "status"}, nil,
+		),
+		label: prometheus.NewDesc(
+			"node_hwmon_sensor_label",
+			"Label for given chip and sensor",
+			[]string{"chip", "sensor", "label"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *hwMonCollector) Update(ch chan<- prometheus.Metric) error {
+	for dev := _C_int(0); ; dev++ {
+		buf, err := sysctl([]_C_int{unix.CTL_HW, HW_SENSORS, dev})
+		if err == unix.ENXIO {
+			// Detached devices leave holes in the numbering.
+			continue
+		}
+		if err == unix.ENOENT {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read sensor device %d: %w", dev, err)
+		}
+		if err := c.updateDevice(ch, dev, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateDevice reads the sensors of a struct sensordev. Its layout is num,
+// xname[16], maxnumt[SENSOR_MAX_TYPES] and sensors_count, the number of types
+// differs between releases.
+func (c *hwMonCollector) updateDevice(ch chan<- prometheus.Metric, dev _C_int, buf []byte) error {
+	if len(buf) < 24 {
+		return fmt.Errorf("sensor device %d has invalid size %d", dev, len(buf))
+	}
+	chip := bytesToString(buf[4:20])
+	types := (len(buf) - 24) / 4
+	for typ := 0; typ < types; typ++ {
+		maxnumt := int32(binary.LittleEndian.Uint32(buf[20+4*typ:]))
+		for numt := int32(0); numt < maxnumt; numt++ {
+			sbuf, err := sysctl([]_C_int{unix.CTL_HW, HW_SENSORS, dev, _C_int(typ), _C_int(numt)})
+			if err == unix.ENOENT || err == unix.ENXIO {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read sensor %s: %w", chip, err)
+			}
+			c.updateSensor(ch, chip, int32(typ), numt, sbuf)
+		}
+	}
+	return nil
+}
+
+// updateSensor exports a struct sensor which consists of desc[32], a struct
+// timeval, the int64 value followed by type, status, numt and flags.
+func (c *hwMonCollector) updateSensor(ch chan<- prometheus.Metric, chip string, typ, numt int32, buf []byte) {
+	if len(buf) < 72 {
+		return
+	}
+	desc := bytesToString(buf[0:32])
+	value := int64(binary.LittleEndian.Uint64(buf[48:]))
+	status := int32(binary.LittleEndian.Uint32(buf[60:]))
+	flags := int32(binary.LittleEndian.Uint32(buf[68:]))
+	if flags&SENSOR_FINVALID != 0 {
+		return
+	}
+
+	oneHot := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	var sensor string
+	if typ == SENSOR_DRIVE {
+		sensor = "drive" + strconv.Itoa(int(numt))
+		for i, state := range openbsdDriveStates {
+			ch <- prometheus.MustNewConstMetric(c.driveState, prometheus.GaugeValue, oneHot(int64(i) == value), chip, sensor, state)
+		}
+	} else {
+		t, ok := openbsdSensorTypes[typ]
+		if !ok {
+			level.Debug(c.logger).Log("msg", "unsupported sensor type", "chip", chip, "type", typ)
+			return
+		}
+		sensor = t.sensor + strconv.Itoa(int(numt))
+		metric := prometheus.NewDesc("node_hwmon_"+t.metric, t.help, []string{"chip", "sensor"}, nil)
+		ch <- prometheus.MustNewConstMetric(metric, prometheus.GaugeValue, t.value(value), chip, sensor)
+	}
+
+	if desc != "" {
+		ch <- prometheus.MustNewConstMetric(c.label, prometheus.GaugeValue, 1, chip, sensor, desc)
+	}
+	if status != 0 {
+		for i, s := range openbsdSensorStatus[1:] {
+			ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, oneHot(int32(i+1) == status), chip, sensor, s)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nohyperv
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const hypervSubsystem = "hyperv"
+
+// hypervServices are the VMBus class IDs of the integration services.
+var hypervServices = map[string]string{
+	"{57164f39-9115-4e78-ab55-382f3bd5422d}": "heartbeat",
+	"{9527e630-d0ae-497b-adce-e80ab0175caf}": "timesync",
+	"{0e0b6031-5213-4934-818b-38d90ced39db}": "shutdown",
+	"{a9a0f4e7-5a45-4d96-b827-8a841e8c03e6}": "kvp",
+	"{35fa2e29-ea23-4236-96ae-3a6ebacba440}": "vss",
+	"{525074dc-8985-46e2-8057-a307dc18a502}": "dynamic_memory",
+}
+
+// hypervBalloonFields are the fields of the hv_balloon debugfs file in pages.
+var hypervBalloonFields = map[string]string{
+	"pages_added":            "hot_added_bytes",
+	"pages_onlined":          "onlined_bytes",
+	"pages_ballooned":        "ballooned_bytes",
+	"total_pages_committed":  "committed_bytes",
+	"max_dynamic_page_count": "max_dynamic_bytes",
+}
+
+type hypervCollector struct {
+	service        *prometheus.Desc
+	balloon        map[string]*prometheus.Desc
+	timesyncOffset *prometheus.Desc
+	logger         log.Logger
+}
+
+func init() {
+	registerCollector(hypervSubsystem, defaultDisabled, NewHypervCollector)
+}
+
+// NewHypervCollector returns a new Collector exposing the integration services
+// of Hyper-V guests, their dynamic memory and the offset to the host clock.
+func NewHypervCollector(logger log.Logger) (Collector, error) {
+	balloon := map[string]*prometheus.Desc{}
+	for field, name := range hypervBalloonFields {
+		balloon[field] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hypervSubsystem, "balloon_"+name),
+			fmt.Sprintf("Dynamic memory of the guest from the hv_balloon field %s.", field),
+			nil, nil,
+		)
+	}
+	return &hypervCollector{
+		service: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hypervSubsystem, "integration_service_up"),
+			"Whether the integration service is offered by the host and bound to a driver, e.g. heartbeat.",
+			[]string{"service"}, nil,
+		),
+		balloon: balloon,
+		timesyncOffset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hypervSubsystem, "timesync_offset_seconds"),
+			"Offset of the host clock to CLOCK_REALTIME of the guest.",
+			nil, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *hypervCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("bus/vmbus/devices/*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		level.Debug(c.logger).Log("msg", "not running on Hyper-V")
+		return ErrNoData
+	}
+
+	up := map[string]float64{}
+	for _, service := range hypervServices {
+		up[service] = 0
+	}
+	for _, path := range devices {
+		classID, err := ioutil.ReadFile(filepath.Join(path, "class_id"))
+		if err != nil {
+			return err
+		}
+		service, ok := hypervServices[strings.TrimSpace(string(classID))]
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(path, "driver")); err == nil {
+			up[service] = 1
+		}
+	}
+	for service, value := range up {
+		ch <- prometheus.MustNewConstMetric(c.service, prometheus.GaugeValue, value, service)
+	}
+
+	if err := c.updateBalloon(ch); err != nil {
+		level.Debug(c.logger).Log("msg", "failed to read dynamic memory", "err", err)
+	}
+	if err := c.updateTimesync(ch); err != nil {
+		level.Debug(c.logger).Log("msg", "failed to read host clock", "err", err)
+	}
+	return nil
+}
+
+// updateBalloon reads the debugfs file of hv_balloon, which has lines like
+// "pages_ballooned       : 1024".
+func (c *hypervCollector) updateBalloon(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(sysFilePath("kernel/debug/hv-balloon"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stats := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 {
+			stats[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	pageSize, err := strconv.ParseFloat(stats["page_size"], 64)
+	if err != nil {
+		return fmt.Errorf("invalid page size: %w", err)
+	}
+	for field, desc := range c.balloon {
+		pages, err := strconv.ParseFloat(stats[field], 64)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, pages*pageSize)
+	}
+	return nil
+}
+
+// updateTimesync compares the clock of the host, exposed by hv_utils as PTP
+// clock, with the realtime clock of the guest.
+func (c *hypervCollector) updateTimesync(ch chan<- prometheus.Metric) error {
+	clocks, err := filepath.Glob(sysFilePath("class/ptp/ptp[0-9]*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range clocks {
+		name, err := ioutil.ReadFile(filepath.Join(path, "clock_name"))
+		if err != nil || strings.TrimSpace(string(name)) != "hyperv" {
+			continue
+		}
+		file, err := os.Open(rootfsFilePath("/dev/" + filepath.Base(path)))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		// Dynamic clock IDs are derived from the file descriptor.
+		clockID := int32((^int(file.Fd()) << 3) | 3)
+		var host, guest unix.Timespec
+		if err := unix.ClockGettime(clockID, &host); err != nil {
+			return err
+		}
+		if err := unix.ClockGettime(unix.CLOCK_REALTIME, &guest); err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.timesyncOffset, prometheus.GaugeValue, float64(host.Nano()-guest.Nano())/1e9)
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noi915
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const i915Subsystem = "i915"
+
+var (
+	i915CardRE = regexp.MustCompile(`^card[0-9]+$`)
+	i915GTRE   = regexp.MustCompile(`^gt[0-9]+$`)
+)
+
+type i915Collector struct {
+	rc6Residency *prometheus.Desc
+	frequency    *prometheus.Desc
+	logger       log.Logger
+}
+
+// i915GTFiles are the attributes of a GT, the graphics engine of an Intel GPU.
+// Kernels before 5.16 have them below card*/power and card* with other names.
+type i915GTFiles struct {
+	rc6, rc6p, rc6pp                        string
+	actual, requested, min, max, boost, rp0 string
+}
+
+var (
+	i915GTFilesLegacy = i915GTFiles{
+		rc6:       "power/rc6_residency_ms",
+		rc6p:      "power/rc6p_residency_ms",
+		rc6pp:     "power/rc6pp_residency_ms",
+		actual:    "gt_act_freq_mhz",
+		requested: "gt_cur_freq_mhz",
+		min:       "gt_min_freq_mhz",
+		max:       "gt_max_freq_mhz",
+		boost:     "gt_boost_freq_mhz",
+		rp0:       "gt_RP0_freq_mhz",
+	}
+	i915GTFilesPerGT = i915GTFiles{
+		rc6:       "rc6_residency_ms",
+		rc6p:      "rc6p_residency_ms",
+		rc6pp:     "rc6pp_residency_ms",
+		actual:    "rps_act_freq_mhz",
+		requested: "rps_cur_freq_mhz",
+		min:       "rps_min_freq_mhz",
+		max:       "rps_max_freq_mhz",
+		boost:     "rps_boost_freq_mhz",
+		rp0:       "rps_RP0_freq_mhz",
+	}
+)
+
+func init() {
+	registerCollector(i915Subsystem, defaultDisabled, NewI915Collector)
+}
+
+// NewI915Collector returns a new Collector exposing the RC6 power saving
+// residency and the frequencies of Intel GPUs.
+func NewI915Collector(logger log.Logger) (Collector, error) {
+	return &i915Collector{
+		rc6Residency: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, i915Subsystem, "rc6_residency_seconds_total"),
+			"Time the GT spent in the RC6 power saving state, deeper states rc6p and rc6pp are only supported by older GPUs.",
+			[]string{"card", "gt", "state"}, nil,
+		),
+		frequency: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, i915Subsystem, "gt_frequency_hertz"),
+			"Frequency of the GT, actual is the frequency of the hardware and requested the one of the driver.",
+			[]string{"card", "gt", "type"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *i915Collector) Update(ch chan<- prometheus.Metric) error {
+	drm, err := openSysfsDir(sysFilePath("class/drm"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "no i915 GPUs found")
+			return ErrNoData
+		}
+		return err
+	}
+	defer drm.Close()
+	names, err := drm.names()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, card := range names {
+		// Connectors like card0-HDMI-A-1 have no driver.
+		if !i915CardRE.MatchString(card) {
+			continue
+		}
+		driver, err := drm.readlink(card + "/device/driver")
+		if err != nil || filepath.Base(driver) != "i915" {
+			continue
+		}
+		cardDir, err := drm.openDir(card)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to open card", "card", card, "err", err)
+			continue
+		}
+		found = true
+		c.updateCard(ch, card, cardDir)
+		cardDir.Close()
+	}
+	if !found {
+		level.Debug(c.logger).Log("msg", "no i915 GPUs found")
+		return ErrNoData
+	}
+	return nil
+}
+
+func (c *i915Collector) updateCard(ch chan<- prometheus.Metric, card string, cardDir *sysfsDir) {
+	gtDir, err := cardDir.openDir("gt")
+	if err != nil {
+		c.updateGT(ch, card, "gt0", cardDir, i915GTFilesLegacy)
+		return
+	}
+	defer gtDir.Close()
+	names, err := gtDir.names()
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to list GTs", "card", card, "err", err)
+		return
+	}
+	for _, gt := range names {
+		if !i915GTRE.MatchString(gt) {
+			continue
+		}
+		dir, err := gtDir.openDir(gt)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to open GT", "card", card, "gt", gt, "err", err)
+			continue
+		}
+		c.updateGT(ch, card, gt, dir, i915GTFilesPerGT)
+		dir.Close()
+	}
+}
+
+func (c *i915Collector) updateGT(ch chan<- prometheus.Metric, card, gt string, dir *sysfsDir, files i915GTFiles) {
+	for state, file := range map[string]string{"rc6": files.rc6, "rc6p": files.rc6p, "rc6pp": files.rc6pp} {
+		value, err := dir.readUint(file)
+		if err != nil {
+			// GPUs without rc6p and rc6pp lack the files.
+			if !os.IsNotExist(err) {
+				level.Debug(c.logger).Log("msg", "failed to read RC6 residency", "card", card, "gt", gt, "file", file, "err", err)
+			}
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.rc6Residency, prometheus.CounterValue, float64(value)/1000, card, gt, state)
+	}
+
+	for typ, file := range map[string]string{
+		"actual":    files.actual,
+		"requested": files.requested,
+		"min":       files.min,
+		"max":       files.max,
+		"boost":     files.boost,
+		"rp0":       files.rp0,
+	} {
+		value, err := dir.readUint(file)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				level.Debug(c.logger).Log("msg", "failed to read GT frequency", "card", card, "gt", gt, "file", file, "err", err)
+			}
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.frequency, prometheus.GaugeValue, float64(value)*1e6, card, gt, typ)
+	}
+}
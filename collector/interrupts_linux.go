@@ -20,15 +20,30 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
 )
 
 var (
 	interruptLabelNames = []string{"cpu", "type", "info", "devices"}
+
+	interruptsAffinityDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "interrupts", "affinity_irqs"),
+		"Number of IRQs whose effective affinity includes the CPU.",
+		[]string{"cpu"}, nil,
+	)
+	interruptsIsolatedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "interrupts", "isolated_cpu_affinity"),
+		"Whether the effective affinity of the IRQ includes CPUs of the isolcpus or nohz_full sets, only exported if CPUs are isolated.",
+		[]string{"irq", "info", "devices"}, nil,
+	)
 )
 
 func (c *interruptsCollector) Update(ch chan<- prometheus.Metric) (err error) {
@@ -45,7 +60,75 @@ func (c *interruptsCollector) Update(ch chan<- prometheus.Metric) (err error) {
 			ch <- c.desc.mustNewConstMetric(fv, strconv.Itoa(cpuNo), name, interrupt.info, interrupt.devices)
 		}
 	}
-	return err
+	return c.updateAffinity(ch, interrupts)
+}
+
+// updateAffinity exports the distribution of the IRQs over the CPUs and the
+// IRQs which disturb isolated CPUs.
+func (c *interruptsCollector) updateAffinity(ch chan<- prometheus.Metric, interrupts map[string]interrupt) error {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return fmt.Errorf("failed to open procfs: %w", err)
+	}
+	sets, err := isolatedCPUs(fs)
+	if err != nil {
+		return err
+	}
+	isolated := map[int]bool{}
+	for _, set := range []string{"isolcpus", "nohz_full"} {
+		for _, cpu := range sets[set] {
+			isolated[cpu] = true
+		}
+	}
+
+	cpuNum := 0
+	for _, interrupt := range interrupts {
+		if len(interrupt.values) > cpuNum {
+			cpuNum = len(interrupt.values)
+		}
+	}
+	irqs := make([]int, cpuNum)
+	for name, interrupt := range interrupts {
+		if _, err := strconv.Atoi(name); err != nil {
+			continue
+		}
+		cpus, err := irqAffinity(name)
+		if err != nil {
+			// Some IRQs like the timer have no affinity.
+			level.Debug(c.logger).Log("msg", "failed to get affinity of IRQ", "irq", name, "err", err)
+			continue
+		}
+		onIsolated := 0.0
+		for _, cpu := range cpus {
+			if cpu < cpuNum {
+				irqs[cpu]++
+			}
+			if isolated[cpu] {
+				onIsolated = 1
+			}
+		}
+		if len(isolated) > 0 {
+			ch <- prometheus.MustNewConstMetric(interruptsIsolatedDesc, prometheus.GaugeValue, onIsolated, name, interrupt.info, interrupt.devices)
+		}
+	}
+	for cpu, count := range irqs {
+		ch <- prometheus.MustNewConstMetric(interruptsAffinityDesc, prometheus.GaugeValue, float64(count), strconv.Itoa(cpu))
+	}
+	return nil
+}
+
+// irqAffinity returns the CPUs an IRQ is delivered to. Kernels before 4.15
+// only have the configured affinity, which can be wider than the effective one.
+func irqAffinity(irq string) ([]int, error) {
+	dir := procFilePath(filepath.Join("irq", irq))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "effective_affinity_list"))
+	if os.IsNotExist(err) {
+		data, err = ioutil.ReadFile(filepath.Join(dir, "smp_affinity_list"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUList(string(data))
 }
 
 type interrupt struct {
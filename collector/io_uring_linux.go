@@ -0,0 +1,320 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var ioUringEnabled = kingpin.Flag("collector.io-uring", "Submit the reads of the sysfs attributes of a device in one batch with io_uring, requires Linux 5.1.").Default("false").Bool()
+
+// Constants of linux/io_uring.h.
+const (
+	ioUringEntries = 256
+
+	ioringOffSQRing      = 0
+	ioringOffCQRing      = 0x8000000
+	ioringOffSQEs        = 0x10000000
+	ioringOpReadv        = 1
+	ioringEnterGetEvents = 1
+)
+
+type ioSqringOffsets struct {
+	Head        uint32
+	Tail        uint32
+	RingMask    uint32
+	RingEntries uint32
+	Flags       uint32
+	Dropped     uint32
+	Array       uint32
+	_           uint32
+	_           uint64
+}
+
+type ioCqringOffsets struct {
+	Head        uint32
+	Tail        uint32
+	RingMask    uint32
+	RingEntries uint32
+	Overflow    uint32
+	Cqes        uint32
+	Flags       uint32
+	_           uint32
+	_           uint64
+}
+
+type ioUringParams struct {
+	SqEntries    uint32
+	CqEntries    uint32
+	Flags        uint32
+	SqThreadCPU  uint32
+	SqThreadIdle uint32
+	Features     uint32
+	WqFd         uint32
+	_            [3]uint32
+	SqOff        ioSqringOffsets
+	CqOff        ioCqringOffsets
+}
+
+type ioUringSqe struct {
+	Opcode   uint8
+	Flags    uint8
+	Ioprio   uint16
+	Fd       int32
+	Off      uint64
+	Addr     uint64
+	Len      uint32
+	OpFlags  uint32
+	UserData uint64
+	_        [3]uint64
+}
+
+type ioUringCqe struct {
+	UserData uint64
+	Res      int32
+	Flags    uint32
+}
+
+// ioUring is a submission and completion queue shared with the kernel.
+type ioUring struct {
+	fd     int
+	sqRing []byte
+	cqRing []byte
+	sqes   []byte
+	params ioUringParams
+
+	// abandoned keeps the buffers of a failed batch alive, as the kernel
+	// may still complete its reads after the ring was closed.
+	abandoned []interface{}
+}
+
+// errIoUringFailed is returned for reads of a ring which failed before, the
+// files have to be read without it.
+var errIoUringFailed = errors.New("io_uring failed")
+
+var (
+	ioUringMtx         sync.Mutex
+	ioUringInstance    *ioUring
+	ioUringUnsupported bool
+)
+
+// sharedIoUring returns the ring of all collectors, which is set up on first
+// use, or nil if io_uring is not enabled or not supported.
+func sharedIoUring() *ioUring {
+	if !*ioUringEnabled {
+		return nil
+	}
+	ioUringMtx.Lock()
+	defer ioUringMtx.Unlock()
+	if ioUringUnsupported {
+		return nil
+	}
+	if ioUringInstance == nil {
+		ring, err := newIoUring(ioUringEntries)
+		if err != nil {
+			// Kernels before 5.1 or seccomp filters.
+			ioUringUnsupported = true
+			return nil
+		}
+		ioUringInstance = ring
+	}
+	return ioUringInstance
+}
+
+func newIoUring(entries uint32) (*ioUring, error) {
+	r := &ioUring{}
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&r.params)), 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("io_uring_setup failed: %w", errno)
+	}
+	r.fd = int(fd)
+
+	var err error
+	p := &r.params
+	if r.sqRing, err = unix.Mmap(r.fd, ioringOffSQRing, int(p.SqOff.Array+p.SqEntries*4), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if r.cqRing, err = unix.Mmap(r.fd, ioringOffCQRing, int(p.CqOff.Cqes+p.CqEntries*uint32(unsafe.Sizeof(ioUringCqe{}))), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if r.sqes, err = unix.Mmap(r.fd, ioringOffSQEs, int(p.SqEntries*uint32(unsafe.Sizeof(ioUringSqe{}))), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *ioUring) Close() error {
+	for _, m := range [][]byte{r.sqRing, r.cqRing, r.sqes} {
+		if m != nil {
+			unix.Munmap(m)
+		}
+	}
+	return unix.Close(r.fd)
+}
+
+func ringUint32(ring []byte, off uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&ring[off]))
+}
+
+// readAll reads up to len(bufs[i]) bytes of each of fds in batches of the
+// size of the ring and returns the number of bytes read or the error of each.
+// If io_uring_enter fails, the ring is closed, io_uring is not used anymore
+// and errIoUringFailed is returned.
+func (r *ioUring) readAll(fds []int, bufs [][]byte) ([]int, []error, error) {
+	ioUringMtx.Lock()
+	defer ioUringMtx.Unlock()
+	if r.abandoned != nil {
+		return nil, nil, errIoUringFailed
+	}
+
+	n := make([]int, len(fds))
+	errs := make([]error, len(fds))
+	iovecs := make([]unix.Iovec, len(fds))
+	for i := range fds {
+		iovecs[i].Base = &bufs[i][0]
+		iovecs[i].SetLen(len(bufs[i]))
+	}
+	for start := 0; start < len(fds); start += int(r.params.SqEntries) {
+		end := start + int(r.params.SqEntries)
+		if end > len(fds) {
+			end = len(fds)
+		}
+		if err := r.submit(fds, iovecs, start, end, n, errs); err != nil {
+			// Completions of the batch may be left in the ring, which
+			// would be taken for those of the next batch.
+			r.abandoned = []interface{}{iovecs, bufs}
+			r.Close()
+			ioUringUnsupported = true
+			return nil, nil, fmt.Errorf("%w: %s", errIoUringFailed, err)
+		}
+	}
+	// The kernel wrote to the buffers via the iovecs.
+	runtime.KeepAlive(iovecs)
+	runtime.KeepAlive(bufs)
+	return n, errs, nil
+}
+
+func (r *ioUring) submit(fds []int, iovecs []unix.Iovec, start, end int, n []int, errs []error) error {
+	p := &r.params
+	sqMask := *ringUint32(r.sqRing, p.SqOff.RingMask)
+	tail := atomic.LoadUint32(ringUint32(r.sqRing, p.SqOff.Tail))
+	for i := start; i < end; i++ {
+		index := tail & sqMask
+		sqe := (*ioUringSqe)(unsafe.Pointer(&r.sqes[uintptr(index)*unsafe.Sizeof(ioUringSqe{})]))
+		*sqe = ioUringSqe{
+			Opcode:   ioringOpReadv,
+			Fd:       int32(fds[i]),
+			Addr:     uint64(uintptr(unsafe.Pointer(&iovecs[i]))),
+			Len:      1,
+			UserData: uint64(i),
+		}
+		*ringUint32(r.sqRing, p.SqOff.Array+index*4) = index
+		tail++
+	}
+	atomic.StoreUint32(ringUint32(r.sqRing, p.SqOff.Tail), tail)
+
+	pending := end - start
+	toSubmit := pending
+	cqMask := *ringUint32(r.cqRing, p.CqOff.RingMask)
+	for pending > 0 {
+		_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(toSubmit), uintptr(pending), ioringEnterGetEvents, 0, 0)
+		if errno == unix.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return fmt.Errorf("io_uring_enter failed: %w", errno)
+		}
+		toSubmit = 0
+
+		head := atomic.LoadUint32(ringUint32(r.cqRing, p.CqOff.Head))
+		for ; head != atomic.LoadUint32(ringUint32(r.cqRing, p.CqOff.Tail)); head++ {
+			cqe := (*ioUringCqe)(unsafe.Pointer(&r.cqRing[uintptr(p.CqOff.Cqes)+uintptr(head&cqMask)*unsafe.Sizeof(ioUringCqe{})]))
+			i := int(cqe.UserData)
+			if i < start || i >= end {
+				// Not a read of this batch.
+				continue
+			}
+			if cqe.Res < 0 {
+				errs[i] = unix.Errno(-cqe.Res)
+			} else {
+				n[i] = int(cqe.Res)
+			}
+			pending--
+		}
+		atomic.StoreUint32(ringUint32(r.cqRing, p.CqOff.Head), head)
+	}
+	return nil
+}
+
+// readStrings reads the attributes of the directory like readString, in one
+// batch with io_uring if enabled. Attributes which fail to open or read have
+// an error instead.
+func (d *sysfsDir) readStrings(names []string) ([]string, []error) {
+	values := make([]string, len(names))
+	errs := make([]error, len(names))
+	ring := sharedIoUring()
+	if ring == nil {
+		for i, name := range names {
+			values[i], errs[i] = d.readString(name)
+		}
+		return values, errs
+	}
+
+	var (
+		fds     []int
+		bufs    [][]byte
+		indexes []int
+	)
+	for i, name := range names {
+		fd, err := d.openat(name, unix.O_RDONLY)
+		if err != nil {
+			errs[i] = &os.PathError{Op: "openat", Path: d.path + "/" + name, Err: err}
+			continue
+		}
+		fds = append(fds, fd)
+		// sysfs attributes are at most a page.
+		bufs = append(bufs, make([]byte, os.Getpagesize()))
+		indexes = append(indexes, i)
+	}
+	n, readErrs, err := ring.readAll(fds, bufs)
+	for _, fd := range fds {
+		unix.Close(fd)
+	}
+	if err != nil {
+		for i, name := range names {
+			values[i], errs[i] = d.readString(name)
+		}
+		return values, errs
+	}
+	for j, i := range indexes {
+		if readErrs[j] != nil {
+			errs[i] = &os.PathError{Op: "read", Path: d.path + "/" + names[i], Err: readErrs[j]}
+			continue
+		}
+		values[i] = string(bufs[j][:n[j]])
+	}
+	return values, errs
+}
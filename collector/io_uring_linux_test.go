@@ -0,0 +1,77 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestReadStringsIoUring(t *testing.T) {
+	enabled := *ioUringEnabled
+	*ioUringEnabled = true
+	defer func() { *ioUringEnabled = enabled }()
+	if sharedIoUring() == nil {
+		t.Skip("io_uring is not supported")
+	}
+
+	dir, err := openSysfsDir("fixtures/sys/class/hwmon/hwmon0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	values, errs := dir.readStrings([]string{"temp1_input", "temp9_input", "temp1_crit"})
+	if errs[0] != nil || values[0] != "55000\n" {
+		t.Errorf("expected temp1_input 55000, got %q (%v)", values[0], errs[0])
+	}
+	if !os.IsNotExist(errs[1]) {
+		t.Errorf("expected not exist error for a missing attribute, got %v", errs[1])
+	}
+	if errs[2] != nil || values[2] != "100000\n" {
+		t.Errorf("expected temp1_crit 100000, got %q (%v)", values[2], errs[2])
+	}
+}
+
+func TestIoUringFailedSubmission(t *testing.T) {
+	ring, err := newIoUring(4)
+	if err != nil {
+		t.Skip("io_uring is not supported")
+	}
+	unsupported := ioUringUnsupported
+	defer func() { ioUringUnsupported = unsupported }()
+
+	f, err := os.Open("fixtures/sys/class/hwmon/hwmon0/temp1_input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// io_uring_enter fails with EBADF on the queued read.
+	fd := ring.fd
+	ring.fd = -1
+	defer unix.Close(fd)
+	if _, _, err := ring.readAll([]int{int(f.Fd())}, [][]byte{make([]byte, 64)}); !errors.Is(err, errIoUringFailed) {
+		t.Fatalf("expected the submission to fail, got %v", err)
+	}
+	if !ioUringUnsupported {
+		t.Error("expected io_uring to be disabled after a failed submission")
+	}
+	if _, _, err := ring.readAll([]int{int(f.Fd())}, [][]byte{make([]byte, 64)}); !errors.Is(err, errIoUringFailed) {
+		t.Errorf("expected the failed ring not to be used again, got %v", err)
+	}
+}
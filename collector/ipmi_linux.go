@@ -0,0 +1,640 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noipmi
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const ipmiSubsystem = "ipmi"
+
+var (
+	ipmiDevicePath = kingpin.Flag("collector.ipmi.device", "OpenIPMI device to query.").Default("/dev/ipmi0").String()
+	ipmiTimeout    = kingpin.Flag("collector.ipmi.timeout", "Timeout for a single IPMI command.").Default("2s").Duration()
+)
+
+// IPMI network functions and commands, see the IPMI v2.0 specification.
+const (
+	ipmiNetFnSensor  = 0x04
+	ipmiNetFnStorage = 0x0a
+
+	ipmiCmdGetSensorReading = 0x2d
+	ipmiCmdGetSDRRepoInfo   = 0x20
+	ipmiCmdReserveSDRRepo   = 0x22
+	ipmiCmdGetSDR           = 0x23
+	ipmiCmdGetSELInfo       = 0x40
+
+	ipmiBMCAddress = 0x20
+
+	// Completion code returned when the SDR reservation was cancelled.
+	ipmiReservationCancelled = 0xc5
+)
+
+// ipmiSensorTypes names the sensor types of table 42-3 of the specification
+// that are most common on servers.
+var ipmiSensorTypes = map[uint8]string{
+	0x01: "temperature",
+	0x02: "voltage",
+	0x03: "current",
+	0x04: "fan",
+	0x05: "physical_security",
+	0x07: "processor",
+	0x08: "power_supply",
+	0x09: "power_unit",
+	0x0c: "memory",
+	0x0d: "drive_slot",
+	0x10: "event_logging_disabled",
+	0x23: "watchdog",
+}
+
+type ipmiCollector struct {
+	temperature   *prometheus.Desc
+	fanSpeed      *prometheus.Desc
+	voltage       *prometheus.Desc
+	current       *prometheus.Desc
+	power         *prometheus.Desc
+	sensorValue   *prometheus.Desc
+	sensorState   *prometheus.Desc
+	discreteState *prometheus.Desc
+	selEntries    *prometheus.Desc
+	selFree       *prometheus.Desc
+	selOverflow   *prometheus.Desc
+	logger        log.Logger
+
+	// The SDR repository rarely changes but takes many commands to
+	// read, so it's cached until the BMC reports a change.
+	sdrMu    sync.Mutex
+	sdrStamp []byte
+	sdrCache []*ipmiSensor
+}
+
+// ipmiSensor is a sensor described by a full or compact SDR record.
+type ipmiSensor struct {
+	recordID    uint16
+	owner       uint8
+	lun         uint8
+	number      uint8
+	sensorType  uint8
+	readingType uint8
+	name        string
+	// The remaining fields are only set for analog sensors, which are
+	// described by full sensor records.
+	analog        bool
+	analogFormat  uint8
+	unit          uint8
+	linearization uint8
+	m, b          int
+	bExp, rExp    int
+}
+
+func init() {
+	registerCollector(ipmiSubsystem, defaultDisabled, NewIPMICollector)
+}
+
+// NewIPMICollector returns a new Collector exposing IPMI sensors and the
+// system event log state read from the OpenIPMI device.
+func NewIPMICollector(logger log.Logger) (Collector, error) {
+	labels := []string{"id", "name"}
+	typedLabels := []string{"id", "name", "type"}
+	return &ipmiCollector{
+		temperature: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "temperature_celsius"),
+			"Temperature reading of the IPMI sensor.",
+			labels, nil,
+		),
+		fanSpeed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "fan_speed_rpm"),
+			"Fan speed reading of the IPMI sensor.",
+			labels, nil,
+		),
+		voltage: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "voltage_volts"),
+			"Voltage reading of the IPMI sensor.",
+			labels, nil,
+		),
+		current: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "current_amperes"),
+			"Current reading of the IPMI sensor.",
+			labels, nil,
+		),
+		power: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "power_watts"),
+			"Power reading of the IPMI sensor.",
+			labels, nil,
+		),
+		sensorValue: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "sensor_value"),
+			"Reading of an IPMI sensor in a unit without a dedicated metric.",
+			append(typedLabels, "unit"), nil,
+		),
+		sensorState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "sensor_state"),
+			"Threshold state of the IPMI sensor: 0 nominal, 1 non-critical, 2 critical.",
+			typedLabels, nil,
+		),
+		discreteState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "sensor_discrete_state"),
+			"Asserted states of a discrete IPMI sensor as a bitmask, see the sensor type in the IPMI specification.",
+			typedLabels, nil,
+		),
+		selEntries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "sel_entries"),
+			"Number of entries in the system event log.",
+			nil, nil,
+		),
+		selFree: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "sel_free_bytes"),
+			"Free space in the system event log.",
+			nil, nil,
+		),
+		selOverflow: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiSubsystem, "sel_overflow"),
+			"Whether events were dropped because the system event log is full.",
+			nil, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *ipmiCollector) Update(ch chan<- prometheus.Metric) error {
+	dev, err := openIPMIDevice(*ipmiDevicePath)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			level.Debug(c.logger).Log("msg", "IPMI device not found, is the ipmi_devintf module loaded?", "device", *ipmiDevicePath)
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to open IPMI device: %w", err)
+	}
+	defer dev.close()
+
+	if err := c.updateSEL(ch, dev); err != nil {
+		return fmt.Errorf("failed to get SEL info: %w", err)
+	}
+
+	sensors, err := c.sensors(dev)
+	if err != nil {
+		return fmt.Errorf("failed to read SDR repository: %w", err)
+	}
+	for _, s := range sensors {
+		if s.owner != ipmiBMCAddress {
+			// Reading sensors of satellite controllers requires bridging.
+			continue
+		}
+		resp, err := dev.command(s.lun, ipmiNetFnSensor, ipmiCmdGetSensorReading, []byte{s.number})
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read IPMI sensor", "name", s.name, "err", err)
+			continue
+		}
+		// Skip sensors that are not scanned or have no reading, e.g.
+		// those of absent components.
+		if len(resp) < 2 || resp[1]&0x40 == 0 || resp[1]&0x20 != 0 {
+			continue
+		}
+		c.updateSensor(ch, s, resp)
+	}
+
+	return nil
+}
+
+func (c *ipmiCollector) updateSEL(ch chan<- prometheus.Metric, dev *ipmiDevice) error {
+	resp, err := dev.command(0, ipmiNetFnStorage, ipmiCmdGetSELInfo, nil)
+	if err != nil {
+		return err
+	}
+	if len(resp) < 14 {
+		return fmt.Errorf("short response, len=%d", len(resp))
+	}
+	overflow := 0.0
+	if resp[13]&0x80 != 0 {
+		overflow = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.selEntries, prometheus.GaugeValue, float64(binary.LittleEndian.Uint16(resp[1:3])))
+	ch <- prometheus.MustNewConstMetric(c.selFree, prometheus.GaugeValue, float64(binary.LittleEndian.Uint16(resp[3:5])))
+	ch <- prometheus.MustNewConstMetric(c.selOverflow, prometheus.GaugeValue, overflow)
+	return nil
+}
+
+func (c *ipmiCollector) updateSensor(ch chan<- prometheus.Metric, s *ipmiSensor, resp []byte) {
+	id := strconv.Itoa(int(s.recordID))
+	sensorType, ok := ipmiSensorTypes[s.sensorType]
+	if !ok {
+		sensorType = fmt.Sprintf("0x%02x", s.sensorType)
+	}
+
+	var states uint16
+	if len(resp) > 2 {
+		states = uint16(resp[2])
+	}
+	if len(resp) > 3 {
+		states |= uint16(resp[3]) << 8
+	}
+
+	// Event/reading type 0x01 marks threshold based sensors.
+	if s.readingType != 0x01 {
+		ch <- prometheus.MustNewConstMetric(c.discreteState, prometheus.GaugeValue, float64(states&0x7fff), id, s.name, sensorType)
+		return
+	}
+
+	state := 0.0
+	switch {
+	case states&0x36 != 0:
+		// Lower/upper critical or non-recoverable.
+		state = 2
+	case states&0x09 != 0:
+		state = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.sensorState, prometheus.GaugeValue, state, id, s.name, sensorType)
+
+	if !s.analog {
+		return
+	}
+	value := s.convert(resp[0])
+	switch s.unit {
+	case 1:
+		ch <- prometheus.MustNewConstMetric(c.temperature, prometheus.GaugeValue, value, id, s.name)
+	case 4:
+		ch <- prometheus.MustNewConstMetric(c.voltage, prometheus.GaugeValue, value, id, s.name)
+	case 5:
+		ch <- prometheus.MustNewConstMetric(c.current, prometheus.GaugeValue, value, id, s.name)
+	case 6:
+		ch <- prometheus.MustNewConstMetric(c.power, prometheus.GaugeValue, value, id, s.name)
+	case 18:
+		ch <- prometheus.MustNewConstMetric(c.fanSpeed, prometheus.GaugeValue, value, id, s.name)
+	default:
+		ch <- prometheus.MustNewConstMetric(c.sensorValue, prometheus.GaugeValue, value, id, s.name, sensorType, strconv.Itoa(int(s.unit)))
+	}
+}
+
+// sensors returns the sensors of the SDR repository, reading it only if it
+// changed since the last scrape.
+func (c *ipmiCollector) sensors(dev *ipmiDevice) ([]*ipmiSensor, error) {
+	c.sdrMu.Lock()
+	defer c.sdrMu.Unlock()
+
+	info, err := dev.command(0, ipmiNetFnStorage, ipmiCmdGetSDRRepoInfo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(info) < 13 {
+		return nil, fmt.Errorf("short SDR repository info, len=%d", len(info))
+	}
+	// The most recent addition and erase timestamps.
+	stamp := info[5:13]
+	if c.sdrCache != nil && bytes.Equal(stamp, c.sdrStamp) {
+		return c.sdrCache, nil
+	}
+
+	records, err := dev.sdrRecords()
+	if err != nil {
+		return nil, err
+	}
+	sensors := []*ipmiSensor{}
+	for _, rec := range records {
+		if s := parseIPMISensorRecord(rec); s != nil {
+			sensors = append(sensors, s)
+		}
+	}
+	c.sdrStamp = append([]byte{}, stamp...)
+	c.sdrCache = sensors
+	return sensors, nil
+}
+
+// parseIPMISensorRecord parses full (type 0x01) and compact (type 0x02)
+// sensor records, see section 43 of the specification. Other record types
+// return nil.
+func parseIPMISensorRecord(rec []byte) *ipmiSensor {
+	if len(rec) < 5 {
+		return nil
+	}
+
+	var nameOffset int
+	switch rec[3] {
+	case 0x01:
+		nameOffset = 47
+	case 0x02:
+		nameOffset = 31
+	default:
+		return nil
+	}
+	if len(rec) <= nameOffset {
+		return nil
+	}
+
+	s := &ipmiSensor{
+		recordID:    binary.LittleEndian.Uint16(rec[0:2]),
+		owner:       rec[5],
+		lun:         rec[6] & 0x03,
+		number:      rec[7],
+		sensorType:  rec[12],
+		readingType: rec[13],
+	}
+	nameLen := int(rec[nameOffset] & 0x1f)
+	if end := nameOffset + 1 + nameLen; end <= len(rec) {
+		s.name = string(bytes.TrimRight(rec[nameOffset+1:end], "\x00 "))
+	}
+
+	if rec[3] == 0x01 {
+		s.analogFormat = rec[20] >> 6
+		s.analog = s.analogFormat != 3
+		s.unit = rec[21]
+		s.linearization = rec[23] & 0x7f
+		s.m = signExtend(int(rec[24])|int(rec[25]>>6)<<8, 10)
+		s.b = signExtend(int(rec[26])|int(rec[27]>>6)<<8, 10)
+		s.rExp = signExtend(int(rec[29]>>4), 4)
+		s.bExp = signExtend(int(rec[29]&0x0f), 4)
+	}
+	return s
+}
+
+// convert converts a raw reading of an analog sensor, see section 36.3 of
+// the specification.
+func (s *ipmiSensor) convert(raw uint8) float64 {
+	var x float64
+	switch s.analogFormat {
+	case 1:
+		// One's complement.
+		x = float64(int8(raw))
+		if x < 0 {
+			x++
+		}
+	case 2:
+		x = float64(int8(raw))
+	default:
+		x = float64(raw)
+	}
+
+	y := (float64(s.m)*x + float64(s.b)*math.Pow10(s.bExp)) * math.Pow10(s.rExp)
+
+	switch s.linearization {
+	case 1:
+		return math.Log(y)
+	case 2:
+		return math.Log10(y)
+	case 3:
+		return math.Log2(y)
+	case 4:
+		return math.Exp(y)
+	case 5:
+		return math.Pow(10, y)
+	case 6:
+		return math.Exp2(y)
+	case 7:
+		return 1 / y
+	case 8:
+		return y * y
+	case 9:
+		return y * y * y
+	case 10:
+		return math.Sqrt(y)
+	case 11:
+		return math.Cbrt(y)
+	}
+	return y
+}
+
+func signExtend(v, bits int) int {
+	if v&(1<<(bits-1)) != 0 {
+		return v - 1<<bits
+	}
+	return v
+}
+
+// ipmiCompletionCode is a non-zero completion code returned by the BMC.
+type ipmiCompletionCode uint8
+
+func (c ipmiCompletionCode) Error() string {
+	return fmt.Sprintf("IPMI completion code %#x", uint8(c))
+}
+
+// The structs below mirror those of /usr/include/linux/ipmi.h.
+type ipmiSystemInterfaceAddr struct {
+	AddrType int32
+	Channel  int16
+	LUN      uint8
+	_        uint8
+}
+
+type ipmiMsg struct {
+	NetFn   uint8
+	Cmd     uint8
+	DataLen uint16
+	Data    unsafe.Pointer
+}
+
+type ipmiReq struct {
+	Addr    unsafe.Pointer
+	AddrLen uint32
+	MsgID   int
+	Msg     ipmiMsg
+}
+
+type ipmiRecv struct {
+	RecvType int32
+	Addr     unsafe.Pointer
+	AddrLen  uint32
+	MsgID    int
+	Msg      ipmiMsg
+}
+
+var (
+	ipmictlSendCommand   = ipmiIOC(2, 13, unsafe.Sizeof(ipmiReq{}))
+	ipmictlReceiveMsgTrc = ipmiIOC(3, 11, unsafe.Sizeof(ipmiRecv{}))
+)
+
+// ipmiIOC encodes an ioctl request number of the 'i' (OpenIPMI) type.
+func ipmiIOC(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'i'<<8 | nr
+}
+
+type ipmiDevice struct {
+	fd    int
+	msgID int
+}
+
+func openIPMIDevice(path string) (*ipmiDevice, error) {
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &ipmiDevice{fd: fd}, nil
+}
+
+func (d *ipmiDevice) close() error {
+	return unix.Close(d.fd)
+}
+
+// command sends a request to the BMC and waits for its response. It
+// returns the response data following the completion code.
+func (d *ipmiDevice) command(lun, netFn, cmd uint8, data []byte) ([]byte, error) {
+	d.msgID++
+	addr := ipmiSystemInterfaceAddr{
+		AddrType: 0x0c, // IPMI_SYSTEM_INTERFACE_ADDR_TYPE
+		Channel:  0x0f, // IPMI_BMC_CHANNEL
+		LUN:      lun,
+	}
+	req := ipmiReq{
+		Addr:    unsafe.Pointer(&addr),
+		AddrLen: uint32(unsafe.Sizeof(addr)),
+		MsgID:   d.msgID,
+		Msg:     ipmiMsg{NetFn: netFn, Cmd: cmd, DataLen: uint16(len(data))},
+	}
+	if len(data) > 0 {
+		req.Msg.Data = unsafe.Pointer(&data[0])
+	}
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(d.fd), ipmictlSendCommand, uintptr(unsafe.Pointer(&req)))
+	runtime.KeepAlive(data)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	deadline := time.Now().Add(*ipmiTimeout)
+	// struct ipmi_addr is at most 40 bytes.
+	raddr := make([]byte, 40)
+	buf := make([]byte, 256)
+	for {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeout waiting for response to command %#x/%#x", netFn, cmd)
+		}
+		fds := []unix.PollFd{{Fd: int32(d.fd), Events: unix.POLLIN}}
+		if _, err := unix.Poll(fds, int(timeout/time.Millisecond)+1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return nil, err
+		}
+		if fds[0].Revents&unix.POLLIN == 0 {
+			continue
+		}
+
+		recv := ipmiRecv{
+			Addr:    unsafe.Pointer(&raddr[0]),
+			AddrLen: uint32(len(raddr)),
+			Msg:     ipmiMsg{DataLen: uint16(len(buf)), Data: unsafe.Pointer(&buf[0])},
+		}
+		_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(d.fd), ipmictlReceiveMsgTrc, uintptr(unsafe.Pointer(&recv)))
+		runtime.KeepAlive(raddr)
+		runtime.KeepAlive(buf)
+		if errno != 0 && errno != unix.EMSGSIZE {
+			return nil, errno
+		}
+		// Only IPMI_RESPONSE_RECV_TYPE messages answer our request, drop
+		// late responses to earlier ones.
+		if recv.RecvType != 1 || recv.MsgID != d.msgID {
+			continue
+		}
+		resp := buf[:recv.Msg.DataLen]
+		if len(resp) == 0 {
+			return nil, errors.New("empty IPMI response")
+		}
+		if resp[0] != 0 {
+			return nil, ipmiCompletionCode(resp[0])
+		}
+		return resp[1:], nil
+	}
+}
+
+// sdrRecords reads all records of the SDR repository.
+func (d *ipmiDevice) sdrRecords() ([][]byte, error) {
+	reservation, err := d.reserveSDR()
+	if err != nil {
+		return nil, err
+	}
+
+	var records [][]byte
+	retries := 0
+	for id := uint16(0); id != 0xffff; {
+		rec, next, err := d.sdrRecord(reservation, id)
+		if code, ok := err.(ipmiCompletionCode); ok && code == ipmiReservationCancelled && retries < 5 {
+			// The repository changed while reading it, start over with
+			// the current record.
+			retries++
+			if reservation, err = d.reserveSDR(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SDR record %d: %w", id, err)
+		}
+		records = append(records, rec)
+		id = next
+	}
+	return records, nil
+}
+
+func (d *ipmiDevice) reserveSDR() ([]byte, error) {
+	resp, err := d.command(0, ipmiNetFnStorage, ipmiCmdReserveSDRRepo, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 2 {
+		return nil, fmt.Errorf("short reservation response, len=%d", len(resp))
+	}
+	return resp[0:2], nil
+}
+
+// sdrRecord reads a single SDR record and returns the ID of the next one.
+// Many BMCs can't return a whole record at once, so it's read in chunks.
+func (d *ipmiDevice) sdrRecord(reservation []byte, id uint16) ([]byte, uint16, error) {
+	const chunkSize = 16
+
+	read := func(offset, n int) ([]byte, uint16, error) {
+		req := []byte{reservation[0], reservation[1], byte(id), byte(id >> 8), byte(offset), byte(n)}
+		resp, err := d.command(0, ipmiNetFnStorage, ipmiCmdGetSDR, req)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(resp) < 2+n {
+			return nil, 0, fmt.Errorf("short SDR response, len=%d", len(resp))
+		}
+		return resp[2 : 2+n], binary.LittleEndian.Uint16(resp[0:2]), nil
+	}
+
+	header, next, err := read(0, 5)
+	if err != nil {
+		return nil, 0, err
+	}
+	rec := append([]byte{}, header...)
+	total := 5 + int(header[4])
+	for offset := 5; offset < total; offset += chunkSize {
+		n := chunkSize
+		if offset+n > total {
+			n = total - offset
+		}
+		data, _, err := read(offset, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		rec = append(rec, data...)
+	}
+	return rec, next, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noipmi
+
+package collector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseIPMISensorRecord(t *testing.T) {
+	name := "PSU1 Input"
+	rec := make([]byte, 48+len(name))
+	rec[0], rec[1] = 0x2a, 0x01 // record ID 0x012a
+	rec[3] = 0x01               // full sensor record
+	rec[4] = byte(len(rec) - 5)
+	rec[5] = 0x20 // owned by the BMC
+	rec[7] = 0x30 // sensor number
+	rec[12] = 0x02
+	rec[13] = 0x01
+	rec[21] = 4 // volts
+	// M = 2, B = -10, R exponent -1, B exponent 1.
+	rec[24] = 2
+	rec[26], rec[27] = 0xf6, 0xc0
+	rec[29] = 0xf1
+	rec[47] = 0xc0 | byte(len(name))
+	copy(rec[48:], name)
+
+	s := parseIPMISensorRecord(rec)
+	if s == nil {
+		t.Fatal("expected sensor record")
+	}
+	if s.recordID != 0x012a || s.number != 0x30 || s.sensorType != 0x02 || s.name != name {
+		t.Errorf("unexpected sensor %+v", s)
+	}
+	if !s.analog || s.unit != 4 || s.m != 2 || s.b != -10 || s.rExp != -1 || s.bExp != 1 {
+		t.Errorf("unexpected conversion factors %+v", s)
+	}
+	if want, got := 10.0, s.convert(100); math.Abs(want-got) > 1e-9 {
+		t.Errorf("want reading %f, got %f", want, got)
+	}
+
+	// Event-only records aren't sensors.
+	rec[3] = 0x03
+	if s := parseIPMISensorRecord(rec); s != nil {
+		t.Errorf("expected no sensor for record type 3, got %+v", s)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nojail
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	jailSubsystem = "jail"
+	// jailNameLen is MAXHOSTNAMELEN, the size of the name parameter.
+	jailNameLen = 256
+)
+
+// jailResources maps the racct resources to metrics, cputime is in seconds
+// and the sizes are in bytes.
+var jailResources = map[string]struct {
+	name      string
+	help      string
+	valueType prometheus.ValueType
+}{
+	"cputime":    {"cpu_seconds_total", "CPU time used by the processes of the jail.", prometheus.CounterValue},
+	"memoryuse":  {"memory_resident_bytes", "Resident memory of the processes of the jail.", prometheus.GaugeValue},
+	"vmemoryuse": {"memory_virtual_bytes", "Virtual memory of the processes of the jail.", prometheus.GaugeValue},
+	"swapuse":    {"swap_reserved_bytes", "Swap space reserved by the processes of the jail.", prometheus.GaugeValue},
+	"maxproc":    {"processes", "Number of processes in the jail.", prometheus.GaugeValue},
+	"nthr":       {"threads", "Number of threads in the jail.", prometheus.GaugeValue},
+	"openfiles":  {"open_files", "Number of file descriptors open in the jail.", prometheus.GaugeValue},
+}
+
+type jailCollector struct {
+	info      *prometheus.Desc
+	resources map[string]typedDesc
+	logger    log.Logger
+}
+
+func init() {
+	registerCollector(jailSubsystem, defaultDisabled, NewJailCollector)
+}
+
+// NewJailCollector returns a new Collector exposing the jails and their
+// resource usage, which requires the kern.racct.enable tunable.
+func NewJailCollector(logger log.Logger) (Collector, error) {
+	resources := map[string]typedDesc{}
+	for resource, r := range jailResources {
+		resources[resource] = typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, jailSubsystem, r.name),
+			r.help,
+			[]string{"jail"}, nil,
+		), r.valueType}
+	}
+	return &jailCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, jailSubsystem, "info"),
+			"Jails running on the host with their ID.",
+			[]string{"jail", "jid"}, nil,
+		),
+		resources: resources,
+		logger:    logger,
+	}, nil
+}
+
+func (c *jailCollector) Update(ch chan<- prometheus.Metric) error {
+	jails, err := listJails()
+	if err != nil {
+		return fmt.Errorf("failed to list jails: %w", err)
+	}
+	for jid, name := range jails {
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, name, strconv.Itoa(jid))
+
+		usage, err := jailRacct(name)
+		if err == unix.ENOSYS {
+			level.Debug(c.logger).Log("msg", "racct is not enabled")
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get resource usage of jail %s: %w", name, err)
+		}
+		for resource, value := range usage {
+			if desc, ok := c.resources[resource]; ok {
+				ch <- desc.mustNewConstMetric(value, name)
+			}
+		}
+	}
+	return nil
+}
+
+// listJails returns the names of the running jails by their ID, iterating
+// with the lastjid parameter of jail_get(2).
+func listJails() (map[int]string, error) {
+	jails := map[int]string{}
+	lastjid := int32(0)
+	for {
+		var jid int32
+		name := make([]byte, jailNameLen)
+		iov := jailParam("lastjid", unsafe.Pointer(&lastjid), 4)
+		iov = append(iov, jailParam("jid", unsafe.Pointer(&jid), 4)...)
+		iov = append(iov, jailParam("name", unsafe.Pointer(&name[0]), len(name))...)
+		_, _, errno := unix.Syscall(unix.SYS_JAIL_GET, uintptr(unsafe.Pointer(&iov[0])), uintptr(len(iov)), 0)
+		if errno == unix.ENOENT {
+			return jails, nil
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+		jails[int(jid)] = bytesToString(name)
+		lastjid = jid
+	}
+}
+
+// jailParam returns the iovecs of the name and the value of a parameter.
+func jailParam(name string, value unsafe.Pointer, size int) []unix.Iovec {
+	key := append([]byte(name), 0)
+	iov := make([]unix.Iovec, 2)
+	iov[0].Base = &key[0]
+	iov[0].SetLen(len(key))
+	iov[1].Base = (*byte)(value)
+	iov[1].SetLen(size)
+	return iov
+}
+
+// jailRacct returns the resource usage of the jail as reported by
+// rctl_get_racct(2), e.g. "cputime=12,datasize=1024,...".
+func jailRacct(name string) (map[string]float64, error) {
+	filter := append([]byte("jail:"+name+":"), 0)
+	out := make([]byte, 4096)
+	for {
+		_, _, errno := unix.Syscall6(unix.SYS_RCTL_GET_RACCT,
+			uintptr(unsafe.Pointer(&filter[0])), uintptr(len(filter)),
+			uintptr(unsafe.Pointer(&out[0])), uintptr(len(out)), 0, 0)
+		if errno == unix.ERANGE {
+			out = make([]byte, 2*len(out))
+			continue
+		}
+		if errno != 0 {
+			return nil, errno
+		}
+		break
+	}
+
+	usage := map[string]float64{}
+	for _, field := range strings.Split(string(bytes.TrimRight(out, "\x00")), ",") {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %w", parts[0], err)
+		}
+		usage[parts[0]] = value
+	}
+	return usage, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build journald
+// +build cgo
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/coreos/go-systemd/sdjournal"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const journaldSubsystem = "journald"
+
+var (
+	journaldUnitInclude = kingpin.Flag("collector.journald.unit-include", "Regexp of systemd units to count error messages of. Units must both match include and not match exclude to be included.").Default(".+").String()
+	journaldUnitExclude = kingpin.Flag("collector.journald.unit-exclude", "Regexp of systemd units to not count error messages of. Units must both match include and not match exclude to be included.").Default(".+\\.(scope|slice)").String()
+)
+
+// journaldPriorities are the syslog priority names, indexed by priority.
+var journaldPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// journaldErrPriority is the lowest priority counted as an error.
+const journaldErrPriority = 3
+
+type journaldCollector struct {
+	messages   *prometheus.Desc
+	unitErrors *prometheus.Desc
+
+	unitIncludePattern *regexp.Regexp
+	unitExcludePattern *regexp.Regexp
+	logger             log.Logger
+
+	// The journal is kept open between scrapes and followed from the tail
+	// it had when it was first opened.
+	mu              sync.Mutex
+	journal         *sdjournal.Journal
+	messagesTotal   [8]uint64
+	unitErrorsTotal map[string]uint64
+}
+
+func init() {
+	registerCollector(journaldSubsystem, defaultDisabled, NewJournaldCollector)
+}
+
+// NewJournaldCollector returns a new Collector counting the messages written
+// to the systemd journal by priority and the error messages by unit.
+func NewJournaldCollector(logger log.Logger) (Collector, error) {
+	return &journaldCollector{
+		messages: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, journaldSubsystem, "messages_total"),
+			"Number of messages written to the journal since the collector started following it.",
+			[]string{"priority"}, nil,
+		),
+		unitErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, journaldSubsystem, "unit_error_messages_total"),
+			"Number of messages with priority err or higher written to the journal by a systemd unit since the collector started following it.",
+			[]string{"unit"}, nil,
+		),
+		unitIncludePattern: regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *journaldUnitInclude)),
+		unitExcludePattern: regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *journaldUnitExclude)),
+		logger:             logger,
+		unitErrorsTotal:    map[string]uint64{},
+	}, nil
+}
+
+func (c *journaldCollector) Update(ch chan<- prometheus.Metric) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.journal == nil {
+		j, err := openJournalTail()
+		if err != nil {
+			return fmt.Errorf("couldn't open journal: %w", err)
+		}
+		c.journal = j
+	}
+	if err := c.readJournal(); err != nil {
+		// Reopen the journal on the next scrape.
+		c.journal.Close()
+		c.journal = nil
+		return fmt.Errorf("couldn't read journal: %w", err)
+	}
+
+	for priority, count := range c.messagesTotal {
+		ch <- prometheus.MustNewConstMetric(c.messages, prometheus.CounterValue, float64(count), journaldPriorities[priority])
+	}
+	for unit, count := range c.unitErrorsTotal {
+		ch <- prometheus.MustNewConstMetric(c.unitErrors, prometheus.CounterValue, float64(count), unit)
+	}
+	return nil
+}
+
+// openJournalTail opens the system journal positioned at its last entry.
+func openJournalTail() (*sdjournal.Journal, error) {
+	j, err := sdjournal.NewJournal()
+	if err != nil {
+		return nil, err
+	}
+	if err := j.SeekTail(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	// SeekTail positions the journal after the last entry, stepping back
+	// is needed for Next to return the entries written afterwards.
+	if _, err := j.Previous(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// readJournal counts the entries written since the previous call.
+func (c *journaldCollector) readJournal() error {
+	// Process pending inotify events so that rotated and new journal files
+	// are picked up.
+	c.journal.Wait(0)
+
+	for {
+		n, err := c.journal.Next()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+
+		value, err := c.journal.GetDataValue(sdjournal.SD_JOURNAL_FIELD_PRIORITY)
+		if err != nil {
+			// Entries without priority default to info.
+			value = "6"
+		}
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 0 || priority >= len(c.messagesTotal) {
+			level.Debug(c.logger).Log("msg", "invalid journal entry priority", "priority", value)
+			continue
+		}
+		c.messagesTotal[priority]++
+
+		if priority > journaldErrPriority {
+			continue
+		}
+		unit, err := c.journal.GetDataValue(sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT)
+		if err != nil {
+			continue
+		}
+		if c.unitIncludePattern.MatchString(unit) && !c.unitExcludePattern.MatchString(unit) {
+			c.unitErrorsTotal[unit]++
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nokdump
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const kdumpSubsystem = "kdump"
+
+type kdumpCollector struct {
+	crashKernelLoaded *prometheus.Desc
+	crashKernelSize   *prometheus.Desc
+	pstoreEntries     *prometheus.Desc
+	pstoreOldestEntry *prometheus.Desc
+	logger            log.Logger
+}
+
+func init() {
+	registerCollector(kdumpSubsystem, defaultDisabled, NewKdumpCollector)
+}
+
+// NewKdumpCollector returns a new Collector exposing the crash kernel state
+// and the crash records kept in pstore.
+func NewKdumpCollector(logger log.Logger) (Collector, error) {
+	return &kdumpCollector{
+		crashKernelLoaded: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kdumpSubsystem, "crash_kernel_loaded"),
+			"Whether a crash kernel is loaded to capture a dump on kernel panic.",
+			nil, nil,
+		),
+		crashKernelSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kdumpSubsystem, "crash_kernel_size_bytes"),
+			"Size of the memory reserved for the crash kernel.",
+			nil, nil,
+		),
+		pstoreEntries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kdumpSubsystem, "pstore_entries"),
+			"Number of crash records in pstore.",
+			nil, nil,
+		),
+		pstoreOldestEntry: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kdumpSubsystem, "pstore_oldest_entry_timestamp_seconds"),
+			"Modification time of the oldest crash record in pstore.",
+			nil, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *kdumpCollector) Update(ch chan<- prometheus.Metric) error {
+	for desc, name := range map[*prometheus.Desc]string{
+		c.crashKernelLoaded: "kernel/kexec_crash_loaded",
+		c.crashKernelSize:   "kernel/kexec_crash_size",
+	} {
+		value, err := readUintFromFile(sysFilePath(name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Kernels without CONFIG_CRASH_DUMP don't have these files.
+				level.Debug(c.logger).Log("msg", "crash kernel not supported", "file", name)
+				continue
+			}
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value))
+	}
+
+	entries, err := ioutil.ReadDir(sysFilePath("fs/pstore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "pstore not mounted")
+			return nil
+		}
+		return fmt.Errorf("failed to read pstore: %w", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.pstoreEntries, prometheus.GaugeValue, float64(len(entries)))
+	if len(entries) == 0 {
+		return nil
+	}
+	oldest := entries[0].ModTime()
+	for _, entry := range entries[1:] {
+		if entry.ModTime().Before(oldest) {
+			oldest = entry.ModTime()
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.pstoreOldestEntry, prometheus.GaugeValue, float64(oldest.UnixNano())/1e9)
+	return nil
+}
@@ -17,6 +17,7 @@ package collector
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/go-kit/log"
@@ -26,6 +27,8 @@ import (
 var (
 	ksmdFiles = []string{"full_scans", "merge_across_nodes", "pages_shared", "pages_sharing",
 		"pages_to_scan", "pages_unshared", "pages_volatile", "run", "sleep_millisecs"}
+	// ksmdOptionalFiles are only available on newer kernels.
+	ksmdOptionalFiles = []string{"max_page_sharing", "stable_node_chains", "stable_node_dups"}
 )
 
 type ksmdCollector struct {
@@ -53,7 +56,7 @@ func NewKsmdCollector(logger log.Logger) (Collector, error) {
 	subsystem := "ksmd"
 	descs := make(map[string]*prometheus.Desc)
 
-	for _, n := range ksmdFiles {
+	for _, n := range append(ksmdFiles, ksmdOptionalFiles...) {
 		descs[n] = prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, subsystem, getCanonicalMetricName(n)),
 			fmt.Sprintf("ksmd '%s' file.", n), nil, nil)
@@ -80,5 +83,16 @@ func (c *ksmdCollector) Update(ch chan<- prometheus.Metric) error {
 		ch <- prometheus.MustNewConstMetric(c.metricDescs[n], t, v)
 	}
 
+	for _, n := range ksmdOptionalFiles {
+		val, err := readUintFromFile(sysFilePath(filepath.Join("kernel/mm/ksm", n)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.metricDescs[n], prometheus.GaugeValue, float64(val))
+	}
+
 	return nil
 }
@@ -0,0 +1,122 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nokvm
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const kvmSubsystem = "kvm"
+
+var (
+	kvmFields = kingpin.Flag("collector.kvm.fields", "Regexp of KVM statistics to return for kvm collector.").Default("^(exits|.*_exits|irq_injections|nmi_injections|mmu_.*)$").String()
+)
+
+// kvmVMDirRE matches the statistics directories of VMs, named after the pid
+// of the process running the VM and the file descriptor of the VM.
+var kvmVMDirRE = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+type kvmCollector struct {
+	fieldPattern *regexp.Regexp
+	vms          *prometheus.Desc
+	vcpus        *prometheus.Desc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector(kvmSubsystem, defaultDisabled, NewKVMCollector)
+}
+
+// NewKVMCollector returns a new Collector exposing the statistics of the KVM
+// hypervisor summed over all VMs, which requires a mounted debugfs.
+func NewKVMCollector(logger log.Logger) (Collector, error) {
+	pattern, err := regexp.Compile(*kvmFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --collector.kvm.fields: %w", err)
+	}
+	return &kvmCollector{
+		fieldPattern: pattern,
+		vms: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kvmSubsystem, "vms"),
+			"Number of running VMs.",
+			nil, nil,
+		),
+		vcpus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kvmSubsystem, "vcpus"),
+			"Number of vCPUs of the running VMs.",
+			nil, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *kvmCollector) Update(ch chan<- prometheus.Metric) error {
+	dir := sysFilePath("kernel/debug/kvm")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			level.Debug(c.logger).Log("msg", "KVM statistics not available", "err", err)
+			return ErrNoData
+		}
+		return err
+	}
+
+	vms, vcpus := 0, 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			if !kvmVMDirRE.MatchString(name) {
+				continue
+			}
+			vms++
+			// VMs exiting while reading them have no vCPUs anymore.
+			cpus, err := filepath.Glob(filepath.Join(dir, name, "vcpu[0-9]*"))
+			if err != nil {
+				return err
+			}
+			vcpus += len(cpus)
+			continue
+		}
+		if !c.fieldPattern.MatchString(name) {
+			continue
+		}
+		value, err := readUintFromFile(filepath.Join(dir, name))
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read KVM statistic", "name", name, "err", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, kvmSubsystem, name),
+				fmt.Sprintf("/sys/kernel/debug/kvm statistic %s.", name),
+				nil, nil),
+			prometheus.UntypedValue,
+			float64(value),
+		)
+	}
+	ch <- prometheus.MustNewConstMetric(c.vms, prometheus.GaugeValue, float64(vms))
+	ch <- prometheus.MustNewConstMetric(c.vcpus, prometheus.GaugeValue, float64(vcpus))
+	return nil
+}
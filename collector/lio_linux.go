@@ -0,0 +1,197 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nolio
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const lioSubsystem = "lio"
+
+type lioCollector struct {
+	tpgEnabled    *prometheus.Desc
+	lunCommands   *prometheus.Desc
+	lunRead       *prometheus.Desc
+	lunWritten    *prometheus.Desc
+	sessions      *prometheus.Desc
+	sessionStates *prometheus.Desc
+	logger        log.Logger
+}
+
+func init() {
+	registerCollector(lioSubsystem, defaultDisabled, NewLIOCollector)
+}
+
+// NewLIOCollector returns a new Collector exposing the LUN statistics and
+// initiator sessions of LIO SCSI targets, e.g. iSCSI or Fibre Channel.
+func NewLIOCollector(logger log.Logger) (Collector, error) {
+	tpgLabels := []string{"fabric", "target", "tpgt"}
+	lunLabels := append(tpgLabels, "lun", "backstore", "device")
+	initiatorLabels := append(tpgLabels, "initiator")
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, lioSubsystem, name), help, labels, nil)
+	}
+	return &lioCollector{
+		tpgEnabled:    desc("tpg_enabled", "Whether the target portal group is enabled.", tpgLabels...),
+		lunCommands:   desc("lun_commands_total", "Number of SCSI commands received for the LUN.", lunLabels...),
+		lunRead:       desc("lun_read_bytes_total", "Number of bytes read from the LUN, counted in MiB.", lunLabels...),
+		lunWritten:    desc("lun_written_bytes_total", "Number of bytes written to the LUN, counted in MiB.", lunLabels...),
+		sessions:      desc("initiator_sessions", "Number of iSCSI sessions of the initiator.", initiatorLabels...),
+		sessionStates: desc("initiator_session_state", "Number of iSCSI sessions of the initiator by state, e.g. logged_in or failed.", append(initiatorLabels, "state")...),
+		logger:        logger,
+	}, nil
+}
+
+func (c *lioCollector) Update(ch chan<- prometheus.Metric) error {
+	// Target portal groups are below the WWN of a target in the directory
+	// of its fabric module, the core directory holds the backstores.
+	tpgs, err := filepath.Glob(sysFilePath("kernel/config/target/*/*/tpgt_*"))
+	if err != nil {
+		return err
+	}
+	if len(tpgs) == 0 {
+		level.Debug(c.logger).Log("msg", "no LIO target portal groups found, configfs not mounted or target_core_mod not loaded")
+		return ErrNoData
+	}
+
+	for _, tpg := range tpgs {
+		target := filepath.Dir(tpg)
+		tpgLabels := []string{filepath.Base(filepath.Dir(target)), filepath.Base(target), strings.TrimPrefix(filepath.Base(tpg), "tpgt_")}
+
+		// Not all fabrics can disable portal groups.
+		if enabled, err := readUintFromFile(filepath.Join(tpg, "enable")); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.tpgEnabled, prometheus.GaugeValue, float64(enabled), tpgLabels...)
+		}
+
+		if err := c.updateLUNs(ch, tpg, tpgLabels); err != nil {
+			return err
+		}
+		if err := c.updateSessions(ch, tpg, tpgLabels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *lioCollector) updateLUNs(ch chan<- prometheus.Metric, tpg string, tpgLabels []string) error {
+	luns, err := filepath.Glob(filepath.Join(tpg, "lun", "lun_*"))
+	if err != nil {
+		return err
+	}
+	for _, lun := range luns {
+		backstore, device, err := lioLUNDevice(lun)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to find backstore of LUN", "lun", lun, "err", err)
+			continue
+		}
+		labels := append(append([]string{}, tpgLabels...), strings.TrimPrefix(filepath.Base(lun), "lun_"), backstore, device)
+
+		stats := filepath.Join(lun, "statistics", "scsi_tgt_port")
+		for name, m := range map[string]struct {
+			desc  *prometheus.Desc
+			scale float64
+		}{
+			"in_cmds":      {c.lunCommands, 1},
+			"read_mbytes":  {c.lunRead, 1 << 20},
+			"write_mbytes": {c.lunWritten, 1 << 20},
+		} {
+			value, err := readUintFromFile(filepath.Join(stats, name))
+			if err != nil {
+				return fmt.Errorf("failed to read LUN statistic %s of %s: %w", name, lun, err)
+			}
+			ch <- prometheus.MustNewConstMetric(m.desc, prometheus.CounterValue, float64(value)*m.scale, labels...)
+		}
+	}
+	return nil
+}
+
+// lioLUNDevice returns the backstore and device name of a LUN, which links to
+// the device as core/<backstore>_<index>/<device>.
+func lioLUNDevice(lun string) (string, string, error) {
+	entries, err := ioutil.ReadDir(lun)
+	if err != nil {
+		return "", "", err
+	}
+	for _, entry := range entries {
+		if entry.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		target, err := os.Readlink(filepath.Join(lun, entry.Name()))
+		if err != nil {
+			return "", "", err
+		}
+		hba := filepath.Base(filepath.Dir(target))
+		if i := strings.LastIndex(hba, "_"); i > 0 {
+			hba = hba[:i]
+		}
+		return hba, filepath.Base(target), nil
+	}
+	return "", "", fmt.Errorf("no device linked")
+}
+
+func (c *lioCollector) updateSessions(ch chan<- prometheus.Metric, tpg string, tpgLabels []string) error {
+	acls, err := filepath.Glob(filepath.Join(tpg, "acls", "*", "info"))
+	if err != nil {
+		return err
+	}
+	for _, acl := range acls {
+		f, err := os.Open(acl)
+		if err != nil {
+			return err
+		}
+		states, err := parseLIOSessionStates(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse sessions of %s: %w", acl, err)
+		}
+
+		labels := append(append([]string{}, tpgLabels...), filepath.Base(filepath.Dir(acl)))
+		sessions := 0
+		for state, count := range states {
+			sessions += count
+			ch <- prometheus.MustNewConstMetric(c.sessionStates, prometheus.GaugeValue, float64(count), append(labels, state)...)
+		}
+		ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(sessions), labels...)
+	}
+	return nil
+}
+
+// parseLIOSessionStates counts the sessions of an iSCSI initiator by state
+// from the info of its ACL, which has a line like
+// "Session State: TARG_SESS_STATE_LOGGED_IN" per session.
+func parseLIOSessionStates(r io.Reader) (map[string]int, error) {
+	states := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "Session State:") {
+			continue
+		}
+		state := strings.TrimSpace(strings.TrimPrefix(line, "Session State:"))
+		states[strings.ToLower(strings.TrimPrefix(state, "TARG_SESS_STATE_"))]++
+	}
+	return states, scanner.Err()
+}
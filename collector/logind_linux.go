@@ -17,8 +17,6 @@ package collector
 
 import (
 	"fmt"
-	"os"
-	"strconv"
 
 	"github.com/go-kit/log"
 	"github.com/godbus/dbus"
@@ -37,11 +35,20 @@ var (
 	attrRemoteValues = []string{"true", "false"}
 	attrTypeValues   = []string{"other", "unspecified", "tty", "x11", "wayland", "mir", "web"}
 	attrClassValues  = []string{"other", "user", "greeter", "lock-screen", "background"}
+	attrStateValues  = []string{"other", "online", "active", "closing"}
 
 	sessionsDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, logindSubsystem, "sessions"),
 		"Number of sessions registered in logind.", []string{"seat", "remote", "type", "class"}, nil,
 	)
+	sessionStatesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, logindSubsystem, "session_states"),
+		"Number of sessions registered in logind by state.", []string{"seat", "state"}, nil,
+	)
+	sessionsIdleDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, logindSubsystem, "sessions_idle"),
+		"Number of sessions registered in logind with the idle hint set.", []string{"seat"}, nil,
+	)
 )
 
 type logindCollector struct {
@@ -64,6 +71,8 @@ type logindSession struct {
 	remote      string
 	sessionType string
 	class       string
+	state       string
+	idle        bool
 }
 
 // Struct elements must be public for the reflection magic of godbus to work.
@@ -111,19 +120,31 @@ func collectMetrics(ch chan<- prometheus.Metric, c logindInterface) error {
 	}
 
 	sessions := make(map[logindSession]float64)
+	states := make(map[[2]string]float64)
+	idle := make(map[string]float64)
 
 	for _, s := range sessionList {
 		session := c.getSession(s)
-		if session != nil {
-			sessions[*session]++
+		if session == nil {
+			continue
+		}
+		states[[2]string{session.seat, session.state}]++
+		if session.idle {
+			idle[session.seat]++
 		}
+		sessions[logindSession{
+			seat:        session.seat,
+			remote:      session.remote,
+			sessionType: session.sessionType,
+			class:       session.class,
+		}]++
 	}
 
 	for _, remote := range attrRemoteValues {
 		for _, sessionType := range attrTypeValues {
 			for _, class := range attrClassValues {
 				for _, seat := range seats {
-					count := sessions[logindSession{seat, remote, sessionType, class}]
+					count := sessions[logindSession{seat: seat, remote: remote, sessionType: sessionType, class: class}]
 
 					ch <- prometheus.MustNewConstMetric(
 						sessionsDesc, prometheus.GaugeValue, count,
@@ -133,6 +154,16 @@ func collectMetrics(ch chan<- prometheus.Metric, c logindInterface) error {
 		}
 	}
 
+	for _, seat := range seats {
+		for _, state := range attrStateValues {
+			ch <- prometheus.MustNewConstMetric(
+				sessionStatesDesc, prometheus.GaugeValue, states[[2]string{seat, state}],
+				seat, state)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			sessionsIdleDesc, prometheus.GaugeValue, idle[seat], seat)
+	}
+
 	return nil
 }
 
@@ -147,22 +178,8 @@ func knownStringOrOther(value string, known []string) string {
 }
 
 func newDbus() (*logindDbus, error) {
-	conn, err := dbus.SystemBusPrivate()
-	if err != nil {
-		return nil, err
-	}
-
-	methods := []dbus.Auth{dbus.AuthExternal(strconv.Itoa(os.Getuid()))}
-
-	err = conn.Auth(methods)
+	conn, err := newSystemBusPrivate()
 	if err != nil {
-		conn.Close()
-		return nil, err
-	}
-
-	err = conn.Hello()
-	if err != nil {
-		conn.Close()
 		return nil, err
 	}
 
@@ -261,10 +278,23 @@ func (c *logindDbus) getSession(session logindSessionEntry) *logindSession {
 		return nil
 	}
 
+	// State and IdleHint are optional, sessions without them are counted
+	// as "other" and not idle.
+	var stateStr string
+	if state, err := object.GetProperty(dbusObject + ".Session.State"); err == nil {
+		stateStr, _ = state.Value().(string)
+	}
+	var idleHint bool
+	if idle, err := object.GetProperty(dbusObject + ".Session.IdleHint"); err == nil {
+		idleHint, _ = idle.Value().(bool)
+	}
+
 	return &logindSession{
 		seat:        session.SeatID,
 		remote:      remote.String(),
 		sessionType: knownStringOrOther(sessionTypeStr, attrTypeValues),
 		class:       knownStringOrOther(classStr, attrClassValues),
+		state:       knownStringOrOther(stateStr, attrStateValues),
+		idle:        idleHint,
 	}
 }
@@ -54,12 +54,15 @@ func (c *testLogindInterface) getSession(session logindSessionEntry) *logindSess
 			remote:      "true",
 			sessionType: knownStringOrOther("tty", attrTypeValues),
 			class:       knownStringOrOther("user", attrClassValues),
+			state:       knownStringOrOther("active", attrStateValues),
+			idle:        true,
 		},
 		dbus.ObjectPath("/org/freedesktop/login1/session/2"): {
 			seat:        session.SeatID,
 			remote:      "false",
 			sessionType: knownStringOrOther("x11", attrTypeValues),
 			class:       knownStringOrOther("greeter", attrClassValues),
+			state:       knownStringOrOther("online", attrStateValues),
 		},
 	}
 
@@ -95,7 +98,7 @@ func TestLogindCollectorCollectMetrics(t *testing.T) {
 		count++
 	}
 
-	expected := len(testSeats) * len(attrRemoteValues) * len(attrTypeValues) * len(attrClassValues)
+	expected := len(testSeats) * (len(attrRemoteValues)*len(attrTypeValues)*len(attrClassValues) + len(attrStateValues) + 1)
 	if count != expected {
 		t.Errorf("collectMetrics did not generate the expected number of metrics: got %d, expected %d.", count, expected)
 	}
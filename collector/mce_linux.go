@@ -0,0 +1,127 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomce
+// +build 386 amd64 arm arm64 s390x
+
+package collector
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+	_ "modernc.org/sqlite"
+)
+
+var (
+	mceRasdaemonDB = kingpin.Flag("collector.mce.rasdaemon-db", "Path of the rasdaemon event database.").Default("/var/lib/rasdaemon/ras-mc_event.db").String()
+)
+
+type mceCollector struct {
+	errors *prometheus.Desc
+	logger log.Logger
+}
+
+// mceErrorKey identifies a machine check error counter.
+type mceErrorKey struct {
+	cpu, bank string
+	corrected bool
+}
+
+func init() {
+	registerCollector("mce", defaultDisabled, NewMCECollector)
+}
+
+// NewMCECollector returns a new Collector exposing machine check errors
+// recorded by rasdaemon.
+func NewMCECollector(logger log.Logger) (Collector, error) {
+	return &mceCollector{
+		errors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "mce", "errors_total"),
+			"Number of machine check errors recorded by rasdaemon.",
+			[]string{"cpu", "bank", "type"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *mceCollector) Update(ch chan<- prometheus.Metric) error {
+	counts, err := readRasdaemonMCE(*mceRasdaemonDB)
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "rasdaemon database not found", "path", *mceRasdaemonDB)
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to read rasdaemon database: %w", err)
+	}
+
+	for key, count := range counts {
+		errorType := "uncorrected"
+		if key.corrected {
+			errorType = "corrected"
+		}
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(count), key.cpu, key.bank, errorType)
+	}
+	return nil
+}
+
+// readRasdaemonMCE counts the records of the mce_record table of a rasdaemon
+// database by CPU, bank and whether the error was corrected.
+func readRasdaemonMCE(path string) (map[mceErrorKey]int, error) {
+	// SQLite doesn't fail to open a missing database in read-only mode
+	// until it's queried.
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	// The database is opened read-only, SQLite takes a shared lock and
+	// reads the WAL while rasdaemon writes to it.
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// Bit 61 of MCi_STATUS is UC, set for uncorrected errors. Columns added
+	// by later schema versions are NULL in older rows.
+	rows, err := db.Query(`SELECT IFNULL(cpu, 0), IFNULL(bank, 0), (IFNULL(status, 0) >> 61) & 1, COUNT(*)
+		FROM mce_record GROUP BY 1, 2, 3`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[mceErrorKey]int{}
+	for rows.Next() {
+		var (
+			cpu, bank, uncorrected int64
+			count                  int
+		)
+		if err := rows.Scan(&cpu, &bank, &uncorrected, &count); err != nil {
+			return nil, err
+		}
+		counts[mceErrorKey{
+			cpu:       strconv.FormatInt(cpu, 10),
+			bank:      strconv.FormatInt(bank, 10),
+			corrected: uncorrected == 0,
+		}] += count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomce
+// +build 386 amd64 arm arm64 s390x
+
+package collector
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadRasdaemonMCE(t *testing.T) {
+	counts, err := readRasdaemonMCE("fixtures/rasdaemon/ras-mc_event.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[mceErrorKey]int{
+		{cpu: "2", bank: "7", corrected: true}:  30,
+		{cpu: "0", bank: "4", corrected: true}:  5,
+		{cpu: "0", bank: "4", corrected: false}: 1,
+	}
+	if !reflect.DeepEqual(want, counts) {
+		t.Errorf("want %v, got %v", want, counts)
+	}
+}
+
+func TestReadRasdaemonMCEWAL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rasdaemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ras-mc_event.db")
+
+	// While rasdaemon keeps the database open, new records are only in the
+	// WAL.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"PRAGMA journal_mode=WAL",
+		"PRAGMA wal_autocheckpoint=0",
+		"CREATE TABLE mce_record (id INTEGER PRIMARY KEY, status INTEGER, cpu INTEGER, bank INTEGER)",
+		"INSERT INTO mce_record (status, cpu, bank) VALUES (-7205759128898043759, 1, 5)",
+		"INSERT INTO mce_record (status, cpu, bank) VALUES (-4899916119684349935, 1, 5)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts, err := readRasdaemonMCE(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[mceErrorKey]int{
+		{cpu: "1", bank: "5", corrected: true}:  1,
+		{cpu: "1", bank: "5", corrected: false}: 1,
+	}
+	if !reflect.DeepEqual(want, counts) {
+		t.Errorf("want %v, got %v", want, counts)
+	}
+
+	if _, err := readRasdaemonMCE(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error for a missing database, got %v", err)
+	}
+}
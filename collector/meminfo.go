@@ -11,7 +11,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// +build darwin linux openbsd
+// +build aix,cgo darwin linux openbsd
 // +build !nomeminfo
 
 package collector
@@ -0,0 +1,53 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomeminfo
+// +build cgo
+
+package collector
+
+import (
+	"errors"
+)
+
+/*
+#cgo LDFLAGS: -lperfstat
+#include <libperfstat.h>
+*/
+import "C"
+
+// perfstatPageSize is the size of the pages perfstat_memory_total reports.
+const perfstatPageSize = 4096
+
+func (c *meminfoCollector) getMemInfo() (map[string]float64, error) {
+	var mem C.perfstat_memory_total_t
+	if C.perfstat_memory_total(nil, &mem, C.sizeof_perfstat_memory_total_t, 1) != 1 {
+		return nil, errors.New("perfstat_memory_total failed")
+	}
+
+	return map[string]float64{
+		"total_bytes":          perfstatPageSize * float64(mem.real_total),
+		"free_bytes":           perfstatPageSize * float64(mem.real_free),
+		"inuse_bytes":          perfstatPageSize * float64(mem.real_inuse),
+		"pinned_bytes":         perfstatPageSize * float64(mem.real_pinned),
+		"file_cache_bytes":     perfstatPageSize * float64(mem.numperm),
+		"virtual_total_bytes":  perfstatPageSize * float64(mem.virt_total),
+		"virtual_active_bytes": perfstatPageSize * float64(mem.virt_active),
+		"swap_total_bytes":     perfstatPageSize * float64(mem.pgsp_total),
+		"swap_free_bytes":      perfstatPageSize * float64(mem.pgsp_free),
+		"page_ins_total":       float64(mem.pgins),
+		"page_outs_total":      float64(mem.pgouts),
+		"swap_ins_total":       float64(mem.pgspins),
+		"swap_outs_total":      float64(mem.pgspouts),
+	}, nil
+}
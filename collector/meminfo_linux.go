@@ -16,17 +16,24 @@
 package collector
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
-	"strconv"
-	"strings"
 )
 
 var (
 	reParens = regexp.MustCompile(`\((.*)\)`)
+
+	// memInfoKeys caches the metric names of the fields of /proc/meminfo,
+	// e.g. Active(anon) -> Active_anon.
+	memInfoKeys = newStringCache(func(b []byte) string {
+		return reParens.ReplaceAllString(string(b), "_${1}")
+	})
+	memInfoBytesKeys = newStringCache(func(b []byte) string {
+		return memInfoKeys.get(b) + "_bytes"
+	})
 )
 
 func (c *meminfoCollector) getMemInfo() (map[string]float64, error) {
@@ -40,34 +47,34 @@ func (c *meminfoCollector) getMemInfo() (map[string]float64, error) {
 }
 
 func parseMemInfo(r io.Reader) (map[string]float64, error) {
-	var (
-		memInfo = map[string]float64{}
-		scanner = bufio.NewScanner(r)
-	)
+	memInfo := map[string]float64{}
+	scanner, release := newPooledScanner(r)
+	defer release()
 
+	var parts [][]byte
 	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Fields(line)
+		line := scanner.Bytes()
+		parts = appendFields(parts[:0], line)
 		// Workaround for empty lines occasionally occur in CentOS 6.2 kernel 3.10.90.
 		if len(parts) == 0 {
 			continue
 		}
-		fv, err := strconv.ParseFloat(parts[1], 64)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid line in meminfo: %s", line)
+		}
+		fv, err := parseFloatBytes(parts[1])
 		if err != nil {
 			return nil, fmt.Errorf("invalid value in meminfo: %w", err)
 		}
-		key := parts[0][:len(parts[0])-1] // remove trailing : from key
-		// Active(anon) -> Active_anon
-		key = reParens.ReplaceAllString(key, "_${1}")
+		key := bytes.TrimSuffix(parts[0], []byte(":"))
 		switch len(parts) {
 		case 2: // no unit
+			memInfo[memInfoKeys.get(key)] = fv
 		case 3: // has unit, we presume kB
-			fv *= 1024
-			key = key + "_bytes"
+			memInfo[memInfoBytesKeys.get(key)] = fv * 1024
 		default:
 			return nil, fmt.Errorf("invalid line in meminfo: %s", line)
 		}
-		memInfo[key] = fv
 	}
 
 	return memInfo, scanner.Err()
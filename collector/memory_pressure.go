@@ -0,0 +1,119 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"math"
+	"os"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	memoryLimitThreshold = kingpin.Flag("collector.memory-limit.threshold", "Fraction of the memory limit of GOMEMLIMIT from which the optional collectors are skipped, 0 never skips them.").Default("0.9").Float64()
+	memoryLimitOptional  = kingpin.Flag("collector.memory-limit.optional", "Comma separated list of collectors skipped when the memory usage exceeds the threshold of --collector.memory-limit.threshold.").Default("ethtool,interrupts,mountstats,netclass,network_route,processes,qdisc,schedstat,softnet,systemd,zoneinfo").String()
+)
+
+var scrapeMemoryDegradedDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "scrape", "memory_degraded"),
+	"node_exporter: Whether the optional collectors were skipped as the memory usage is close to GOMEMLIMIT.",
+	nil,
+	nil,
+)
+
+// Memory the Go runtime compares against its limit, all mapped memory except
+// the heap returned to the OS.
+var memoryLimitSamples = []string{
+	"/memory/classes/total:bytes",
+	"/memory/classes/heap/released:bytes",
+}
+
+// optionalCollectors returns the set of --collector.memory-limit.optional.
+func optionalCollectors() map[string]bool {
+	optional := map[string]bool{}
+	for _, name := range strings.Split(*memoryLimitOptional, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			optional[name] = true
+		}
+	}
+	return optional
+}
+
+// memoryLimitSuffixes are the units of GOMEMLIMIT, the longest first.
+var memoryLimitSuffixes = []struct {
+	suffix string
+	factor uint64
+}{
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"B", 1},
+}
+
+// memoryLimit returns the limit of GOMEMLIMIT, and false if none is set. The
+// variable is parsed like the runtime of Go 1.19 does, older runtimes ignore
+// it but the scrapes are still degraded close to it.
+func memoryLimit() (uint64, bool) {
+	return parseMemoryLimit(os.Getenv("GOMEMLIMIT"))
+}
+
+func parseMemoryLimit(value string) (uint64, bool) {
+	if value == "" || value == "off" {
+		return 0, false
+	}
+	factor := uint64(1)
+	for _, s := range memoryLimitSuffixes {
+		if strings.HasSuffix(value, s.suffix) {
+			value = strings.TrimSuffix(value, s.suffix)
+			factor = s.factor
+			break
+		}
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || limit == 0 || limit > math.MaxInt64/factor {
+		return 0, false
+	}
+	return limit * factor, true
+}
+
+// memoryUsage returns the memory of the runtime accounted against its limit.
+func memoryUsage() uint64 {
+	samples := make([]metrics.Sample, len(memoryLimitSamples))
+	for i, name := range memoryLimitSamples {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	for _, s := range samples {
+		if s.Value.Kind() != metrics.KindUint64 {
+			return 0
+		}
+	}
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
+
+// memoryDegraded returns whether a memory limit is set and whether the usage
+// exceeds the threshold of it, in which case the scrape skips the optional
+// collectors.
+func memoryDegraded() (limited bool, degraded bool) {
+	limit, ok := memoryLimit()
+	if !ok || *memoryLimitThreshold <= 0 {
+		return false, false
+	}
+	return true, float64(memoryUsage()) >= *memoryLimitThreshold*float64(limit)
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomodemmanager
+
+package collector
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/godbus/dbus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	modemSubsystem         = "modem"
+	modemManagerDbusObject = "org.freedesktop.ModemManager1"
+	modemManagerDbusPath   = "/org/freedesktop/ModemManager1"
+)
+
+// modemStates are the names of the MMModemState values, offset by one as
+// the first value is -1.
+var modemStates = []string{
+	"failed", "unknown", "initializing", "locked", "disabled", "disabling", "enabling",
+	"enabled", "searching", "registered", "disconnecting", "connecting", "connected",
+}
+
+// modemRegistrationStates are the names of the MMModem3gppRegistrationState
+// values.
+var modemRegistrationStates = []string{
+	"idle", "home", "searching", "denied", "unknown", "roaming", "home-sms-only",
+	"roaming-sms-only", "emergency-only", "home-csfb-not-preferred",
+	"roaming-csfb-not-preferred", "attached-rlos",
+}
+
+// modemSignalTechnologies are the properties of the Modem.Signal interface
+// holding the extended signal information of an access technology.
+var modemSignalTechnologies = map[string]string{
+	"Gsm":  "gsm",
+	"Umts": "umts",
+	"Lte":  "lte",
+	"Nr5g": "5gnr",
+}
+
+type modemManagerCollector struct {
+	info               *prometheus.Desc
+	state              *prometheus.Desc
+	registrationState  *prometheus.Desc
+	signalQuality      *prometheus.Desc
+	signal             map[string]*prometheus.Desc
+	bearerConnected    *prometheus.Desc
+	bearerReceiveBytes *prometheus.Desc
+	bearerSendBytes    *prometheus.Desc
+	logger             log.Logger
+}
+
+func init() {
+	registerCollector("modemmanager", defaultDisabled, NewModemManagerCollector)
+}
+
+// NewModemManagerCollector returns a new Collector exposing the signal and
+// connection state of cellular modems managed by ModemManager.
+func NewModemManagerCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, modemSubsystem, name),
+			help, append([]string{"modem"}, labels...), nil,
+		)
+	}
+	return &modemManagerCollector{
+		info:              desc("info", "Modem managed by ModemManager, value is always 1.", "manufacturer", "model", "operator"),
+		state:             desc("state", "State of the modem, the current state has a value of 1.", "state"),
+		registrationState: desc("registration_state", "3GPP network registration state of the modem, the current state has a value of 1.", "state"),
+		signalQuality:     desc("signal_quality_ratio", "Signal quality of the modem as reported by ModemManager."),
+		// Extended signal information is only available once polling was
+		// enabled with "mmcli --signal-setup".
+		signal: map[string]*prometheus.Desc{
+			"rssi": desc("signal_rssi_dbm", "Received signal strength indication.", "technology"),
+			"rsrp": desc("signal_rsrp_dbm", "Reference signal received power.", "technology"),
+			"rsrq": desc("signal_rsrq_db", "Reference signal received quality.", "technology"),
+			"snr":  desc("signal_sinr_db", "Signal to interference plus noise ratio.", "technology"),
+		},
+		bearerConnected:    desc("bearer_connected", "Whether the bearer is connected.", "bearer", "interface"),
+		bearerReceiveBytes: desc("bearer_receive_bytes_total", "Number of bytes received by the bearer during the current connection.", "bearer", "interface"),
+		bearerSendBytes:    desc("bearer_transmit_bytes_total", "Number of bytes transmitted by the bearer during the current connection.", "bearer", "interface"),
+		logger:             logger,
+	}, nil
+}
+
+func (c *modemManagerCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := newSystemBusPrivate()
+	if err != nil {
+		return fmt.Errorf("unable to connect to dbus: %w", err)
+	}
+	defer conn.Close()
+
+	var objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	err = conn.Object(modemManagerDbusObject, modemManagerDbusPath).Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&objects)
+	if err != nil {
+		return fmt.Errorf("unable to get ModemManager objects: %w", err)
+	}
+
+	for objectPath, ifaces := range objects {
+		props, ok := ifaces[modemManagerDbusObject+".Modem"]
+		if !ok {
+			continue
+		}
+		// Modems are exported as /org/freedesktop/ModemManager1/Modem/N.
+		modem := path.Base(string(objectPath))
+		c.updateModem(ch, modem, props, ifaces[modemManagerDbusObject+".Modem.Modem3gpp"], ifaces[modemManagerDbusObject+".Modem.Signal"])
+
+		bearers, _ := props["Bearers"].Value().([]dbus.ObjectPath)
+		for _, bearer := range bearers {
+			if err := c.updateBearer(ch, conn.Object(modemManagerDbusObject, bearer), modem, path.Base(string(bearer))); err != nil {
+				level.Debug(c.logger).Log("msg", "unable to get bearer properties", "modem", modem, "bearer", bearer, "err", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *modemManagerCollector) updateModem(ch chan<- prometheus.Metric, modem string, props, props3gpp, signalProps map[string]dbus.Variant) {
+	manufacturer, _ := props["Manufacturer"].Value().(string)
+	model, _ := props["Model"].Value().(string)
+	operator, _ := props3gpp["OperatorName"].Value().(string)
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, modem, manufacturer, model, operator)
+
+	if state, ok := props["State"].Value().(int32); ok {
+		for i, name := range modemStates {
+			value := 0.0
+			if int(state) == i-1 {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, value, modem, name)
+		}
+	}
+	if state, ok := props3gpp["RegistrationState"].Value().(uint32); ok {
+		for i, name := range modemRegistrationStates {
+			value := 0.0
+			if int(state) == i {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.registrationState, prometheus.GaugeValue, value, modem, name)
+		}
+	}
+
+	// SignalQuality is a (percent, recent) struct.
+	if quality, ok := props["SignalQuality"].Value().([]interface{}); ok && len(quality) == 2 {
+		if percent, ok := quality[0].(uint32); ok {
+			ch <- prometheus.MustNewConstMetric(c.signalQuality, prometheus.GaugeValue, float64(percent)/100, modem)
+		}
+	}
+
+	for property, technology := range modemSignalTechnologies {
+		values, ok := signalProps[property].Value().(map[string]dbus.Variant)
+		if !ok {
+			continue
+		}
+		for key, desc := range c.signal {
+			if v, ok := values[key].Value().(float64); ok {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, modem, technology)
+			}
+		}
+	}
+}
+
+func (c *modemManagerCollector) updateBearer(ch chan<- prometheus.Metric, object dbus.BusObject, modem, bearer string) error {
+	v, err := object.GetProperty(modemManagerDbusObject + ".Bearer.Connected")
+	if err != nil {
+		return err
+	}
+	connected, _ := v.Value().(bool)
+	iface := ""
+	if v, err := object.GetProperty(modemManagerDbusObject + ".Bearer.Interface"); err == nil {
+		iface, _ = v.Value().(string)
+	}
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.bearerConnected, prometheus.GaugeValue, value, modem, bearer, iface)
+
+	v, err = object.GetProperty(modemManagerDbusObject + ".Bearer.Stats")
+	if err != nil {
+		return err
+	}
+	stats, _ := v.Value().(map[string]dbus.Variant)
+	if rx, ok := stats["rx-bytes"].Value().(uint64); ok {
+		ch <- prometheus.MustNewConstMetric(c.bearerReceiveBytes, prometheus.CounterValue, float64(rx), modem, bearer, iface)
+	}
+	if tx, ok := stats["tx-bytes"].Value().(uint64); ok {
+		ch <- prometheus.MustNewConstMetric(c.bearerSendBytes, prometheus.CounterValue, float64(tx), modem, bearer, iface)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nomounts
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"golang.org/x/sys/unix"
+)
+
+const mountsSubsystem = "mounts"
+
+type mountsCollector struct {
+	// changeCount is updated by the watcher of the mount table, watching is
+	// false if that failed. It is first for 64-bit alignment on 32-bit
+	// platforms.
+	changeCount uint64
+	watching    bool
+
+	fs          procfs.FS
+	mounts      *prometheus.Desc
+	propagation *prometheus.Desc
+	changes     *prometheus.Desc
+	logger      log.Logger
+}
+
+func init() {
+	registerCollector(mountsSubsystem, defaultDisabled, NewMountsCollector)
+}
+
+// NewMountsCollector returns a new Collector exposing the number of mounts and
+// the changes of the mount table.
+func NewMountsCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	c := &mountsCollector{
+		fs: fs,
+		mounts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", mountsSubsystem),
+			"Number of mounts by filesystem type.",
+			[]string{"fstype"}, nil,
+		),
+		propagation: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mountsSubsystem, "propagation"),
+			"Number of mounts by propagation type, mounts can be both shared and slave.",
+			[]string{"type"}, nil,
+		),
+		changes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, mountsSubsystem, "changes_total"),
+			"Number of times the mount table was seen changing since the exporter started, changes in quick succession are counted once.",
+			nil, nil,
+		),
+		logger: logger,
+	}
+
+	fd, err := openMountInfo()
+	if err != nil {
+		level.Warn(logger).Log("msg", "failed to watch mount table for changes", "err", err)
+	} else {
+		c.watching = true
+		go c.watch(fd)
+	}
+	return c, nil
+}
+
+// openMountInfo opens the mounts of the root mount namespace, falling back to
+// the mounts of the exporter if hidepid prevents reading them. The file is
+// not opened with os.Open, the poller of the runtime would consume the
+// changes.
+func openMountInfo() (int, error) {
+	fd, err := unix.Open(procFilePath("1/mountinfo"), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		fd, err = unix.Open(procFilePath("self/mountinfo"), unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	}
+	return fd, err
+}
+
+// watch counts the changes of the mount table, the kernel signals them as
+// exceptional condition on open mountinfo files.
+func (c *mountsCollector) watch(fd int) {
+	defer unix.Close(fd)
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLPRI}}
+	for {
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			level.Error(c.logger).Log("msg", "failed to watch mount table for changes", "err", err)
+			return
+		}
+		if fds[0].Revents&(unix.POLLPRI|unix.POLLERR) != 0 {
+			atomic.AddUint64(&c.changeCount, 1)
+		}
+	}
+}
+
+func (c *mountsCollector) Update(ch chan<- prometheus.Metric) error {
+	p, err := c.fs.Proc(1)
+	var mounts []*procfs.MountInfo
+	if err == nil {
+		mounts, err = p.MountInfo()
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		level.Debug(c.logger).Log("msg", "Reading root mounts failed, falling back to system mounts", "err", err)
+		if p, err = c.fs.Self(); err == nil {
+			mounts, err = p.MountInfo()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read mounts: %w", err)
+	}
+
+	fsTypes := map[string]int{}
+	propagation := map[string]int{"shared": 0, "slave": 0, "private": 0, "unbindable": 0}
+	for _, m := range mounts {
+		fsTypes[m.FSType]++
+
+		_, shared := m.OptionalFields["shared"]
+		_, slave := m.OptionalFields["master"]
+		_, unbindable := m.OptionalFields["unbindable"]
+		switch {
+		case unbindable:
+			propagation["unbindable"]++
+		case shared || slave:
+			if shared {
+				propagation["shared"]++
+			}
+			if slave {
+				propagation["slave"]++
+			}
+		default:
+			propagation["private"]++
+		}
+	}
+	for fsType, count := range fsTypes {
+		ch <- prometheus.MustNewConstMetric(c.mounts, prometheus.GaugeValue, float64(count), fsType)
+	}
+	for typ, count := range propagation {
+		ch <- prometheus.MustNewConstMetric(c.propagation, prometheus.GaugeValue, float64(count), typ)
+	}
+
+	if c.watching {
+		ch <- prometheus.MustNewConstMetric(c.changes, prometheus.CounterValue, float64(atomic.LoadUint64(&c.changeCount)))
+	}
+	return nil
+}
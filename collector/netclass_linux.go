@@ -20,24 +20,25 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs/sysfs"
+	"golang.org/x/sys/unix"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
-	netclassIgnoredDevices = kingpin.Flag("collector.netclass.ignored-devices", "Regexp of net devices to ignore for netclass collector.").Default("^$").String()
+	netclassIgnoredDevices = kingpin.Flag("collector.netclass.ignored-devices", "Regexp, or comma separated globs prefixed with glob:, of net devices to ignore for netclass collector.").Default("^$").String()
 	netclassInvalidSpeed   = kingpin.Flag("collector.netclass.ignore-invalid-speed", "Ignore devices where the speed is invalid. This will be the default behavior in 2.x.").Bool()
 )
 
 type netClassCollector struct {
-	fs                    sysfs.FS
 	subsystem             string
-	ignoredDevicesPattern *regexp.Regexp
+	ignoredDevicesPattern *deviceMatcher
 	metricDescs           map[string]*prometheus.Desc
 	logger                log.Logger
 }
@@ -48,13 +49,8 @@ func init() {
 
 // NewNetClassCollector returns a new Collector exposing network class stats.
 func NewNetClassCollector(logger log.Logger) (Collector, error) {
-	fs, err := sysfs.NewFS(*sysPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open sysfs: %w", err)
-	}
-	pattern := regexp.MustCompile(*netclassIgnoredDevices)
+	pattern := mustNewDeviceMatcher(*netclassIgnoredDevices)
 	return &netClassCollector{
-		fs:                    fs,
 		subsystem:             "network",
 		ignoredDevicesPattern: pattern,
 		metricDescs:           map[string]*prometheus.Desc{},
@@ -182,23 +178,127 @@ func pushMetric(ch chan<- prometheus.Metric, subsystem string, name string, valu
 	ch <- prometheus.MustNewConstMetric(fieldDesc, valueType, float64(value), ifaceName)
 }
 
+// getNetClassInfo reads the devices of /sys/class/net like
+// sysfs.FS.NetClass, but lists them and opens their attributes relative to
+// the open directories instead of resolving and stat'ing every path.
 func (c *netClassCollector) getNetClassInfo() (sysfs.NetClass, error) {
 	netClass := sysfs.NetClass{}
-	netDevices, err := c.fs.NetClassDevices()
+	dir, err := openSysfsDir(sysFilePath("class/net"))
+	if err != nil {
+		return netClass, err
+	}
+	defer dir.Close()
+	netDevices, err := dir.names()
 	if err != nil {
 		return netClass, err
 	}
 
 	for _, device := range netDevices {
-		if c.ignoredDevicesPattern.MatchString(device) {
+		if c.ignoredDevicesPattern.matches(device) {
 			continue
 		}
-		interfaceClass, err := c.fs.NetClassByIface(device)
+		deviceDir, err := dir.openDir(device)
+		if err != nil {
+			// Files like bonding_masters are not devices, and devices
+			// can be removed while reading.
+			if errors.Is(err, unix.ENOTDIR) || errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return netClass, err
+		}
+		interfaceClass, err := readNetClassIface(deviceDir)
+		deviceDir.Close()
 		if err != nil {
 			return netClass, err
 		}
-		netClass[device] = *interfaceClass
+		interfaceClass.Name = device
+		netClass[device] = interfaceClass
 	}
 
 	return netClass, nil
 }
+
+// netClassFiles are the attributes of a device parsed by readNetClassIface.
+var netClassFiles = []string{
+	"addr_assign_type", "addr_len", "address", "broadcast", "carrier",
+	"carrier_changes", "carrier_up_count", "carrier_down_count", "dev_id",
+	"dormant", "duplex", "flags", "ifalias", "ifindex", "iflink", "link_mode",
+	"mtu", "name_assign_type", "netdev_group", "operstate", "phys_port_id",
+	"phys_port_name", "phys_switch_id", "speed", "tx_queue_len", "type",
+}
+
+// readNetClassIface parses the attributes of a device like sysfs does.
+// Attributes which are missing or can't be read in the state of the device,
+// e.g. the speed of a device which is down, are left empty.
+func readNetClassIface(dir *sysfsDir) (sysfs.NetClassIface, error) {
+	iface := sysfs.NetClassIface{}
+	values, errs := dir.readStrings(netClassFiles)
+	for i, name := range netClassFiles {
+		if err := errs[i]; err != nil {
+			if os.IsNotExist(err) || os.IsPermission(err) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL) {
+				continue
+			}
+			return iface, fmt.Errorf("failed to read file %q: %w", dir.path+"/"+name, err)
+		}
+		value := strings.TrimSpace(values[i])
+		var number *int64
+		if v, err := strconv.ParseInt(value, 0, 64); err == nil {
+			number = &v
+		}
+		switch name {
+		case "addr_assign_type":
+			iface.AddrAssignType = number
+		case "addr_len":
+			iface.AddrLen = number
+		case "address":
+			iface.Address = value
+		case "broadcast":
+			iface.Broadcast = value
+		case "carrier":
+			iface.Carrier = number
+		case "carrier_changes":
+			iface.CarrierChanges = number
+		case "carrier_up_count":
+			iface.CarrierUpCount = number
+		case "carrier_down_count":
+			iface.CarrierDownCount = number
+		case "dev_id":
+			iface.DevID = number
+		case "dormant":
+			iface.Dormant = number
+		case "duplex":
+			iface.Duplex = value
+		case "flags":
+			iface.Flags = number
+		case "ifalias":
+			iface.IfAlias = value
+		case "ifindex":
+			iface.IfIndex = number
+		case "iflink":
+			iface.IfLink = number
+		case "link_mode":
+			iface.LinkMode = number
+		case "mtu":
+			iface.MTU = number
+		case "name_assign_type":
+			iface.NameAssignType = number
+		case "netdev_group":
+			iface.NetDevGroup = number
+		case "operstate":
+			iface.OperState = value
+		case "phys_port_id":
+			iface.PhysPortID = value
+		case "phys_port_name":
+			iface.PhysPortName = value
+		case "phys_switch_id":
+			iface.PhysSwitchID = value
+		case "speed":
+			iface.Speed = number
+		case "tx_queue_len":
+			iface.TxQueueLen = number
+		case "type":
+			iface.Type = number
+		}
+	}
+	return iface, nil
+}
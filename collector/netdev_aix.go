@@ -0,0 +1,69 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nonetdev
+// +build cgo
+
+package collector
+
+import (
+	"errors"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+/*
+#cgo LDFLAGS: -lperfstat
+#include <libperfstat.h>
+*/
+import "C"
+
+func getNetDevStats(filter *netDevFilter, logger log.Logger) (netDevStats, error) {
+	n := C.perfstat_netinterface(nil, nil, C.sizeof_perfstat_netinterface_t, 0)
+	if n < 0 {
+		return nil, errors.New("perfstat_netinterface failed")
+	}
+	netDev := netDevStats{}
+	if n == 0 {
+		return netDev, nil
+	}
+	ifaces := make([]C.perfstat_netinterface_t, n)
+	// The zero value of the name is FIRST_NETINTERFACE.
+	var first C.perfstat_id_t
+	n = C.perfstat_netinterface(&first, &ifaces[0], C.sizeof_perfstat_netinterface_t, n)
+	if n < 0 {
+		return nil, errors.New("perfstat_netinterface failed")
+	}
+
+	for _, iface := range ifaces[:n] {
+		dev := C.GoString(&iface.name[0])
+		if filter.ignored(dev) {
+			level.Debug(logger).Log("msg", "Ignoring device", "device", dev)
+			continue
+		}
+
+		netDev[dev] = map[string]uint64{
+			"receive_packets":  uint64(iface.ipackets),
+			"transmit_packets": uint64(iface.opackets),
+			"receive_errs":     uint64(iface.ierrors),
+			"transmit_errs":    uint64(iface.oerrors),
+			"receive_bytes":    uint64(iface.ibytes),
+			"transmit_bytes":   uint64(iface.obytes),
+			"receive_drop":     uint64(iface.if_iqdrops),
+			"transmit_drop":    uint64(iface.xmitdrops),
+			"transmit_colls":   uint64(iface.collisions),
+		}
+	}
+	return netDev, nil
+}
@@ -11,9 +11,9 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-//go:build !nonetdev && (linux || freebsd || openbsd || dragonfly || darwin)
+//go:build !nonetdev && (linux || freebsd || openbsd || dragonfly || darwin || solaris || (aix && cgo))
 // +build !nonetdev
-// +build linux freebsd openbsd dragonfly darwin
+// +build linux freebsd openbsd dragonfly darwin solaris aix,cgo
 
 package collector
 
@@ -30,9 +30,9 @@ import (
 )
 
 var (
-	netdevDeviceInclude    = kingpin.Flag("collector.netdev.device-include", "Regexp of net devices to include (mutually exclusive to device-exclude).").String()
+	netdevDeviceInclude    = kingpin.Flag("collector.netdev.device-include", "Regexp, or comma separated globs prefixed with glob:, of net devices to include (mutually exclusive to device-exclude).").String()
 	oldNetdevDeviceInclude = kingpin.Flag("collector.netdev.device-whitelist", "DEPRECATED: Use collector.netdev.device-include").Hidden().String()
-	netdevDeviceExclude    = kingpin.Flag("collector.netdev.device-exclude", "Regexp of net devices to exclude (mutually exclusive to device-include).").String()
+	netdevDeviceExclude    = kingpin.Flag("collector.netdev.device-exclude", "Regexp, or comma separated globs prefixed with glob:, of net devices to exclude (mutually exclusive to device-include).").String()
 	oldNetdevDeviceExclude = kingpin.Flag("collector.netdev.device-blacklist", "DEPRECATED: Use collector.netdev.device-exclude").Hidden().String()
 	netdevAddressInfo      = kingpin.Flag("collector.netdev.address-info", "Collect address-info for every device").Bool()
 )
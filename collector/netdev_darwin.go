@@ -55,6 +55,10 @@ func getNetDevStats(filter *netDevFilter, logger log.Logger) (netDevStats, error
 			"transmit_bytes":     ifaceData.Data.Obytes,
 			"receive_multicast":  ifaceData.Data.Imcasts,
 			"transmit_multicast": ifaceData.Data.Omcasts,
+			"receive_drop":       ifaceData.Data.Iqdrops,
+			"receive_noproto":    ifaceData.Data.Noproto,
+			"transmit_drop":      uint64(ifaceData.SndDrops),
+			"transmit_colls":     ifaceData.Data.Collisions,
 		}
 	}
 
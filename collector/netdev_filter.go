@@ -13,22 +13,18 @@
 
 package collector
 
-import (
-	"regexp"
-)
-
 type netDevFilter struct {
-	ignorePattern *regexp.Regexp
-	acceptPattern *regexp.Regexp
+	ignorePattern *deviceMatcher
+	acceptPattern *deviceMatcher
 }
 
 func newNetDevFilter(ignoredPattern, acceptPattern string) (f netDevFilter) {
 	if ignoredPattern != "" {
-		f.ignorePattern = regexp.MustCompile(ignoredPattern)
+		f.ignorePattern = mustNewDeviceMatcher(ignoredPattern)
 	}
 
 	if acceptPattern != "" {
-		f.acceptPattern = regexp.MustCompile(acceptPattern)
+		f.acceptPattern = mustNewDeviceMatcher(acceptPattern)
 	}
 
 	return
@@ -36,6 +32,6 @@ func newNetDevFilter(ignoredPattern, acceptPattern string) (f netDevFilter) {
 
 // ignores returns whether the device should be ignored
 func (f *netDevFilter) ignored(name string) bool {
-	return ((f.ignorePattern != nil && f.ignorePattern.MatchString(name)) ||
-		(f.acceptPattern != nil && !f.acceptPattern.MatchString(name)))
+	return ((f.ignorePattern != nil && f.ignorePattern.matches(name)) ||
+		(f.acceptPattern != nil && !f.acceptPattern.matches(name)))
 }
@@ -26,14 +26,137 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
+	netdevNetlink = kingpin.Flag("collector.netdev.netlink", "Use netlink to gather stats instead of /proc/net/dev. The stats are those of the network namespace of node_exporter, --path.procfs does not apply.").Default("false").Bool()
+
 	procNetDevInterfaceRE = regexp.MustCompile(`^(.+): *(.+)$`)
 	procNetDevFieldSep    = regexp.MustCompile(` +`)
 )
 
+// rtnlLinkStats64Fields are the counters of struct rtnl_link_stats64 in
+// order, kernels before 4.6 lack rx_nohandler.
+var rtnlLinkStats64Fields = []string{
+	"rx_packets", "tx_packets", "rx_bytes", "tx_bytes", "rx_errors", "tx_errors",
+	"rx_dropped", "tx_dropped", "multicast", "collisions", "rx_length_errors",
+	"rx_over_errors", "rx_crc_errors", "rx_frame_errors", "rx_fifo_errors",
+	"rx_missed_errors", "tx_aborted_errors", "tx_carrier_errors",
+	"tx_fifo_errors", "tx_heartbeat_errors", "tx_window_errors",
+	"rx_compressed", "tx_compressed", "rx_nohandler",
+}
+
 func getNetDevStats(filter *netDevFilter, logger log.Logger) (netDevStats, error) {
+	if *netdevNetlink {
+		stats, err := netlinkStats(filter, logger)
+		if err == nil {
+			return stats, nil
+		}
+		level.Debug(logger).Log("msg", "failed to get stats via netlink, falling back to /proc/net/dev", "err", err)
+	}
+	return procNetDevStats(filter, logger)
+}
+
+// netlinkStats dumps all devices with RTM_GETLINK and names their 64 bit
+// counters like the columns of /proc/net/dev. The names are taken from the
+// same dump, so devices created or renamed meanwhile can't be mislabeled.
+func netlinkStats(filter *netDevFilter, logger log.Logger) (netDevStats, error) {
+	conn, err := netlink.Dial(unix.NETLINK_ROUTE, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	msgs, err := conn.Execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.RTM_GETLINK,
+			Flags: netlink.Request | netlink.Dump,
+		},
+		// struct ifinfomsg selecting all devices.
+		Data: make([]byte, unix.SizeofIfInfomsg),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	netDev := netDevStats{}
+	for _, msg := range msgs {
+		dev, stats, err := parseLinkMessage(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		if dev == "" || stats == nil {
+			continue
+		}
+		if filter.ignored(dev) {
+			level.Debug(logger).Log("msg", "Ignoring device", "device", dev)
+			continue
+		}
+		netDev[dev] = parseLinkStats64(stats)
+	}
+	return netDev, nil
+}
+
+// parseLinkMessage returns the IFLA_IFNAME and IFLA_STATS64 attributes of a
+// RTM_NEWLINK message, they are empty if the message lacks them.
+func parseLinkMessage(data []byte) (string, []byte, error) {
+	if len(data) < unix.SizeofIfInfomsg {
+		return "", nil, fmt.Errorf("invalid size %d of RTM_NEWLINK message", len(data))
+	}
+	ad, err := netlink.NewAttributeDecoder(data[unix.SizeofIfInfomsg:])
+	if err != nil {
+		return "", nil, err
+	}
+	var (
+		name  string
+		stats []byte
+	)
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.IFLA_IFNAME:
+			name = ad.String()
+		case unix.IFLA_STATS64:
+			stats = ad.Bytes()
+		}
+	}
+	return name, stats, ad.Err()
+}
+
+// parseLinkStats64 sums up the counters of struct rtnl_link_stats64 like the
+// kernel does for /proc/net/dev.
+func parseLinkStats64(b []byte) map[string]uint64 {
+	s := map[string]uint64{}
+	for i, field := range rtnlLinkStats64Fields {
+		if len(b) < 8*(i+1) {
+			break
+		}
+		s[field] = nlenc.Uint64(b[8*i : 8*(i+1)])
+	}
+	return map[string]uint64{
+		"receive_bytes":       s["rx_bytes"],
+		"receive_packets":     s["rx_packets"],
+		"receive_errs":        s["rx_errors"],
+		"receive_drop":        s["rx_dropped"] + s["rx_missed_errors"],
+		"receive_fifo":        s["rx_fifo_errors"],
+		"receive_frame":       s["rx_length_errors"] + s["rx_over_errors"] + s["rx_crc_errors"] + s["rx_frame_errors"],
+		"receive_compressed":  s["rx_compressed"],
+		"receive_multicast":   s["multicast"],
+		"transmit_bytes":      s["tx_bytes"],
+		"transmit_packets":    s["tx_packets"],
+		"transmit_errs":       s["tx_errors"],
+		"transmit_drop":       s["tx_dropped"],
+		"transmit_fifo":       s["tx_fifo_errors"],
+		"transmit_colls":      s["collisions"],
+		"transmit_carrier":    s["tx_carrier_errors"] + s["tx_aborted_errors"] + s["tx_window_errors"] + s["tx_heartbeat_errors"],
+		"transmit_compressed": s["tx_compressed"],
+	}
+}
+
+func procNetDevStats(filter *netDevFilter, logger log.Logger) (netDevStats, error) {
 	file, err := os.Open(procFilePath("net/dev"))
 	if err != nil {
 		return nil, err
@@ -18,6 +18,9 @@ import (
 	"testing"
 
 	"github.com/go-kit/log"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
 )
 
 func TestNetDevStatsIgnore(t *testing.T) {
@@ -83,3 +86,48 @@ func TestNetDevStatsAccept(t *testing.T) {
 		t.Error("want fixture interface 💩0 to exist, but it does not")
 	}
 }
+
+func TestParseLinkStats64(t *testing.T) {
+	// struct rtnl_link_stats64 of kernels before 4.6, without rx_nohandler.
+	b := make([]byte, 8*23)
+	for i := range rtnlLinkStats64Fields[:23] {
+		nlenc.PutUint64(b[8*i:8*(i+1)], uint64(i+1))
+	}
+	stats := parseLinkStats64(b)
+
+	if want, got := uint64(3), stats["receive_bytes"]; want != got {
+		t.Errorf("want receive_bytes %d, got %d", want, got)
+	}
+	// rx_dropped + rx_missed_errors
+	if want, got := uint64(7+16), stats["receive_drop"]; want != got {
+		t.Errorf("want receive_drop %d, got %d", want, got)
+	}
+	// tx_carrier_errors + tx_aborted_errors + tx_window_errors + tx_heartbeat_errors
+	if want, got := uint64(18+17+21+20), stats["transmit_carrier"]; want != got {
+		t.Errorf("want transmit_carrier %d, got %d", want, got)
+	}
+	if want, got := 16, len(stats); want != got {
+		t.Errorf("want %d stats like /proc/net/dev, got %d", want, got)
+	}
+}
+
+func TestParseLinkMessage(t *testing.T) {
+	ae := netlink.NewAttributeEncoder()
+	ae.String(unix.IFLA_IFNAME, "veth1a2b3c")
+	ae.Uint32(unix.IFLA_MTU, 1500)
+	ae.Bytes(unix.IFLA_STATS64, make([]byte, 8*24))
+	attrs, err := ae.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, stats, err := parseLinkMessage(append(make([]byte, unix.SizeofIfInfomsg), attrs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev != "veth1a2b3c" || len(stats) != 8*24 {
+		t.Errorf("want device veth1a2b3c with stats, got %q with %d bytes", dev, len(stats))
+	}
+	if _, _, err := parseLinkMessage(make([]byte, 8)); err == nil {
+		t.Error("expected error for a truncated message")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nonetdev
+
+package collector
+
+import (
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/illumos/go-kstat"
+)
+
+// netdevKstatFields maps the statistics of the link kstats, which cover
+// physical links, VNICs and etherstubs, to the names used on Linux.
+var netdevKstatFields = map[string]string{
+	"rbytes64":   "receive_bytes",
+	"ipackets64": "receive_packets",
+	"ierrors":    "receive_errs",
+	"norcvbuf":   "receive_drop",
+	"multircv":   "receive_multicast",
+	"brdcstrcv":  "receive_broadcast",
+	"obytes64":   "transmit_bytes",
+	"opackets64": "transmit_packets",
+	"oerrors":    "transmit_errs",
+	"noxmtbuf":   "transmit_drop",
+	"multixmt":   "transmit_multicast",
+	"brdcstxmt":  "transmit_broadcast",
+	"collisions": "transmit_colls",
+}
+
+func getNetDevStats(filter *netDevFilter, logger log.Logger) (netDevStats, error) {
+	tok, err := kstat.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer tok.Close()
+
+	netDev := netDevStats{}
+	for _, ks := range tok.All() {
+		if ks.Module != "link" || ks.Class != "net" {
+			continue
+		}
+		dev := ks.Name
+		if filter.ignored(dev) {
+			level.Debug(logger).Log("msg", "Ignoring device", "device", dev)
+			continue
+		}
+
+		named, err := ks.AllNamed()
+		if err != nil {
+			return nil, err
+		}
+		stats := map[string]uint64{}
+		for _, n := range named {
+			if field, ok := netdevKstatFields[n.Name]; ok {
+				stats[field] = n.UintVal
+			}
+		}
+		netDev[dev] = stats
+	}
+	return netDev, nil
+}
@@ -0,0 +1,366 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nonetns,!nonetdev,!nonetstat,!nosockstat
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const netnsSubsystem = "netns"
+
+var (
+	netnsDirectory = kingpin.Flag("collector.netns.directory", "Directory containing named network namespaces, as created by `ip netns add`.").Default("/run/netns").String()
+	netnsProcesses = kingpin.Flag("collector.netns.processes", "Also discover network namespaces from the processes in procfs.").Bool()
+)
+
+// netnsCollector exports the metrics of the netdev, netstat and sockstat
+// collectors of other network namespaces, with the same names and an
+// additional netns label.
+type netnsCollector struct {
+	deviceFilter  netDevFilter
+	fieldPattern  *regexp.Regexp
+	namespaceInfo *prometheus.Desc
+	logger        log.Logger
+
+	mtx sync.Mutex
+	// Descs by the key of the statistic, fields of netstat not matching
+	// the pattern are cached as nil.
+	netDevDescs   map[string]*prometheus.Desc
+	netStatDescs  map[string]*prometheus.Desc
+	sockStatDescs map[string]*prometheus.Desc
+}
+
+// netNamespace is a network namespace other than the one node_exporter runs in.
+type netNamespace struct {
+	name  string
+	inode uint64
+	// nsPath is a file referring to the namespace which can be passed to setns(2).
+	nsPath string
+	// procDir is the procfs directory of a process running inside the namespace.
+	// If set, the namespace statistics are read from it without entering the namespace.
+	procDir string
+}
+
+func init() {
+	registerCollector(netnsSubsystem, defaultDisabled, NewNetNSCollector)
+}
+
+// NewNetNSCollector returns a new Collector exposing network statistics of
+// network namespaces other than the one node_exporter runs in.
+func NewNetNSCollector(logger log.Logger) (Collector, error) {
+	if *netdevDeviceExclude != "" && *netdevDeviceInclude != "" {
+		return nil, errors.New("device-exclude & device-include are mutually exclusive")
+	}
+	pattern, err := regexp.Compile(*netStatFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid netstat fields pattern: %w", err)
+	}
+	return &netnsCollector{
+		deviceFilter:  newNetDevFilter(*netdevDeviceExclude, *netdevDeviceInclude),
+		fieldPattern:  pattern,
+		netDevDescs:   map[string]*prometheus.Desc{},
+		netStatDescs:  map[string]*prometheus.Desc{},
+		sockStatDescs: map[string]*prometheus.Desc{},
+		namespaceInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, netnsSubsystem, "info"),
+			"Network namespaces found on the host, value is always 1.",
+			[]string{"netns", "inode"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *netnsCollector) Update(ch chan<- prometheus.Metric) error {
+	namespaces, err := c.namespaces()
+	if err != nil {
+		return err
+	}
+	if len(namespaces) == 0 {
+		return ErrNoData
+	}
+
+	for _, ns := range namespaces {
+		ch <- prometheus.MustNewConstMetric(c.namespaceInfo, prometheus.GaugeValue, 1,
+			ns.name, strconv.FormatUint(ns.inode, 10))
+
+		err := ns.read(func(procDir string) error {
+			return c.updateNamespace(ch, ns.name, procDir)
+		})
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to collect network namespace statistics", "netns", ns.name, "err", err)
+		}
+	}
+	return nil
+}
+
+// namespaces returns all network namespaces found, excluding the one
+// node_exporter runs in since it is covered by the regular collectors.
+func (c *netnsCollector) namespaces() ([]netNamespace, error) {
+	self, err := netnsInode(procFilePath("self/ns/net"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get own network namespace: %w", err)
+	}
+	seen := map[uint64]struct{}{self: {}}
+
+	var namespaces []netNamespace
+
+	entries, err := ioutil.ReadDir(*netnsDirectory)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read network namespace directory: %w", err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(*netnsDirectory, entry.Name())
+		inode, err := netnsInode(path)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to stat network namespace", "path", path, "err", err)
+			continue
+		}
+		if _, ok := seen[inode]; ok {
+			continue
+		}
+		seen[inode] = struct{}{}
+		namespaces = append(namespaces, netNamespace{
+			name:   entry.Name(),
+			inode:  inode,
+			nsPath: path,
+		})
+	}
+
+	if !*netnsProcesses {
+		return namespaces, nil
+	}
+
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	procs, err := fs.AllProcs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list all processes: %w", err)
+	}
+	for _, p := range procs {
+		procDir := procFilePath(strconv.Itoa(p.PID))
+		inode, err := netnsInode(filepath.Join(procDir, "ns/net"))
+		if err != nil {
+			// Processes may exit while iterating, or belong to other users.
+			continue
+		}
+		if _, ok := seen[inode]; ok {
+			continue
+		}
+		seen[inode] = struct{}{}
+		namespaces = append(namespaces, netNamespace{
+			name:    fmt.Sprintf("net:[%d]", inode),
+			inode:   inode,
+			procDir: procDir,
+		})
+	}
+	return namespaces, nil
+}
+
+func (c *netnsCollector) updateNamespace(ch chan<- prometheus.Metric, name, procDir string) error {
+	file, err := os.Open(filepath.Join(procDir, "net/dev"))
+	if err != nil {
+		return err
+	}
+	netDev, err := parseNetDevStats(file, &c.deviceFilter, c.logger)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't get netstats: %w", err)
+	}
+
+	netStats, err := getNetStats(filepath.Join(procDir, "net/netstat"))
+	if err != nil {
+		return fmt.Errorf("couldn't get netstats: %w", err)
+	}
+	snmpStats, err := getNetStats(filepath.Join(procDir, "net/snmp"))
+	if err != nil {
+		return fmt.Errorf("couldn't get SNMP stats: %w", err)
+	}
+	snmp6Stats, err := getSNMP6Stats(filepath.Join(procDir, "net/snmp6"))
+	if err != nil {
+		return fmt.Errorf("couldn't get SNMP6 stats: %w", err)
+	}
+	for k, v := range snmpStats {
+		netStats[k] = v
+	}
+	for k, v := range snmp6Stats {
+		netStats[k] = v
+	}
+
+	fs, err := procfs.NewFS(procDir)
+	if err != nil {
+		return fmt.Errorf("failed to open procfs: %w", err)
+	}
+	sockstat, err := fs.NetSockstat()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to get sockstat data: %w", err)
+	}
+
+	// Updates of timed out scrapes may still run alongside.
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for dev, devStats := range netDev {
+		for key, value := range devStats {
+			desc, ok := c.netDevDescs[key]
+			if !ok {
+				desc = prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "network", key+"_total"),
+					fmt.Sprintf("Network device statistic %s.", key),
+					[]string{"device", "netns"}, nil,
+				)
+				c.netDevDescs[key] = desc
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), dev, name)
+		}
+	}
+
+	for protocol, protocolStats := range netStats {
+		for field, value := range protocolStats {
+			key := protocol + "_" + field
+			desc, ok := c.netStatDescs[key]
+			if !ok {
+				if c.fieldPattern.MatchString(key) {
+					desc = prometheus.NewDesc(
+						prometheus.BuildFQName(namespace, netStatsSubsystem, key),
+						fmt.Sprintf("Statistic %s.", protocol+field),
+						[]string{"netns"}, nil,
+					)
+				}
+				c.netStatDescs[key] = desc
+			}
+			if desc == nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.UntypedValue, value, name)
+		}
+	}
+
+	if sockstat == nil {
+		return nil
+	}
+	if sockstat.Used != nil {
+		c.sendSockStat(ch, "sockets_used", "Number of IPv4 sockets in use.", *sockstat.Used, name)
+	}
+	type sockStatField struct {
+		name string
+		v    *int
+	}
+	for _, p := range sockstat.Protocols {
+		fields := []sockStatField{
+			{"inuse", &p.InUse},
+			{"orphan", p.Orphan},
+			{"tw", p.TW},
+			{"alloc", p.Alloc},
+			{"mem", p.Mem},
+			{"memory", p.Memory},
+		}
+		if p.Mem != nil {
+			v := *p.Mem * os.Getpagesize()
+			fields = append(fields, sockStatField{"mem_bytes", &v})
+		}
+		for _, field := range fields {
+			if field.v == nil {
+				continue
+			}
+			c.sendSockStat(ch, p.Protocol+"_"+field.name,
+				fmt.Sprintf("Number of %s sockets in state %s.", p.Protocol, field.name), *field.v, name)
+		}
+	}
+	return nil
+}
+
+// sendSockStat sends a metric of the sockstat collector, c.mtx must be held.
+func (c *netnsCollector) sendSockStat(ch chan<- prometheus.Metric, key, help string, value int, netns string) {
+	desc, ok := c.sockStatDescs[key]
+	if !ok {
+		desc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, sockStatSubsystem, key),
+			help, []string{"netns"}, nil,
+		)
+		c.sockStatDescs[key] = desc
+	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), netns)
+}
+
+// read calls fn with a procfs directory whose net/ entries reflect the
+// namespace. Namespaces without a known process are temporarily entered by
+// the calling OS thread, which requires CAP_SYS_ADMIN.
+func (ns netNamespace) read(fn func(procDir string) error) error {
+	if ns.procDir != "" {
+		return fn(ns.procDir)
+	}
+
+	runtime.LockOSThread()
+
+	orig, err := os.Open(procFilePath("thread-self/ns/net"))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer orig.Close()
+
+	target, err := os.Open(ns.nsPath)
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer target.Close()
+
+	if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter network namespace: %w", err)
+	}
+
+	fnErr := fn(procFilePath("thread-self"))
+
+	if err := unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET); err != nil {
+		// Leave the thread locked, the runtime terminates it once the
+		// goroutine exits instead of reusing it in the wrong namespace.
+		return fmt.Errorf("failed to restore network namespace: %w", err)
+	}
+	runtime.UnlockOSThread()
+	return fnErr
+}
+
+func netnsInode(path string) (uint64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("unable to get inode of %s", path)
+	}
+	return st.Ino, nil
+}
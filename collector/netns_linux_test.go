@@ -0,0 +1,149 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nonetns,!nonetdev,!nonetstat,!nosockstat
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestNetNSNamespaces(t *testing.T) {
+	procPathOrig, netnsDirectoryOrig, netnsProcessesOrig := *procPath, *netnsDirectory, *netnsProcesses
+	*procPath, *netnsDirectory, *netnsProcesses = "fixtures/proc", "fixtures/netns-missing", true
+	defer func() {
+		*procPath, *netnsDirectory, *netnsProcesses = procPathOrig, netnsDirectoryOrig, netnsProcessesOrig
+	}()
+
+	c := netnsCollector{logger: log.NewNopLogger()}
+	namespaces, err := c.namespaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Process 10 is self, the other processes have no ns/net.
+	if len(namespaces) != 1 {
+		t.Fatalf("expected the namespace of process 20, got %+v", namespaces)
+	}
+	ns := namespaces[0]
+	if ns.procDir != "fixtures/proc/20" || ns.name != fmt.Sprintf("net:[%d]", ns.inode) {
+		t.Errorf("unexpected namespace %+v", ns)
+	}
+}
+
+func TestNetNSUpdateNamespace(t *testing.T) {
+	c := netnsCollector{
+		deviceFilter:  newNetDevFilter("^lo$", ""),
+		fieldPattern:  regexp.MustCompile("^(Ip_Forwarding|IpExt_InOctets|Ip6_InReceives|Tcp_CurrEstab|TcpExt_ListenDrops|Udp_InErrors)$"),
+		netDevDescs:   map[string]*prometheus.Desc{},
+		netStatDescs:  map[string]*prometheus.Desc{},
+		sockStatDescs: map[string]*prometheus.Desc{},
+		logger:        log.NewNopLogger(),
+	}
+	ch := make(chan prometheus.Metric, 100)
+	if err := c.updateNamespace(ch, "net:[1]", "fixtures/proc/20"); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	got := map[string]float64{}
+	for m := range ch {
+		var metric dto.Metric
+		if err := m.Write(&metric); err != nil {
+			t.Fatal(err)
+		}
+		var labels []string
+		for _, l := range metric.GetLabel() {
+			if l.GetName() != "netns" {
+				labels = append(labels, l.GetName()+"="+l.GetValue())
+			}
+			if l.GetName() == "netns" && l.GetValue() != "net:[1]" {
+				t.Errorf("unexpected netns label %q", l.GetValue())
+			}
+		}
+		name := regexp.MustCompile(`fqName: "([^"]+)"`).FindStringSubmatch(m.Desc().String())[1]
+		var value float64
+		switch {
+		case metric.Counter != nil:
+			value = metric.GetCounter().GetValue()
+		case metric.Gauge != nil:
+			value = metric.GetGauge().GetValue()
+		default:
+			value = metric.GetUntyped().GetValue()
+		}
+		got[name+"{"+strings.Join(labels, ",")+"}"] = value
+	}
+
+	for key, want := range map[string]float64{
+		"node_network_receive_bytes_total{device=eth0}":    68210,
+		"node_network_receive_drop_total{device=eth0}":     3,
+		"node_network_transmit_packets_total{device=eth0}": 201,
+		"node_netstat_Ip_Forwarding{}":                     2,
+		"node_netstat_IpExt_InOctets{}":                    68210,
+		"node_netstat_Ip6_InReceives{}":                    4,
+		"node_netstat_Tcp_CurrEstab{}":                     2,
+		"node_netstat_TcpExt_ListenDrops{}":                2,
+		"node_netstat_Udp_InErrors{}":                      1,
+		"node_sockstat_TCP_inuse{}":                        2,
+		"node_sockstat_TCP_tw{}":                           1,
+		"node_sockstat_UDP_inuse{}":                        1,
+	} {
+		if v, ok := got[key]; !ok || v != want {
+			t.Errorf("%s: expected %v, got %v (found %t)", key, want, v, ok)
+		}
+	}
+	for key := range got {
+		if strings.Contains(key, "device=lo") {
+			t.Errorf("expected excluded device lo, got %s", key)
+		}
+		if strings.HasPrefix(key, "node_netstat_Tcp_ActiveOpens") {
+			t.Errorf("expected field not matching the pattern to be skipped, got %s", key)
+		}
+	}
+}
+
+func TestNetNSUpdateNamespaceConcurrent(t *testing.T) {
+	c := netnsCollector{
+		deviceFilter:  newNetDevFilter("", ""),
+		fieldPattern:  regexp.MustCompile(".*"),
+		netDevDescs:   map[string]*prometheus.Desc{},
+		netStatDescs:  map[string]*prometheus.Desc{},
+		sockStatDescs: map[string]*prometheus.Desc{},
+		logger:        log.NewNopLogger(),
+	}
+	// A timed out update may still run while the next scrape updates.
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ch := make(chan prometheus.Metric)
+			go func() {
+				for range ch {
+				}
+			}()
+			errs <- c.updateNamespace(ch, "net:[1]", "fixtures/proc/20")
+			close(ch)
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
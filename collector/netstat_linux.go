@@ -16,14 +16,14 @@
 package collector
 
 import (
-	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
@@ -40,6 +40,8 @@ var (
 
 type netStatCollector struct {
 	fieldPattern *regexp.Regexp
+	mtx          sync.Mutex
+	descs        map[string]*prometheus.Desc
 	logger       log.Logger
 }
 
@@ -53,6 +55,7 @@ func NewNetStatCollector(logger log.Logger) (Collector, error) {
 	pattern := regexp.MustCompile(*netStatFields)
 	return &netStatCollector{
 		fieldPattern: pattern,
+		descs:        map[string]*prometheus.Desc{},
 		logger:       logger,
 	}, nil
 }
@@ -78,30 +81,33 @@ func (c *netStatCollector) Update(ch chan<- prometheus.Metric) error {
 	for k, v := range snmp6Stats {
 		netStats[k] = v
 	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	for protocol, protocolStats := range netStats {
 		for name, value := range protocolStats {
 			key := protocol + "_" + name
-			v, err := strconv.ParseFloat(value, 64)
-			if err != nil {
-				return fmt.Errorf("invalid value %s in netstats: %w", value, err)
+			desc, ok := c.descs[key]
+			if !ok {
+				// Fields not matching the pattern are cached as nil.
+				if c.fieldPattern.MatchString(key) {
+					desc = prometheus.NewDesc(
+						prometheus.BuildFQName(namespace, netStatsSubsystem, key),
+						fmt.Sprintf("Statistic %s.", protocol+name),
+						nil, nil,
+					)
+				}
+				c.descs[key] = desc
 			}
-			if !c.fieldPattern.MatchString(key) {
+			if desc == nil {
 				continue
 			}
-			ch <- prometheus.MustNewConstMetric(
-				prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, netStatsSubsystem, key),
-					fmt.Sprintf("Statistic %s.", protocol+name),
-					nil, nil,
-				),
-				prometheus.UntypedValue, v,
-			)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.UntypedValue, value)
 		}
 	}
 	return nil
 }
 
-func getNetStats(fileName string) (map[string]map[string]string, error) {
+func getNetStats(fileName string) (map[string]map[string]float64, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
 		return nil, err
@@ -111,32 +117,48 @@ func getNetStats(fileName string) (map[string]map[string]string, error) {
 	return parseNetStats(file, fileName)
 }
 
-func parseNetStats(r io.Reader, fileName string) (map[string]map[string]string, error) {
+func parseNetStats(r io.Reader, fileName string) (map[string]map[string]float64, error) {
+	netStats := map[string]map[string]float64{}
+	scanner, release := newPooledScanner(r)
+	defer release()
+
 	var (
-		netStats = map[string]map[string]string{}
-		scanner  = bufio.NewScanner(r)
+		parts [][]byte
+		names []string
 	)
-
 	for scanner.Scan() {
-		nameParts := strings.Split(scanner.Text(), " ")
+		// The names have to be converted before scanning the values
+		// overwrites the buffer.
+		parts = appendFields(parts[:0], scanner.Bytes())
+		names = names[:0]
+		for _, part := range parts {
+			names = append(names, internedStrings.get(part))
+		}
 		scanner.Scan()
-		valueParts := strings.Split(scanner.Text(), " ")
+		parts = appendFields(parts[:0], scanner.Bytes())
+		if len(names) == 0 {
+			return nil, fmt.Errorf("invalid line in %s", fileName)
+		}
 		// Remove trailing :.
-		protocol := nameParts[0][:len(nameParts[0])-1]
-		netStats[protocol] = map[string]string{}
-		if len(nameParts) != len(valueParts) {
+		protocol := strings.TrimSuffix(names[0], ":")
+		netStats[protocol] = map[string]float64{}
+		if len(names) != len(parts) {
 			return nil, fmt.Errorf("mismatch field count mismatch in %s: %s",
 				fileName, protocol)
 		}
-		for i := 1; i < len(nameParts); i++ {
-			netStats[protocol][nameParts[i]] = valueParts[i]
+		for i := 1; i < len(names); i++ {
+			v, err := parseFloatBytes(parts[i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %s in %s: %w", parts[i], fileName, err)
+			}
+			netStats[protocol][names[i]] = v
 		}
 	}
 
 	return netStats, scanner.Err()
 }
 
-func getSNMP6Stats(fileName string) (map[string]map[string]string, error) {
+func getSNMP6Stats(fileName string) (map[string]map[string]float64, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
 		// On systems with IPv6 disabled, this file won't exist.
@@ -152,25 +174,29 @@ func getSNMP6Stats(fileName string) (map[string]map[string]string, error) {
 	return parseSNMP6Stats(file)
 }
 
-func parseSNMP6Stats(r io.Reader) (map[string]map[string]string, error) {
-	var (
-		netStats = map[string]map[string]string{}
-		scanner  = bufio.NewScanner(r)
-	)
+func parseSNMP6Stats(r io.Reader) (map[string]map[string]float64, error) {
+	netStats := map[string]map[string]float64{}
+	scanner, release := newPooledScanner(r)
+	defer release()
 
+	var stat [][]byte
 	for scanner.Scan() {
-		stat := strings.Fields(scanner.Text())
+		stat = appendFields(stat[:0], scanner.Bytes())
 		if len(stat) < 2 {
 			continue
 		}
 		// Expect to have "6" in metric name, skip line otherwise
-		if sixIndex := strings.Index(stat[0], "6"); sixIndex != -1 {
-			protocol := stat[0][:sixIndex+1]
-			name := stat[0][sixIndex+1:]
+		if sixIndex := bytes.IndexByte(stat[0], '6'); sixIndex != -1 {
+			protocol := internedStrings.get(stat[0][:sixIndex+1])
+			name := internedStrings.get(stat[0][sixIndex+1:])
+			v, err := parseFloatBytes(stat[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %s in snmp6: %w", stat[1], err)
+			}
 			if _, present := netStats[protocol]; !present {
-				netStats[protocol] = map[string]string{}
+				netStats[protocol] = map[string]float64{}
 			}
-			netStats[protocol][name] = stat[1]
+			netStats[protocol][name] = v
 		}
 	}
 
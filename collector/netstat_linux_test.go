@@ -36,12 +36,12 @@ func testNetStats(t *testing.T, fileName string) {
 		t.Fatal(err)
 	}
 
-	if want, got := "102471", netStats["TcpExt"]["DelayedACKs"]; want != got {
-		t.Errorf("want netstat TCP DelayedACKs %s, got %s", want, got)
+	if want, got := 102471.0, netStats["TcpExt"]["DelayedACKs"]; want != got {
+		t.Errorf("want netstat TCP DelayedACKs %f, got %f", want, got)
 	}
 
-	if want, got := "2786264347", netStats["IpExt"]["OutOctets"]; want != got {
-		t.Errorf("want netstat IP OutOctets %s, got %s", want, got)
+	if want, got := 2786264347.0, netStats["IpExt"]["OutOctets"]; want != got {
+		t.Errorf("want netstat IP OutOctets %f, got %f", want, got)
 	}
 }
 
@@ -57,12 +57,12 @@ func testSNMPStats(t *testing.T, fileName string) {
 		t.Fatal(err)
 	}
 
-	if want, got := "9", snmpStats["Udp"]["RcvbufErrors"]; want != got {
-		t.Errorf("want netstat Udp RcvbufErrors %s, got %s", want, got)
+	if want, got := 9.0, snmpStats["Udp"]["RcvbufErrors"]; want != got {
+		t.Errorf("want netstat Udp RcvbufErrors %f, got %f", want, got)
 	}
 
-	if want, got := "8", snmpStats["Udp"]["SndbufErrors"]; want != got {
-		t.Errorf("want netstat Udp SndbufErrors %s, got %s", want, got)
+	if want, got := 8.0, snmpStats["Udp"]["SndbufErrors"]; want != got {
+		t.Errorf("want netstat Udp SndbufErrors %f, got %f", want, got)
 	}
 }
 
@@ -78,19 +78,19 @@ func testSNMP6Stats(t *testing.T, fileName string) {
 		t.Fatal(err)
 	}
 
-	if want, got := "460", snmp6Stats["Ip6"]["InOctets"]; want != got {
-		t.Errorf("want netstat IPv6 InOctets %s, got %s", want, got)
+	if want, got := 460.0, snmp6Stats["Ip6"]["InOctets"]; want != got {
+		t.Errorf("want netstat IPv6 InOctets %f, got %f", want, got)
 	}
 
-	if want, got := "8", snmp6Stats["Icmp6"]["OutMsgs"]; want != got {
-		t.Errorf("want netstat ICPM6 OutMsgs %s, got %s", want, got)
+	if want, got := 8.0, snmp6Stats["Icmp6"]["OutMsgs"]; want != got {
+		t.Errorf("want netstat ICPM6 OutMsgs %f, got %f", want, got)
 	}
 
-	if want, got := "9", snmp6Stats["Udp6"]["RcvbufErrors"]; want != got {
-		t.Errorf("want netstat Udp6 RcvbufErrors %s, got %s", want, got)
+	if want, got := 9.0, snmp6Stats["Udp6"]["RcvbufErrors"]; want != got {
+		t.Errorf("want netstat Udp6 RcvbufErrors %f, got %f", want, got)
 	}
 
-	if want, got := "8", snmp6Stats["Udp6"]["SndbufErrors"]; want != got {
-		t.Errorf("want netstat Udp6 SndbufErrors %s, got %s", want, got)
+	if want, got := 8.0, snmp6Stats["Udp6"]["SndbufErrors"]; want != got {
+		t.Errorf("want netstat Udp6 SndbufErrors %f, got %f", want, got)
 	}
 }
@@ -0,0 +1,263 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nonut
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const upsSubsystem = "ups"
+
+var (
+	nutAddress = kingpin.Flag("collector.nut.address", "Address of the NUT upsd daemon.").Default("localhost:3493").String()
+	nutTimeout = kingpin.Flag("collector.nut.timeout", "Timeout for querying upsd.").Default("2s").Duration()
+)
+
+// nutVariables are the numeric NUT variables exported as metrics, scaled to
+// base units.
+var nutVariables = []struct {
+	variable, name, help string
+	scale                float64
+}{
+	{"ups.load", "load_ratio", "Load of the UPS relative to its capacity.", 0.01},
+	{"ups.realpower", "real_power_watts", "Real power drawn from the UPS.", 1},
+	{"battery.charge", "battery_charge_ratio", "Charge level of the UPS battery.", 0.01},
+	{"battery.runtime", "battery_runtime_seconds", "Estimated remaining runtime of the UPS on battery.", 1},
+	{"battery.voltage", "battery_voltage_volts", "Voltage of the UPS battery.", 1},
+	{"input.voltage", "input_voltage_volts", "Input voltage of the UPS.", 1},
+	{"output.voltage", "output_voltage_volts", "Output voltage of the UPS.", 1},
+}
+
+// nutStatusFlags are the ups.status flags always exported, others are only
+// exported while set.
+var nutStatusFlags = []string{"OL", "OB", "LB", "HB", "RB", "CHRG", "DISCHRG", "BYPASS", "CAL", "OFF", "OVER", "TRIM", "BOOST", "FSD"}
+
+type nutCollector struct {
+	info      *prometheus.Desc
+	status    *prometheus.Desc
+	variables map[string]*prometheus.Desc
+	logger    log.Logger
+}
+
+func init() {
+	registerCollector("nut", defaultDisabled, NewNUTCollector)
+}
+
+// NewNUTCollector returns a new Collector exposing the state of the UPSes
+// managed by a Network UPS Tools upsd.
+func NewNUTCollector(logger log.Logger) (Collector, error) {
+	c := &nutCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, upsSubsystem, "info"),
+			"UPS managed by NUT, value is always 1.",
+			[]string{"ups", "description", "manufacturer", "model", "serial"}, nil,
+		),
+		status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, upsSubsystem, "status"),
+			"Whether the UPS status flag is set, e.g. OL for online or OB for on battery.",
+			[]string{"ups", "flag"}, nil,
+		),
+		variables: map[string]*prometheus.Desc{},
+		logger:    logger,
+	}
+	for _, v := range nutVariables {
+		c.variables[v.variable] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, upsSubsystem, v.name),
+			v.help, []string{"ups"}, nil,
+		)
+	}
+	return c, nil
+}
+
+func (c *nutCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := net.DialTimeout("tcp", *nutAddress, *nutTimeout)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to upsd: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(*nutTimeout)); err != nil {
+		return err
+	}
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	upses, err := nutList(rw, "UPS")
+	if err != nil {
+		return fmt.Errorf("couldn't list UPSes: %w", err)
+	}
+	for _, ups := range upses {
+		if len(ups) < 1 {
+			continue
+		}
+		name, description := ups[0], ""
+		if len(ups) > 1 {
+			description = ups[1]
+		}
+		vars, err := nutList(rw, "VAR", name)
+		if err != nil {
+			return fmt.Errorf("couldn't list variables of UPS %s: %w", name, err)
+		}
+		values := map[string]string{}
+		for _, v := range vars {
+			if len(v) == 3 {
+				values[v[1]] = v[2]
+			}
+		}
+		c.updateUPS(ch, name, description, values)
+	}
+
+	fmt.Fprintf(rw, "LOGOUT\n")
+	rw.Flush()
+	return nil
+}
+
+func (c *nutCollector) updateUPS(ch chan<- prometheus.Metric, name, description string, values map[string]string) {
+	manufacturer, model := values["device.mfr"], values["device.model"]
+	if manufacturer == "" {
+		manufacturer = values["ups.mfr"]
+	}
+	if model == "" {
+		model = values["ups.model"]
+	}
+	serial := values["device.serial"]
+	if serial == "" {
+		serial = values["ups.serial"]
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, name, description, manufacturer, model, serial)
+
+	for _, v := range nutVariables {
+		value, ok := values[v.variable]
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "invalid UPS variable value", "ups", name, "variable", v.variable, "value", value)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.variables[v.variable], prometheus.GaugeValue, f*v.scale, name)
+	}
+
+	status, ok := values["ups.status"]
+	if !ok {
+		return
+	}
+	set := map[string]bool{}
+	for _, flag := range strings.Fields(status) {
+		set[flag] = true
+	}
+	for _, flag := range nutStatusFlags {
+		value := 0.0
+		if set[flag] {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, value, name, flag)
+		delete(set, flag)
+	}
+	for flag := range set {
+		ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, 1, name, flag)
+	}
+}
+
+// nutList sends a LIST command to upsd and returns the fields of each line of
+// the response, without the leading item type. See
+// https://networkupstools.org/docs/developer-guide.chunked/ar01s09.html.
+func nutList(rw *bufio.ReadWriter, args ...string) ([][]string, error) {
+	query := strings.Join(args, " ")
+	if _, err := fmt.Fprintf(rw, "LIST %s\n", query); err != nil {
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	var items [][]string
+	begun := false
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "ERR "):
+			return nil, fmt.Errorf("upsd returned an error: %s", strings.TrimPrefix(line, "ERR "))
+		case line == "BEGIN LIST "+query:
+			begun = true
+		case line == "END LIST "+query:
+			return items, nil
+		case !begun:
+			return nil, fmt.Errorf("unexpected upsd response %q", line)
+		default:
+			fields, err := nutSplit(line)
+			if err != nil {
+				return nil, err
+			}
+			if len(fields) > 1 && fields[0] == args[0] {
+				items = append(items, fields[1:])
+			}
+		}
+	}
+}
+
+// nutSplit splits a line of the NUT protocol into its words, which are
+// separated by spaces and can be quoted with backslash escapes.
+func nutSplit(line string) ([]string, error) {
+	var (
+		fields  []string
+		field   strings.Builder
+		inField bool
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case r == ' ' && !quoted:
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted || escaped {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nonut
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNUTList(t *testing.T) {
+	response := `BEGIN LIST VAR rack1
+VAR rack1 battery.charge "100"
+VAR rack1 device.model "Smart-UPS 1500"
+VAR rack1 ups.status "OL CHRG"
+VAR rack1 ups.test.result "Done \"passed\""
+END LIST VAR rack1
+`
+	var query bytes.Buffer
+	rw := bufio.NewReadWriter(bufio.NewReader(strings.NewReader(response)), bufio.NewWriter(&query))
+
+	vars, err := nutList(rw, "VAR", "rack1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "LIST VAR rack1\n"; query.String() != want {
+		t.Errorf("want query %q, got %q", want, query.String())
+	}
+	want := [][]string{
+		{"rack1", "battery.charge", "100"},
+		{"rack1", "device.model", "Smart-UPS 1500"},
+		{"rack1", "ups.status", "OL CHRG"},
+		{"rack1", "ups.test.result", `Done "passed"`},
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("want %q, got %q", want, vars)
+	}
+}
+
+func TestNUTListError(t *testing.T) {
+	rw := bufio.NewReadWriter(bufio.NewReader(strings.NewReader("ERR UNKNOWN-UPS\n")), bufio.NewWriter(&bytes.Buffer{}))
+	if _, err := nutList(rw, "VAR", "missing"); err == nil || !strings.Contains(err.Error(), "UNKNOWN-UPS") {
+		t.Errorf("expected UNKNOWN-UPS error, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nonvmeof
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const nvmeofSubsystem = "nvmeof"
+
+// nvmeofStates are the controller states of the kernel, see
+// nvme_sysfs_show_state.
+var nvmeofStates = []string{"new", "live", "resetting", "connecting", "deleting", "deleting (no IO)", "dead"}
+
+type nvmeofCollector struct {
+	info           *prometheus.Desc
+	state          *prometheus.Desc
+	queues         *prometheus.Desc
+	queueSize      *prometheus.Desc
+	reconnectDelay *prometheus.Desc
+	lossTimeout    *prometheus.Desc
+	reconnects     *prometheus.Desc
+	logger         log.Logger
+
+	// The kernel doesn't count reconnects, they are counted by the changes
+	// to the connecting state between scrapes.
+	mtx             sync.Mutex
+	lastStates      map[string]string
+	reconnectCounts map[string]uint64
+}
+
+func init() {
+	registerCollector(nvmeofSubsystem, defaultDisabled, NewNVMeoFCollector)
+}
+
+// NewNVMeoFCollector returns a new Collector exposing the state of NVMe over
+// Fabrics host controllers.
+func NewNVMeoFCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, nvmeofSubsystem, name), help, append([]string{"controller"}, labels...), nil)
+	}
+	return &nvmeofCollector{
+		info:            desc("controller_info", "Non-numeric data of the NVMe over Fabrics controller, value is always 1.", "transport", "traddr", "trsvcid", "subsysnqn"),
+		state:           desc("controller_state", "State of the NVMe over Fabrics controller, the current state has a value of 1.", "state"),
+		queues:          desc("controller_queues", "Number of queues of the controller, including the admin queue."),
+		queueSize:       desc("controller_queue_size", "Number of entries of the I/O queues of the controller."),
+		reconnectDelay:  desc("controller_reconnect_delay_seconds", "Time between reconnect attempts of the controller."),
+		lossTimeout:     desc("controller_loss_timeout_seconds", "Time after which the controller is removed when reconnecting fails."),
+		reconnects:      desc("controller_reconnects_total", "Number of times the controller was seen reconnecting since the exporter started, reconnects between scrapes are missed."),
+		logger:          logger,
+		lastStates:      map[string]string{},
+		reconnectCounts: map[string]uint64{},
+	}, nil
+}
+
+func (c *nvmeofCollector) Update(ch chan<- prometheus.Metric) error {
+	controllers, err := filepath.Glob(sysFilePath("class/nvme-fabrics/ctl/nvme*"))
+	if err != nil {
+		return err
+	}
+	if len(controllers) == 0 {
+		level.Debug(c.logger).Log("msg", "no NVMe over Fabrics controllers found")
+		return ErrNoData
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	seen := map[string]bool{}
+	for _, dir := range controllers {
+		controller := filepath.Base(dir)
+		attrs := map[string]string{}
+		for _, name := range []string{"state", "transport", "address", "subsysnqn", "queue_count", "sqsize", "reconnect_delay", "ctrl_loss_tmo"} {
+			value, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("failed to read %s of NVMe controller %s: %w", name, controller, err)
+			}
+			attrs[name] = strings.TrimSpace(string(value))
+		}
+		seen[controller] = true
+
+		address := parseNVMeoFAddress(attrs["address"])
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, controller, attrs["transport"], address["traddr"], address["trsvcid"], attrs["subsysnqn"])
+
+		state := attrs["state"]
+		known := false
+		for _, s := range nvmeofStates {
+			value := 0.0
+			if s == state {
+				value, known = 1, true
+			}
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, value, controller, s)
+		}
+		if !known {
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, 1, controller, state)
+		}
+
+		if last, ok := c.lastStates[controller]; ok && last != "connecting" && state == "connecting" {
+			c.reconnectCounts[controller]++
+		}
+		c.lastStates[controller] = state
+		ch <- prometheus.MustNewConstMetric(c.reconnects, prometheus.CounterValue, float64(c.reconnectCounts[controller]), controller)
+
+		for name, m := range map[string]struct {
+			desc   *prometheus.Desc
+			offset float64
+		}{
+			"queue_count":     {c.queues, 0},
+			"sqsize":          {c.queueSize, 1}, // The queue size is zero based.
+			"reconnect_delay": {c.reconnectDelay, 0},
+			"ctrl_loss_tmo":   {c.lossTimeout, 0},
+		} {
+			// The timeouts are "off" if the controller reconnects forever.
+			value, err := strconv.ParseFloat(attrs[name], 64)
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, value+m.offset, controller)
+		}
+	}
+
+	// Forget removed controllers, the names are reused.
+	for controller := range c.lastStates {
+		if !seen[controller] {
+			delete(c.lastStates, controller)
+			delete(c.reconnectCounts, controller)
+		}
+	}
+	return nil
+}
+
+// parseNVMeoFAddress parses the address of a controller, like
+// "traddr=192.168.10.20,trsvcid=4420,src_addr=192.168.10.5".
+func parseNVMeoFAddress(address string) map[string]string {
+	fields := map[string]string{}
+	for _, field := range strings.Split(address, ",") {
+		if kv := strings.SplitN(field, "=", 2); len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	return fields
+}
@@ -0,0 +1,147 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nooverlayfs
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+)
+
+const overlayfsSubsystem = "overlayfs"
+
+type overlayfsCollector struct {
+	fs          procfs.FS
+	lowerLayers *prometheus.Desc
+	upperBytes  *prometheus.Desc
+	upperInodes *prometheus.Desc
+	logger      log.Logger
+}
+
+func init() {
+	registerCollector(overlayfsSubsystem, defaultDisabled, NewOverlayfsCollector)
+}
+
+// NewOverlayfsCollector returns a new Collector exposing the layers of overlay
+// mounts and the usage of their writable upper layer.
+func NewOverlayfsCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, overlayfsSubsystem, name), help, []string{"mountpoint"}, nil)
+	}
+	return &overlayfsCollector{
+		fs:          fs,
+		lowerLayers: desc("lower_layers", "Number of read-only lower layers of the overlay mount."),
+		upperBytes:  desc("upper_size_bytes", "Disk space used by the files of the writable upper layer of the overlay mount."),
+		upperInodes: desc("upper_inodes", "Number of inodes of the writable upper layer of the overlay mount, including whiteouts of deleted files."),
+		logger:      logger,
+	}, nil
+}
+
+func (c *overlayfsCollector) Update(ch chan<- prometheus.Metric) error {
+	p, err := c.fs.Proc(1)
+	if err != nil {
+		// Fall back to the mounts of the exporter if hidepid hides init.
+		if p, err = c.fs.Self(); err != nil {
+			return err
+		}
+	}
+	mounts, err := p.MountInfo()
+	if err != nil {
+		return fmt.Errorf("failed to read mounts: %w", err)
+	}
+
+	found := false
+	for _, m := range mounts {
+		if m.FSType != "overlay" {
+			continue
+		}
+		found = true
+		mountPoint := rootfsStripPrefix(m.MountPoint)
+		ch <- prometheus.MustNewConstMetric(c.lowerLayers, prometheus.GaugeValue, float64(overlayfsLowerLayers(m.SuperOptions["lowerdir"])), mountPoint)
+
+		// Overlays of only lower layers are read-only.
+		upper, ok := m.SuperOptions["upperdir"]
+		if !ok {
+			continue
+		}
+		bytes, inodes, err := overlayfsUsage(rootfsFilePath(upper))
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to get usage of upper layer", "mountpoint", mountPoint, "upperdir", upper, "err", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.upperBytes, prometheus.GaugeValue, float64(bytes), mountPoint)
+		ch <- prometheus.MustNewConstMetric(c.upperInodes, prometheus.GaugeValue, float64(inodes), mountPoint)
+	}
+	if !found {
+		level.Debug(c.logger).Log("msg", "no overlay mounts found")
+		return ErrNoData
+	}
+	return nil
+}
+
+// overlayfsLowerLayers returns the number of layers of the lowerdir option,
+// which separates them by a colon, or two for data-only layers.
+func overlayfsLowerLayers(lowerdir string) int {
+	layers := 0
+	for _, layer := range strings.Split(lowerdir, ":") {
+		if layer != "" {
+			layers++
+		}
+	}
+	return layers
+}
+
+// overlayfsUsage returns the disk space used and the number of inodes below a
+// directory, like du. Files of running containers come and go while walking
+// the directory, they are counted if they were seen.
+func overlayfsUsage(dir string) (uint64, uint64, error) {
+	var bytes, inodes uint64
+	seen := map[uint64]bool{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != dir {
+				return nil
+			}
+			return err
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("unsupported file info of %s", path)
+		}
+		// Hard links are counted once.
+		if stat.Nlink > 1 {
+			if seen[stat.Ino] {
+				return nil
+			}
+			seen[stat.Ino] = true
+		}
+		bytes += uint64(stat.Blocks) * 512
+		inodes++
+		return nil
+	})
+	return bytes, inodes, err
+}
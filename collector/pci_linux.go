@@ -0,0 +1,192 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nopci
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const pciSubsystem = "pci"
+
+// pciAERFiles maps the AER statistics files of a device to the severity
+// label of their counters.
+var pciAERFiles = map[string]string{
+	"aer_dev_correctable": "correctable",
+	"aer_dev_nonfatal":    "nonfatal",
+	"aer_dev_fatal":       "fatal",
+}
+
+type pciCollector struct {
+	info         *prometheus.Desc
+	linkSpeed    *prometheus.Desc
+	linkMaxSpeed *prometheus.Desc
+	linkWidth    *prometheus.Desc
+	linkMaxWidth *prometheus.Desc
+	aerErrors    *prometheus.Desc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector(pciSubsystem, defaultDisabled, NewPCICollector)
+}
+
+// NewPCICollector returns a new Collector exposing PCI devices, their link
+// status and AER error counters.
+func NewPCICollector(logger log.Logger) (Collector, error) {
+	return &pciCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "device_info"),
+			"PCI device present on the system, value is always 1.",
+			[]string{"slot", "vendor_id", "device_id", "subsystem_vendor_id", "subsystem_device_id", "class_id", "driver", "numa_node"}, nil,
+		),
+		linkSpeed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "link_transfers_per_second"),
+			"Current PCIe link speed of the device.",
+			[]string{"slot"}, nil,
+		),
+		linkMaxSpeed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "link_max_transfers_per_second"),
+			"Maximum PCIe link speed supported by the device.",
+			[]string{"slot"}, nil,
+		),
+		linkWidth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "link_width"),
+			"Current number of PCIe lanes of the device.",
+			[]string{"slot"}, nil,
+		),
+		linkMaxWidth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "link_max_width"),
+			"Maximum number of PCIe lanes supported by the device.",
+			[]string{"slot"}, nil,
+		),
+		aerErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pciSubsystem, "aer_errors_total"),
+			"Number of PCIe errors reported by Advanced Error Reporting.",
+			[]string{"slot", "severity", "error"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *pciCollector) Update(ch chan<- prometheus.Metric) error {
+	devicesPath := sysFilePath("bus/pci/devices")
+	devices, err := ioutil.ReadDir(devicesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "PCI devices not found, skipping")
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to list PCI devices: %w", err)
+	}
+
+	for _, device := range devices {
+		slot := device.Name()
+		path := filepath.Join(devicesPath, slot)
+
+		attr := func(name string) string {
+			value, err := ioutil.ReadFile(filepath.Join(path, name))
+			if err != nil {
+				return ""
+			}
+			return strings.TrimSpace(string(value))
+		}
+		driver := ""
+		if link, err := os.Readlink(filepath.Join(path, "driver")); err == nil {
+			driver = filepath.Base(link)
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, slot,
+			attr("vendor"), attr("device"), attr("subsystem_vendor"), attr("subsystem_device"), attr("class"), driver, attr("numa_node"))
+
+		// Link attributes are only present for PCIe devices, and report
+		// "Unknown" for integrated ones.
+		if speed, ok := parsePCILinkSpeed(attr("current_link_speed")); ok {
+			ch <- prometheus.MustNewConstMetric(c.linkSpeed, prometheus.GaugeValue, speed, slot)
+		}
+		if speed, ok := parsePCILinkSpeed(attr("max_link_speed")); ok {
+			ch <- prometheus.MustNewConstMetric(c.linkMaxSpeed, prometheus.GaugeValue, speed, slot)
+		}
+		if width, err := strconv.ParseFloat(attr("current_link_width"), 64); err == nil && width > 0 {
+			ch <- prometheus.MustNewConstMetric(c.linkWidth, prometheus.GaugeValue, width, slot)
+		}
+		if width, err := strconv.ParseFloat(attr("max_link_width"), 64); err == nil && width > 0 {
+			ch <- prometheus.MustNewConstMetric(c.linkMaxWidth, prometheus.GaugeValue, width, slot)
+		}
+
+		for file, severity := range pciAERFiles {
+			counters, err := parsePCIAERFile(filepath.Join(path, file))
+			if err != nil {
+				if !os.IsNotExist(err) {
+					level.Debug(c.logger).Log("msg", "failed to read AER statistics", "slot", slot, "file", file, "err", err)
+				}
+				continue
+			}
+			for name, value := range counters {
+				ch <- prometheus.MustNewConstMetric(c.aerErrors, prometheus.CounterValue, value, slot, severity, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parsePCILinkSpeed parses link speeds like "8.0 GT/s PCIe" into transfers
+// per second.
+func parsePCILinkSpeed(s string) (float64, bool) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 || fields[1] != "GT/s" {
+		return 0, false
+	}
+	speed, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return speed * 1e9, true
+}
+
+// parsePCIAERFile parses an AER statistics file of "<error> <count>" lines.
+// The TOTAL_ERR_* line is skipped, as it's the sum of the others.
+func parsePCIAERFile(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	counters := map[string]float64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "TOTAL_") {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in %s: %w", path, err)
+		}
+		counters[fields[0]] = value
+	}
+	return counters, scanner.Err()
+}
@@ -0,0 +1,306 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noperf
+
+package collector
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/hodgesds/perf-utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v2"
+)
+
+var perfEventNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// perfGenericEvents maps the names of the generic events, as used by perf
+// list, to their type and config.
+var perfGenericEvents = map[string]struct {
+	eventType uint32
+	config    uint64
+}{
+	"cpu-cycles":              {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_CPU_CYCLES},
+	"instructions":            {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_INSTRUCTIONS},
+	"cache-references":        {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_CACHE_REFERENCES},
+	"cache-misses":            {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_CACHE_MISSES},
+	"branch-instructions":     {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_BRANCH_INSTRUCTIONS},
+	"branch-misses":           {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_BRANCH_MISSES},
+	"bus-cycles":              {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_BUS_CYCLES},
+	"stalled-cycles-frontend": {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_STALLED_CYCLES_FRONTEND},
+	"stalled-cycles-backend":  {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_STALLED_CYCLES_BACKEND},
+	"ref-cycles":              {unix.PERF_TYPE_HARDWARE, unix.PERF_COUNT_HW_REF_CPU_CYCLES},
+	"cpu-clock":               {unix.PERF_TYPE_SOFTWARE, unix.PERF_COUNT_SW_CPU_CLOCK},
+	"task-clock":              {unix.PERF_TYPE_SOFTWARE, unix.PERF_COUNT_SW_TASK_CLOCK},
+	"page-faults":             {unix.PERF_TYPE_SOFTWARE, unix.PERF_COUNT_SW_PAGE_FAULTS},
+	"context-switches":        {unix.PERF_TYPE_SOFTWARE, unix.PERF_COUNT_SW_CONTEXT_SWITCHES},
+	"cpu-migrations":          {unix.PERF_TYPE_SOFTWARE, unix.PERF_COUNT_SW_CPU_MIGRATIONS},
+	"minor-faults":            {unix.PERF_TYPE_SOFTWARE, unix.PERF_COUNT_SW_PAGE_FAULTS_MIN},
+	"major-faults":            {unix.PERF_TYPE_SOFTWARE, unix.PERF_COUNT_SW_PAGE_FAULTS_MAJ},
+}
+
+// perfEventsConfig is the configuration file format of
+// --collector.perf.events-config, e.g.
+//
+//	groups:
+//	- name: cache
+//	  cgroups: [system.slice, user.slice]
+//	  events:
+//	  - name: llc_misses
+//	    event: cache-misses
+//	  - name: l2_request_misses
+//	    type: raw
+//	    config: 0x3f24
+type perfEventsConfig struct {
+	Groups []perfEventGroupConfig `yaml:"groups"`
+}
+
+// perfEventGroupConfig is a set of events that is scheduled onto the PMU
+// together, so their ratios are consistent even when multiplexed.
+type perfEventGroupConfig struct {
+	Name string `yaml:"name"`
+	// Cgroups are paths relative to the cgroup mount point, if set the events
+	// are counted per cgroup instead of for all processes.
+	Cgroups []string          `yaml:"cgroups"`
+	Events  []perfEventConfig `yaml:"events"`
+}
+
+// perfEventConfig is either a generic event by name or an event of a type,
+// i.e. hardware, software, hw_cache, raw or the name of a PMU in
+// /sys/bus/event_source/devices, with its config.
+type perfEventConfig struct {
+	Name    string `yaml:"name"`
+	Event   string `yaml:"event"`
+	Type    string `yaml:"type"`
+	Config  uint64 `yaml:"config"`
+	Config1 uint64 `yaml:"config1"`
+}
+
+// parsePerfEventsConfig parses and validates a perf events configuration.
+func parsePerfEventsConfig(r io.Reader) (*perfEventsConfig, error) {
+	var config perfEventsConfig
+	if err := yaml.NewDecoder(r).Decode(&config); err != nil {
+		return nil, err
+	}
+
+	groups := map[string]bool{}
+	events := map[string]bool{}
+	for _, group := range config.Groups {
+		if !perfEventNameRE.MatchString(group.Name) {
+			return nil, fmt.Errorf("invalid group name %q", group.Name)
+		}
+		if groups[group.Name] {
+			return nil, fmt.Errorf("duplicate group %q", group.Name)
+		}
+		groups[group.Name] = true
+		if len(group.Events) == 0 {
+			return nil, fmt.Errorf("group %q has no events", group.Name)
+		}
+		for _, event := range group.Events {
+			if !perfEventNameRE.MatchString(event.Name) {
+				return nil, fmt.Errorf("invalid event name %q in group %q", event.Name, group.Name)
+			}
+			// The event name is used as metric name, the labels of which
+			// depend on the group.
+			if events[event.Name] {
+				return nil, fmt.Errorf("duplicate event %q", event.Name)
+			}
+			events[event.Name] = true
+			if (event.Event == "") == (event.Type == "") {
+				return nil, fmt.Errorf("event %q needs either an event or a type", event.Name)
+			}
+			if _, ok := perfGenericEvents[event.Event]; event.Event != "" && !ok {
+				return nil, fmt.Errorf("unknown event %q of %q", event.Event, event.Name)
+			}
+		}
+	}
+	return &config, nil
+}
+
+// eventAttr returns the perf_event_attr of the event.
+func (e perfEventConfig) eventAttr() (unix.PerfEventAttr, error) {
+	attr := unix.PerfEventAttr{Config: e.Config, Ext1: e.Config1}
+	if e.Event != "" {
+		generic := perfGenericEvents[e.Event]
+		attr.Type, attr.Config = generic.eventType, generic.config
+		return attr, nil
+	}
+
+	switch e.Type {
+	case "hardware":
+		attr.Type = unix.PERF_TYPE_HARDWARE
+	case "software":
+		attr.Type = unix.PERF_TYPE_SOFTWARE
+	case "hw_cache":
+		attr.Type = unix.PERF_TYPE_HW_CACHE
+	case "raw":
+		attr.Type = unix.PERF_TYPE_RAW
+	default:
+		// Dynamic PMUs, e.g. uncore ones, have their type id in sysfs.
+		pmuType, err := readUintFromFile(sysFilePath(filepath.Join("bus/event_source/devices", e.Type, "type")))
+		if err != nil {
+			return attr, fmt.Errorf("unknown PMU %q: %w", e.Type, err)
+		}
+		attr.Type = uint32(pmuType)
+	}
+	return attr, nil
+}
+
+// perfEventGroupProfiler is a group profiler of a CPU and optionally cgroup.
+type perfEventGroupProfiler struct {
+	group    int
+	cpu      string
+	cgroup   string
+	profiler perf.GroupProfiler
+}
+
+// perfEventGroupCollector collects the event groups of the perf events
+// configuration file.
+type perfEventGroupCollector struct {
+	groups       []perfEventGroupConfig
+	descs        [][]*prometheus.Desc
+	runningRatio *prometheus.Desc
+	profilers    []perfEventGroupProfiler
+	logger       log.Logger
+}
+
+// newPerfEventGroupCollector returns a perfEventGroupCollector with started
+// profilers for the configured groups on the CPUs.
+func newPerfEventGroupCollector(logger log.Logger, configFile string, cpus []int) (*perfEventGroupCollector, error) {
+	f, err := os.Open(configFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	config, err := parsePerfEventsConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid perf events config %s: %w", configFile, err)
+	}
+
+	c := &perfEventGroupCollector{
+		groups: config.Groups,
+		descs:  make([][]*prometheus.Desc, len(config.Groups)),
+		runningRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, perfSubsystem, "group_running_ratio"),
+			"Ratio of the time the event group was counting while enabled, the event values are scaled by it if the PMU is multiplexed. The cgroup label is empty for groups of all processes.",
+			[]string{"group", "cpu", "cgroup"}, nil,
+		),
+		logger: logger,
+	}
+	for i, group := range config.Groups {
+		labels := []string{"cpu"}
+		if len(group.Cgroups) > 0 {
+			labels = append(labels, "cgroup")
+		}
+		eventAttrs := make([]unix.PerfEventAttr, len(group.Events))
+		c.descs[i] = make([]*prometheus.Desc, len(group.Events))
+		for j, event := range group.Events {
+			eventAttrs[j], err = event.eventAttr()
+			if err != nil {
+				return nil, err
+			}
+			help := "Perf event " + event.Event
+			if event.Event == "" {
+				help = fmt.Sprintf("Perf event %s with config %#x", event.Type, event.Config)
+			}
+			c.descs[i][j] = prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, perfSubsystem, event.Name+"_total"),
+				help+" of the "+group.Name+" group.",
+				labels, nil,
+			)
+		}
+
+		for _, cpu := range cpus {
+			if len(group.Cgroups) == 0 {
+				profiler, err := perf.NewGroupProfiler(-1, cpu, 0, eventAttrs...)
+				if err != nil {
+					return nil, fmt.Errorf("failed to open perf event group %s on CPU %d: %w", group.Name, cpu, err)
+				}
+				c.profilers = append(c.profilers, perfEventGroupProfiler{group: i, cpu: strconv.Itoa(cpu), profiler: profiler})
+				continue
+			}
+			for _, cgroup := range group.Cgroups {
+				profiler, err := newPerfCgroupProfiler(cgroup, cpu, eventAttrs)
+				if err != nil {
+					return nil, fmt.Errorf("failed to open perf event group %s for cgroup %s on CPU %d: %w", group.Name, cgroup, cpu, err)
+				}
+				c.profilers = append(c.profilers, perfEventGroupProfiler{group: i, cpu: strconv.Itoa(cpu), cgroup: strings.Trim(cgroup, "/"), profiler: profiler})
+			}
+		}
+	}
+
+	for _, p := range c.profilers {
+		if err := p.profiler.Start(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// newPerfCgroupProfiler returns a group profiler counting the events of the
+// tasks in the cgroup on the CPU.
+func newPerfCgroupProfiler(cgroup string, cpu int, eventAttrs []unix.PerfEventAttr) (perf.GroupProfiler, error) {
+	// The kernel holds a reference to the cgroup, the descriptor is only
+	// needed to open the events.
+	dir, err := os.Open(sysFilePath(filepath.Join("fs/cgroup", cgroup)))
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	return perf.NewGroupProfiler(int(dir.Fd()), cpu, unix.PERF_FLAG_PID_CGROUP, eventAttrs...)
+}
+
+// update collects all configured event groups.
+func (c *perfEventGroupCollector) update(ch chan<- prometheus.Metric) error {
+	for _, p := range c.profilers {
+		profile, err := p.profiler.Profile()
+		if err != nil {
+			level.Error(c.logger).Log("msg", "Failed to collect perf event group profile", "group", c.groups[p.group].Name, "err", err)
+			return err
+		}
+
+		// Groups that didn't fit onto the PMU are multiplexed, the values are
+		// estimated from the share of the time they were counting.
+		ratio := 1.0
+		if profile.TimeEnabled > 0 {
+			ratio = float64(profile.TimeRunning) / float64(profile.TimeEnabled)
+		}
+		ch <- prometheus.MustNewConstMetric(c.runningRatio, prometheus.GaugeValue, ratio, c.groups[p.group].Name, p.cpu, p.cgroup)
+		if ratio == 0 {
+			// The group was never scheduled, there is nothing to scale.
+			continue
+		}
+
+		labels := []string{p.cpu}
+		if p.cgroup != "" {
+			labels = append(labels, p.cgroup)
+		}
+		for i, value := range profile.Values {
+			if i >= len(c.descs[p.group]) {
+				break
+			}
+			ch <- prometheus.MustNewConstMetric(c.descs[p.group][i], prometheus.CounterValue, float64(value)/ratio, labels...)
+		}
+	}
+	return nil
+}
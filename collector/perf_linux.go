@@ -34,8 +34,9 @@ const (
 )
 
 var (
-	perfCPUsFlag       = kingpin.Flag("collector.perf.cpus", "List of CPUs from which perf metrics should be collected").Default("").String()
-	perfTracepointFlag = kingpin.Flag("collector.perf.tracepoint", "perf tracepoint that should be collected").Strings()
+	perfCPUsFlag         = kingpin.Flag("collector.perf.cpus", "List of CPUs from which perf metrics should be collected").Default("").String()
+	perfTracepointFlag   = kingpin.Flag("collector.perf.tracepoint", "perf tracepoint that should be collected").Strings()
+	perfEventsConfigFlag = kingpin.Flag("collector.perf.events-config", "Path to a file with perf event groups that are collected instead of the default events.").Default("").String()
 )
 
 func init() {
@@ -135,6 +136,7 @@ type perfCollector struct {
 	desc                map[string]*prometheus.Desc
 	logger              log.Logger
 	tracepointCollector *perfTracepointCollector
+	eventGroupCollector *perfEventGroupCollector
 }
 
 type perfTracepointCollector struct {
@@ -280,6 +282,16 @@ func NewPerfCollector(logger log.Logger) (Collector, error) {
 		collector.tracepointCollector = tracepointCollector
 	}
 
+	// Configured event groups replace the default profilers.
+	if *perfEventsConfigFlag != "" {
+		eventGroupCollector, err := newPerfEventGroupCollector(logger, *perfEventsConfigFlag, cpus)
+		if err != nil {
+			return nil, err
+		}
+		collector.eventGroupCollector = eventGroupCollector
+		cpus = nil
+	}
+
 	// Configure all profilers for the specified CPUs.
 	for _, cpu := range cpus {
 		// Use -1 to profile all processes on the CPU, see:
@@ -565,6 +577,11 @@ func (c *perfCollector) Update(ch chan<- prometheus.Metric) error {
 	if err := c.updateCacheStats(ch); err != nil {
 		return err
 	}
+	if c.eventGroupCollector != nil {
+		if err := c.eventGroupCollector.update(ch); err != nil {
+			return err
+		}
+	}
 	if c.tracepointCollector != nil {
 		return c.tracepointCollector.update(ch)
 	}
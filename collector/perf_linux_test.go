@@ -25,6 +25,7 @@ import (
 	"github.com/go-kit/log"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
 )
 
 func canTestPerf(t *testing.T) {
@@ -254,3 +255,89 @@ func TestPerfTracepointFlagToTracepoints(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePerfEventsConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errStr string
+	}{
+		{
+			name: "valid config",
+			config: `
+groups:
+- name: cache
+  cgroups: [system.slice]
+  events:
+  - name: llc_misses
+    event: cache-misses
+  - name: l2_request_misses
+    type: raw
+    config: 0x3f24
+`,
+		},
+		{
+			name: "duplicate event",
+			config: `
+groups:
+- name: a
+  events: [{name: misses, event: cache-misses}]
+- name: b
+  events: [{name: misses, event: branch-misses}]
+`,
+			errStr: `duplicate event "misses"`,
+		},
+		{
+			name: "unknown event",
+			config: `
+groups:
+- name: a
+  events: [{name: misses, event: llc-misses}]
+`,
+			errStr: `unknown event "llc-misses" of "misses"`,
+		},
+		{
+			name: "event and type",
+			config: `
+groups:
+- name: a
+  events: [{name: misses, event: cache-misses, type: raw}]
+`,
+			errStr: `event "misses" needs either an event or a type`,
+		},
+		{
+			name: "invalid group name",
+			config: `
+groups:
+- name: l2-cache
+  events: [{name: misses, event: cache-misses}]
+`,
+			errStr: `invalid group name "l2-cache"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := parsePerfEventsConfig(strings.NewReader(test.config))
+			if test.errStr != "" {
+				if err == nil {
+					t.Fatal("expected error to not be nil")
+				}
+				if test.errStr != err.Error() {
+					t.Fatalf("expected error %q, got %q", test.errStr, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			attr, err := config.Groups[0].Events[1].eventAttr()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attr.Type != unix.PERF_TYPE_RAW || attr.Config != 0x3f24 {
+				t.Fatalf("expected raw event 0x3f24, got type %d config %#x", attr.Type, attr.Config)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build freebsd openbsd
+// +build !nopf
+
+package collector
+
+import (
+	"errors"
+	"os"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+#include <sys/types.h>
+#include <sys/ioctl.h>
+#include <sys/socket.h>
+#include <net/if.h>
+#include <netinet/in.h>
+#include <net/pfvar.h>
+#include <string.h>
+
+typedef struct {
+	uint64_t	states;
+	uint64_t	statesLimit;
+	uint64_t	searches;
+	uint64_t	inserts;
+	uint64_t	removals;
+	int		running;
+} PFStatus;
+
+typedef struct {
+	char		label[PF_RULE_LABEL_SIZE];
+	uint64_t	evaluations;
+	uint64_t	packets[2];
+	uint64_t	bytes[2];
+} PFRule;
+
+static int _pf_status(int fd, PFStatus *s) {
+	struct pf_status status;
+	struct pfioc_limit limit;
+
+	memset(&status, 0, sizeof(status));
+	if (ioctl(fd, DIOCGETSTATUS, &status) == -1) {
+		return -1;
+	}
+	memset(&limit, 0, sizeof(limit));
+	limit.index = PF_LIMIT_STATES;
+	if (ioctl(fd, DIOCGETLIMIT, &limit) == -1) {
+		return -1;
+	}
+	s->states = status.states;
+	s->statesLimit = limit.limit;
+	s->searches = status.fcounters[FCNT_STATE_SEARCH];
+	s->inserts = status.fcounters[FCNT_STATE_INSERT];
+	s->removals = status.fcounters[FCNT_STATE_REMOVALS];
+	s->running = status.running;
+	return 0;
+}
+
+static int _pf_rules(int fd, uint32_t *nr, uint32_t *ticket) {
+	struct pfioc_rule pr;
+
+	memset(&pr, 0, sizeof(pr));
+	// FreeBSD selects the filter ruleset by the action.
+	pr.rule.action = PF_PASS;
+	if (ioctl(fd, DIOCGETRULES, &pr) == -1) {
+		return -1;
+	}
+	*nr = pr.nr;
+	*ticket = pr.ticket;
+	return 0;
+}
+
+static int _pf_rule(int fd, uint32_t nr, uint32_t ticket, PFRule *r) {
+	struct pfioc_rule pr;
+
+	memset(&pr, 0, sizeof(pr));
+	pr.rule.action = PF_PASS;
+	pr.nr = nr;
+	pr.ticket = ticket;
+	if (ioctl(fd, DIOCGETRULE, &pr) == -1) {
+		return -1;
+	}
+#ifdef PF_RULE_MAX_LABEL_COUNT
+	// Rules have several labels since FreeBSD 13, pfctl -s labels uses the first.
+	strlcpy(r->label, pr.rule.label[0], sizeof(r->label));
+#else
+	strlcpy(r->label, pr.rule.label, sizeof(r->label));
+#endif
+	r->evaluations = pr.rule.evaluations;
+	r->packets[0] = pr.rule.packets[0];
+	r->packets[1] = pr.rule.packets[1];
+	r->bytes[0] = pr.rule.bytes[0];
+	r->bytes[1] = pr.rule.bytes[1];
+	return 0;
+}
+*/
+import "C"
+
+const pfSubsystem = "pf"
+
+type pfCollector struct {
+	running         *prometheus.Desc
+	states          *prometheus.Desc
+	statesLimit     *prometheus.Desc
+	stateSearches   *prometheus.Desc
+	stateInserts    *prometheus.Desc
+	stateRemovals   *prometheus.Desc
+	ruleEvaluations *prometheus.Desc
+	rulePackets     *prometheus.Desc
+	ruleBytes       *prometheus.Desc
+	logger          log.Logger
+}
+
+// pfRuleCounters are the counters of the rules with the same label.
+type pfRuleCounters struct {
+	evaluations    float64
+	packets, bytes [2]float64
+}
+
+func init() {
+	registerCollector(pfSubsystem, defaultDisabled, NewPFCollector)
+}
+
+// NewPFCollector returns a new Collector exposing the state table and the
+// counters of labeled rules of the pf firewall, which requires read access
+// to /dev/pf.
+func NewPFCollector(logger log.Logger) (Collector, error) {
+	return &pfCollector{
+		running: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "running"),
+			"Whether pf is enabled.",
+			nil, nil,
+		),
+		states: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "states"),
+			"Number of entries in the state table.",
+			nil, nil,
+		),
+		statesLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "states_limit"),
+			"Maximum number of entries in the state table.",
+			nil, nil,
+		),
+		stateSearches: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "state_searches_total"),
+			"Total number of searches in the state table.",
+			nil, nil,
+		),
+		stateInserts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "state_inserts_total"),
+			"Total number of entries inserted into the state table.",
+			nil, nil,
+		),
+		stateRemovals: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "state_removals_total"),
+			"Total number of entries removed from the state table.",
+			nil, nil,
+		),
+		ruleEvaluations: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "rule_evaluations_total"),
+			"Total number of evaluations of the filter rules with the label.",
+			[]string{"label"}, nil,
+		),
+		rulePackets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "rule_packets_total"),
+			"Total number of packets matched by the filter rules with the label.",
+			[]string{"label", "direction"}, nil,
+		),
+		ruleBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pfSubsystem, "rule_bytes_total"),
+			"Total number of bytes matched by the filter rules with the label.",
+			[]string{"label", "direction"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *pfCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(rootfsFilePath("/dev/pf"))
+	if err != nil {
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			level.Debug(c.logger).Log("msg", "pf not available", "err", err)
+			return ErrNoData
+		}
+		return err
+	}
+	defer file.Close()
+	fd := C.int(file.Fd())
+
+	var status C.PFStatus
+	if C._pf_status(fd, &status) == -1 {
+		return errors.New("DIOCGETSTATUS failed")
+	}
+	ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(status.running))
+	ch <- prometheus.MustNewConstMetric(c.states, prometheus.GaugeValue, float64(status.states))
+	ch <- prometheus.MustNewConstMetric(c.statesLimit, prometheus.GaugeValue, float64(status.statesLimit))
+	ch <- prometheus.MustNewConstMetric(c.stateSearches, prometheus.CounterValue, float64(status.searches))
+	ch <- prometheus.MustNewConstMetric(c.stateInserts, prometheus.CounterValue, float64(status.inserts))
+	ch <- prometheus.MustNewConstMetric(c.stateRemovals, prometheus.CounterValue, float64(status.removals))
+
+	var nr, ticket C.uint32_t
+	if C._pf_rules(fd, &nr, &ticket) == -1 {
+		return errors.New("DIOCGETRULES failed")
+	}
+	labels := map[string]*pfRuleCounters{}
+	for i := C.uint32_t(0); i < nr; i++ {
+		var rule C.PFRule
+		if C._pf_rule(fd, i, ticket, &rule) == -1 {
+			// The ruleset was reloaded, the ticket is no longer valid.
+			return errors.New("DIOCGETRULE failed")
+		}
+		label := C.GoString(&rule.label[0])
+		if label == "" {
+			continue
+		}
+		counters, ok := labels[label]
+		if !ok {
+			counters = &pfRuleCounters{}
+			labels[label] = counters
+		}
+		counters.evaluations += float64(rule.evaluations)
+		for dir := 0; dir < 2; dir++ {
+			counters.packets[dir] += float64(rule.packets[dir])
+			counters.bytes[dir] += float64(rule.bytes[dir])
+		}
+	}
+	for label, counters := range labels {
+		ch <- prometheus.MustNewConstMetric(c.ruleEvaluations, prometheus.CounterValue, counters.evaluations, label)
+		for dir, direction := range []string{"in", "out"} {
+			ch <- prometheus.MustNewConstMetric(c.rulePackets, prometheus.CounterValue, counters.packets[dir], label, direction)
+			ch <- prometheus.MustNewConstMetric(c.ruleBytes, prometheus.CounterValue, counters.bytes[dir], label, direction)
+		}
+	}
+	return nil
+}
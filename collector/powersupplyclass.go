@@ -20,8 +20,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
@@ -134,6 +136,22 @@ func (c *powerSupplyClassCollector) Update(ch chan<- prometheus.Metric) error {
 			}
 		}
 
+		// Batteries report their capacity either as charge or as energy,
+		// the ratio of the two full values tracks wear either way.
+		for _, capacity := range [][2]*int64{
+			{powerSupply.ChargeFull, powerSupply.ChargeFullDesign},
+			{powerSupply.EnergyFull, powerSupply.EnergyFullDesign},
+		} {
+			if capacity[0] != nil && capacity[1] != nil && *capacity[1] > 0 {
+				pushPowerSupplyMetric(ch, c.subsystem, "full_design_ratio", float64(*capacity[0])/float64(*capacity[1]), powerSupply.Name, prometheus.GaugeValue)
+				break
+			}
+		}
+
+		if date, ok := readPowerSupplyManufactureDate(powerSupply.Name); ok {
+			pushPowerSupplyMetric(ch, c.subsystem, "manufacture_date_seconds", float64(date.Unix()), powerSupply.Name, prometheus.GaugeValue)
+		}
+
 		var (
 			keys   []string
 			values []string
@@ -182,6 +200,25 @@ func pushPowerSupplyMetric(ch chan<- prometheus.Metric, subsystem string, name s
 	ch <- prometheus.MustNewConstMetric(fieldDesc, valueType, value, powerSupplyName)
 }
 
+// readPowerSupplyManufactureDate reads the manufacture_{year,month,day}
+// attributes reported by some battery drivers.
+func readPowerSupplyManufactureDate(name string) (time.Time, bool) {
+	path := sysFilePath(filepath.Join("class/power_supply", name))
+	year, err := readUintFromFile(filepath.Join(path, "manufacture_year"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	month, err := readUintFromFile(filepath.Join(path, "manufacture_month"))
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	day, err := readUintFromFile(filepath.Join(path, "manufacture_day"))
+	if err != nil || day < 1 || day > 31 {
+		day = 1
+	}
+	return time.Date(int(year), time.Month(month), int(day), 0, 0, 0, 0, time.UTC), true
+}
+
 func getPowerSupplyClassInfo(ignore *regexp.Regexp) (sysfs.PowerSupplyClass, error) {
 	fs, err := sysfs.NewFS(*sysPath)
 	if err != nil {
@@ -16,19 +16,27 @@
 package collector
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
+	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 var (
 	psiResources = []string{"cpu", "io", "memory"}
+
+	pressureCgroupInclude = kingpin.Flag("collector.pressure.cgroup-include", "Regexp of cgroups, relative to the cgroup v2 root, to collect pressure stall information of.").String()
 )
 
 type pressureStatsCollector struct {
@@ -38,7 +46,13 @@ type pressureStatsCollector struct {
 	mem     *prometheus.Desc
 	memFull *prometheus.Desc
 
-	fs procfs.FS
+	cgroupWaiting      *prometheus.Desc
+	cgroupStalled      *prometheus.Desc
+	cgroupWaitingAvg10 *prometheus.Desc
+	cgroupStalledAvg10 *prometheus.Desc
+
+	fs            procfs.FS
+	cgroupInclude *regexp.Regexp
 
 	logger log.Logger
 }
@@ -54,6 +68,11 @@ func NewPressureStatsCollector(logger log.Logger) (Collector, error) {
 		return nil, fmt.Errorf("failed to open procfs: %w", err)
 	}
 
+	var cgroupInclude *regexp.Regexp
+	if *pressureCgroupInclude != "" {
+		cgroupInclude = regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *pressureCgroupInclude))
+	}
+
 	return &pressureStatsCollector{
 		cpu: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "pressure", "cpu_waiting_seconds_total"),
@@ -80,8 +99,29 @@ func NewPressureStatsCollector(logger log.Logger) (Collector, error) {
 			"Total time in seconds no process could make progress due to memory congestion",
 			nil, nil,
 		),
-		fs:     fs,
-		logger: logger,
+		cgroupWaiting: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pressure", "cgroup_waiting_seconds_total"),
+			"Total time in seconds that processes of the cgroup have waited for the resource",
+			[]string{"cgroup", "resource"}, nil,
+		),
+		cgroupStalled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pressure", "cgroup_stalled_seconds_total"),
+			"Total time in seconds no process of the cgroup could make progress due to resource congestion",
+			[]string{"cgroup", "resource"}, nil,
+		),
+		cgroupWaitingAvg10: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pressure", "cgroup_waiting_avg10_ratio"),
+			"Ratio of time in the last 10 seconds that processes of the cgroup have waited for the resource",
+			[]string{"cgroup", "resource"}, nil,
+		),
+		cgroupStalledAvg10: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pressure", "cgroup_stalled_avg10_ratio"),
+			"Ratio of time in the last 10 seconds no process of the cgroup could make progress due to resource congestion",
+			[]string{"cgroup", "resource"}, nil,
+		),
+		fs:            fs,
+		cgroupInclude: cgroupInclude,
+		logger:        logger,
 	}, nil
 }
 
@@ -115,5 +155,95 @@ func (c *pressureStatsCollector) Update(ch chan<- prometheus.Metric) error {
 		}
 	}
 
+	if c.cgroupInclude != nil {
+		return c.updateCgroups(ch)
+	}
 	return nil
 }
+
+// updateCgroups exposes the pressure stall information of the cgroups
+// matching the include pattern.
+func (c *pressureStatsCollector) updateCgroups(ch chan<- prometheus.Metric) error {
+	root := sysFilePath("fs/cgroup")
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Cgroups may be removed while walking the hierarchy.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() || path == root {
+			return nil
+		}
+		cgroup, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if !c.cgroupInclude.MatchString(cgroup) {
+			return nil
+		}
+		for _, res := range psiResources {
+			stats, err := parseCgroupPSIStats(filepath.Join(path, res+".pressure"))
+			if err != nil {
+				level.Debug(c.logger).Log("msg", "failed to read cgroup pressure stats", "cgroup", cgroup, "resource", res, "err", err)
+				continue
+			}
+			if stats.Some != nil {
+				ch <- prometheus.MustNewConstMetric(c.cgroupWaiting, prometheus.CounterValue, float64(stats.Some.Total)/1000.0/1000.0, cgroup, res)
+				ch <- prometheus.MustNewConstMetric(c.cgroupWaitingAvg10, prometheus.GaugeValue, stats.Some.Avg10/100, cgroup, res)
+			}
+			if stats.Full != nil {
+				ch <- prometheus.MustNewConstMetric(c.cgroupStalled, prometheus.CounterValue, float64(stats.Full.Total)/1000.0/1000.0, cgroup, res)
+				ch <- prometheus.MustNewConstMetric(c.cgroupStalledAvg10, prometheus.GaugeValue, stats.Full.Avg10/100, cgroup, res)
+			}
+		}
+		return nil
+	})
+}
+
+// parseCgroupPSIStats parses a cgroup <resource>.pressure file, which has the
+// same format as /proc/pressure/<resource>.
+func parseCgroupPSIStats(path string) (procfs.PSIStats, error) {
+	var stats procfs.PSIStats
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		line := &procfs.PSILine{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return stats, fmt.Errorf("invalid field %q", field)
+			}
+			switch kv[0] {
+			case "avg10":
+				line.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				line.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				line.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				line.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return stats, fmt.Errorf("invalid field %q: %w", field, err)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			stats.Some = line
+		case "full":
+			stats.Full = line
+		}
+	}
+	return stats, scanner.Err()
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noprocess_fds
+
+package collector
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const processFDsSubsystem = "process_fds"
+
+var (
+	processFDsGroupBy = kingpin.Flag("collector.process_fds.group-by", "Aggregate the file descriptors of processes by their name (comm) or cgroup.").Default("comm").Enum("comm", "cgroup")
+	processFDsTop     = kingpin.Flag("collector.process_fds.top", "Number of groups of processes to export, the groups closest to their limit are exported.").Default("10").Int()
+)
+
+type processFDsCollector struct {
+	fs         procfs.FS
+	open       *prometheus.Desc
+	processes  *prometheus.Desc
+	usageRatio *prometheus.Desc
+	logger     log.Logger
+}
+
+// processFDsGroup is the file descriptor usage of the processes of a group.
+type processFDsGroup struct {
+	name      string
+	open      int
+	processes int
+	maxRatio  float64
+}
+
+func init() {
+	registerCollector(processFDsSubsystem, defaultDisabled, NewProcessFDsCollector)
+}
+
+// NewProcessFDsCollector returns a new Collector exposing the open file
+// descriptors of groups of processes relative to their limits.
+func NewProcessFDsCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	labels := []string{*processFDsGroupBy}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, processFDsSubsystem, name), help, labels, nil)
+	}
+	return &processFDsCollector{
+		fs:         fs,
+		open:       desc("open", "Number of open file descriptors of the processes of the group."),
+		processes:  desc("processes", "Number of processes of the group."),
+		usageRatio: desc("max_usage_ratio", "Highest ratio of open file descriptors to the soft limit of a process of the group."),
+		logger:     logger,
+	}, nil
+}
+
+func (c *processFDsCollector) Update(ch chan<- prometheus.Metric) error {
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return fmt.Errorf("unable to list all processes: %w", err)
+	}
+
+	groups := map[string]*processFDsGroup{}
+	for _, p := range procs {
+		// Processes can vanish while reading them and the file descriptors of
+		// other users are only readable with CAP_SYS_PTRACE.
+		name, err := c.groupName(p)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to get group of process", "pid", p.PID, "err", err)
+			continue
+		}
+		open, err := p.FileDescriptorsLen()
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to count file descriptors of process", "pid", p.PID, "err", err)
+			continue
+		}
+		limits, err := p.Limits()
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to get limits of process", "pid", p.PID, "err", err)
+			continue
+		}
+
+		g, ok := groups[name]
+		if !ok {
+			g = &processFDsGroup{name: name}
+			groups[name] = g
+		}
+		g.open += open
+		g.processes++
+		if limits.OpenFiles > 0 && limits.OpenFiles != math.MaxUint64 {
+			if ratio := float64(open) / float64(limits.OpenFiles); ratio > g.maxRatio {
+				g.maxRatio = ratio
+			}
+		}
+	}
+
+	for _, g := range topProcessFDsGroups(groups, *processFDsTop) {
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(g.open), g.name)
+		ch <- prometheus.MustNewConstMetric(c.processes, prometheus.GaugeValue, float64(g.processes), g.name)
+		ch <- prometheus.MustNewConstMetric(c.usageRatio, prometheus.GaugeValue, g.maxRatio, g.name)
+	}
+	return nil
+}
+
+// groupName returns the name or the cgroup of a process, the cgroup of the
+// unified hierarchy is preferred over the one of systemd.
+func (c *processFDsCollector) groupName(p procfs.Proc) (string, error) {
+	if *processFDsGroupBy == "comm" {
+		return p.Comm()
+	}
+	cgroups, err := p.Cgroups()
+	if err != nil {
+		return "", err
+	}
+	name := ""
+	for _, cg := range cgroups {
+		if cg.HierarchyID == 0 {
+			return cg.Path, nil
+		}
+		for _, controller := range cg.Controllers {
+			if controller == "name=systemd" || name == "" {
+				name = cg.Path
+			}
+		}
+	}
+	return name, nil
+}
+
+// topProcessFDsGroups returns up to n groups which are closest to their limit,
+// ties are broken by the number of open file descriptors.
+func topProcessFDsGroups(groups map[string]*processFDsGroup, n int) []*processFDsGroup {
+	sorted := make([]*processFDsGroup, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].maxRatio != sorted[j].maxRatio {
+			return sorted[i].maxRatio > sorted[j].maxRatio
+		}
+		if sorted[i].open != sorted[j].open {
+			return sorted[i].open > sorted[j].open
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	if n >= 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
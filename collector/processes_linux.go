@@ -16,26 +16,53 @@
 package collector
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/mdlayher/genetlink"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
+	"golang.org/x/sys/unix"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	processesThreadStates = kingpin.Flag("collector.processes.thread-states", "Export the number of threads in each state, reads the stat of every thread.").Default("false").Bool()
+	processesByUser       = kingpin.Flag("collector.processes.by-user", "Export the number of processes in each state by user, reads the status of every process.").Default("false").Bool()
+	processesTaskstats    = kingpin.Flag("collector.processes.taskstats", "Count the thread states of --collector.processes.thread-states with the cgroupstats netlink interface instead of reading the stat of every thread. Requires a cgroup v1 hierarchy, without one the stats are read. Zombie threads are counted as zombie processes, idle threads are not counted and since Linux 6.1 neither are freezable sleeping threads.").Default("false").Bool()
 )
 
 type processCollector struct {
-	fs          procfs.FS
-	threadAlloc *prometheus.Desc
-	threadLimit *prometheus.Desc
-	procsState  *prometheus.Desc
-	pidUsed     *prometheus.Desc
-	pidMax      *prometheus.Desc
-	logger      log.Logger
+	fs           procfs.FS
+	threadAlloc  *prometheus.Desc
+	threadLimit  *prometheus.Desc
+	procsState   *prometheus.Desc
+	threadsState *prometheus.Desc
+	userState    *prometheus.Desc
+	pidUsed      *prometheus.Desc
+	pidMax       *prometheus.Desc
+	logger       log.Logger
+
+	taskstatsFallback sync.Once
+}
+
+// processStats are the states of the processes and threads of the system.
+type processStats struct {
+	pids, threads int
+	states        map[string]int32
+	threadStates  map[string]int32
+	userStates    map[string]map[string]int32
 }
 
 func init() {
@@ -66,6 +93,16 @@ func NewProcessStatCollector(logger log.Logger) (Collector, error) {
 			"Number of processes in each state.",
 			[]string{"state"}, nil,
 		),
+		threadsState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "threads_state"),
+			"Number of threads in each state.",
+			[]string{"thread_state"}, nil,
+		),
+		userState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "user_state"),
+			"Number of processes of a user in each state.",
+			[]string{"user", "state"}, nil,
+		),
 		pidUsed: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "pids"),
 			"Number of PIDs", nil, nil,
 		),
@@ -76,54 +113,350 @@ func NewProcessStatCollector(logger log.Logger) (Collector, error) {
 	}, nil
 }
 func (c *processCollector) Update(ch chan<- prometheus.Metric) error {
-	pids, states, threads, err := c.getAllocatedThreads()
+	stats, err := c.getAllocatedThreads()
 	if err != nil {
 		return fmt.Errorf("unable to retrieve number of allocated threads: %w", err)
 	}
 
-	ch <- prometheus.MustNewConstMetric(c.threadAlloc, prometheus.GaugeValue, float64(threads))
+	ch <- prometheus.MustNewConstMetric(c.threadAlloc, prometheus.GaugeValue, float64(stats.threads))
 	maxThreads, err := readUintFromFile(procFilePath("sys/kernel/threads-max"))
 	if err != nil {
 		return fmt.Errorf("unable to retrieve limit number of threads: %w", err)
 	}
 	ch <- prometheus.MustNewConstMetric(c.threadLimit, prometheus.GaugeValue, float64(maxThreads))
 
-	for state := range states {
-		ch <- prometheus.MustNewConstMetric(c.procsState, prometheus.GaugeValue, float64(states[state]), state)
+	for state := range stats.states {
+		ch <- prometheus.MustNewConstMetric(c.procsState, prometheus.GaugeValue, float64(stats.states[state]), state)
+	}
+	for state := range stats.threadStates {
+		ch <- prometheus.MustNewConstMetric(c.threadsState, prometheus.GaugeValue, float64(stats.threadStates[state]), state)
+	}
+	for user, states := range stats.userStates {
+		for state := range states {
+			ch <- prometheus.MustNewConstMetric(c.userState, prometheus.GaugeValue, float64(states[state]), user, state)
+		}
 	}
 
 	pidM, err := readUintFromFile(procFilePath("sys/kernel/pid_max"))
 	if err != nil {
 		return fmt.Errorf("unable to retrieve limit number of maximum pids alloved: %w", err)
 	}
-	ch <- prometheus.MustNewConstMetric(c.pidUsed, prometheus.GaugeValue, float64(pids))
+	ch <- prometheus.MustNewConstMetric(c.pidUsed, prometheus.GaugeValue, float64(stats.pids))
 	ch <- prometheus.MustNewConstMetric(c.pidMax, prometheus.GaugeValue, float64(pidM))
 
 	return nil
 }
 
-func (c *processCollector) getAllocatedThreads() (int, map[string]int32, int, error) {
+func (c *processCollector) getAllocatedThreads() (processStats, error) {
 	p, err := c.fs.AllProcs()
 	if err != nil {
-		return 0, nil, 0, fmt.Errorf("unable to list all processes: %w", err)
+		return processStats{}, fmt.Errorf("unable to list all processes: %w", err)
+	}
+	// Processes in uninterruptible sleep, usually waiting for storage, and
+	// zombies are always exported to alert on.
+	stats := processStats{
+		states: map[string]int32{"D": 0, "Z": 0},
+	}
+	// The stat of every thread is only read if their states are not counted
+	// by cgroupstats.
+	var readThreadStates bool
+	if *processesThreadStates {
+		if *processesTaskstats {
+			if stats.threadStates, err = taskstatsThreadStates(); err != nil {
+				c.taskstatsFallback.Do(func() {
+					level.Info(c.logger).Log("msg", "failed to get thread states via cgroupstats, reading the stat of every thread", "err", err)
+				})
+			}
+		}
+		if stats.threadStates == nil {
+			stats.threadStates = map[string]int32{"D": 0, "Z": 0}
+			readThreadStates = true
+		}
+	}
+	var users map[string]string
+	if *processesByUser {
+		stats.userStates = map[string]map[string]int32{}
+		if users, err = readPasswdUsers(rootfsFilePath("etc/passwd")); err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read user names, using uids", "err", err)
+		}
 	}
-	pids := 0
-	thread := 0
-	procStates := make(map[string]int32)
 	for _, pid := range p {
 		stat, err := pid.Stat()
 		if err != nil {
 			// PIDs can vanish between getting the list and getting stats.
-			if errors.Is(err, os.ErrNotExist) || strings.Contains(err.Error(), syscall.ESRCH.Error()) {
+			if isVanishedProcessError(err) {
 				level.Debug(c.logger).Log("msg", "file not found when retrieving stats for pid", "pid", pid, "err", err)
 				continue
 			}
 			level.Debug(c.logger).Log("msg", "error reading stat for pid", "pid", pid.PID, "err", err)
-			return 0, nil, 0, fmt.Errorf("error reading stat for pid %d: %w", pid.PID, err)
+			return processStats{}, fmt.Errorf("error reading stat for pid %d: %w", pid.PID, err)
+		}
+		stats.pids++
+		stats.states[stat.State]++
+		stats.threads += stat.NumThreads
+		if readThreadStates {
+			if err := c.getThreadStates(pid.PID, stat, stats.threadStates); err != nil {
+				return processStats{}, err
+			}
+		}
+
+		if stats.userStates == nil {
+			continue
+		}
+		status, err := pid.NewStatus()
+		if err != nil {
+			if isVanishedProcessError(err) {
+				continue
+			}
+			return processStats{}, fmt.Errorf("error reading status for pid %d: %w", pid.PID, err)
+		}
+		name, ok := users[status.UIDs[0]]
+		if !ok {
+			name = status.UIDs[0]
+		}
+		if stats.userStates[name] == nil {
+			stats.userStates[name] = map[string]int32{}
+		}
+		stats.userStates[name][stat.State]++
+	}
+	if stats.threadStates != nil && !readThreadStates {
+		// cgroupstats does not count zombies, of which only the main
+		// thread is left.
+		stats.threadStates["Z"] = stats.states["Z"]
+	}
+	return stats, nil
+}
+
+// taskstatsThreadStates counts the states of all threads with cgroupstats
+// instead of reading the stat of every thread.
+func taskstatsThreadStates() (map[string]int32, error) {
+	root, err := cgroupV1Root()
+	if err != nil {
+		return nil, err
+	}
+	return cgroupThreadStates(root)
+}
+
+// getThreadStates counts the states of the threads of a process, the state of
+// the main thread is the one of the process.
+func (c *processCollector) getThreadStates(pid int, pidStat procfs.ProcStat, threadStates map[string]int32) error {
+	fs, err := procfs.NewFS(procFilePath(filepath.Join(strconv.Itoa(pid), "task")))
+	if err != nil {
+		if isVanishedProcessError(err) {
+			level.Debug(c.logger).Log("msg", "file not found when retrieving tasks for pid", "pid", pid, "err", err)
+			return nil
+		}
+		return fmt.Errorf("error reading tasks of pid %d: %w", pid, err)
+	}
+	threads, err := fs.AllProcs()
+	if err != nil {
+		if isVanishedProcessError(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to list all threads of pid %d: %w", pid, err)
+	}
+	for _, thread := range threads {
+		if thread.PID == pid {
+			threadStates[pidStat.State]++
+			continue
+		}
+		threadStat, err := thread.Stat()
+		if err != nil {
+			if isVanishedProcessError(err) {
+				continue
+			}
+			return fmt.Errorf("error reading stat for pid %d thread %d: %w", pid, thread.PID, err)
+		}
+		threadStates[threadStat.State]++
+	}
+	return nil
+}
+
+// cgroupStats is struct cgroupstats of linux/cgroupstats.h.
+type cgroupStats struct {
+	sleeping, running, stopped, uninterruptible, ioWait uint64
+}
+
+// cgroupThreadStates counts the states of all threads by summing the
+// cgroupstats of every cgroup of a v1 hierarchy, as the kernel only counts
+// the tasks of the cgroup itself and not those of its children. Each thread
+// is in exactly one cgroup of the hierarchy. The kernel does not count
+// zombies, and idle threads only if they wait for I/O, which are counted as
+// uninterruptible. Since Linux 6.1 threads sleeping freezable, like in futex
+// waits, are not counted as sleeping either.
+func cgroupThreadStates(root string) (map[string]int32, error) {
+	conn, err := genetlink.Dial(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	family, err := conn.GetFamily(unix.TASKSTATS_GENL_NAME)
+	if err != nil {
+		return nil, err
+	}
+	return sumCgroupStats(root, func(dir *sysfsDir) (cgroupStats, error) {
+		return netlinkCgroupStats(conn, family, dir.fd)
+	})
+}
+
+// sumCgroupStats sums the stats of the cgroup at root and all cgroups below
+// it, cgroups removed while walking are skipped. Only the subdirectories of
+// each cgroup are walked, by their getdents(2) entries and opened relative to
+// their parent, its control files are not stat'ed.
+func sumCgroupStats(root string, get func(dir *sysfsDir) (cgroupStats, error)) (map[string]int32, error) {
+	dir, err := openSysfsDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var sum cgroupStats
+	if err := walkCgroupStats(dir, get, &sum); err != nil {
+		return nil, err
+	}
+	return map[string]int32{
+		"S": int32(sum.sleeping),
+		"R": int32(sum.running),
+		"T": int32(sum.stopped),
+		"D": int32(sum.uninterruptible + sum.ioWait),
+	}, nil
+}
+
+// walkCgroupStats adds the stats of the cgroup of dir and its children to sum
+// and closes dir.
+func walkCgroupStats(dir *sysfsDir, get func(dir *sysfsDir) (cgroupStats, error), sum *cgroupStats) error {
+	defer dir.Close()
+	stats, err := get(dir)
+	if err != nil {
+		return fmt.Errorf("cgroupstats of %s: %w", dir.path, err)
+	}
+	sum.sleeping += stats.sleeping
+	sum.running += stats.running
+	sum.stopped += stats.stopped
+	sum.uninterruptible += stats.uninterruptible
+	sum.ioWait += stats.ioWait
+
+	children, err := dir.subdirs()
+	if err != nil {
+		return err
+	}
+	for _, name := range children {
+		child, err := dir.openDir(name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if err := walkCgroupStats(child, get, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// netlinkCgroupStats sends CGROUPSTATS_CMD_GET of the taskstats genetlink
+// family for the cgroup directory.
+func netlinkCgroupStats(conn *genetlink.Conn, family genetlink.Family, fd int) (cgroupStats, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.CGROUPSTATS_CMD_ATTR_FD, uint32(fd))
+	data, err := ae.Encode()
+	if err != nil {
+		return cgroupStats{}, err
+	}
+	msgs, err := conn.Execute(genetlink.Message{
+		Header: genetlink.Header{
+			Command: unix.CGROUPSTATS_CMD_GET,
+			Version: family.Version,
+		},
+		Data: data,
+	}, family.ID, netlink.Request)
+	if err != nil {
+		return cgroupStats{}, err
+	}
+
+	for _, msg := range msgs {
+		ad, err := netlink.NewAttributeDecoder(msg.Data)
+		if err != nil {
+			return cgroupStats{}, err
+		}
+		for ad.Next() {
+			if ad.Type() == unix.CGROUPSTATS_TYPE_CGROUP_STATS {
+				return parseCgroupStats(ad.Bytes())
+			}
+		}
+		if err := ad.Err(); err != nil {
+			return cgroupStats{}, err
+		}
+	}
+	return cgroupStats{}, errors.New("no cgroupstats in netlink response")
+}
+
+// parseCgroupStats parses the fields nr_sleeping, nr_running, nr_stopped,
+// nr_uninterruptible and nr_io_wait in native byte order.
+func parseCgroupStats(b []byte) (cgroupStats, error) {
+	if len(b) < 40 {
+		return cgroupStats{}, fmt.Errorf("invalid size %d of cgroupstats", len(b))
+	}
+	field := func(i int) uint64 {
+		return nlenc.Uint64(b[8*i : 8*(i+1)])
+	}
+	return cgroupStats{
+		sleeping:        field(0),
+		running:         field(1),
+		stopped:         field(2),
+		uninterruptible: field(3),
+		ioWait:          field(4),
+	}, nil
+}
+
+// cgroupV1Root returns the mount point of the first cgroup v1 hierarchy.
+func cgroupV1Root() (string, error) {
+	file, err := os.Open(procFilePath("mounts"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) > 2 && parts[2] == "cgroup" {
+			return rootfsFilePath(parts[1]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("no cgroup v1 hierarchy mounted")
+}
+
+// isVanishedProcessError returns whether the error is caused by a process or
+// thread which exited while reading it.
+func isVanishedProcessError(err error) bool {
+	return errors.Is(err, os.ErrNotExist) || strings.Contains(err.Error(), syscall.ESRCH.Error())
+}
+
+// readPasswdUsers returns the user names of the uids of a passwd file. The
+// file of the root filesystem is read instead of looking the users up with
+// os/user, which would read the passwd of the container of node_exporter.
+func readPasswdUsers(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	users := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// name:password:uid:gid:gecos:home:shell
+		parts := strings.Split(scanner.Text(), ":")
+		if len(parts) < 3 || strings.HasPrefix(parts[0], "#") {
+			continue
+		}
+		// The first entry of a uid is the one of getpwuid(3).
+		if _, ok := users[parts[2]]; !ok {
+			users[parts[2]] = parts[0]
 		}
-		pids++
-		procStates[stat.State]++
-		thread += stat.NumThreads
 	}
-	return pids, procStates, thread, nil
+	return users, scanner.Err()
 }
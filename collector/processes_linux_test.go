@@ -16,9 +16,14 @@
 package collector
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/go-kit/log"
+	"github.com/mdlayher/netlink/nlenc"
 	"github.com/prometheus/procfs"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
@@ -33,7 +38,8 @@ func TestReadProcessStatus(t *testing.T) {
 		t.Errorf("failed to open procfs: %v", err)
 	}
 	c := processCollector{fs: fs, logger: log.NewNopLogger()}
-	pids, states, threads, err := c.getAllocatedThreads()
+	stats, err := c.getAllocatedThreads()
+	pids, states, threads := stats.pids, stats.states, stats.threads
 	if err != nil {
 		t.Fatalf("Cannot retrieve data from procfs getAllocatedThreads function: %v ", err)
 	}
@@ -52,3 +58,73 @@ func TestReadProcessStatus(t *testing.T) {
 		t.Fatalf("Total running pids cannot be greater than %d or equals to 0", maxPid)
 	}
 }
+
+func TestSumCgroupStats(t *testing.T) {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, dir := range []string{"system.slice/sshd.service", "system.slice/cron.service", "user.slice"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A file like cgroup.procs is not a cgroup.
+	if err := ioutil.WriteFile(filepath.Join(root, "cgroup.procs"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The root holds only kernel threads, the tasks are in the leaves.
+	cgroups := map[string]cgroupStats{
+		root:                                {sleeping: 2},
+		filepath.Join(root, "system.slice"): {},
+		filepath.Join(root, "system.slice/sshd.service"): {sleeping: 3, running: 1},
+		filepath.Join(root, "system.slice/cron.service"): {sleeping: 1, uninterruptible: 1, ioWait: 2},
+		filepath.Join(root, "user.slice"):                {running: 2, stopped: 1},
+	}
+	visited := map[string]bool{}
+	states, err := sumCgroupStats(root, func(dir *sysfsDir) (cgroupStats, error) {
+		visited[dir.path] = true
+		return cgroups[dir.path], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visited) != len(cgroups) {
+		t.Errorf("expected %d cgroups to be visited, got %v", len(cgroups), visited)
+	}
+	want := map[string]int32{"S": 6, "R": 3, "T": 1, "D": 3}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("expected %v, got %v", want, states)
+	}
+}
+
+func TestParseCgroupStats(t *testing.T) {
+	b := make([]byte, 40)
+	for i, v := range []uint64{10, 2, 1, 3, 4} {
+		nlenc.PutUint64(b[8*i:8*(i+1)], v)
+	}
+	stats, err := parseCgroupStats(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cgroupStats{sleeping: 10, running: 2, stopped: 1, uninterruptible: 3, ioWait: 4}
+	if stats != want {
+		t.Errorf("expected %+v, got %+v", want, stats)
+	}
+	if _, err := parseCgroupStats(b[:32]); err == nil {
+		t.Error("expected error for truncated cgroupstats")
+	}
+}
+
+func TestReadPasswdUsers(t *testing.T) {
+	users, err := readPasswdUsers("fixtures/etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"0": "root", "1": "daemon", "998": "node_exporter"}
+	if !reflect.DeepEqual(users, want) {
+		t.Errorf("expected %v, got %v", want, users)
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noptp
+
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const ptpSubsystem = "ptp"
+
+var (
+	ptpDevPath      = kingpin.Flag("collector.ptp.dev-path", "Directory containing the PTP hardware clock devices.").Default("/dev").String()
+	ptp4lSocket     = kingpin.Flag("collector.ptp.ptp4l-socket", "Path of the ptp4l management socket. Leave empty to disable querying ptp4l.").Default("").String()
+	ptp4lDomain     = kingpin.Flag("collector.ptp.ptp4l-domain", "PTP domain number ptp4l is configured for.").Default("0").Uint8()
+	ptp4lTimeout    = kingpin.Flag("collector.ptp.ptp4l-timeout", "Timeout for responses from ptp4l.").Default("500ms").Duration()
+	ptpPortStates   = []string{"initializing", "faulty", "disabled", "listening", "pre_master", "master", "passive", "uncalibrated", "slave"}
+	errPTPMgmtError = errors.New("ptp4l returned a management error")
+
+	ptp4lClientID uint64
+)
+
+// PTP management IDs, see IEEE 1588-2008 section 15.5.2.3 and linuxptp's tlv.h.
+const (
+	ptpMgmtDefaultDataSet = 0x2000
+	ptpMgmtCurrentDataSet = 0x2001
+	ptpMgmtPortDataSet    = 0x2004
+	ptpMgmtTimeStatusNP   = 0xc000
+)
+
+type ptpCollector struct {
+	clockInfo     *prometheus.Desc
+	clockOffset   *prometheus.Desc
+	masterOffset  *prometheus.Desc
+	gmPresent     *prometheus.Desc
+	stepsRemoved  *prometheus.Desc
+	meanPathDelay *prometheus.Desc
+	portState     *prometheus.Desc
+	ptp4lUp       *prometheus.Desc
+	logger        log.Logger
+}
+
+func init() {
+	registerCollector(ptpSubsystem, defaultDisabled, NewPTPCollector)
+}
+
+// NewPTPCollector returns a new Collector exposing PTP hardware clock offsets
+// and ptp4l synchronization state.
+func NewPTPCollector(logger log.Logger) (Collector, error) {
+	return &ptpCollector{
+		clockInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ptpSubsystem, "clock_info"),
+			"PTP hardware clock information from /sys/class/ptp, value is always 1.",
+			[]string{"clock", "name"}, nil,
+		),
+		clockOffset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ptpSubsystem, "clock_offset_seconds"),
+			"Offset of the PTP hardware clock from the system clock (CLOCK_REALTIME). PTP clocks usually run on TAI.",
+			[]string{"clock"}, nil,
+		),
+		masterOffset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ptpSubsystem, "ptp4l_master_offset_seconds"),
+			"Offset from the master clock as measured by ptp4l.",
+			nil, nil,
+		),
+		gmPresent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ptpSubsystem, "ptp4l_grandmaster_present"),
+			"Whether ptp4l is synchronized to a grandmaster clock.",
+			nil, nil,
+		),
+		stepsRemoved: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ptpSubsystem, "ptp4l_steps_removed"),
+			"Number of communication paths between the local clock and the grandmaster.",
+			nil, nil,
+		),
+		meanPathDelay: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ptpSubsystem, "ptp4l_mean_path_delay_seconds"),
+			"Mean propagation delay between the local clock and the master.",
+			nil, nil,
+		),
+		portState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ptpSubsystem, "ptp4l_port_state"),
+			"State of the ptp4l port.",
+			[]string{"port", "state"}, nil,
+		),
+		ptp4lUp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ptpSubsystem, "ptp4l_up"),
+			"Whether ptp4l answered management requests.",
+			nil, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *ptpCollector) Update(ch chan<- prometheus.Metric) error {
+	clocks, err := filepath.Glob(sysFilePath("class/ptp/ptp[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(clocks) == 0 && *ptp4lSocket == "" {
+		return ErrNoData
+	}
+
+	for _, clockPath := range clocks {
+		clock := filepath.Base(clockPath)
+		name, err := ioutil.ReadFile(filepath.Join(clockPath, "clock_name"))
+		if err != nil {
+			return fmt.Errorf("failed to read name of PTP clock %s: %w", clock, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.clockInfo, prometheus.GaugeValue, 1, clock, strings.TrimSpace(string(name)))
+
+		offset, err := phcOffset(filepath.Join(*ptpDevPath, clock))
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read PTP clock", "clock", clock, "err", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.clockOffset, prometheus.GaugeValue, offset.Seconds(), clock)
+	}
+
+	if *ptp4lSocket != "" {
+		up := 1.0
+		if err := c.updatePtp4l(ch); err != nil {
+			level.Debug(c.logger).Log("msg", "failed to query ptp4l", "socket", *ptp4lSocket, "err", err)
+			up = 0
+		}
+		ch <- prometheus.MustNewConstMetric(c.ptp4lUp, prometheus.GaugeValue, up)
+	}
+	return nil
+}
+
+// phcOffset returns the offset of a PTP hardware clock from CLOCK_REALTIME,
+// taking the midpoint of two system clock readings around the PHC reading.
+func phcOffset(device string) (time.Duration, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// FD_TO_CLOCKID, see Documentation/driver-api/ptp.rst in the kernel sources.
+	clockID := int32((^int(f.Fd()))<<3 | 3)
+
+	var before, phc, after unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_REALTIME, &before); err != nil {
+		return 0, err
+	}
+	if err := unix.ClockGettime(clockID, &phc); err != nil {
+		return 0, err
+	}
+	if err := unix.ClockGettime(unix.CLOCK_REALTIME, &after); err != nil {
+		return 0, err
+	}
+
+	sys := before.Nano() + (after.Nano()-before.Nano())/2
+	return time.Duration(phc.Nano() - sys), nil
+}
+
+func (c *ptpCollector) updatePtp4l(ch chan<- prometheus.Metric) error {
+	// ptp4l replies to the address of the sending socket, so the client
+	// needs a bound socket of its own, much like pmc(8). An abstract socket
+	// avoids leaving files behind.
+	local := fmt.Sprintf("@node_exporter/ptp/%d/%d", os.Getpid(), atomic.AddUint64(&ptp4lClientID, 1))
+	conn, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: local, Net: "unixgram"},
+		&net.UnixAddr{Name: *ptp4lSocket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	timeStatus, err := ptpManagementGet(conn, ptpMgmtTimeStatusNP, 1)
+	if err != nil {
+		return err
+	}
+	if len(timeStatus[0]) < 50 {
+		return fmt.Errorf("short TIME_STATUS_NP response, len=%d", len(timeStatus[0]))
+	}
+	masterOffset := int64(binary.BigEndian.Uint64(timeStatus[0][0:8]))
+	gmPresent := int32(binary.BigEndian.Uint32(timeStatus[0][38:42]))
+	ch <- prometheus.MustNewConstMetric(c.masterOffset, prometheus.GaugeValue, float64(masterOffset)/1e9)
+	ch <- prometheus.MustNewConstMetric(c.gmPresent, prometheus.GaugeValue, float64(gmPresent))
+
+	current, err := ptpManagementGet(conn, ptpMgmtCurrentDataSet, 1)
+	if err != nil {
+		return err
+	}
+	if len(current[0]) < 18 {
+		return fmt.Errorf("short CURRENT_DATA_SET response, len=%d", len(current[0]))
+	}
+	// offsetFromMaster and meanPathDelay are TimeIntervals, nanoseconds scaled by 2^16.
+	ch <- prometheus.MustNewConstMetric(c.stepsRemoved, prometheus.GaugeValue, float64(binary.BigEndian.Uint16(current[0][0:2])))
+	ch <- prometheus.MustNewConstMetric(c.meanPathDelay, prometheus.GaugeValue, float64(int64(binary.BigEndian.Uint64(current[0][10:18])))/65536/1e9)
+
+	defaults, err := ptpManagementGet(conn, ptpMgmtDefaultDataSet, 1)
+	if err != nil {
+		return err
+	}
+	if len(defaults[0]) < 4 {
+		return fmt.Errorf("short DEFAULT_DATA_SET response, len=%d", len(defaults[0]))
+	}
+	numberPorts := int(binary.BigEndian.Uint16(defaults[0][2:4]))
+
+	ports, err := ptpManagementGet(conn, ptpMgmtPortDataSet, numberPorts)
+	if err != nil {
+		return err
+	}
+	for _, port := range ports {
+		if len(port) < 11 {
+			return fmt.Errorf("short PORT_DATA_SET response, len=%d", len(port))
+		}
+		portNumber := strconv.Itoa(int(binary.BigEndian.Uint16(port[8:10])))
+		portState := int(port[10])
+		for i, state := range ptpPortStates {
+			isState := 0.0
+			if portState == i+1 {
+				isState = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.portState, prometheus.GaugeValue, isState, portNumber, state)
+		}
+	}
+	return nil
+}
+
+// ptpManagementGet sends a management GET request for all ports and returns
+// the data of the expected number of responses.
+func ptpManagementGet(conn *net.UnixConn, id uint16, responses int) ([][]byte, error) {
+	// Each request uses a new socket, the management ID is enough to tell
+	// the responses apart.
+	if _, err := conn.Write(ptpManagementRequest(*ptp4lDomain, id)); err != nil {
+		return nil, err
+	}
+
+	var res [][]byte
+	buf := make([]byte, 1500)
+	for len(res) < responses {
+		if err := conn.SetReadDeadline(time.Now().Add(*ptp4lTimeout)); err != nil {
+			return nil, err
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		data, err := parsePTPManagementResponse(buf[:n], id)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, data)
+	}
+	return res, nil
+}
+
+// ptpManagementRequest builds a zero length management GET message, see
+// IEEE 1588-2008 section 15.
+func ptpManagementRequest(domain uint8, id uint16) []byte {
+	msg := make([]byte, 54)
+	msg[0] = 0x0d // messageType: management
+	msg[1] = 0x02 // versionPTP
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(msg)))
+	msg[4] = domain
+	// sourcePortIdentity is left zero, like pmc does on the UDS.
+	msg[32] = 0x04 // controlField: management
+	msg[33] = 0x7f // logMessageInterval
+	// targetPortIdentity: all clocks and ports.
+	for i := 34; i < 44; i++ {
+		msg[i] = 0xff
+	}
+	// startingBoundaryHops and boundaryHops stay at 0, only the local
+	// clock should answer. actionField GET is 0.
+	binary.BigEndian.PutUint16(msg[48:50], 0x0001) // tlvType: MANAGEMENT
+	binary.BigEndian.PutUint16(msg[50:52], 2)      // lengthField
+	binary.BigEndian.PutUint16(msg[52:54], id)
+	return msg
+}
+
+// parsePTPManagementResponse returns the data of the management TLV of a
+// management response.
+func parsePTPManagementResponse(msg []byte, id uint16) ([]byte, error) {
+	if len(msg) < 54 {
+		return nil, fmt.Errorf("short management message, len=%d", len(msg))
+	}
+	if msg[0]&0x0f != 0x0d {
+		return nil, fmt.Errorf("unexpected message type %d", msg[0]&0x0f)
+	}
+	if msg[46]&0x0f != 2 {
+		return nil, fmt.Errorf("unexpected management action %d", msg[46]&0x0f)
+	}
+	tlvType := binary.BigEndian.Uint16(msg[48:50])
+	if tlvType == 0x0002 {
+		return nil, errPTPMgmtError
+	}
+	if tlvType != 0x0001 {
+		return nil, fmt.Errorf("unexpected TLV type %d", tlvType)
+	}
+	if got := binary.BigEndian.Uint16(msg[52:54]); got != id {
+		return nil, fmt.Errorf("unexpected management ID %#x, want %#x", got, id)
+	}
+	length := int(binary.BigEndian.Uint16(msg[50:52]))
+	if length < 2 || 52+length > len(msg) {
+		return nil, fmt.Errorf("invalid TLV length %d", length)
+	}
+
+	return msg[54 : 52+length], nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noptp
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestPTPManagementResponse(t *testing.T) {
+	req := ptpManagementRequest(24, ptpMgmtDefaultDataSet)
+	if len(req) != 54 {
+		t.Fatalf("want request length 54, got %d", len(req))
+	}
+	if req[4] != 24 {
+		t.Errorf("want domain 24, got %d", req[4])
+	}
+
+	// Turn the request into a RESPONSE carrying four bytes of data.
+	data := []byte{0x03, 0x00, 0x00, 0x01}
+	resp := append(append([]byte{}, req...), data...)
+	binary.BigEndian.PutUint16(resp[2:4], uint16(len(resp)))
+	resp[46] = 2
+	binary.BigEndian.PutUint16(resp[50:52], uint16(2+len(data)))
+
+	got, err := parsePTPManagementResponse(resp, ptpMgmtDefaultDataSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("want data %x, got %x", data, got)
+	}
+
+	if _, err := parsePTPManagementResponse(resp, ptpMgmtCurrentDataSet); err == nil {
+		t.Error("expected error for unexpected management ID")
+	}
+
+	binary.BigEndian.PutUint16(resp[48:50], 0x0002)
+	if _, err := parsePTPManagementResponse(resp, ptpMgmtDefaultDataSet); err != errPTPMgmtError {
+		t.Errorf("want management error, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noqdisc
+
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"path/filepath"
+
+	"github.com/ema/qdisc"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+// qdiscClassInfo holds the statistics of a single traffic control class,
+// e.g. an HTB leaf or an fq_codel flow.
+type qdiscClassInfo struct {
+	IfaceName  string
+	Handle     uint32
+	Parent     uint32
+	Kind       string
+	Bytes      uint64
+	Packets    uint32
+	Drops      uint32
+	Requeues   uint32
+	Overlimits uint32
+	Qlen       uint32
+	Backlog    uint32
+	// HasRate is set when the class has a rate estimator attached.
+	HasRate bool
+	RateBps uint64
+	RatePps uint64
+	// Lends, Borrows and Giants are only reported by HTB classes.
+	Lends   uint32
+	Borrows uint32
+	Giants  uint32
+}
+
+// tcHandleString formats a traffic control handle the way tc(8) does.
+func tcHandleString(h uint32) string {
+	switch h {
+	case math.MaxUint32:
+		return "root"
+	case 0:
+		return "none"
+	}
+	return fmt.Sprintf("%x:%x", h>>16, h&0xffff)
+}
+
+func testQdiscClassGet(fixtures string) ([]qdiscClassInfo, error) {
+	var res []qdiscClassInfo
+
+	b, err := ioutil.ReadFile(filepath.Join(fixtures, "classes.json"))
+	if err != nil {
+		return res, err
+	}
+
+	err = json.Unmarshal(b, &res)
+	return res, err
+}
+
+// getQdiscClasses dumps the traffic control classes of all interfaces.
+// Unlike qdiscs, the kernel only dumps classes of a single interface per
+// request. Interfaces whose dump fails, e.g. with ENODEV as they were removed
+// meanwhile, are skipped.
+func getQdiscClasses(logger log.Logger) ([]qdiscClassInfo, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("could not get network interfaces: %w", err)
+	}
+
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial netlink: %w", err)
+	}
+	defer c.Close()
+
+	var res []qdiscClassInfo
+	for _, iface := range ifaces {
+		// struct tcmsg, see /usr/include/linux/rtnetlink.h
+		data := make([]byte, 20)
+		nlenc.PutInt32(data[4:8], int32(iface.Index))
+
+		msgs, err := c.Execute(netlink.Message{
+			Header: netlink.Header{
+				Flags: netlink.Request | netlink.Dump,
+				Type:  unix.RTM_GETTCLASS,
+			},
+			Data: data,
+		})
+		if err != nil {
+			level.Debug(logger).Log("msg", "failed to dump classes", "device", iface.Name, "err", err)
+			continue
+		}
+
+		for _, msg := range msgs {
+			info, err := parseQdiscClassMessage(msg.Data)
+			if err != nil {
+				return nil, err
+			}
+			info.IfaceName = iface.Name
+			res = append(res, info)
+		}
+	}
+	return res, nil
+}
+
+func parseQdiscClassMessage(data []byte) (qdiscClassInfo, error) {
+	var info qdiscClassInfo
+
+	if len(data) < 20 {
+		return info, fmt.Errorf("short message, len=%d", len(data))
+	}
+	info.Handle = nlenc.Uint32(data[8:12])
+	info.Parent = nlenc.Uint32(data[12:16])
+
+	attrs, err := netlink.UnmarshalAttributes(data[20:])
+	if err != nil {
+		return info, fmt.Errorf("failed to unmarshal attributes: %w", err)
+	}
+
+	var xstats []byte
+	for _, attr := range attrs {
+		switch attr.Type {
+		case qdisc.TCA_KIND:
+			info.Kind = nlenc.String(attr.Data)
+		case qdisc.TCA_STATS2:
+			nested, err := netlink.UnmarshalAttributes(attr.Data)
+			if err != nil {
+				return info, fmt.Errorf("failed to unmarshal stats: %w", err)
+			}
+			for _, a := range nested {
+				switch {
+				case a.Type == qdisc.TCA_STATS_BASIC && len(a.Data) >= 12:
+					info.Bytes = nlenc.Uint64(a.Data[0:8])
+					info.Packets = nlenc.Uint32(a.Data[8:12])
+				case a.Type == qdisc.TCA_STATS_QUEUE && len(a.Data) >= 20:
+					info.Qlen = nlenc.Uint32(a.Data[0:4])
+					info.Backlog = nlenc.Uint32(a.Data[4:8])
+					info.Drops = nlenc.Uint32(a.Data[8:12])
+					info.Requeues = nlenc.Uint32(a.Data[12:16])
+					info.Overlimits = nlenc.Uint32(a.Data[16:20])
+				case a.Type == qdisc.TCA_STATS_RATE_EST && len(a.Data) >= 8 && !info.HasRate:
+					info.HasRate = true
+					info.RateBps = uint64(nlenc.Uint32(a.Data[0:4]))
+					info.RatePps = uint64(nlenc.Uint32(a.Data[4:8]))
+				case a.Type == qdisc.TCA_STATS_RATE_EST64 && len(a.Data) >= 16:
+					// Prefer the 64 bit estimator, which doesn't saturate above 34 Gbit/s.
+					info.HasRate = true
+					info.RateBps = nlenc.Uint64(a.Data[0:8])
+					info.RatePps = nlenc.Uint64(a.Data[8:16])
+				}
+			}
+		case qdisc.TCA_XSTATS:
+			xstats = attr.Data
+		}
+	}
+
+	// struct tc_htb_xstats, see /usr/include/linux/pkt_sched.h
+	if info.Kind == "htb" && len(xstats) >= 12 {
+		info.Lends = nlenc.Uint32(xstats[0:4])
+		info.Borrows = nlenc.Uint32(xstats[4:8])
+		info.Giants = nlenc.Uint32(xstats[8:12])
+	}
+
+	return info, nil
+}
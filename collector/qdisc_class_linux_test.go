@@ -0,0 +1,102 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noqdisc
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/ema/qdisc"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+func TestParseQdiscClassMessage(t *testing.T) {
+	basic := make([]byte, 16)
+	nlenc.PutUint64(basic[0:8], 123456)
+	nlenc.PutUint32(basic[8:12], 789)
+
+	queue := make([]byte, 20)
+	nlenc.PutUint32(queue[0:4], 2)
+	nlenc.PutUint32(queue[4:8], 3028)
+	nlenc.PutUint32(queue[8:12], 5)
+	nlenc.PutUint32(queue[12:16], 1)
+	nlenc.PutUint32(queue[16:20], 42)
+
+	rate := make([]byte, 8)
+	nlenc.PutUint32(rate[0:4], 125000)
+	nlenc.PutUint32(rate[4:8], 100)
+
+	stats, err := netlink.MarshalAttributes([]netlink.Attribute{
+		{Type: qdisc.TCA_STATS_BASIC, Data: basic},
+		{Type: qdisc.TCA_STATS_RATE_EST, Data: rate},
+		{Type: qdisc.TCA_STATS_QUEUE, Data: queue},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xstats := make([]byte, 20)
+	nlenc.PutUint32(xstats[0:4], 700)
+	nlenc.PutUint32(xstats[4:8], 89)
+	nlenc.PutUint32(xstats[8:12], 0)
+
+	attrs, err := netlink.MarshalAttributes([]netlink.Attribute{
+		{Type: qdisc.TCA_KIND, Data: nlenc.Bytes("htb")},
+		{Type: qdisc.TCA_STATS2, Data: stats},
+		{Type: qdisc.TCA_XSTATS, Data: xstats},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcmsg := make([]byte, 20)
+	nlenc.PutInt32(tcmsg[4:8], 2)
+	nlenc.PutUint32(tcmsg[8:12], 0x10010)
+	nlenc.PutUint32(tcmsg[12:16], 0x10001)
+
+	info, err := parseQdiscClassMessage(append(tcmsg, attrs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := qdiscClassInfo{
+		Handle:     0x10010,
+		Parent:     0x10001,
+		Kind:       "htb",
+		Bytes:      123456,
+		Packets:    789,
+		Drops:      5,
+		Requeues:   1,
+		Overlimits: 42,
+		Qlen:       2,
+		Backlog:    3028,
+		HasRate:    true,
+		RateBps:    125000,
+		RatePps:    100,
+		Lends:      700,
+		Borrows:    89,
+	}
+	if info != want {
+		t.Errorf("want %+v, got %+v", want, info)
+	}
+
+	if want, got := "1:10", tcHandleString(info.Handle); want != got {
+		t.Errorf("want handle %q, got %q", want, got)
+	}
+	if want, got := "root", tcHandleString(0xffffffff); want != got {
+		t.Errorf("want handle %q, got %q", want, got)
+	}
+}
@@ -35,12 +35,28 @@ type qdiscStatCollector struct {
 	qlength    typedDesc
 	backlog    typedDesc
 	logger     log.Logger
+
+	classBytes      typedDesc
+	classPackets    typedDesc
+	classDrops      typedDesc
+	classRequeues   typedDesc
+	classOverlimits typedDesc
+	classQlength    typedDesc
+	classBacklog    typedDesc
+	classRateBytes  typedDesc
+	classRatePkts   typedDesc
+	classLends      typedDesc
+	classBorrows    typedDesc
+	classGiants     typedDesc
 }
 
 var (
-	collectorQdisc = kingpin.Flag("collector.qdisc.fixtures", "test fixtures to use for qdisc collector end-to-end testing").Default("").String()
+	collectorQdisc        = kingpin.Flag("collector.qdisc.fixtures", "test fixtures to use for qdisc collector end-to-end testing").Default("").String()
+	collectorQdiscClasses = kingpin.Flag("collector.qdisc.classes", "Expose statistics of traffic control classes, e.g. of HTB or fq_codel qdiscs.").Bool()
 )
 
+var qdiscClassLabelNames = []string{"device", "kind", "class", "parent"}
+
 func init() {
 	registerCollector("qdisc", defaultDisabled, NewQdiscStatCollector)
 }
@@ -83,6 +99,66 @@ func NewQdiscStatCollector(logger log.Logger) (Collector, error) {
 			"Number of bytes currently in queue to be sent.",
 			[]string{"device", "kind"}, nil,
 		), prometheus.GaugeValue},
+		classBytes: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_bytes_total"),
+			"Number of bytes sent by the class.",
+			qdiscClassLabelNames, nil,
+		), prometheus.CounterValue},
+		classPackets: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_packets_total"),
+			"Number of packets sent by the class.",
+			qdiscClassLabelNames, nil,
+		), prometheus.CounterValue},
+		classDrops: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_drops_total"),
+			"Number of packets dropped by the class.",
+			qdiscClassLabelNames, nil,
+		), prometheus.CounterValue},
+		classRequeues: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_requeues_total"),
+			"Number of packets dequeued, not transmitted, and requeued by the class.",
+			qdiscClassLabelNames, nil,
+		), prometheus.CounterValue},
+		classOverlimits: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_overlimits_total"),
+			"Number of overlimit packets of the class.",
+			qdiscClassLabelNames, nil,
+		), prometheus.CounterValue},
+		classQlength: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_current_queue_length"),
+			"Number of packets currently in the class queue.",
+			qdiscClassLabelNames, nil,
+		), prometheus.GaugeValue},
+		classBacklog: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_backlog"),
+			"Number of bytes currently in the class queue.",
+			qdiscClassLabelNames, nil,
+		), prometheus.GaugeValue},
+		classRateBytes: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_rate_bytes"),
+			"Estimated rate of the class in bytes per second.",
+			qdiscClassLabelNames, nil,
+		), prometheus.GaugeValue},
+		classRatePkts: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_rate_packets"),
+			"Estimated rate of the class in packets per second.",
+			qdiscClassLabelNames, nil,
+		), prometheus.GaugeValue},
+		classLends: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_lends_total"),
+			"Number of packets sent by an HTB class within its own rate.",
+			qdiscClassLabelNames, nil,
+		), prometheus.CounterValue},
+		classBorrows: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_borrows_total"),
+			"Number of packets sent by an HTB class borrowing from its parent.",
+			qdiscClassLabelNames, nil,
+		), prometheus.CounterValue},
+		classGiants: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "qdisc", "class_giants_total"),
+			"Number of packets larger than the MTU of an HTB class.",
+			qdiscClassLabelNames, nil,
+		), prometheus.CounterValue},
 		logger: logger,
 	}, nil
 }
@@ -130,5 +206,47 @@ func (c *qdiscStatCollector) Update(ch chan<- prometheus.Metric) error {
 		ch <- c.backlog.mustNewConstMetric(float64(msg.Backlog), msg.IfaceName, msg.Kind)
 	}
 
+	if *collectorQdiscClasses {
+		return c.updateClasses(ch, fixtures)
+	}
+
+	return nil
+}
+
+func (c *qdiscStatCollector) updateClasses(ch chan<- prometheus.Metric, fixtures string) error {
+	var classes []qdiscClassInfo
+	var err error
+
+	if fixtures == "" {
+		classes, err = getQdiscClasses(c.logger)
+	} else {
+		classes, err = testQdiscClassGet(fixtures)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, cl := range classes {
+		labels := []string{cl.IfaceName, cl.Kind, tcHandleString(cl.Handle), tcHandleString(cl.Parent)}
+
+		ch <- c.classBytes.mustNewConstMetric(float64(cl.Bytes), labels...)
+		ch <- c.classPackets.mustNewConstMetric(float64(cl.Packets), labels...)
+		ch <- c.classDrops.mustNewConstMetric(float64(cl.Drops), labels...)
+		ch <- c.classRequeues.mustNewConstMetric(float64(cl.Requeues), labels...)
+		ch <- c.classOverlimits.mustNewConstMetric(float64(cl.Overlimits), labels...)
+		ch <- c.classQlength.mustNewConstMetric(float64(cl.Qlen), labels...)
+		ch <- c.classBacklog.mustNewConstMetric(float64(cl.Backlog), labels...)
+		if cl.HasRate {
+			ch <- c.classRateBytes.mustNewConstMetric(float64(cl.RateBps), labels...)
+			ch <- c.classRatePkts.mustNewConstMetric(float64(cl.RatePps), labels...)
+		}
+		if cl.Kind == "htb" {
+			ch <- c.classLends.mustNewConstMetric(float64(cl.Lends), labels...)
+			ch <- c.classBorrows.mustNewConstMetric(float64(cl.Borrows), labels...)
+			ch <- c.classGiants.mustNewConstMetric(float64(cl.Giants), labels...)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,208 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noquota
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"golang.org/x/sys/unix"
+)
+
+const quotaSubsystem = "quota"
+
+// Commands of quotactl(2), from linux/quota.h.
+const (
+	quotaGetInfo      = 0x800005
+	quotaGetNextQuota = 0x800009
+
+	// quotaBlockSize is the unit of the block limits.
+	quotaBlockSize = 1024
+)
+
+// quotaTypes are the quota types of the kernel with their index.
+var quotaTypes = []string{"user", "group", "project"}
+
+// quotaNextDqblk is struct if_nextdqblk of linux/quota.h.
+type quotaNextDqblk struct {
+	BHardLimit uint64
+	BSoftLimit uint64
+	CurSpace   uint64
+	IHardLimit uint64
+	ISoftLimit uint64
+	CurInodes  uint64
+	BTime      uint64
+	ITime      uint64
+	Valid      uint32
+	ID         uint32
+}
+
+// quotaDqinfo is struct if_dqinfo of linux/quota.h.
+type quotaDqinfo struct {
+	BGrace uint64
+	IGrace uint64
+	Flags  uint32
+	Valid  uint32
+}
+
+type quotaCollector struct {
+	fs              procfs.FS
+	usedBytes       *prometheus.Desc
+	softLimitBytes  *prometheus.Desc
+	hardLimitBytes  *prometheus.Desc
+	usedInodes      *prometheus.Desc
+	softLimitInodes *prometheus.Desc
+	hardLimitInodes *prometheus.Desc
+	logger          log.Logger
+}
+
+func init() {
+	registerCollector(quotaSubsystem, defaultDisabled, NewQuotaCollector)
+}
+
+// NewQuotaCollector returns a new Collector exposing the usage and limits of
+// the user, group and project quotas of filesystems.
+func NewQuotaCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, quotaSubsystem, name), help, []string{"device", "mountpoint", "type", "id"}, nil)
+	}
+	return &quotaCollector{
+		fs:              fs,
+		usedBytes:       desc("used_bytes", "Space used by the user, group or project on the filesystem."),
+		softLimitBytes:  desc("soft_limit_bytes", "Space the user, group or project may use before the grace period starts, not exported if unlimited."),
+		hardLimitBytes:  desc("hard_limit_bytes", "Space the user, group or project may use at most, not exported if unlimited."),
+		usedInodes:      desc("used_inodes", "Number of inodes used by the user, group or project on the filesystem."),
+		softLimitInodes: desc("soft_limit_inodes", "Number of inodes the user, group or project may use before the grace period starts, not exported if unlimited."),
+		hardLimitInodes: desc("hard_limit_inodes", "Number of inodes the user, group or project may use at most, not exported if unlimited."),
+		logger:          logger,
+	}, nil
+}
+
+func (c *quotaCollector) Update(ch chan<- prometheus.Metric) error {
+	mounts, err := c.mounts()
+	if err != nil {
+		return fmt.Errorf("failed to read mounts: %w", err)
+	}
+
+	found := false
+	seen := map[string]bool{}
+	for _, m := range mounts {
+		// Quotas belong to the block device, bind mounts would repeat them.
+		if !strings.HasPrefix(m.Source, "/dev/") || seen[m.Source] {
+			continue
+		}
+		seen[m.Source] = true
+		mountPoint := rootfsStripPrefix(m.MountPoint)
+
+		for qtype, name := range quotaTypes {
+			var info quotaDqinfo
+			if err := quotactl(quotaGetInfo, qtype, m.Source, 0, unsafe.Pointer(&info)); err != nil {
+				// Quotas of this type are not enabled or not supported by the filesystem.
+				continue
+			}
+			found = true
+			if err := c.updateQuotas(ch, m.Source, mountPoint, qtype, name); err != nil {
+				return fmt.Errorf("failed to get %s quotas of %s: %w", name, mountPoint, err)
+			}
+		}
+	}
+	if !found {
+		level.Debug(c.logger).Log("msg", "no filesystems with quotas enabled found")
+		return ErrNoData
+	}
+	return nil
+}
+
+// mounts returns the mounts of the root mount namespace, falling back to the
+// mounts of the exporter if hidepid prevents reading them.
+func (c *quotaCollector) mounts() ([]*procfs.MountInfo, error) {
+	p, err := c.fs.Proc(1)
+	if err == nil {
+		var mounts []*procfs.MountInfo
+		if mounts, err = p.MountInfo(); !errors.Is(err, os.ErrNotExist) {
+			return mounts, err
+		}
+	}
+	level.Debug(c.logger).Log("msg", "Reading root mounts failed, falling back to system mounts", "err", err)
+	if p, err = c.fs.Self(); err != nil {
+		return nil, err
+	}
+	return p.MountInfo()
+}
+
+func (c *quotaCollector) updateQuotas(ch chan<- prometheus.Metric, device, mountPoint string, qtype int, name string) error {
+	// Only ids with usage or limits are returned, starting at the given id.
+	for id := uint32(0); ; {
+		var q quotaNextDqblk
+		err := quotactl(quotaGetNextQuota, qtype, device, id, unsafe.Pointer(&q))
+		if errors.Is(err, unix.ENOENT) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		labels := []string{device, mountPoint, name, strconv.FormatUint(uint64(q.ID), 10)}
+		ch <- prometheus.MustNewConstMetric(c.usedBytes, prometheus.GaugeValue, float64(q.CurSpace), labels...)
+		ch <- prometheus.MustNewConstMetric(c.usedInodes, prometheus.GaugeValue, float64(q.CurInodes), labels...)
+		// A limit of zero means unlimited.
+		for _, l := range []struct {
+			desc  *prometheus.Desc
+			value uint64
+		}{
+			{c.softLimitBytes, q.BSoftLimit * quotaBlockSize},
+			{c.hardLimitBytes, q.BHardLimit * quotaBlockSize},
+			{c.softLimitInodes, q.ISoftLimit},
+			{c.hardLimitInodes, q.IHardLimit},
+		} {
+			if l.value != 0 {
+				ch <- prometheus.MustNewConstMetric(l.desc, prometheus.GaugeValue, float64(l.value), labels...)
+			}
+		}
+
+		if q.ID == ^uint32(0) {
+			return nil
+		}
+		id = q.ID + 1
+	}
+}
+
+// quotactl calls quotactl(2) for the quota type of a block device.
+func quotactl(cmd, qtype int, device string, id uint32, addr unsafe.Pointer) error {
+	p, err := unix.BytePtrFromString(device)
+	if err != nil {
+		return err
+	}
+	// QCMD of linux/quota.h.
+	qcmd := uint32(cmd)<<8 | uint32(qtype)&0xff
+	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, uintptr(qcmd), uintptr(unsafe.Pointer(p)), uintptr(id), uintptr(addr), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -18,8 +18,12 @@ package collector
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -30,6 +34,17 @@ import (
 type raplCollector struct {
 	fs     sysfs.FS
 	logger log.Logger
+
+	// The energy counters wrap at their max_energy_range_uj, which takes
+	// minutes for a busy package, so the exported totals are accumulated.
+	mtx      sync.Mutex
+	counters map[string]*raplCounter
+}
+
+// raplCounter is the accumulated energy of a zone.
+type raplCounter struct {
+	last       uint64
+	microjoule uint64
 }
 
 func init() {
@@ -45,8 +60,9 @@ func NewRaplCollector(logger log.Logger) (Collector, error) {
 	}
 
 	collector := raplCollector{
-		fs:     fs,
-		logger: logger,
+		fs:       fs,
+		logger:   logger,
+		counters: map[string]*raplCounter{},
 	}
 	return &collector, nil
 }
@@ -67,6 +83,8 @@ func (c *raplCollector) Update(ch chan<- prometheus.Metric) error {
 		return fmt.Errorf("failed to retrieve rapl stats: %w", err)
 	}
 
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	for _, rz := range zones {
 		newMicrojoules, err := rz.GetEnergyMicrojoules()
 		if err != nil {
@@ -81,15 +99,53 @@ func (c *raplCollector) Update(ch chan<- prometheus.Metric) error {
 		descriptor := prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "rapl", rz.Name+"_joules_total"),
 			"Current RAPL "+rz.Name+" value in joules",
-			[]string{"index"}, nil,
+			[]string{"index", "package"}, nil,
 		)
 
 		ch <- prometheus.MustNewConstMetric(
 			descriptor,
 			prometheus.CounterValue,
-			float64(newMicrojoules)/1000000.0,
-			index,
+			float64(c.accumulate(rz, newMicrojoules))/1000000.0,
+			index, raplZonePackage(rz),
 		)
 	}
 	return nil
 }
+
+// accumulate returns the energy of the zone since the first scrape, starting
+// at the counter value, counting through wraparounds of the counter.
+func (c *raplCollector) accumulate(rz sysfs.RaplZone, microjoules uint64) uint64 {
+	counter, ok := c.counters[rz.Path]
+	if !ok {
+		c.counters[rz.Path] = &raplCounter{last: microjoules, microjoule: microjoules}
+		return microjoules
+	}
+	if microjoules >= counter.last {
+		counter.microjoule += microjoules - counter.last
+	} else {
+		counter.microjoule += rz.MaxMicrojoules - counter.last + microjoules
+	}
+	counter.last = microjoules
+	return counter.microjoule
+}
+
+// raplZonePackage returns the package of a zone, e.g. 1 for the package-1
+// zone intel-rapl:1 and its subzones like intel-rapl:1:0. Zones that aren't
+// part of a package, like psys, have no package.
+func raplZonePackage(rz sysfs.RaplZone) string {
+	// Subzones share the id of their package zone, but it isn't guaranteed
+	// to be the package id, which is only part of the package zone name.
+	parts := strings.Split(filepath.Base(rz.Path), ":")
+	if len(parts) < 2 {
+		return ""
+	}
+	name, err := ioutil.ReadFile(filepath.Join(filepath.Dir(rz.Path), parts[0]+":"+parts[1], "name"))
+	if err != nil {
+		return ""
+	}
+	pkg := strings.TrimPrefix(strings.TrimSpace(string(name)), "package-")
+	if _, err := strconv.Atoi(pkg); err != nil {
+		return ""
+	}
+	return pkg
+}
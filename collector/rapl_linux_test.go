@@ -0,0 +1,62 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !norapl
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/procfs/sysfs"
+)
+
+func TestRaplAccumulate(t *testing.T) {
+	c := &raplCollector{counters: map[string]*raplCounter{}}
+	rz := sysfs.RaplZone{Path: "intel-rapl:0", MaxMicrojoules: 1000}
+
+	for _, tt := range []struct {
+		microjoules uint64
+		want        uint64
+	}{
+		{microjoules: 900, want: 900},
+		{microjoules: 950, want: 950},
+		// The counter wrapped at 1000.
+		{microjoules: 50, want: 1050},
+		{microjoules: 50, want: 1050},
+		{microjoules: 20, want: 2020},
+	} {
+		if got := c.accumulate(rz, tt.microjoules); got != tt.want {
+			t.Errorf("accumulate(%d): want %d, got %d", tt.microjoules, tt.want, got)
+		}
+	}
+}
+
+func TestRaplZonePackage(t *testing.T) {
+	*sysPath = "fixtures/sys"
+	dir := sysFilePath("class/powercap")
+
+	for _, tt := range []struct {
+		zone string
+		want string
+	}{
+		{zone: "intel-rapl:0", want: "0"},
+		{zone: "intel-rapl:0:2", want: "0"},
+		{zone: "intel-rapl:1", want: ""},
+		{zone: "intel-rapl", want: ""},
+	} {
+		if got := raplZonePackage(sysfs.RaplZone{Path: dir + "/" + tt.zone}); got != tt.want {
+			t.Errorf("%s: want package %q, got %q", tt.zone, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noredfish
+
+package collector
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const bmcSubsystem = "bmc"
+
+var (
+	redfishURL          = kingpin.Flag("collector.redfish.url", "Base URL of the local BMC Redfish service.").Default("https://localhost").String()
+	redfishUsername     = kingpin.Flag("collector.redfish.username", "Username for the Redfish service.").Default("").String()
+	redfishPasswordFile = kingpin.Flag("collector.redfish.password-file", "File containing the password for the Redfish service.").Default("").String()
+	redfishInsecure     = kingpin.Flag("collector.redfish.insecure-skip-verify", "Skip verification of the Redfish service's TLS certificate, BMCs commonly use self-signed ones.").Default("false").Bool()
+	redfishTimeout      = kingpin.Flag("collector.redfish.timeout", "Timeout for a Redfish request.").Default("10s").Duration()
+)
+
+// redfishHealthValues maps the Redfish Health enumeration to metric values.
+var redfishHealthValues = map[string]float64{
+	"OK":       0,
+	"Warning":  1,
+	"Critical": 2,
+}
+
+type redfishCollector struct {
+	client            *http.Client
+	chassisHealth     *prometheus.Desc
+	powerConsumed     *prometheus.Desc
+	powerSupplyHealth *prometheus.Desc
+	temperature       *prometheus.Desc
+	temperatureHealth *prometheus.Desc
+	fanSpeedRPM       *prometheus.Desc
+	fanSpeedPercent   *prometheus.Desc
+	fanHealth         *prometheus.Desc
+	logger            log.Logger
+}
+
+type redfishStatus struct {
+	State        string
+	Health       string
+	HealthRollup string
+}
+
+type redfishLink struct {
+	ID string `json:"@odata.id"`
+}
+
+type redfishCollection struct {
+	Members []redfishLink
+}
+
+// redfishMember is the identity of a member of an array of a resource like
+// PowerSupplies. Names are not unique, e.g. all supplies may be named
+// "Power Supply", so members are identified by their MemberId or, if a
+// service omits it, by their @odata.id.
+type redfishMember struct {
+	MemberID string `json:"MemberId"`
+	ODataID  string `json:"@odata.id"`
+	Name     string
+}
+
+// id returns the identifying label value of the member.
+func (m redfishMember) id() string {
+	if m.MemberID != "" {
+		return m.MemberID
+	}
+	return m.ODataID
+}
+
+type redfishChassis struct {
+	ID      string `json:"Id"`
+	Status  redfishStatus
+	Power   *redfishLink
+	Thermal *redfishLink
+}
+
+type redfishPower struct {
+	PowerControl []struct {
+		redfishMember
+		PowerConsumedWatts *float64
+	}
+	PowerSupplies []struct {
+		redfishMember
+		Status redfishStatus
+	}
+}
+
+type redfishThermal struct {
+	Temperatures []struct {
+		redfishMember
+		ReadingCelsius *float64
+		Status         redfishStatus
+	}
+	Fans []struct {
+		redfishMember
+		Reading      *float64
+		ReadingUnits string
+		Status       redfishStatus
+	}
+}
+
+func init() {
+	registerCollector("redfish", defaultDisabled, NewRedfishCollector)
+}
+
+// NewRedfishCollector returns a new Collector exposing chassis power,
+// thermal and health state from a Redfish service.
+func NewRedfishCollector(logger log.Logger) (Collector, error) {
+	if _, err := url.Parse(*redfishURL); err != nil {
+		return nil, fmt.Errorf("invalid Redfish URL: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: *redfishInsecure}
+
+	labels := func(names ...string) []string {
+		return append([]string{"chassis"}, names...)
+	}
+	return &redfishCollector{
+		client: &http.Client{Transport: transport, Timeout: *redfishTimeout},
+		chassisHealth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bmcSubsystem, "chassis_health"),
+			"Health rollup of the chassis and its components: 0 OK, 1 warning, 2 critical.",
+			labels(), nil,
+		),
+		powerConsumed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bmcSubsystem, "power_consumed_watts"),
+			"Power consumption measured by the power control of the chassis.",
+			labels("control", "name"), nil,
+		),
+		powerSupplyHealth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bmcSubsystem, "power_supply_health"),
+			"Health of the power supply: 0 OK, 1 warning, 2 critical.",
+			labels("power_supply", "name"), nil,
+		),
+		temperature: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bmcSubsystem, "temperature_celsius"),
+			"Reading of the temperature sensor.",
+			labels("sensor", "name"), nil,
+		),
+		temperatureHealth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bmcSubsystem, "temperature_health"),
+			"Health of the temperature sensor: 0 OK, 1 warning, 2 critical.",
+			labels("sensor", "name"), nil,
+		),
+		fanSpeedRPM: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bmcSubsystem, "fan_speed_rpm"),
+			"Speed of the fan, for fans reporting RPM.",
+			labels("fan", "name"), nil,
+		),
+		fanSpeedPercent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bmcSubsystem, "fan_speed_percent"),
+			"Speed of the fan, for fans reporting a percentage.",
+			labels("fan", "name"), nil,
+		),
+		fanHealth: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bmcSubsystem, "fan_health"),
+			"Health of the fan: 0 OK, 1 warning, 2 critical.",
+			labels("fan", "name"), nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *redfishCollector) Update(ch chan<- prometheus.Metric) error {
+	// The password is read on every scrape so it can be rotated without
+	// restarting the exporter.
+	var password string
+	if *redfishPasswordFile != "" {
+		b, err := ioutil.ReadFile(*redfishPasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read Redfish password: %w", err)
+		}
+		password = strings.TrimSpace(string(b))
+	}
+
+	var chassisList redfishCollection
+	if err := c.get("/redfish/v1/Chassis", password, &chassisList); err != nil {
+		return err
+	}
+
+	for _, member := range chassisList.Members {
+		var chassis redfishChassis
+		if err := c.get(member.ID, password, &chassis); err != nil {
+			return err
+		}
+		if chassis.Status.State == "Absent" {
+			continue
+		}
+		health := chassis.Status.HealthRollup
+		if health == "" {
+			health = chassis.Status.Health
+		}
+		if v, ok := redfishHealthValues[health]; ok {
+			ch <- prometheus.MustNewConstMetric(c.chassisHealth, prometheus.GaugeValue, v, chassis.ID)
+		}
+
+		if chassis.Power != nil {
+			if err := c.updatePower(ch, chassis.ID, chassis.Power.ID, password); err != nil {
+				return err
+			}
+		}
+		if chassis.Thermal != nil {
+			if err := c.updateThermal(ch, chassis.ID, chassis.Thermal.ID, password); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *redfishCollector) updatePower(ch chan<- prometheus.Metric, chassis, path, password string) error {
+	var power redfishPower
+	if err := c.get(path, password, &power); err != nil {
+		return err
+	}
+	for _, control := range power.PowerControl {
+		if control.PowerConsumedWatts == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.powerConsumed, prometheus.GaugeValue, *control.PowerConsumedWatts, chassis, control.id(), control.Name)
+	}
+	for _, psu := range power.PowerSupplies {
+		if v, ok := redfishHealthValues[psu.Status.Health]; ok && psu.Status.State != "Absent" {
+			ch <- prometheus.MustNewConstMetric(c.powerSupplyHealth, prometheus.GaugeValue, v, chassis, psu.id(), psu.Name)
+		}
+	}
+	return nil
+}
+
+func (c *redfishCollector) updateThermal(ch chan<- prometheus.Metric, chassis, path, password string) error {
+	var thermal redfishThermal
+	if err := c.get(path, password, &thermal); err != nil {
+		return err
+	}
+	for _, t := range thermal.Temperatures {
+		if t.Status.State == "Absent" {
+			continue
+		}
+		if t.ReadingCelsius != nil {
+			ch <- prometheus.MustNewConstMetric(c.temperature, prometheus.GaugeValue, *t.ReadingCelsius, chassis, t.id(), t.Name)
+		}
+		if v, ok := redfishHealthValues[t.Status.Health]; ok {
+			ch <- prometheus.MustNewConstMetric(c.temperatureHealth, prometheus.GaugeValue, v, chassis, t.id(), t.Name)
+		}
+	}
+	for _, f := range thermal.Fans {
+		if f.Status.State == "Absent" {
+			continue
+		}
+		if f.Reading != nil {
+			switch f.ReadingUnits {
+			case "RPM":
+				ch <- prometheus.MustNewConstMetric(c.fanSpeedRPM, prometheus.GaugeValue, *f.Reading, chassis, f.id(), f.Name)
+			case "Percent":
+				ch <- prometheus.MustNewConstMetric(c.fanSpeedPercent, prometheus.GaugeValue, *f.Reading, chassis, f.id(), f.Name)
+			default:
+				level.Debug(c.logger).Log("msg", "unknown fan reading units", "fan", f.id(), "units", f.ReadingUnits)
+			}
+		}
+		if v, ok := redfishHealthValues[f.Status.Health]; ok {
+			ch <- prometheus.MustNewConstMetric(c.fanHealth, prometheus.GaugeValue, v, chassis, f.id(), f.Name)
+		}
+	}
+	return nil
+}
+
+// get fetches a Redfish resource by its path and decodes it into v.
+func (c *redfishCollector) get(path, password string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(*redfishURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if *redfishUsername != "" {
+		req.SetBasicAuth(*redfishUsername, password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get Redfish resource %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get Redfish resource %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode Redfish resource %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noredfish
+
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var redfishResources = map[string]string{
+	"/redfish/v1/Chassis": `{"Members": [{"@odata.id": "/redfish/v1/Chassis/1"}, {"@odata.id": "/redfish/v1/Chassis/2"}]}`,
+	"/redfish/v1/Chassis/1": `{
+		"Id": "1",
+		"Status": {"State": "Enabled", "Health": "OK", "HealthRollup": "Warning"},
+		"Power": {"@odata.id": "/redfish/v1/Chassis/1/Power"},
+		"Thermal": {"@odata.id": "/redfish/v1/Chassis/1/Thermal"}
+	}`,
+	"/redfish/v1/Chassis/2": `{"Id": "2", "Status": {"State": "Absent"}}`,
+	"/redfish/v1/Chassis/1/Power": `{
+		"PowerControl": [{"MemberId": "0", "Name": "System Power Control", "PowerConsumedWatts": 224}],
+		"PowerSupplies": [
+			{"@odata.id": "/redfish/v1/Chassis/1/Power#/PowerSupplies/0", "MemberId": "0", "Name": "Power Supply", "Status": {"State": "Enabled", "Health": "OK"}},
+			{"@odata.id": "/redfish/v1/Chassis/1/Power#/PowerSupplies/1", "MemberId": "1", "Name": "Power Supply", "Status": {"State": "Enabled", "Health": "Warning"}},
+			{"@odata.id": "/redfish/v1/Chassis/1/Power#/PowerSupplies/2", "Name": "Power Supply", "Status": {"State": "Absent"}}
+		]
+	}`,
+	"/redfish/v1/Chassis/1/Thermal": `{
+		"Temperatures": [
+			{"@odata.id": "/redfish/v1/Chassis/1/Thermal#/Temperatures/0", "Name": "CPU Temp", "ReadingCelsius": 41, "Status": {"State": "Enabled", "Health": "OK"}},
+			{"@odata.id": "/redfish/v1/Chassis/1/Thermal#/Temperatures/1", "Name": "CPU Temp", "ReadingCelsius": 43, "Status": {"State": "Enabled", "Health": "OK"}}
+		],
+		"Fans": [
+			{"MemberId": "0", "Name": "Fan", "Reading": 5400, "ReadingUnits": "RPM", "Status": {"State": "Enabled", "Health": "OK"}},
+			{"MemberId": "1", "Name": "Fan", "Reading": 35, "ReadingUnits": "Percent", "Status": {"State": "Enabled", "Health": "Critical"}}
+		]
+	}`,
+}
+
+func TestRedfishCollector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := redfishResources[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	url := *redfishURL
+	*redfishURL = server.URL
+	defer func() { *redfishURL = url }()
+
+	c, err := NewRedfishCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Gathering fails on duplicate series.
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NodeCollector{Collectors: map[string]Collector{"redfish": c}, logger: log.NewNopLogger()})
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "node_bmc_") {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetName()+"="+l.GetValue())
+			}
+			got = append(got, family.GetName()+"{"+strings.Join(labels, ",")+"}")
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"node_bmc_chassis_health{chassis=1}",
+		"node_bmc_fan_health{chassis=1,fan=0,name=Fan}",
+		"node_bmc_fan_health{chassis=1,fan=1,name=Fan}",
+		"node_bmc_fan_speed_percent{chassis=1,fan=1,name=Fan}",
+		"node_bmc_fan_speed_rpm{chassis=1,fan=0,name=Fan}",
+		"node_bmc_power_consumed_watts{chassis=1,control=0,name=System Power Control}",
+		"node_bmc_power_supply_health{chassis=1,name=Power Supply,power_supply=0}",
+		"node_bmc_power_supply_health{chassis=1,name=Power Supply,power_supply=1}",
+		"node_bmc_temperature_celsius{chassis=1,name=CPU Temp,sensor=/redfish/v1/Chassis/1/Thermal#/Temperatures/0}",
+		"node_bmc_temperature_celsius{chassis=1,name=CPU Temp,sensor=/redfish/v1/Chassis/1/Thermal#/Temperatures/1}",
+		"node_bmc_temperature_health{chassis=1,name=CPU Temp,sensor=/redfish/v1/Chassis/1/Thermal#/Temperatures/0}",
+		"node_bmc_temperature_health{chassis=1,name=CPU Temp,sensor=/redfish/v1/Chassis/1/Thermal#/Temperatures/1}",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	for _, family := range families {
+		if family.GetName() == "node_bmc_fan_health" {
+			if v := family.GetMetric()[1].GetGauge().GetValue(); v != 2 {
+				t.Errorf("expected critical fan health 2, got %v", v)
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noresctrl
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const resctrlSubsystem = "resctrl"
+
+type resctrlCollector struct {
+	llcOccupancy *prometheus.Desc
+	mbmTotal     *prometheus.Desc
+	mbmLocal     *prometheus.Desc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector(resctrlSubsystem, defaultDisabled, NewResctrlCollector)
+}
+
+// NewResctrlCollector returns a new Collector exposing the cache occupancy and
+// memory bandwidth of resctrl groups, i.e. Intel RDT or AMD PQoS.
+func NewResctrlCollector(logger log.Logger) (Collector, error) {
+	labels := []string{"ctrl_group", "mon_group", "domain"}
+	return &resctrlCollector{
+		llcOccupancy: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resctrlSubsystem, "llc_occupancy_bytes"),
+			"Last level cache used by the tasks of the resctrl group.",
+			labels, nil,
+		),
+		mbmTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resctrlSubsystem, "mbm_total_bytes_total"),
+			"Memory bandwidth used by the tasks of the resctrl group.",
+			labels, nil,
+		),
+		mbmLocal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resctrlSubsystem, "mbm_local_bytes_total"),
+			"Memory bandwidth to the local NUMA node used by the tasks of the resctrl group.",
+			labels, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *resctrlCollector) Update(ch chan<- prometheus.Metric) error {
+	root := sysFilePath("fs/resctrl")
+	if _, err := os.Stat(filepath.Join(root, "info")); err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "resctrl not mounted", "path", root)
+			return ErrNoData
+		}
+		return err
+	}
+
+	// The root directory is the default control group, other control groups
+	// are its subdirectories besides the resctrl files.
+	ctrlGroups := map[string]string{"/": root}
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return fmt.Errorf("failed to list resctrl groups: %w", err)
+	}
+	for _, entry := range entries {
+		switch entry.Name() {
+		case "info", "mon_data", "mon_groups":
+			continue
+		}
+		if entry.IsDir() {
+			ctrlGroups[entry.Name()] = filepath.Join(root, entry.Name())
+		}
+	}
+
+	for ctrlGroup, dir := range ctrlGroups {
+		if err := c.updateGroup(ch, dir, ctrlGroup, ""); err != nil {
+			return err
+		}
+		monGroups, err := ioutil.ReadDir(filepath.Join(dir, "mon_groups"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to list resctrl monitoring groups: %w", err)
+		}
+		for _, monGroup := range monGroups {
+			if err := c.updateGroup(ch, filepath.Join(dir, "mon_groups", monGroup.Name()), ctrlGroup, monGroup.Name()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *resctrlCollector) updateGroup(ch chan<- prometheus.Metric, dir, ctrlGroup, monGroup string) error {
+	domains, err := filepath.Glob(filepath.Join(dir, "mon_data", "mon_L3_*"))
+	if err != nil {
+		return err
+	}
+	for _, domainDir := range domains {
+		domain := strings.TrimPrefix(filepath.Base(domainDir), "mon_L3_")
+		if id, err := strconv.Atoi(domain); err == nil {
+			domain = strconv.Itoa(id)
+		}
+		for desc, m := range map[*prometheus.Desc]struct {
+			file      string
+			valueType prometheus.ValueType
+		}{
+			c.llcOccupancy: {"llc_occupancy", prometheus.GaugeValue},
+			c.mbmTotal:     {"mbm_total_bytes", prometheus.CounterValue},
+			c.mbmLocal:     {"mbm_local_bytes", prometheus.CounterValue},
+		} {
+			value, err := readUintFromFile(filepath.Join(domainDir, m.file))
+			if err != nil {
+				// Files of unsupported events are missing, events that
+				// can't be read return "Unavailable".
+				level.Debug(c.logger).Log("msg", "failed to read resctrl monitoring data", "path", filepath.Join(domainDir, m.file), "err", err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(desc, m.valueType, float64(value), ctrlGroup, monGroup, domain)
+		}
+	}
+	return nil
+}
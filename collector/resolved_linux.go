@@ -0,0 +1,247 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noresolved
+
+package collector
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/godbus/dbus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	resolvedSubsystem  = "resolved"
+	resolvedDbusObject = "org.freedesktop.resolve1"
+	resolvedDbusPath   = "/org/freedesktop/resolve1"
+)
+
+// resolvedDNSSECVerdicts are the fields of the DNSSECStatistics property, in order.
+var resolvedDNSSECVerdicts = []string{"secure", "insecure", "bogus", "indeterminate"}
+
+type resolvedCollector struct {
+	transactionsCurrent *prometheus.Desc
+	transactions        *prometheus.Desc
+	cacheSize           *prometheus.Desc
+	cacheHits           *prometheus.Desc
+	cacheMisses         *prometheus.Desc
+	dnssecVerdicts      *prometheus.Desc
+	linkDNSSECSupported *prometheus.Desc
+	linkInfo            *prometheus.Desc
+	logger              log.Logger
+}
+
+type resolvedStatistics struct {
+	transactionsCurrent uint64
+	transactions        uint64
+	cacheSize           uint64
+	cacheHits           uint64
+	cacheMisses         uint64
+	dnssecVerdicts      []uint64
+}
+
+type resolvedLink struct {
+	device          string
+	dnssec          string
+	dnssecSupported bool
+	dnsOverTLS      string
+}
+
+type resolvedInterface interface {
+	statistics() (*resolvedStatistics, error)
+	links() ([]resolvedLink, error)
+}
+
+type resolvedDbus struct {
+	conn   *dbus.Conn
+	object dbus.BusObject
+	logger log.Logger
+}
+
+func init() {
+	registerCollector(resolvedSubsystem, defaultDisabled, NewResolvedCollector)
+}
+
+// NewResolvedCollector returns a new Collector exposing systemd-resolved statistics.
+func NewResolvedCollector(logger log.Logger) (Collector, error) {
+	return &resolvedCollector{
+		transactionsCurrent: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resolvedSubsystem, "transactions_current"),
+			"Number of DNS transactions currently in progress.",
+			nil, nil,
+		),
+		transactions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resolvedSubsystem, "transactions_total"),
+			"Total number of DNS transactions.",
+			nil, nil,
+		),
+		cacheSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resolvedSubsystem, "cache_size"),
+			"Number of entries in the DNS cache.",
+			nil, nil,
+		),
+		cacheHits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resolvedSubsystem, "cache_hits_total"),
+			"Total number of DNS cache hits.",
+			nil, nil,
+		),
+		cacheMisses: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resolvedSubsystem, "cache_misses_total"),
+			"Total number of DNS cache misses.",
+			nil, nil,
+		),
+		dnssecVerdicts: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resolvedSubsystem, "dnssec_verdicts_total"),
+			"Total number of DNSSEC validations by verdict.",
+			[]string{"verdict"}, nil,
+		),
+		linkDNSSECSupported: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resolvedSubsystem, "link_dnssec_supported"),
+			"Whether the DNS servers of the link support DNSSEC.",
+			[]string{"device"}, nil,
+		),
+		linkInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, resolvedSubsystem, "link_info"),
+			"DNS settings of the link, value is always 1.",
+			[]string{"device", "dnssec", "dns_over_tls"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *resolvedCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := newSystemBusPrivate()
+	if err != nil {
+		return fmt.Errorf("unable to connect to dbus: %w", err)
+	}
+	defer conn.Close()
+
+	return c.collect(ch, &resolvedDbus{
+		conn:   conn,
+		object: conn.Object(resolvedDbusObject, dbus.ObjectPath(resolvedDbusPath)),
+		logger: c.logger,
+	})
+}
+
+func (c *resolvedCollector) collect(ch chan<- prometheus.Metric, r resolvedInterface) error {
+	stats, err := r.statistics()
+	if err != nil {
+		return fmt.Errorf("unable to get resolved statistics: %w", err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.transactionsCurrent, prometheus.GaugeValue, float64(stats.transactionsCurrent))
+	ch <- prometheus.MustNewConstMetric(c.transactions, prometheus.CounterValue, float64(stats.transactions))
+	ch <- prometheus.MustNewConstMetric(c.cacheSize, prometheus.GaugeValue, float64(stats.cacheSize))
+	ch <- prometheus.MustNewConstMetric(c.cacheHits, prometheus.CounterValue, float64(stats.cacheHits))
+	ch <- prometheus.MustNewConstMetric(c.cacheMisses, prometheus.CounterValue, float64(stats.cacheMisses))
+	for i, verdict := range resolvedDNSSECVerdicts {
+		if i < len(stats.dnssecVerdicts) {
+			ch <- prometheus.MustNewConstMetric(c.dnssecVerdicts, prometheus.CounterValue, float64(stats.dnssecVerdicts[i]), verdict)
+		}
+	}
+
+	links, err := r.links()
+	if err != nil {
+		return fmt.Errorf("unable to get resolved links: %w", err)
+	}
+	for _, link := range links {
+		supported := 0.0
+		if link.dnssecSupported {
+			supported = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.linkDNSSECSupported, prometheus.GaugeValue, supported, link.device)
+		ch <- prometheus.MustNewConstMetric(c.linkInfo, prometheus.GaugeValue, 1, link.device, link.dnssec, link.dnsOverTLS)
+	}
+
+	return nil
+}
+
+func (r *resolvedDbus) statistics() (*resolvedStatistics, error) {
+	transactions, err := r.uint64sProperty(r.object, "Manager.TransactionStatistics", 2)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := r.uint64sProperty(r.object, "Manager.CacheStatistics", 3)
+	if err != nil {
+		return nil, err
+	}
+	dnssec, err := r.uint64sProperty(r.object, "Manager.DNSSECStatistics", len(resolvedDNSSECVerdicts))
+	if err != nil {
+		return nil, err
+	}
+
+	return &resolvedStatistics{
+		transactionsCurrent: transactions[0],
+		transactions:        transactions[1],
+		cacheSize:           cache[0],
+		cacheHits:           cache[1],
+		cacheMisses:         cache[2],
+		dnssecVerdicts:      dnssec,
+	}, nil
+}
+
+func (r *resolvedDbus) links() ([]resolvedLink, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("could not get network interfaces: %w", err)
+	}
+
+	var links []resolvedLink
+	for _, iface := range ifaces {
+		var path dbus.ObjectPath
+		err := r.object.Call(resolvedDbusObject+".Manager.GetLink", 0, int32(iface.Index)).Store(&path)
+		if err != nil {
+			// Links unknown to resolved, e.g. loopback, can't be queried.
+			level.Debug(r.logger).Log("msg", "unable to get resolved link", "device", iface.Name, "err", err)
+			continue
+		}
+		object := r.conn.Object(resolvedDbusObject, path)
+
+		link := resolvedLink{device: iface.Name}
+		if v, err := object.GetProperty(resolvedDbusObject + ".Link.DNSSECSupported"); err == nil {
+			link.dnssecSupported, _ = v.Value().(bool)
+		}
+		if v, err := object.GetProperty(resolvedDbusObject + ".Link.DNSSEC"); err == nil {
+			link.dnssec, _ = v.Value().(string)
+		}
+		if v, err := object.GetProperty(resolvedDbusObject + ".Link.DNSOverTLS"); err == nil {
+			link.dnsOverTLS, _ = v.Value().(string)
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// uint64sProperty reads a property consisting of a struct of n uint64 values.
+func (r *resolvedDbus) uint64sProperty(object dbus.BusObject, name string, n int) ([]uint64, error) {
+	v, err := object.GetProperty(resolvedDbusObject + "." + name)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := v.Value().([]interface{})
+	if !ok || len(fields) != n {
+		return nil, fmt.Errorf("unexpected value for property %s: %s", name, v.String())
+	}
+	values := make([]uint64, n)
+	for i, f := range fields {
+		if values[i], ok = f.(uint64); !ok {
+			return nil, fmt.Errorf("unexpected value for property %s: %s", name, v.String())
+		}
+	}
+	return values, nil
+}
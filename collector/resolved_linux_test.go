@@ -0,0 +1,68 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noresolved
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type testResolvedInterface struct{}
+
+func (r *testResolvedInterface) statistics() (*resolvedStatistics, error) {
+	return &resolvedStatistics{
+		transactionsCurrent: 1,
+		transactions:        2550,
+		cacheSize:           42,
+		cacheHits:           1800,
+		cacheMisses:         750,
+		dnssecVerdicts:      []uint64{10, 20, 1, 0},
+	}, nil
+}
+
+func (r *testResolvedInterface) links() ([]resolvedLink, error) {
+	return []resolvedLink{
+		{device: "eth0", dnssec: "allow-downgrade", dnssecSupported: true, dnsOverTLS: "no"},
+		{device: "wlan0", dnssec: "no", dnsOverTLS: "opportunistic"},
+	}, nil
+}
+
+func TestResolvedCollectorCollect(t *testing.T) {
+	c, err := NewResolvedCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		if err := c.(*resolvedCollector).collect(ch, &testResolvedInterface{}); err != nil {
+			t.Error(err)
+		}
+		close(ch)
+	}()
+
+	count := 0
+	for range ch {
+		count++
+	}
+
+	// 5 statistics, 4 DNSSEC verdicts and 2 metrics per link.
+	if expected := 5 + len(resolvedDNSSECVerdicts) + 2*2; count != expected {
+		t.Errorf("collect did not generate the expected number of metrics: got %d, expected %d.", count, expected)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nosecureboot
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	secureBootSubsystem = "secureboot"
+	// efiGlobalVariable is the vendor GUID of the EFI global variables.
+	efiGlobalVariable = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+)
+
+type secureBootCollector struct {
+	enabled   *prometheus.Desc
+	setupMode *prometheus.Desc
+	lockdown  *prometheus.Desc
+	logger    log.Logger
+}
+
+func init() {
+	registerCollector(secureBootSubsystem, defaultDisabled, NewSecureBootCollector)
+}
+
+// NewSecureBootCollector returns a new Collector exposing the Secure Boot
+// and kernel lockdown state.
+func NewSecureBootCollector(logger log.Logger) (Collector, error) {
+	return &secureBootCollector{
+		enabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, secureBootSubsystem, "enabled"),
+			"Whether the system was booted with UEFI Secure Boot enabled.",
+			nil, nil,
+		),
+		setupMode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, secureBootSubsystem, "setup_mode"),
+			"Whether the UEFI firmware is in setup mode, i.e. no platform key is enrolled.",
+			nil, nil,
+		),
+		lockdown: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, secureBootSubsystem, "kernel_lockdown"),
+			"Kernel lockdown mode, the active mode has a value of 1.",
+			[]string{"mode"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *secureBootCollector) Update(ch chan<- prometheus.Metric) error {
+	found := false
+
+	for desc, name := range map[*prometheus.Desc]string{c.enabled: "SecureBoot", c.setupMode: "SetupMode"} {
+		value, err := readEFIBoolVariable(name)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to read EFI variable %s: %w", name, err)
+			}
+			level.Debug(c.logger).Log("msg", "EFI variable not found, not booted with UEFI?", "variable", name)
+			continue
+		}
+		found = true
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+
+	// securityfs lists the available modes, with the active one in brackets.
+	lockdown, err := ioutil.ReadFile(sysFilePath("kernel/security/lockdown"))
+	switch {
+	case err == nil:
+		found = true
+		for _, mode := range strings.Fields(string(lockdown)) {
+			active := 0.0
+			if strings.HasPrefix(mode, "[") && strings.HasSuffix(mode, "]") {
+				active = 1
+				mode = strings.Trim(mode, "[]")
+			}
+			ch <- prometheus.MustNewConstMetric(c.lockdown, prometheus.GaugeValue, active, mode)
+		}
+	case os.IsNotExist(err):
+		level.Debug(c.logger).Log("msg", "kernel lockdown not supported or securityfs not mounted")
+	default:
+		return fmt.Errorf("failed to read kernel lockdown state: %w", err)
+	}
+
+	if !found {
+		return ErrNoData
+	}
+	return nil
+}
+
+// readEFIBoolVariable reads a single byte EFI global variable. efivarfs
+// prefixes the value with its 4 byte attributes.
+func readEFIBoolVariable(name string) (float64, error) {
+	data, err := ioutil.ReadFile(sysFilePath("firmware/efi/efivars/" + name + "-" + efiGlobalVariable))
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 5 {
+		return 0, fmt.Errorf("unexpected length %d", len(data))
+	}
+	return float64(data[4]), nil
+}
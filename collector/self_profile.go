@@ -0,0 +1,117 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var selfProfileEnabled = kingpin.Flag("collector.self-profile", "Expose the CPU time and the bytes allocated by every collector. Allocations of collectors running in parallel are attributed to each other, use --collector.max-parallel=1 for exact numbers.").Default("false").Bool()
+
+var (
+	scrapeCPUDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_cpu_seconds_total"),
+		"node_exporter: CPU time spent by the collector in its scrapes.",
+		[]string{"collector"},
+		nil,
+	)
+	scrapeAllocatedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_allocated_bytes_total"),
+		"node_exporter: Bytes allocated during the scrapes of the collector.",
+		[]string{"collector"},
+		nil,
+	)
+)
+
+// heapAllocsMetric is the cumulative count of bytes allocated on the heap.
+const heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+// collectorProfile is the accumulated usage of a collector.
+type collectorProfile struct {
+	cpu       time.Duration
+	allocated uint64
+}
+
+var (
+	collectorProfilesMtx sync.Mutex
+	collectorProfiles    = map[string]*collectorProfile{}
+)
+
+// profiledCollector measures the Update of a collector. The CPU time is the
+// one of the thread running Update, which is locked to it, so that work of
+// goroutines started by the collector is not included.
+type profiledCollector struct {
+	name string
+	c    Collector
+}
+
+func (p profiledCollector) Update(ch chan<- prometheus.Metric) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	sample := []metrics.Sample{{Name: heapAllocsMetric}}
+	metrics.Read(sample)
+	allocsBefore := heapAllocs(sample[0])
+	cpuBefore, cpuOK := threadCPUTime()
+
+	err := p.c.Update(ch)
+
+	cpuAfter, _ := threadCPUTime()
+	metrics.Read(sample)
+	allocsAfter := heapAllocs(sample[0])
+
+	collectorProfilesMtx.Lock()
+	defer collectorProfilesMtx.Unlock()
+	profile, ok := collectorProfiles[p.name]
+	if !ok {
+		profile = &collectorProfile{}
+		collectorProfiles[p.name] = profile
+	}
+	if cpuOK {
+		profile.cpu += cpuAfter - cpuBefore
+	}
+	profile.allocated += allocsAfter - allocsBefore
+	return err
+}
+
+func heapAllocs(s metrics.Sample) uint64 {
+	if s.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s.Value.Uint64()
+}
+
+// collectProfile sends the accumulated usage of the collector.
+func collectProfile(name string, ch chan<- prometheus.Metric) {
+	collectorProfilesMtx.Lock()
+	profile, ok := collectorProfiles[name]
+	var p collectorProfile
+	if ok {
+		p = *profile
+	}
+	collectorProfilesMtx.Unlock()
+	if !ok {
+		return
+	}
+	if _, cpuOK := threadCPUTime(); cpuOK {
+		ch <- prometheus.MustNewConstMetric(scrapeCPUDesc, prometheus.CounterValue, p.cpu.Seconds(), name)
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeAllocatedDesc, prometheus.CounterValue, float64(p.allocated), name)
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SMBIOS structure types, see the DMTF SMBIOS reference specification.
+const (
+	smbiosTypeMemoryDevice = 17
+)
+
+// smbiosMemoryTypes are the names of the memory device types.
+var smbiosMemoryTypes = map[byte]string{
+	0x01: "Other", 0x02: "Unknown", 0x03: "DRAM", 0x04: "EDRAM", 0x05: "VRAM",
+	0x06: "SRAM", 0x07: "RAM", 0x08: "ROM", 0x09: "Flash", 0x0a: "EEPROM",
+	0x0b: "FEPROM", 0x0c: "EPROM", 0x0d: "CDRAM", 0x0e: "3DRAM", 0x0f: "SDRAM",
+	0x10: "SGRAM", 0x11: "RDRAM", 0x12: "DDR", 0x13: "DDR2", 0x14: "DDR2 FB-DIMM",
+	0x18: "DDR3", 0x19: "FBD2", 0x1a: "DDR4", 0x1b: "LPDDR", 0x1c: "LPDDR2",
+	0x1d: "LPDDR3", 0x1e: "LPDDR4", 0x1f: "Logical non-volatile device",
+	0x20: "HBM", 0x21: "HBM2", 0x22: "DDR5", 0x23: "LPDDR5",
+}
+
+// smbiosMemoryDevice is a SMBIOS memory device (type 17) structure.
+type smbiosMemoryDevice struct {
+	Locator      string
+	BankLocator  string
+	Installed    bool
+	Type         string
+	Manufacturer string
+	SerialNumber string
+	PartNumber   string
+	// Size in bytes, 0 if unknown.
+	Size uint64
+	// Speed and ConfiguredSpeed in MT/s, 0 if unknown.
+	Speed           uint64
+	ConfiguredSpeed uint64
+}
+
+// readSMBIOSStructures returns the raw SMBIOS structures of the given type
+// in table order. The kernel exports them as /sys/firmware/dmi/entries/<type>-<n>,
+// readable by root only.
+func readSMBIOSStructures(typ int) ([][]byte, error) {
+	prefix := strconv.Itoa(typ) + "-"
+	entries, err := filepath.Glob(sysFilePath(filepath.Join("firmware/dmi/entries", prefix+"[0-9]*")))
+	if err != nil {
+		return nil, err
+	}
+	instance := func(path string) int {
+		n, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), prefix))
+		return n
+	}
+	sort.Slice(entries, func(i, j int) bool { return instance(entries[i]) < instance(entries[j]) })
+
+	structures := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		raw, err := ioutil.ReadFile(filepath.Join(entry, "raw"))
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) < 4 || int(raw[1]) > len(raw) || raw[0] != byte(typ) {
+			return nil, fmt.Errorf("invalid SMBIOS structure %s", entry)
+		}
+		structures = append(structures, raw)
+	}
+	return structures, nil
+}
+
+// smbiosString returns the string referenced by the string number at offset
+// of a raw structure. The strings follow the formatted area, each terminated
+// by a NUL byte.
+func smbiosString(raw []byte, offset int) string {
+	if offset >= int(raw[1]) || raw[offset] == 0 {
+		return ""
+	}
+	strs := strings.Split(string(raw[raw[1]:]), "\x00")
+	if n := int(raw[offset]); n <= len(strs) {
+		return strings.TrimSpace(strs[n-1])
+	}
+	return ""
+}
+
+// readSMBIOSMemoryDevices returns the memory devices of the system, one per
+// memory slot.
+func readSMBIOSMemoryDevices() ([]smbiosMemoryDevice, error) {
+	structures, err := readSMBIOSStructures(smbiosTypeMemoryDevice)
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]smbiosMemoryDevice, 0, len(structures))
+	for _, raw := range structures {
+		length := int(raw[1])
+		if length < 0x15 {
+			return nil, fmt.Errorf("short SMBIOS memory device structure, len=%d", length)
+		}
+		device := smbiosMemoryDevice{
+			Locator:      smbiosString(raw, 0x10),
+			BankLocator:  smbiosString(raw, 0x11),
+			Type:         smbiosMemoryTypes[raw[0x12]],
+			Manufacturer: smbiosString(raw, 0x17),
+			SerialNumber: smbiosString(raw, 0x18),
+			PartNumber:   smbiosString(raw, 0x1a),
+		}
+		size := binary.LittleEndian.Uint16(raw[0x0c:])
+		device.Installed = size != 0
+		switch {
+		case size == 0xffff:
+			// Installed, but of unknown size.
+		case size == 0x7fff && length >= 0x20:
+			device.Size = uint64(binary.LittleEndian.Uint32(raw[0x1c:])&0x7fffffff) << 20
+		case size&0x8000 != 0:
+			device.Size = uint64(size&0x7fff) << 10
+		default:
+			device.Size = uint64(size) << 20
+		}
+		// Speeds that don't fit 16 bits are in the extended fields of
+		// SMBIOS 3.3.
+		if length >= 0x17 {
+			device.Speed = smbiosSpeed(raw, 0x15, 0x54)
+		}
+		if length >= 0x22 {
+			device.ConfiguredSpeed = smbiosSpeed(raw, 0x20, 0x58)
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+func smbiosSpeed(raw []byte, offset, extendedOffset int) uint64 {
+	speed := binary.LittleEndian.Uint16(raw[offset:])
+	if speed != 0xffff {
+		return uint64(speed)
+	}
+	if int(raw[1]) < extendedOffset+4 {
+		return 0
+	}
+	return uint64(binary.LittleEndian.Uint32(raw[extendedOffset:]) & 0x7fffffff)
+}
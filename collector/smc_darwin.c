@@ -0,0 +1,154 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nosmc
+
+#include <IOKit/IOKitLib.h>
+#include <string.h>
+#include <smc_darwin.h>
+
+// The AppleSMC user client has no public headers, the layout of its
+// structures is the one used by tools like smcFanControl.
+#define KERNEL_INDEX_SMC	2
+#define SMC_CMD_READ_BYTES	5
+#define SMC_CMD_READ_INDEX	8
+#define SMC_CMD_READ_KEYINFO	9
+
+typedef struct {
+	char		major;
+	char		minor;
+	char		build;
+	char		reserved[1];
+	uint16_t	release;
+} SMCKeyDataVers;
+
+typedef struct {
+	uint16_t	version;
+	uint16_t	length;
+	uint32_t	cpuPLimit;
+	uint32_t	gpuPLimit;
+	uint32_t	memPLimit;
+} SMCKeyDataPLimit;
+
+typedef struct {
+	uint32_t	dataSize;
+	uint32_t	dataType;
+	char		dataAttributes;
+} SMCKeyDataKeyInfo;
+
+typedef struct {
+	uint32_t		key;
+	SMCKeyDataVers		vers;
+	SMCKeyDataPLimit	pLimitData;
+	SMCKeyDataKeyInfo	keyInfo;
+	char			result;
+	char			status;
+	char			data8;
+	uint32_t		data32;
+	uint8_t			bytes[32];
+} SMCKeyData;
+
+static uint32_t _smc_key_to_int(const char *key) {
+	return ((uint32_t)key[0] << 24) | ((uint32_t)key[1] << 16) | ((uint32_t)key[2] << 8) | (uint32_t)key[3];
+}
+
+static void _smc_int_to_key(uint32_t value, char key[5]) {
+	key[0] = (char)(value >> 24);
+	key[1] = (char)(value >> 16);
+	key[2] = (char)(value >> 8);
+	key[3] = (char)value;
+	key[4] = '\0';
+}
+
+static int _smc_call(io_connect_t conn, SMCKeyData *in, SMCKeyData *out) {
+	size_t size = sizeof(SMCKeyData);
+
+	if (IOConnectCallStructMethod(conn, KERNEL_INDEX_SMC, in, sizeof(SMCKeyData), out, &size) != kIOReturnSuccess) {
+		return -1;
+	}
+	// A result other than 0 is returned for keys the SMC does not know.
+	if (out->result != 0) {
+		return -1;
+	}
+	return 0;
+}
+
+int _smc_open(io_connect_t *conn) {
+	io_service_t service = IOServiceGetMatchingService(kIOMasterPortDefault, IOServiceMatching("AppleSMC"));
+
+	if (service == 0) {
+		return -1;
+	}
+	kern_return_t ret = IOServiceOpen(service, mach_task_self(), 0, conn);
+	IOObjectRelease(service);
+	if (ret != kIOReturnSuccess) {
+		return -1;
+	}
+	return 0;
+}
+
+void _smc_close(io_connect_t conn) {
+	IOServiceClose(conn);
+}
+
+int _smc_read_key(io_connect_t conn, const char *key, SMCValue *val) {
+	SMCKeyData in, out;
+
+	memset(&in, 0, sizeof(in));
+	memset(&out, 0, sizeof(out));
+	memset(val, 0, sizeof(*val));
+	in.key = _smc_key_to_int(key);
+	in.data8 = SMC_CMD_READ_KEYINFO;
+	if (_smc_call(conn, &in, &out) == -1) {
+		return -1;
+	}
+	strncpy(val->key, key, 4);
+	val->size = out.keyInfo.dataSize;
+	_smc_int_to_key(out.keyInfo.dataType, val->type);
+	if (val->size > sizeof(val->bytes)) {
+		return -1;
+	}
+
+	in.keyInfo.dataSize = val->size;
+	in.data8 = SMC_CMD_READ_BYTES;
+	memset(&out, 0, sizeof(out));
+	if (_smc_call(conn, &in, &out) == -1) {
+		return -1;
+	}
+	memcpy(val->bytes, out.bytes, val->size);
+	return 0;
+}
+
+int _smc_key_count(io_connect_t conn, uint32_t *count) {
+	SMCValue val;
+
+	if (_smc_read_key(conn, "#KEY", &val) == -1 || val.size != 4) {
+		return -1;
+	}
+	*count = ((uint32_t)val.bytes[0] << 24) | ((uint32_t)val.bytes[1] << 16) | ((uint32_t)val.bytes[2] << 8) | (uint32_t)val.bytes[3];
+	return 0;
+}
+
+int _smc_key_at(io_connect_t conn, uint32_t index, char key[5]) {
+	SMCKeyData in, out;
+
+	memset(&in, 0, sizeof(in));
+	memset(&out, 0, sizeof(out));
+	in.data8 = SMC_CMD_READ_INDEX;
+	in.data32 = index;
+	if (_smc_call(conn, &in, &out) == -1) {
+		return -1;
+	}
+	_smc_int_to_key(out.key, key);
+	return 0;
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nosmc
+
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// #cgo LDFLAGS: -framework IOKit
+// #include <stdlib.h>
+// #include "smc_darwin.h"
+import "C"
+
+const smcSubsystem = "smc"
+
+// smcPowerKeys are the keys of the power sensors known on Intel and Apple
+// Silicon machines, the keys of other sensors are undocumented.
+var smcPowerKeys = map[string]string{
+	"PSTR": "system",
+	"PCPC": "cpu_package",
+	"PCPG": "gpu",
+	"PPBR": "battery",
+	"PDTR": "dc_in",
+}
+
+type smcCollector struct {
+	temperature *prometheus.Desc
+	fanSpeed    *prometheus.Desc
+	power       *prometheus.Desc
+	logger      log.Logger
+}
+
+func init() {
+	registerCollector(smcSubsystem, defaultDisabled, NewSMCCollector)
+}
+
+// NewSMCCollector returns a new Collector exposing the temperature, fan and
+// power sensors of the System Management Controller of Macs.
+func NewSMCCollector(logger log.Logger) (Collector, error) {
+	return &smcCollector{
+		temperature: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, smcSubsystem, "temperature_celsius"),
+			"Temperature of the SMC sensor, the keys of CPU and GPU sensors differ between models.",
+			[]string{"key"}, nil,
+		),
+		fanSpeed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, smcSubsystem, "fan_speed_rpm"),
+			"Speed of the fan, actual is the current speed.",
+			[]string{"fan", "type"}, nil,
+		),
+		power: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, smcSubsystem, "power_watts"),
+			"Power drawn as measured by the SMC.",
+			[]string{"key", "sensor"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *smcCollector) Update(ch chan<- prometheus.Metric) error {
+	var conn C.io_connect_t
+	if C._smc_open(&conn) == -1 {
+		level.Debug(c.logger).Log("msg", "failed to open AppleSMC")
+		return ErrNoData
+	}
+	defer C._smc_close(conn)
+
+	var count C.uint32_t
+	if C._smc_key_count(conn, &count) == -1 {
+		return errors.New("failed to get number of SMC keys")
+	}
+	key := (*C.char)(C.malloc(5))
+	defer C.free(unsafe.Pointer(key))
+	for i := C.uint32_t(0); i < count; i++ {
+		if C._smc_key_at(conn, i, key) == -1 {
+			continue
+		}
+		name := C.GoString(key)
+		if !strings.HasPrefix(name, "T") {
+			continue
+		}
+		value, err := readSMCKey(conn, name)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read SMC key", "key", name, "err", err)
+			continue
+		}
+		// Sensors which are not fitted read as zero or implausible values.
+		if value <= 0 || value >= 150 {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.temperature, prometheus.GaugeValue, value, name)
+	}
+
+	// Macs without fans like the MacBook Air lack FNum.
+	if fans, err := readSMCKey(conn, "FNum"); err == nil {
+		for fan := 0; fan < int(fans); fan++ {
+			for typ, suffix := range map[string]string{"actual": "Ac", "min": "Mn", "max": "Mx", "target": "Tg"} {
+				value, err := readSMCKey(conn, fmt.Sprintf("F%d%s", fan, suffix))
+				if err != nil {
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(c.fanSpeed, prometheus.GaugeValue, value, strconv.Itoa(fan), typ)
+			}
+		}
+	}
+
+	for name, sensor := range smcPowerKeys {
+		value, err := readSMCKey(conn, name)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.power, prometheus.GaugeValue, value, name, sensor)
+	}
+	return nil
+}
+
+// readSMCKey reads a key and decodes its value.
+func readSMCKey(conn C.io_connect_t, name string) (float64, error) {
+	key := C.CString(name)
+	defer C.free(unsafe.Pointer(key))
+
+	var val C.SMCValue
+	if C._smc_read_key(conn, key, &val) == -1 {
+		return 0, errors.New("failed to read key")
+	}
+	data := C.GoBytes(unsafe.Pointer(&val.bytes[0]), C.int(val.size))
+	return decodeSMCValue(C.GoString(&val._type[0]), data)
+}
+
+// decodeSMCValue decodes the value of a key by its type. Intel Macs use big
+// endian fixed point types, Apple Silicon Macs little endian floats.
+func decodeSMCValue(typ string, data []byte) (float64, error) {
+	switch typ {
+	case "flt ":
+		if len(data) == 4 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), nil
+		}
+	case "ui8 ":
+		if len(data) == 1 {
+			return float64(data[0]), nil
+		}
+	case "ui16":
+		if len(data) == 2 {
+			return float64(binary.BigEndian.Uint16(data)), nil
+		}
+	case "ui32":
+		if len(data) == 4 {
+			return float64(binary.BigEndian.Uint32(data)), nil
+		}
+	case "sp78":
+		if len(data) == 2 {
+			return float64(int16(binary.BigEndian.Uint16(data))) / 256, nil
+		}
+	case "sp96":
+		if len(data) == 2 {
+			return float64(int16(binary.BigEndian.Uint16(data))) / 64, nil
+		}
+	case "fpe2":
+		if len(data) == 2 {
+			return float64(binary.BigEndian.Uint16(data)) / 4, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported type %q of size %d", typ, len(data))
+}
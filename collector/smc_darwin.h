@@ -0,0 +1,28 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <IOKit/IOKitLib.h>
+#include <stdint.h>
+
+typedef struct {
+	char		key[5];
+	char		type[5];
+	uint32_t	size;
+	uint8_t		bytes[32];
+} SMCValue;
+
+int _smc_open(io_connect_t *conn);
+void _smc_close(io_connect_t conn);
+int _smc_key_count(io_connect_t conn, uint32_t *count);
+int _smc_key_at(io_connect_t conn, uint32_t index, char key[5]);
+int _smc_read_key(io_connect_t conn, const char *key, SMCValue *val);
@@ -16,9 +16,14 @@
 package collector
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -88,9 +93,119 @@ func (c *sockStatCollector) Update(ch chan<- prometheus.Metric) error {
 		c.update(ch, s.isIPv6, s.stat)
 	}
 
+	return c.updateMemoryPressure(ch)
+}
+
+// updateMemoryPressure exports the memory thresholds of TCP and UDP sockets
+// and whether the protocols are currently under memory pressure.
+func (c *sockStatCollector) updateMemoryPressure(ch chan<- prometheus.Metric) error {
+	for _, protocol := range []string{"TCP", "UDP"} {
+		limits, err := readSockMemLimits(procFilePath("sys/net/ipv4/" + strings.ToLower(protocol) + "_mem"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				level.Debug(c.logger).Log("msg", "socket memory limits not found, skipping", "protocol", protocol)
+				continue
+			}
+			return fmt.Errorf("failed to get %s memory limits: %w", protocol, err)
+		}
+		for i, threshold := range []string{"min", "pressure", "max"} {
+			ch <- prometheus.MustNewConstMetric(
+				prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, sockStatSubsystem, fmt.Sprintf("%s_mem_%s_pages", protocol, threshold)),
+					fmt.Sprintf("The %s memory threshold of %s sockets in pages.", threshold, protocol),
+					nil, nil,
+				),
+				prometheus.GaugeValue,
+				float64(limits[i]),
+			)
+		}
+	}
+
+	file, err := os.Open(procFilePath("net/protocols"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			level.Debug(c.logger).Log("msg", "net/protocols not found, skipping")
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	pressure, err := parseProtocolsMemoryPressure(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse net/protocols: %w", err)
+	}
+	desc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, sockStatSubsystem, "memory_pressure"),
+		"Whether the protocol is under memory pressure.",
+		[]string{"protocol"}, nil,
+	)
+	for protocol, underPressure := range pressure {
+		v := 0.0
+		if underPressure {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, protocol)
+	}
 	return nil
 }
 
+// readSockMemLimits reads the min, pressure and max values of a
+// tcp_mem or udp_mem sysctl.
+func readSockMemLimits(path string) ([3]uint64, error) {
+	var limits [3]uint64
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return limits, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != len(limits) {
+		return limits, fmt.Errorf("unexpected number of fields in %s: %d", path, len(fields))
+	}
+	for i, f := range fields {
+		if limits[i], err = strconv.ParseUint(f, 10, 64); err != nil {
+			return limits, err
+		}
+	}
+	return limits, nil
+}
+
+// parseProtocolsMemoryPressure returns the "press" column of /proc/net/protocols
+// for all protocols implementing memory pressure.
+func parseProtocolsMemoryPressure(r io.Reader) (map[string]bool, error) {
+	pressure := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	header := strings.Fields(scanner.Text())
+	column := -1
+	for i, name := range header {
+		if name == "press" {
+			column = i
+		}
+	}
+	if column < 0 {
+		return nil, errors.New("press column not found")
+	}
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) <= column {
+			continue
+		}
+		switch fields[column] {
+		case "yes":
+			pressure[fields[0]] = true
+		case "no":
+			pressure[fields[0]] = false
+		}
+	}
+	return pressure, scanner.Err()
+}
+
 func (c *sockStatCollector) update(ch chan<- prometheus.Metric, isIPv6 bool, s *procfs.NetSockstat) {
 	if s == nil {
 		// IPv6 disabled or similar; nothing to do.
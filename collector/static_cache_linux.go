@@ -0,0 +1,173 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var staticCacheEnabled = kingpin.Flag("collector.static-cache", "Cache sysfs values which only change on hotplug, e.g. DMI strings and the CPU topology, until the kernel sends a uevent for their subsystem.").Default("false").Bool()
+
+// staticFile is the cached content of a file, files which do not exist are
+// cached as well.
+type staticFile struct {
+	data []byte
+	err  error
+}
+
+// staticCache caches the content of sysfs files by the subsystem of their
+// device. A uevent of a subsystem, e.g. of a CPU going online, drops the
+// files of that subsystem.
+type staticCache struct {
+	mtx        sync.Mutex
+	subsystems map[string]map[string]staticFile
+	once       sync.Once
+	listening  bool // guarded by mtx
+	// generation is incremented by every invalidation, guarded by mtx.
+	generation uint64
+}
+
+var staticValues = &staticCache{subsystems: map[string]map[string]staticFile{}}
+
+// readStaticFile returns the content of a file of a device of the subsystem,
+// which is only read again after a uevent of the subsystem. Without
+// --collector.static-cache, or if uevents can't be received, it is read every
+// time.
+func readStaticFile(subsystem, path string) ([]byte, error) {
+	if !*staticCacheEnabled || !staticValues.listen() {
+		return ioutil.ReadFile(path)
+	}
+	return staticValues.read(subsystem, path)
+}
+
+// readStaticUint is readUintFromFile using the static cache.
+func readStaticUint(subsystem, path string) (uint64, error) {
+	data, err := readStaticFile(subsystem, path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func (c *staticCache) read(subsystem, path string) ([]byte, error) {
+	c.mtx.Lock()
+	file, ok := c.subsystems[subsystem][path]
+	generation := c.generation
+	c.mtx.Unlock()
+	if ok {
+		return file.data, file.err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	// Errors other than missing files, e.g. EAGAIN of some drivers, are not
+	// static.
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	c.store(subsystem, path, generation, staticFile{data, err})
+	return data, err
+}
+
+// store caches a file read at the generation. If the cache was invalidated
+// since, the file may have been read before the change of the uevent and is
+// not cached.
+func (c *staticCache) store(subsystem, path string, generation uint64, file staticFile) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if c.subsystems[subsystem] == nil {
+		c.subsystems[subsystem] = map[string]staticFile{}
+	}
+	c.subsystems[subsystem][path] = file
+}
+
+func (c *staticCache) invalidate(subsystem string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.generation++
+	if subsystem == "" {
+		c.subsystems = map[string]map[string]staticFile{}
+		return
+	}
+	delete(c.subsystems, subsystem)
+}
+
+// listen subscribes to the uevents of the kernel once and returns whether
+// the cache can be used.
+func (c *staticCache) listen() bool {
+	c.once.Do(func() {
+		fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+		if err != nil {
+			return
+		}
+		// Group 1 receives the uevents of the kernel, group 2 those
+		// rebroadcast by udev.
+		if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: 1}); err != nil {
+			unix.Close(fd)
+			return
+		}
+		c.listening = true
+		go c.receive(fd)
+	})
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.listening
+}
+
+func (c *staticCache) receive(fd int) {
+	buf := make([]byte, 8192)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err == unix.ENOBUFS {
+			// The socket overran and uevents were lost.
+			c.invalidate("")
+			continue
+		}
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			c.mtx.Lock()
+			c.listening = false
+			c.generation++
+			c.subsystems = map[string]map[string]staticFile{}
+			c.mtx.Unlock()
+			unix.Close(fd)
+			return
+		}
+		c.invalidate(ueventSubsystem(buf[:n]))
+	}
+}
+
+// ueventSubsystem returns the SUBSYSTEM of a uevent, which consists of a
+// header like "add@/devices/system/cpu/cpu1" and NUL separated KEY=value
+// pairs. An unknown subsystem is returned as empty.
+func ueventSubsystem(msg []byte) string {
+	for _, field := range bytes.Split(msg, []byte{0}) {
+		if bytes.HasPrefix(field, []byte("SUBSYSTEM=")) {
+			return string(field[len("SUBSYSTEM="):])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUeventSubsystem(t *testing.T) {
+	msg := []byte("online@/devices/system/cpu/cpu1\x00ACTION=online\x00DEVPATH=/devices/system/cpu/cpu1\x00SUBSYSTEM=cpu\x00SEQNUM=4242\x00")
+	if got := ueventSubsystem(msg); got != "cpu" {
+		t.Errorf("expected subsystem cpu, got %q", got)
+	}
+	if got := ueventSubsystem([]byte("libudev\x00")); got != "" {
+		t.Errorf("expected no subsystem, got %q", got)
+	}
+}
+
+func TestStaticCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "static_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "core_id")
+	if err := ioutil.WriteFile(path, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &staticCache{subsystems: map[string]map[string]staticFile{}}
+	if _, err := c.read("cpu", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := c.read("cpu", path); string(data) != "1\n" {
+		t.Errorf("expected cached value, got %q", data)
+	}
+	c.invalidate("block")
+	if data, _ := c.read("cpu", path); string(data) != "1\n" {
+		t.Errorf("expected cached value after uevent of another subsystem, got %q", data)
+	}
+	c.invalidate("cpu")
+	if data, _ := c.read("cpu", path); string(data) != "2\n" {
+		t.Errorf("expected new value after uevent, got %q", data)
+	}
+}
+
+func TestStaticCacheInvalidatedWhileReading(t *testing.T) {
+	c := &staticCache{subsystems: map[string]map[string]staticFile{}}
+	generation := c.generation
+	// The uevent arrives after the file was read with the old value.
+	c.invalidate("cpu")
+	c.store("cpu", "core_id", generation, staticFile{data: []byte("1\n")})
+	if _, ok := c.subsystems["cpu"]["core_id"]; ok {
+		t.Error("expected a value read before an invalidation not to be cached")
+	}
+	c.store("cpu", "core_id", c.generation, staticFile{data: []byte("2\n")})
+	if _, ok := c.subsystems["cpu"]["core_id"]; !ok {
+		t.Error("expected a value read after the invalidation to be cached")
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sysfsDirentBufSize is the buffer of getdents(2), which fits the entries of
+// the directories of most devices in a single call.
+const sysfsDirentBufSize = 32 * 1024
+
+// openat2Unsupported is set once openat2(2) failed with ENOSYS, before Linux
+// 5.6, or EPERM of seccomp filters not knowing it.
+var openat2Unsupported int32
+
+// sysfsDir is an open sysfs directory. Its entries are listed by batched
+// getdents(2) instead of the lstat(2) of every entry by ioutil.ReadDir, and
+// its attributes are opened relative to the directory instead of resolving
+// their whole path again.
+type sysfsDir struct {
+	fd   int
+	path string
+	buf  []byte
+}
+
+func openSysfsDir(path string) (*sysfsDir, error) {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return &sysfsDir{fd: fd, path: path}, nil
+}
+
+func (d *sysfsDir) Close() error {
+	return unix.Close(d.fd)
+}
+
+// names returns the names of the entries of the directory without . and ..,
+// it can only be called once.
+func (d *sysfsDir) names() ([]string, error) {
+	var names []string
+	err := d.getdents(func(buf []byte) {
+		_, _, names = unix.ParseDirent(buf, -1, names)
+	})
+	return names, err
+}
+
+// subdirs returns the names of the subdirectories of the directory by the
+// d_type of their entries, only entries of an unknown type are stat'ed. Like
+// names, it can only be called once.
+func (d *sysfsDir) subdirs() ([]string, error) {
+	var names []string
+	err := d.getdents(func(buf []byte) {
+		for len(buf) > 0 {
+			// struct linux_dirent64 of getdents64(2).
+			reclen := int(*(*uint16)(unsafe.Pointer(&buf[unsafe.Offsetof(unix.Dirent{}.Reclen)])))
+			typ := buf[unsafe.Offsetof(unix.Dirent{}.Type)]
+			name := buf[unsafe.Offsetof(unix.Dirent{}.Name):reclen]
+			if i := bytes.IndexByte(name, 0); i >= 0 {
+				name = name[:i]
+			}
+			buf = buf[reclen:]
+
+			if string(name) == "." || string(name) == ".." {
+				continue
+			}
+			if typ == unix.DT_UNKNOWN {
+				var stat unix.Stat_t
+				if unix.Fstatat(d.fd, string(name), &stat, unix.AT_SYMLINK_NOFOLLOW) == nil && stat.Mode&unix.S_IFMT == unix.S_IFDIR {
+					typ = unix.DT_DIR
+				}
+			}
+			if typ == unix.DT_DIR {
+				names = append(names, string(name))
+			}
+		}
+	})
+	return names, err
+}
+
+// getdents reads the entries of the directory with getdents(2) and passes the
+// buffer of each call to parse.
+func (d *sysfsDir) getdents(parse func(buf []byte)) error {
+	buf := make([]byte, sysfsDirentBufSize)
+	for {
+		n, err := unix.Getdents(d.fd, buf)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return &os.PathError{Op: "getdents", Path: d.path, Err: err}
+		}
+		if n <= 0 {
+			return nil
+		}
+		parse(buf[:n])
+	}
+}
+
+// openat opens an entry of the directory, refusing to resolve outside of it.
+func (d *sysfsDir) openat(name string, flags int) (int, error) {
+	if atomic.LoadInt32(&openat2Unsupported) == 0 {
+		fd, err := unix.Openat2(d.fd, name, &unix.OpenHow{
+			Flags:   uint64(flags | unix.O_CLOEXEC),
+			Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+		})
+		if err != unix.ENOSYS && err != unix.EPERM {
+			return fd, err
+		}
+		atomic.StoreInt32(&openat2Unsupported, 1)
+	}
+	return unix.Openat(d.fd, name, flags|unix.O_CLOEXEC, 0)
+}
+
+// openDir opens a subdirectory, which may be a symlink like device, without
+// the restriction to the directory.
+func (d *sysfsDir) openDir(name string) (*sysfsDir, error) {
+	fd, err := unix.Openat(d.fd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: d.path + "/" + name, Err: err}
+	}
+	return &sysfsDir{fd: fd, path: d.path + "/" + name}, nil
+}
+
+// readlink reads a symlink below the directory like the device/driver link of
+// a device.
+func (d *sysfsDir) readlink(name string) (string, error) {
+	buf := make([]byte, unix.PathMax)
+	n, err := unix.Readlinkat(d.fd, name, buf)
+	if err != nil {
+		return "", &os.PathError{Op: "readlinkat", Path: d.path + "/" + name, Err: err}
+	}
+	return string(buf[:n]), nil
+}
+
+// readString reads an attribute with a single read(2) like sysReadFile, the
+// buffer of a page is reused between the attributes of the directory.
+func (d *sysfsDir) readString(name string) (string, error) {
+	fd, err := d.openat(name, unix.O_RDONLY)
+	if err != nil {
+		return "", &os.PathError{Op: "openat", Path: d.path + "/" + name, Err: err}
+	}
+	defer unix.Close(fd)
+
+	if d.buf == nil {
+		d.buf = make([]byte, os.Getpagesize())
+	}
+	n, err := unix.Read(fd, d.buf)
+	if err != nil {
+		return "", &os.PathError{Op: "read", Path: d.path + "/" + name, Err: err}
+	}
+	return string(d.buf[:n]), nil
+}
+
+// readUint is readUintFromFile for an attribute of the directory.
+func (d *sysfsDir) readUint(name string) (uint64, error) {
+	value, err := d.readString(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSysfsDir(t *testing.T) {
+	dir, err := openSysfsDir("fixtures/sys/class/hwmon/hwmon0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	names, err := dir.names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if len(names) == 0 || names[0] != "device" {
+		t.Errorf("expected the entries to start with device, got %v", names)
+	}
+
+	if link, err := dir.readlink("device"); err != nil || link != "../../../coretemp.0" {
+		t.Errorf("expected device link ../../../coretemp.0, got %q (%v)", link, err)
+	}
+	if value, err := dir.readUint("temp1_input"); err != nil || value != 55000 {
+		t.Errorf("expected temp1_input 55000, got %d (%v)", value, err)
+	}
+	if _, err := dir.readString("temp9_input"); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error for a missing attribute, got %v", err)
+	}
+	// Without openat2 the fallback resolves paths outside.
+	if _, err := dir.readString("../hwmon1/name"); err == nil && openat2Unsupported == 0 {
+		t.Error("expected error for an attribute outside of the directory")
+	}
+}
+
+func TestSysfsDirSubdirs(t *testing.T) {
+	for path, want := range map[string][]string{
+		// The device symlink is not a subdirectory.
+		"fixtures/sys/class/hwmon/hwmon0":    nil,
+		"fixtures/sys/class/power_supply/AC": {"power"},
+	} {
+		dir, err := openSysfsDir(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subdirs, err := dir.subdirs()
+		dir.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(subdirs, want) {
+			t.Errorf("%s: expected subdirectories %v, got %v", path, want, subdirs)
+		}
+	}
+}
@@ -67,6 +67,7 @@ type systemdCollector struct {
 	summaryDesc                   *prometheus.Desc
 	nRestartsDesc                 *prometheus.Desc
 	timerLastTriggerDesc          *prometheus.Desc
+	timerNextTriggerDesc          *prometheus.Desc
 	socketAcceptedConnectionsDesc *prometheus.Desc
 	socketCurrentConnectionsDesc  *prometheus.Desc
 	socketRefusedConnectionsDesc  *prometheus.Desc
@@ -117,6 +118,9 @@ func NewSystemdCollector(logger log.Logger) (Collector, error) {
 	timerLastTriggerDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystem, "timer_last_trigger_seconds"),
 		"Seconds since epoch of last trigger.", []string{"name"}, nil)
+	timerNextTriggerDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "timer_next_trigger_seconds"),
+		"Seconds since epoch of next trigger.", []string{"name"}, nil)
 	socketAcceptedConnectionsDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystem, "socket_accepted_connections_total"),
 		"Total number of accepted socket connections", []string{"name"}, nil)
@@ -166,6 +170,7 @@ func NewSystemdCollector(logger log.Logger) (Collector, error) {
 		summaryDesc:                   summaryDesc,
 		nRestartsDesc:                 nRestartsDesc,
 		timerLastTriggerDesc:          timerLastTriggerDesc,
+		timerNextTriggerDesc:          timerNextTriggerDesc,
 		socketAcceptedConnectionsDesc: socketAcceptedConnectionsDesc,
 		socketCurrentConnectionsDesc:  socketCurrentConnectionsDesc,
 		socketRefusedConnectionsDesc:  socketRefusedConnectionsDesc,
@@ -408,6 +413,19 @@ func (c *systemdCollector) collectTimers(conn *dbus.Conn, ch chan<- prometheus.M
 		ch <- prometheus.MustNewConstMetric(
 			c.timerLastTriggerDesc, prometheus.GaugeValue,
 			float64(lastTriggerValue.Value.Value().(uint64))/1e6, unit.Name)
+
+		// Timers without a calendar event only have a monotonic next
+		// elapse time, which is relative to boot and not exported.
+		nextTriggerValue, err := conn.GetUnitTypeProperty(unit.Name, "Timer", "NextElapseUSecRealtime")
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "couldn't get unit NextElapseUSecRealtime", "unit", unit.Name, "err", err)
+			continue
+		}
+		if next := nextTriggerValue.Value.Value().(uint64); next != 0 && next != math.MaxUint64 {
+			ch <- prometheus.MustNewConstMetric(
+				c.timerNextTriggerDesc, prometheus.GaugeValue,
+				float64(next)/1e6, unit.Name)
+		}
 	}
 }
 
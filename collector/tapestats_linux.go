@@ -18,7 +18,6 @@ package collector
 import (
 	"fmt"
 	"os"
-	"regexp"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -28,11 +27,11 @@ import (
 )
 
 var (
-	ignoredTapeDevices = kingpin.Flag("collector.tapestats.ignored-devices", "Regexp of devices to ignore for tapestats.").Default("^$").String()
+	ignoredTapeDevices = kingpin.Flag("collector.tapestats.ignored-devices", "Regexp, or comma separated globs prefixed with glob:, of devices to ignore for tapestats.").Default("^$").String()
 )
 
 type tapestatsCollector struct {
-	ignoredDevicesPattern *regexp.Regexp
+	ignoredDevicesPattern *deviceMatcher
 	ioNow                 *prometheus.Desc
 	ioTimeSeconds         *prometheus.Desc
 	othersCompletedTotal  *prometheus.Desc
@@ -64,7 +63,7 @@ func NewTapestatsCollector(logger log.Logger) (Collector, error) {
 	tapeSubsystem := "tape"
 
 	return &tapestatsCollector{
-		ignoredDevicesPattern: regexp.MustCompile(*ignoredTapeDevices),
+		ignoredDevicesPattern: mustNewDeviceMatcher(*ignoredTapeDevices),
 
 		ioNow: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, tapeSubsystem, "io_now"),
@@ -132,7 +131,7 @@ func (c *tapestatsCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	for _, tape := range tapes {
-		if c.ignoredDevicesPattern.MatchString(tape.Name) {
+		if c.ignoredDevicesPattern.matches(tape.Name) {
 			level.Debug(c.logger).Log("msg", "Ignoring device", "device", tape.Name)
 			continue
 		}
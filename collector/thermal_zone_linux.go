@@ -17,8 +17,14 @@ package collector
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs/sysfs"
 )
@@ -31,6 +37,9 @@ type thermalZoneCollector struct {
 	coolingDeviceCurState *prometheus.Desc
 	coolingDeviceMaxState *prometheus.Desc
 	zoneTemp              *prometheus.Desc
+	tripPointTemp         *prometheus.Desc
+	tripPointHyst         *prometheus.Desc
+	zoneCoolingDevice     *prometheus.Desc
 	logger                log.Logger
 }
 
@@ -52,6 +61,21 @@ func NewThermalZoneCollector(logger log.Logger) (Collector, error) {
 			"Zone temperature in Celsius",
 			[]string{"zone", "type"}, nil,
 		),
+		tripPointTemp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, thermalZone, "trip_point_temp"),
+			"Temperature in Celsius at which the trip point of the zone is crossed, e.g. passive for throttling and critical for shutdown",
+			[]string{"zone", "type", "trip_point", "trip_type"}, nil,
+		),
+		tripPointHyst: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, thermalZone, "trip_point_hyst"),
+			"Hysteresis in Celsius below the trip point temperature until the trip point is no longer crossed",
+			[]string{"zone", "type", "trip_point", "trip_type"}, nil,
+		),
+		zoneCoolingDevice: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, thermalZone, "cooling_device_info"),
+			"Cooling device bound to a trip point of the zone, value is always 1",
+			[]string{"zone", "type", "trip_point", "name"}, nil,
+		),
 		coolingDeviceCurState: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, coolingDevice, "cur_state"),
 			"Current throttle state of the cooling device",
@@ -80,6 +104,7 @@ func (c *thermalZoneCollector) Update(ch chan<- prometheus.Metric) error {
 			stats.Name,
 			stats.Type,
 		)
+		c.updateTripPoints(ch, stats.Name, stats.Type)
 	}
 
 	coolingDevices, err := c.fs.ClassCoolingDeviceStats()
@@ -107,3 +132,62 @@ func (c *thermalZoneCollector) Update(ch chan<- prometheus.Metric) error {
 
 	return nil
 }
+
+// updateTripPoints exports the trip points of a zone and the cooling devices
+// bound to them. Zones without trip points, e.g. of sensors only, are common.
+func (c *thermalZoneCollector) updateTripPoints(ch chan<- prometheus.Metric, zone, zoneType string) {
+	dir := sysFilePath(filepath.Join("class/thermal", thermalZone+zone))
+	temps, err := filepath.Glob(filepath.Join(dir, "trip_point_[0-9]*_temp"))
+	if err != nil {
+		return
+	}
+	for _, path := range temps {
+		tripPoint := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "trip_point_"), "_temp")
+		temp, err := readThermalMillidegrees(path)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read trip point", "zone", zone, "trip_point", tripPoint, "err", err)
+			continue
+		}
+		tripType, err := ioutil.ReadFile(filepath.Join(dir, "trip_point_"+tripPoint+"_type"))
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read trip point type", "zone", zone, "trip_point", tripPoint, "err", err)
+			continue
+		}
+		labels := []string{zone, zoneType, tripPoint, strings.TrimSpace(string(tripType))}
+		ch <- prometheus.MustNewConstMetric(c.tripPointTemp, prometheus.GaugeValue, temp, labels...)
+		if hyst, err := readThermalMillidegrees(filepath.Join(dir, "trip_point_"+tripPoint+"_hyst")); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.tripPointHyst, prometheus.GaugeValue, hyst, labels...)
+		}
+	}
+
+	// The cooling devices of a zone are linked as cdevN with the trip point
+	// in cdevN_trip_point.
+	cdevs, err := filepath.Glob(filepath.Join(dir, "cdev[0-9]*_trip_point"))
+	if err != nil {
+		return
+	}
+	for _, path := range cdevs {
+		tripPoint, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		target, err := os.Readlink(strings.TrimSuffix(path, "_trip_point"))
+		if err != nil {
+			continue
+		}
+		name := strings.TrimPrefix(filepath.Base(target), coolingDevice)
+		ch <- prometheus.MustNewConstMetric(c.zoneCoolingDevice, prometheus.GaugeValue, 1, zone, zoneType, strings.TrimSpace(string(tripPoint)), name)
+	}
+}
+
+func readThermalMillidegrees(path string) (float64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(value) / 1000.0, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nothp
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const thpSubsystem = "thp"
+
+type thpCollector struct {
+	enabled                 *prometheus.Desc
+	defrag                  *prometheus.Desc
+	events                  *prometheus.Desc
+	khugepagedCollapsed     *prometheus.Desc
+	khugepagedFullScans     *prometheus.Desc
+	khugepagedPagesToScan   *prometheus.Desc
+	khugepagedScanSleep     *prometheus.Desc
+	khugepagedAllocSleep    *prometheus.Desc
+	khugepagedMaxPtesNone   *prometheus.Desc
+	khugepagedDefragEnabled *prometheus.Desc
+	logger                  log.Logger
+}
+
+func init() {
+	registerCollector(thpSubsystem, defaultDisabled, NewTHPCollector)
+}
+
+// NewTHPCollector returns a new Collector exposing the transparent huge page
+// configuration, events and khugepaged progress.
+func NewTHPCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, thpSubsystem, name), help, labels, nil)
+	}
+	return &thpCollector{
+		enabled:                 desc("enabled", "Transparent huge page mode, the active mode has a value of 1.", "mode"),
+		defrag:                  desc("defrag", "Transparent huge page defrag mode, the active mode has a value of 1.", "mode"),
+		events:                  desc("events_total", "Number of transparent huge page events from /proc/vmstat, e.g. fault_alloc or fault_fallback.", "event"),
+		khugepagedCollapsed:     desc("khugepaged_pages_collapsed_total", "Number of huge pages collapsed by khugepaged."),
+		khugepagedFullScans:     desc("khugepaged_full_scans_total", "Number of full scans of all memory by khugepaged."),
+		khugepagedPagesToScan:   desc("khugepaged_pages_to_scan", "Number of pages khugepaged scans in each pass."),
+		khugepagedScanSleep:     desc("khugepaged_scan_sleep_seconds", "Time khugepaged sleeps between passes."),
+		khugepagedAllocSleep:    desc("khugepaged_alloc_sleep_seconds", "Time khugepaged sleeps after a failed huge page allocation."),
+		khugepagedMaxPtesNone:   desc("khugepaged_max_ptes_none", "Maximum number of unmapped pages of a huge page khugepaged collapses."),
+		khugepagedDefragEnabled: desc("khugepaged_defrag", "Whether khugepaged defragments memory to allocate huge pages."),
+		logger:                  logger,
+	}, nil
+}
+
+func (c *thpCollector) Update(ch chan<- prometheus.Metric) error {
+	dir := sysFilePath("kernel/mm/transparent_hugepage")
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "transparent huge pages not supported by the kernel")
+			return ErrNoData
+		}
+		return err
+	}
+
+	for desc, name := range map[*prometheus.Desc]string{c.enabled: "enabled", c.defrag: "defrag"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read transparent huge page %s mode: %w", name, err)
+		}
+		// The available modes are listed with the active one in brackets.
+		for _, mode := range strings.Fields(string(data)) {
+			active := 0.0
+			if strings.HasPrefix(mode, "[") && strings.HasSuffix(mode, "]") {
+				active = 1
+				mode = strings.Trim(mode, "[]")
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, active, mode)
+		}
+	}
+
+	for name, m := range map[string]struct {
+		desc      *prometheus.Desc
+		valueType prometheus.ValueType
+		scale     float64
+	}{
+		"pages_collapsed":       {c.khugepagedCollapsed, prometheus.CounterValue, 1},
+		"full_scans":            {c.khugepagedFullScans, prometheus.CounterValue, 1},
+		"pages_to_scan":         {c.khugepagedPagesToScan, prometheus.GaugeValue, 1},
+		"scan_sleep_millisecs":  {c.khugepagedScanSleep, prometheus.GaugeValue, 0.001},
+		"alloc_sleep_millisecs": {c.khugepagedAllocSleep, prometheus.GaugeValue, 0.001},
+		"max_ptes_none":         {c.khugepagedMaxPtesNone, prometheus.GaugeValue, 1},
+		"defrag":                {c.khugepagedDefragEnabled, prometheus.GaugeValue, 1},
+	} {
+		value, err := readUintFromFile(filepath.Join(dir, "khugepaged", name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to read khugepaged %s: %w", name, err)
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, float64(value)*m.scale)
+	}
+
+	events, err := readVmstatFields("thp_")
+	if err != nil {
+		return fmt.Errorf("failed to read vmstat: %w", err)
+	}
+	for event, value := range events {
+		ch <- prometheus.MustNewConstMetric(c.events, prometheus.CounterValue, float64(value), event)
+	}
+	return nil
+}
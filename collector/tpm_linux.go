@@ -0,0 +1,252 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notpm
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const tpmSubsystem = "tpm"
+
+var (
+	tpmDevPath = kingpin.Flag("collector.tpm.dev-path", "Directory containing the TPM resource manager devices, used to query TPM 2.0 lockout state.").Default("/dev").String()
+)
+
+// TPM 2.0 properties of the TPM_PT_VAR group, see part 2 of the TPM 2.0
+// library specification.
+const (
+	tpm2PTPermanent       = 0x200
+	tpm2PTStartupClear    = 0x201
+	tpm2PTLockoutCounter  = 0x20e
+	tpm2PTMaxAuthFail     = 0x20f
+	tpm2PTLockoutInterval = 0x210
+	tpm2PTLockoutRecovery = 0x211
+)
+
+// Bits of TPMA_PERMANENT.
+const (
+	tpmaPermanentOwnerAuthSet = 0
+	tpmaPermanentInLockout    = 9
+)
+
+// tpm2Hierarchies are the hierarchy enable bits of TPM_PT_STARTUP_CLEAR.
+var tpm2Hierarchies = []string{"platform", "storage", "endorsement"}
+
+type tpmCollector struct {
+	info               *prometheus.Desc
+	enabled            *prometheus.Desc
+	active             *prometheus.Desc
+	owned              *prometheus.Desc
+	hierarchyEnabled   *prometheus.Desc
+	inLockout          *prometheus.Desc
+	lockoutCounter     *prometheus.Desc
+	lockoutMaxAuthFail *prometheus.Desc
+	lockoutInterval    *prometheus.Desc
+	lockoutRecovery    *prometheus.Desc
+	logger             log.Logger
+}
+
+func init() {
+	registerCollector(tpmSubsystem, defaultDisabled, NewTPMCollector)
+}
+
+// NewTPMCollector returns a new Collector exposing TPM presence and status.
+func NewTPMCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, tpmSubsystem, name),
+			help, append([]string{"tpm"}, labels...), nil,
+		)
+	}
+	return &tpmCollector{
+		info:               desc("info", "TPM present on the system, value is always 1.", "version"),
+		enabled:            desc("enabled", "Whether the TPM 1.2 is enabled."),
+		active:             desc("active", "Whether the TPM 1.2 is active."),
+		owned:              desc("owned", "Whether the TPM is owned, for TPM 2.0 whether the owner authorization is set."),
+		hierarchyEnabled:   desc("hierarchy_enabled", "Whether the TPM 2.0 hierarchy is enabled.", "hierarchy"),
+		inLockout:          desc("in_lockout", "Whether the TPM 2.0 is in dictionary attack lockout."),
+		lockoutCounter:     desc("lockout_counter", "Number of authorization failures counted by the TPM 2.0 dictionary attack protection."),
+		lockoutMaxAuthFail: desc("lockout_max_auth_fail", "Number of authorization failures before the TPM 2.0 enters lockout."),
+		lockoutInterval:    desc("lockout_interval_seconds", "Time after which the TPM 2.0 decrements the lockout counter."),
+		lockoutRecovery:    desc("lockout_recovery_seconds", "Time after which the TPM 2.0 recovers from a lockout authorization failure."),
+		logger:             logger,
+	}, nil
+}
+
+func (c *tpmCollector) Update(ch chan<- prometheus.Metric) error {
+	tpms, err := filepath.Glob(sysFilePath("class/tpm/tpm[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(tpms) == 0 {
+		level.Debug(c.logger).Log("msg", "no TPM found")
+		return ErrNoData
+	}
+
+	for _, path := range tpms {
+		name := filepath.Base(path)
+		version := tpmVersion(path)
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, name, version)
+
+		if version == "2.0" {
+			if err := c.updateTPM2(ch, name); err != nil {
+				level.Debug(c.logger).Log("msg", "failed to query TPM 2.0 properties", "tpm", name, "err", err)
+			}
+			continue
+		}
+
+		// TPM 1.2 attributes live in the device directory on older kernels.
+		for desc, file := range map[*prometheus.Desc]string{c.enabled: "enabled", c.active: "active", c.owned: "owned"} {
+			for _, dir := range []string{path, filepath.Join(path, "device")} {
+				if value, err := readUintFromFile(filepath.Join(dir, file)); err == nil {
+					ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), name)
+					break
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// tpmVersion returns the TCG specification version of a TPM, or an empty
+// string if it can't be determined.
+func tpmVersion(path string) string {
+	// Available since Linux 5.6.
+	if major, err := ioutil.ReadFile(filepath.Join(path, "tpm_version_major")); err == nil {
+		switch strings.TrimSpace(string(major)) {
+		case "1":
+			return "1.2"
+		case "2":
+			return "2.0"
+		}
+	}
+	for _, dir := range []string{path, filepath.Join(path, "device")} {
+		caps, err := ioutil.ReadFile(filepath.Join(dir, "caps"))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(caps), "\n") {
+			if v := strings.TrimPrefix(line, "TCG version: "); v != line {
+				return strings.TrimSpace(v)
+			}
+		}
+	}
+	// Only TPM 1.2 drivers create the caps attribute, while the resource
+	// manager device only exists for TPM 2.0.
+	if _, err := os.Stat(filepath.Join(*tpmDevPath, "tpmrm"+strings.TrimPrefix(filepath.Base(path), "tpm"))); err == nil {
+		return "2.0"
+	}
+	return ""
+}
+
+func (c *tpmCollector) updateTPM2(ch chan<- prometheus.Metric, name string) error {
+	// The resource manager device can be shared with other TPM users.
+	dev, err := os.OpenFile(filepath.Join(*tpmDevPath, "tpmrm"+strings.TrimPrefix(name, "tpm")), os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer dev.Close()
+
+	if _, err := dev.Write(tpm2GetPropertiesCommand(tpm2PTPermanent, tpm2PTLockoutRecovery-tpm2PTPermanent+1)); err != nil {
+		return err
+	}
+	resp := make([]byte, 4096)
+	n, err := dev.Read(resp)
+	if err != nil {
+		return err
+	}
+	props, err := parseTPM2Properties(resp[:n])
+	if err != nil {
+		return err
+	}
+
+	if v, ok := props[tpm2PTPermanent]; ok {
+		ch <- prometheus.MustNewConstMetric(c.owned, prometheus.GaugeValue, tpmaBit(v, tpmaPermanentOwnerAuthSet), name)
+		ch <- prometheus.MustNewConstMetric(c.inLockout, prometheus.GaugeValue, tpmaBit(v, tpmaPermanentInLockout), name)
+	}
+	if v, ok := props[tpm2PTStartupClear]; ok {
+		for i, hierarchy := range tpm2Hierarchies {
+			ch <- prometheus.MustNewConstMetric(c.hierarchyEnabled, prometheus.GaugeValue, tpmaBit(v, uint(i)), name, hierarchy)
+		}
+	}
+	for desc, prop := range map[*prometheus.Desc]uint32{
+		c.lockoutCounter:     tpm2PTLockoutCounter,
+		c.lockoutMaxAuthFail: tpm2PTMaxAuthFail,
+		c.lockoutInterval:    tpm2PTLockoutInterval,
+		c.lockoutRecovery:    tpm2PTLockoutRecovery,
+	} {
+		if v, ok := props[prop]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), name)
+		}
+	}
+	return nil
+}
+
+// tpmaBit returns a bit of a TPMA attribute value as 0 or 1.
+func tpmaBit(v uint32, bit uint) float64 {
+	return float64((v >> bit) & 1)
+}
+
+// tpm2GetPropertiesCommand builds a TPM2_GetCapability command for count
+// TPM_CAP_TPM_PROPERTIES starting at property.
+func tpm2GetPropertiesCommand(property, count uint32) []byte {
+	cmd := make([]byte, 22)
+	binary.BigEndian.PutUint16(cmd[0:2], 0x8001) // TPM_ST_NO_SESSIONS
+	binary.BigEndian.PutUint32(cmd[2:6], uint32(len(cmd)))
+	binary.BigEndian.PutUint32(cmd[6:10], 0x17a) // TPM_CC_GetCapability
+	binary.BigEndian.PutUint32(cmd[10:14], 6)    // TPM_CAP_TPM_PROPERTIES
+	binary.BigEndian.PutUint32(cmd[14:18], property)
+	binary.BigEndian.PutUint32(cmd[18:22], count)
+	return cmd
+}
+
+// parseTPM2Properties parses the response to a TPM2_GetCapability command
+// for TPM_CAP_TPM_PROPERTIES.
+func parseTPM2Properties(resp []byte) (map[uint32]uint32, error) {
+	if len(resp) < 10 {
+		return nil, fmt.Errorf("short TPM response, len=%d", len(resp))
+	}
+	if rc := binary.BigEndian.Uint32(resp[6:10]); rc != 0 {
+		return nil, fmt.Errorf("TPM returned response code %#x", rc)
+	}
+	// moreData, capability and count follow the header.
+	if len(resp) < 19 {
+		return nil, fmt.Errorf("short TPM response, len=%d", len(resp))
+	}
+	count := int(binary.BigEndian.Uint32(resp[15:19]))
+	r := bytes.NewReader(resp[19:])
+	props := make(map[uint32]uint32, count)
+	for i := 0; i < count; i++ {
+		var prop [2]uint32
+		if err := binary.Read(r, binary.BigEndian, &prop); err != nil {
+			return nil, fmt.Errorf("truncated TPM properties: %w", err)
+		}
+		props[prop[0]] = prop[1]
+	}
+	return props, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !notpm
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestTPM2GetPropertiesCommand(t *testing.T) {
+	want := []byte{
+		0x80, 0x01, // TPM_ST_NO_SESSIONS
+		0x00, 0x00, 0x00, 0x16, // size
+		0x00, 0x00, 0x01, 0x7a, // TPM_CC_GetCapability
+		0x00, 0x00, 0x00, 0x06, // TPM_CAP_TPM_PROPERTIES
+		0x00, 0x00, 0x02, 0x00, // TPM_PT_PERMANENT
+		0x00, 0x00, 0x00, 0x12, // count
+	}
+	if got := tpm2GetPropertiesCommand(tpm2PTPermanent, tpm2PTLockoutRecovery-tpm2PTPermanent+1); !bytes.Equal(got, want) {
+		t.Errorf("expected command %x, got %x", want, got)
+	}
+}
+
+func TestParseTPM2Properties(t *testing.T) {
+	props := [][2]uint32{
+		// ownerAuthSet, lockoutAuthSet and inLockout.
+		{tpm2PTPermanent, 1<<0 | 1<<2 | 1<<9},
+		{tpm2PTStartupClear, 1<<0 | 1<<1},
+		{tpm2PTLockoutCounter, 3},
+		{tpm2PTMaxAuthFail, 32},
+	}
+	var resp bytes.Buffer
+	binary.Write(&resp, binary.BigEndian, uint16(0x8001)) // TPM_ST_NO_SESSIONS
+	binary.Write(&resp, binary.BigEndian, uint32(19+8*len(props)))
+	binary.Write(&resp, binary.BigEndian, uint32(0)) // TPM_RC_SUCCESS
+	resp.WriteByte(0)                                // moreData
+	binary.Write(&resp, binary.BigEndian, uint32(6)) // TPM_CAP_TPM_PROPERTIES
+	binary.Write(&resp, binary.BigEndian, uint32(len(props)))
+	binary.Write(&resp, binary.BigEndian, props)
+
+	got, err := parseTPM2Properties(resp.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint32]uint32{
+		tpm2PTPermanent:      1<<0 | 1<<2 | 1<<9,
+		tpm2PTStartupClear:   1<<0 | 1<<1,
+		tpm2PTLockoutCounter: 3,
+		tpm2PTMaxAuthFail:    32,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if v := tpmaBit(got[tpm2PTPermanent], tpmaPermanentInLockout); v != 1 {
+		t.Errorf("expected inLockout to be set, got %v", v)
+	}
+	// lockoutAuthSet alone is no lockout.
+	if v := tpmaBit(1<<2, tpmaPermanentInLockout); v != 0 {
+		t.Errorf("expected inLockout not to be set with only lockoutAuthSet, got %v", v)
+	}
+
+	if _, err := parseTPM2Properties(resp.Bytes()[:30]); err == nil {
+		t.Error("expected error for truncated properties")
+	}
+	failed := append([]byte(nil), resp.Bytes()[:10]...)
+	binary.BigEndian.PutUint32(failed[6:10], 0x101) // TPM_RC_FAILURE
+	if _, err := parseTPM2Properties(failed); err == nil {
+		t.Error("expected error for a failed command")
+	}
+}
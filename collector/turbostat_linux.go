@@ -0,0 +1,282 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noturbostat
+
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const turbostatSubsystem = "turbostat"
+
+var (
+	turbostatDevPath = kingpin.Flag("collector.turbostat.dev-path", "Directory containing the MSR devices of the CPUs, provided by the msr kernel module.").Default("/dev").String()
+)
+
+// Model specific registers, see the Intel SDM volume 4 and the AMD PPR.
+const (
+	msrTSC                = 0x10
+	msrMPERF              = 0xe7
+	msrAPERF              = 0xe8
+	msrRAPLPowerUnit      = 0x606
+	msrPkgEnergyStatus    = 0x611
+	msrAMDRAPLPowerUnit   = 0xc0010299
+	msrAMDPkgEnergyStatus = 0xc001029b
+)
+
+// turbostatPkgCStates are the package C-state residency counters of Intel
+// CPUs, they count at the TSC frequency.
+var turbostatPkgCStates = map[string]int64{
+	"pc2":  0x60d,
+	"pc3":  0x3f8,
+	"pc6":  0x3f9,
+	"pc7":  0x3fa,
+	"pc8":  0x630,
+	"pc9":  0x631,
+	"pc10": 0x632,
+}
+
+type turbostatCollector struct {
+	averageFrequency *prometheus.Desc
+	busyFrequency    *prometheus.Desc
+	tscFrequency     *prometheus.Desc
+	busy             *prometheus.Desc
+	power            *prometheus.Desc
+	cstateResidency  *prometheus.Desc
+	logger           log.Logger
+
+	amd bool
+	// The values are averages since the last scrape, which requires the
+	// previous sample of each package.
+	mtx      sync.Mutex
+	previous map[string]turbostatSample
+}
+
+// turbostatSample holds the counters of a package, the per CPU ones summed up
+// over its CPUs.
+type turbostatSample struct {
+	time    time.Time
+	cpus    int
+	tsc     uint64
+	aperf   uint64
+	mperf   uint64
+	energy  uint64
+	unit    float64
+	cstates map[string]uint64
+}
+
+func init() {
+	registerCollector(turbostatSubsystem, defaultDisabled, NewTurbostatCollector)
+}
+
+// NewTurbostatCollector returns a new Collector exposing the frequency, busy
+// ratio, power and C-state residency of CPU packages, like turbostat.
+func NewTurbostatCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	// The MSRs only exist on x86, the cpuinfo of other architectures may
+	// not be parsed at all.
+	info, err := fs.CPUInfo()
+	if err != nil {
+		level.Debug(logger).Log("msg", "failed to read cpuinfo", "err", err)
+	}
+	amd := len(info) > 0 && (info[0].VendorID == "AuthenticAMD" || info[0].VendorID == "HygonGenuine")
+
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, turbostatSubsystem, name), help, append([]string{"package"}, labels...), nil)
+	}
+	return &turbostatCollector{
+		averageFrequency: desc("package_average_frequency_hertz", "Average frequency of the CPUs of the package since the last scrape, including idle time."),
+		busyFrequency:    desc("package_busy_frequency_hertz", "Average frequency of the CPUs of the package since the last scrape while not idle."),
+		tscFrequency:     desc("package_tsc_frequency_hertz", "Frequency of the time stamp counter of the package."),
+		busy:             desc("package_busy_ratio", "Ratio of time the CPUs of the package were not idle since the last scrape."),
+		power:            desc("package_power_watts", "Average power consumption of the package since the last scrape."),
+		cstateResidency:  desc("package_cstate_residency_ratio", "Ratio of time the package was in the C-state since the last scrape.", "state"),
+		logger:           logger,
+		amd:              amd,
+		previous:         map[string]turbostatSample{},
+	}, nil
+}
+
+func (c *turbostatCollector) Update(ch chan<- prometheus.Metric) error {
+	if _, err := os.Stat(filepath.Join(*turbostatDevPath, "cpu/0/msr")); err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "MSR devices not available, msr kernel module not loaded")
+			return ErrNoData
+		}
+		return err
+	}
+
+	samples, err := c.sample()
+	if err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for pkg, cur := range samples {
+		prev, ok := c.previous[pkg]
+		c.previous[pkg] = cur
+		if !ok || cur.cpus != prev.cpus {
+			// CPUs of the package went on- or offline, the
+			// averages of the interval are meaningless.
+			continue
+		}
+		c.updatePackage(ch, pkg, prev, cur)
+	}
+	return nil
+}
+
+func (c *turbostatCollector) updatePackage(ch chan<- prometheus.Metric, pkg string, prev, cur turbostatSample) {
+	// The TSC, APERF and MPERF are 64 bit counters, which don't wrap.
+	seconds := cur.time.Sub(prev.time).Seconds()
+	tsc, aperf, mperf := float64(cur.tsc-prev.tsc), float64(cur.aperf-prev.aperf), float64(cur.mperf-prev.mperf)
+	if seconds <= 0 || tsc == 0 {
+		return
+	}
+
+	tscHz := tsc / float64(cur.cpus) / seconds
+	ch <- prometheus.MustNewConstMetric(c.tscFrequency, prometheus.GaugeValue, tscHz, pkg)
+	ch <- prometheus.MustNewConstMetric(c.averageFrequency, prometheus.GaugeValue, aperf/float64(cur.cpus)/seconds, pkg)
+	ch <- prometheus.MustNewConstMetric(c.busy, prometheus.GaugeValue, mperf/tsc, pkg)
+	if mperf > 0 {
+		ch <- prometheus.MustNewConstMetric(c.busyFrequency, prometheus.GaugeValue, tscHz*aperf/mperf, pkg)
+	}
+
+	if cur.unit > 0 {
+		// The energy status is a 32 bit counter, which wraps within
+		// minutes under load.
+		energy := uint32(cur.energy) - uint32(prev.energy)
+		ch <- prometheus.MustNewConstMetric(c.power, prometheus.GaugeValue, float64(energy)*cur.unit/seconds, pkg)
+	}
+
+	// The C-state residencies are read on a single CPU of the package.
+	pkgTSC := tsc / float64(cur.cpus)
+	for state, value := range cur.cstates {
+		if prevValue, ok := prev.cstates[state]; ok {
+			ch <- prometheus.MustNewConstMetric(c.cstateResidency, prometheus.GaugeValue, float64(value-prevValue)/pkgTSC, pkg, state)
+		}
+	}
+}
+
+// sample reads the counters of all online CPUs and sums them up per package.
+func (c *turbostatCollector) sample() (map[string]turbostatSample, error) {
+	topologies, err := filepath.Glob(sysFilePath("devices/system/cpu/cpu[0-9]*/topology/physical_package_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	samples := map[string]turbostatSample{}
+	for _, topology := range topologies {
+		cpu := strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(topology))), "cpu")
+		pkgID, err := readUintFromFile(topology)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package of CPU %s: %w", cpu, err)
+		}
+		pkg := strconv.FormatUint(pkgID, 10)
+
+		msr, err := os.Open(filepath.Join(*turbostatDevPath, "cpu", cpu, "msr"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// The CPU is offline.
+				continue
+			}
+			return nil, err
+		}
+		s, ok := samples[pkg]
+		err = c.sampleCPU(msr, &s, !ok)
+		msr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MSRs of CPU %s: %w", cpu, err)
+		}
+		samples[pkg] = s
+	}
+	return samples, nil
+}
+
+// sampleCPU adds the counters of the CPU to the package sample, the package
+// counters are read on the first CPU of a package.
+func (c *turbostatCollector) sampleCPU(msr io.ReaderAt, s *turbostatSample, first bool) error {
+	var tsc, aperf, mperf uint64
+	for _, r := range []struct {
+		addr  int64
+		value *uint64
+	}{
+		{msrTSC, &tsc},
+		{msrAPERF, &aperf},
+		{msrMPERF, &mperf},
+	} {
+		value, err := readMSR(msr, r.addr)
+		if err != nil {
+			return err
+		}
+		*r.value = value
+	}
+	s.cpus++
+	s.tsc += tsc
+	s.aperf += aperf
+	s.mperf += mperf
+	if !first {
+		return nil
+	}
+
+	s.time = time.Now()
+	unitAddr, energyAddr := int64(msrRAPLPowerUnit), int64(msrPkgEnergyStatus)
+	if c.amd {
+		unitAddr, energyAddr = msrAMDRAPLPowerUnit, msrAMDPkgEnergyStatus
+	}
+	// Not all CPUs support RAPL, unsupported MSRs fail to read.
+	if unit, err := readMSR(msr, unitAddr); err == nil {
+		if energy, err := readMSR(msr, energyAddr); err == nil {
+			s.unit = 1 / float64(uint64(1)<<((unit>>8)&0x1f))
+			s.energy = energy
+		}
+	}
+	if c.amd {
+		return nil
+	}
+	s.cstates = map[string]uint64{}
+	for state, addr := range turbostatPkgCStates {
+		if value, err := readMSR(msr, addr); err == nil {
+			s.cstates[state] = value
+		}
+	}
+	return nil
+}
+
+// readMSR reads a model specific register of the CPU of the MSR device.
+func readMSR(msr io.ReaderAt, addr int64) (uint64, error) {
+	buf := make([]byte, 8)
+	if _, err := msr.ReadAt(buf, addr); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noturbostat
+
+package collector
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// fakeMSR reads registers like the MSR device of a CPU, unsupported ones fail
+// to read.
+type fakeMSR map[int64]uint64
+
+func (m fakeMSR) ReadAt(p []byte, off int64) (int, error) {
+	value, ok := m[off]
+	if !ok || len(p) != 8 {
+		return 0, syscall.EIO
+	}
+	binary.LittleEndian.PutUint64(p, value)
+	return 8, nil
+}
+
+func TestTurbostatSampleCPU(t *testing.T) {
+	msr := fakeMSR{
+		msrTSC:             3000,
+		msrAPERF:           2000,
+		msrMPERF:           1000,
+		msrRAPLPowerUnit:   0xa0e03,
+		msrPkgEnergyStatus: 0xffffff00,
+		0x3f9:              500,
+	}
+	c := &turbostatCollector{}
+	var s turbostatSample
+	for _, first := range []bool{true, false} {
+		if err := c.sampleCPU(msr, &s, first); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if s.cpus != 2 || s.tsc != 6000 || s.aperf != 4000 || s.mperf != 2000 {
+		t.Errorf("unexpected counters %+v", s)
+	}
+	if s.unit != 1.0/(1<<14) || s.energy != 0xffffff00 {
+		t.Errorf("unexpected energy %d with unit %v", s.energy, s.unit)
+	}
+	if len(s.cstates) != 1 || s.cstates["pc6"] != 500 {
+		t.Errorf("unexpected C-states %v", s.cstates)
+	}
+}
+
+func TestTurbostatUpdatePackage(t *testing.T) {
+	*procPath = "fixtures/proc"
+	collector, err := NewTurbostatCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := collector.(*turbostatCollector)
+
+	now := time.Now()
+	prev := turbostatSample{
+		time: now, cpus: 2, tsc: 0, aperf: 0, mperf: 0,
+		energy: 0xffffffff - 1<<14 + 1, unit: 1.0 / (1 << 14),
+		cstates: map[string]uint64{"pc6": 0},
+	}
+	cur := turbostatSample{
+		time: now.Add(2 * time.Second), cpus: 2,
+		tsc: 2 * 2 * 2e9, aperf: 2 * 2 * 1.5e9, mperf: 2 * 2 * 1e9,
+		energy: 1<<14*99 + 0x100000000, unit: 1.0 / (1 << 14),
+		cstates: map[string]uint64{"pc6": 1e9},
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	c.updatePackage(ch, "0", prev, cur)
+	close(ch)
+
+	want := map[*prometheus.Desc]float64{
+		c.tscFrequency:     2e9,
+		c.averageFrequency: 1.5e9,
+		c.busy:             0.5,
+		c.busyFrequency:    3e9,
+		c.power:            50,
+		c.cstateResidency:  0.25,
+	}
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.GetGauge().GetValue(); got != want[metric.Desc()] {
+			t.Errorf("%s: want %v, got %v", metric.Desc(), want[metric.Desc()], got)
+		}
+		delete(want, metric.Desc())
+	}
+	for desc := range want {
+		t.Errorf("missing metric %s", desc)
+	}
+}
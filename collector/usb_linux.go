@@ -0,0 +1,88 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nousb
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type usbCollector struct {
+	info   *prometheus.Desc
+	logger log.Logger
+}
+
+func init() {
+	registerCollector("usb", defaultDisabled, NewUSBCollector)
+}
+
+// NewUSBCollector returns a new Collector exposing the USB devices connected
+// to the system.
+func NewUSBCollector(logger log.Logger) (Collector, error) {
+	return &usbCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "usb", "device_info"),
+			"USB device connected to the system, value is always 1. Speed is in Mbit/s.",
+			[]string{"bus", "device", "vendor_id", "product_id", "serial", "speed", "product"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *usbCollector) Update(ch chan<- prometheus.Metric) error {
+	devicesPath := sysFilePath("bus/usb/devices")
+	entries, err := ioutil.ReadDir(devicesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "USB devices not found, skipping")
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to list USB devices: %w", err)
+	}
+
+	for _, entry := range entries {
+		// Interfaces of a device are named <device>:<config>.<interface>.
+		if strings.Contains(entry.Name(), ":") {
+			continue
+		}
+		path := filepath.Join(devicesPath, entry.Name())
+
+		attrs := map[string]string{}
+		for _, attr := range []string{"busnum", "devnum", "idVendor", "idProduct", "serial", "speed", "product"} {
+			value, err := ioutil.ReadFile(filepath.Join(path, attr))
+			if err != nil {
+				// Not all devices have a serial number or product string.
+				if !os.IsNotExist(err) {
+					level.Debug(c.logger).Log("msg", "failed to read USB device attribute", "device", entry.Name(), "attr", attr, "err", err)
+				}
+				continue
+			}
+			attrs[attr] = strings.TrimSpace(string(value))
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+			attrs["busnum"], attrs["devnum"], attrs["idVendor"], attrs["idProduct"], attrs["serial"], attrs["speed"], attrs["product"])
+	}
+
+	return nil
+}
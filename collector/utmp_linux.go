@@ -0,0 +1,158 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noutmp
+
+package collector
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	utmpSubsystem = "utmp"
+
+	// Layout of struct utmp as defined by glibc on Linux.
+	utmpRecordSize  = 384
+	utmpLineOffset  = 8
+	utmpLineSize    = 32
+	utmpUserOffset  = 44
+	utmpUserSize    = 32
+	utmpHostOffset  = 76
+	utmpHostSize    = 256
+	utmpUserProcess = 7
+)
+
+var (
+	utmpPath      = kingpin.Flag("collector.utmp.path", "Path of the utmp file listing logged in users.").Default("/var/run/utmp").String()
+	utmpHostLabel = kingpin.Flag("collector.utmp.host-label", "Add the remote host of sessions as label.").Bool()
+	utmpHashHost  = kingpin.Flag("collector.utmp.hash-host", "Replace the remote host label by a hash of the host.").Default("true").Bool()
+)
+
+type utmpCollector struct {
+	sessions *prometheus.Desc
+	logger   log.Logger
+}
+
+type utmpSession struct {
+	user string
+	tty  string
+	host string
+}
+
+func init() {
+	registerCollector(utmpSubsystem, defaultDisabled, NewUtmpCollector)
+}
+
+// NewUtmpCollector returns a new Collector exposing the sessions of logged in
+// users from utmp.
+func NewUtmpCollector(logger log.Logger) (Collector, error) {
+	labels := []string{"user", "tty", "remote"}
+	if *utmpHostLabel {
+		labels = append(labels, "host")
+	}
+	return &utmpCollector{
+		sessions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, utmpSubsystem, "sessions"),
+			"Number of sessions of logged in users from utmp.",
+			labels, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *utmpCollector) Update(ch chan<- prometheus.Metric) error {
+	f, err := os.Open(*utmpPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "utmp file not found", "path", *utmpPath)
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to open utmp: %w", err)
+	}
+	defer f.Close()
+
+	sessions, err := parseUtmp(f)
+	if err != nil {
+		return fmt.Errorf("failed to read utmp: %w", err)
+	}
+
+	counts := map[utmpSession]float64{}
+	for _, s := range sessions {
+		if !*utmpHostLabel {
+			s.host = ""
+		} else if *utmpHashHost && s.host != "" {
+			sum := sha256.Sum256([]byte(s.host))
+			s.host = hex.EncodeToString(sum[:6])
+		}
+		// Only keep the kind of terminal, e.g. "pts" for "pts/3".
+		if i := strings.IndexAny(s.tty, "/0123456789"); i > 0 {
+			s.tty = s.tty[:i]
+		}
+		counts[s]++
+	}
+	for s, count := range counts {
+		remote := "false"
+		if s.host != "" {
+			remote = "true"
+		}
+		labels := []string{s.user, s.tty, remote}
+		if *utmpHostLabel {
+			labels = append(labels, s.host)
+		}
+		ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, count, labels...)
+	}
+	return nil
+}
+
+// parseUtmp returns the user processes, i.e. login sessions, of an utmp file.
+// Records are expected in little endian byte order.
+func parseUtmp(r io.Reader) ([]utmpSession, error) {
+	var sessions []utmpSession
+	record := make([]byte, utmpRecordSize)
+	for {
+		if _, err := io.ReadFull(r, record); err != nil {
+			if err == io.EOF {
+				return sessions, nil
+			}
+			return nil, err
+		}
+		if int16(binary.LittleEndian.Uint16(record)) != utmpUserProcess {
+			continue
+		}
+		field := func(offset, size int) string {
+			b := record[offset : offset+size]
+			if i := bytes.IndexByte(b, 0); i >= 0 {
+				b = b[:i]
+			}
+			return string(b)
+		}
+		sessions = append(sessions, utmpSession{
+			user: field(utmpUserOffset, utmpUserSize),
+			tty:  field(utmpLineOffset, utmpLineSize),
+			host: field(utmpHostOffset, utmpHostSize),
+		})
+	}
+}
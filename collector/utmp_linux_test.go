@@ -0,0 +1,58 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noutmp
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestParseUtmp(t *testing.T) {
+	var buf bytes.Buffer
+	record := func(typ int16, line, user, host string) {
+		r := make([]byte, utmpRecordSize)
+		binary.LittleEndian.PutUint16(r, uint16(typ))
+		copy(r[utmpLineOffset:], line)
+		copy(r[utmpUserOffset:], user)
+		copy(r[utmpHostOffset:], host)
+		buf.Write(r)
+	}
+	// Boot time and login process records are skipped.
+	record(2, "~", "reboot", "5.10.0-8-amd64")
+	record(6, "tty1", "LOGIN", "")
+	record(utmpUserProcess, "tty2", "root", "")
+	record(utmpUserProcess, "pts/0", "alice", "203.0.113.7")
+
+	sessions, err := parseUtmp(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []utmpSession{
+		{user: "root", tty: "tty2"},
+		{user: "alice", tty: "pts/0", host: "203.0.113.7"},
+	}
+	if !reflect.DeepEqual(sessions, want) {
+		t.Errorf("want %+v, got %+v", want, sessions)
+	}
+
+	// Truncated records are an error.
+	buf.Write(make([]byte, 10))
+	if _, err := parseUtmp(&buf); err == nil {
+		t.Error("expected error for truncated utmp")
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !novirtio_balloon
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const virtioBalloonSubsystem = "virtio_balloon"
+
+// virtioBalloonFeatures are the feature bits of the virtio balloon device.
+var virtioBalloonFeatures = map[int]string{
+	0: "must_tell_host",
+	1: "stats_vq",
+	2: "deflate_on_oom",
+	3: "free_page_hint",
+	4: "page_poison",
+	5: "page_reporting",
+}
+
+type virtioBalloonCollector struct {
+	size     *prometheus.Desc
+	inflated *prometheus.Desc
+	deflated *prometheus.Desc
+	migrated *prometheus.Desc
+	feature  *prometheus.Desc
+	logger   log.Logger
+}
+
+func init() {
+	registerCollector(virtioBalloonSubsystem, defaultDisabled, NewVirtioBalloonCollector)
+}
+
+// NewVirtioBalloonCollector returns a new Collector exposing the memory the
+// hypervisor reclaimed from the guest with the virtio balloon. The target size
+// of the balloon is only known to the hypervisor and the driver.
+func NewVirtioBalloonCollector(logger log.Logger) (Collector, error) {
+	return &virtioBalloonCollector{
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtioBalloonSubsystem, "size_bytes"),
+			"Memory of the guest currently held by the balloon.",
+			nil, nil,
+		),
+		inflated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtioBalloonSubsystem, "inflated_bytes_total"),
+			"Memory taken from the guest by inflating the balloon.",
+			nil, nil,
+		),
+		deflated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtioBalloonSubsystem, "deflated_bytes_total"),
+			"Memory given back to the guest by deflating the balloon.",
+			nil, nil,
+		),
+		migrated: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtioBalloonSubsystem, "migrated_bytes_total"),
+			"Memory of the balloon migrated for memory compaction.",
+			nil, nil,
+		),
+		feature: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, virtioBalloonSubsystem, "feature_enabled"),
+			"Whether the feature was negotiated with the hypervisor, e.g. free page hinting and reporting.",
+			[]string{"device", "feature"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *virtioBalloonCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("bus/virtio/drivers/virtio_balloon/virtio[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		level.Debug(c.logger).Log("msg", "no virtio balloon devices found")
+		return ErrNoData
+	}
+
+	for _, path := range devices {
+		device := filepath.Base(path)
+		features, err := ioutil.ReadFile(filepath.Join(path, "features"))
+		if err != nil {
+			return fmt.Errorf("failed to read features of %s: %w", device, err)
+		}
+		// The features are a string of bits with bit 0 first.
+		bits := strings.TrimSpace(string(features))
+		for bit, feature := range virtioBalloonFeatures {
+			enabled := 0.0
+			if bit < len(bits) && bits[bit] == '1' {
+				enabled = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.feature, prometheus.GaugeValue, enabled, device, feature)
+		}
+	}
+
+	stats, err := readVirtioBalloonVMStat()
+	if err != nil {
+		return fmt.Errorf("failed to read vmstat: %w", err)
+	}
+	pageSize := float64(os.Getpagesize())
+	for desc, field := range map[*prometheus.Desc]string{
+		c.inflated: "balloon_inflate",
+		c.deflated: "balloon_deflate",
+		c.migrated: "balloon_migrate",
+	} {
+		if value, ok := stats[field]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value*pageSize)
+		}
+	}
+	// Kernels before 5.2 lack nr_balloon_pages.
+	if value, ok := stats["nr_balloon_pages"]; ok {
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, value*pageSize)
+	}
+	return nil
+}
+
+// readVirtioBalloonVMStat returns the balloon fields of /proc/vmstat in pages.
+func readVirtioBalloonVMStat() (map[string]float64, error) {
+	file, err := os.Open(procFilePath("vmstat"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stats := map[string]float64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 || !strings.Contains(parts[0], "balloon") {
+			continue
+		}
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		stats[parts[0]] = value
+	}
+	return stats, scanner.Err()
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !novmware_balloon
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const vmwareBalloonSubsystem = "vmware_balloon"
+
+// vmwareBalloonPageSize is the size of the pages vmw_balloon reports, large
+// pages are counted as 512 pages.
+const vmwareBalloonPageSize = 4096
+
+type vmwareBalloonCollector struct {
+	target *prometheus.Desc
+	size   *prometheus.Desc
+	logger log.Logger
+}
+
+func init() {
+	registerCollector(vmwareBalloonSubsystem, defaultDisabled, NewVMwareBalloonCollector)
+}
+
+// NewVMwareBalloonCollector returns a new Collector exposing the memory the
+// ESXi host reclaimed from the guest with the vmw_balloon driver, which
+// requires a mounted debugfs.
+func NewVMwareBalloonCollector(logger log.Logger) (Collector, error) {
+	return &vmwareBalloonCollector{
+		target: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, vmwareBalloonSubsystem, "target_bytes"),
+			"Memory the host requested the balloon to hold.",
+			nil, nil,
+		),
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, vmwareBalloonSubsystem, "size_bytes"),
+			"Memory of the guest currently held by the balloon and reclaimed by the host.",
+			nil, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *vmwareBalloonCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(sysFilePath("kernel/debug/vmmemctl"))
+	if err != nil {
+		if os.IsNotExist(err) || os.IsPermission(err) {
+			level.Debug(c.logger).Log("msg", "vmw_balloon statistics not available", "err", err)
+			return ErrNoData
+		}
+		return err
+	}
+	defer file.Close()
+
+	pages, err := parseVMwareBalloon(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse vmmemctl: %w", err)
+	}
+	for desc, field := range map[*prometheus.Desc]string{
+		c.target: "target",
+		c.size:   "current",
+	} {
+		if value, ok := pages[field]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value*vmwareBalloonPageSize)
+		}
+	}
+	return nil
+}
+
+// parseVMwareBalloon returns the fields in pages of lines like
+// "target:             1024 pages", statistics of operations are skipped.
+func parseVMwareBalloon(r io.Reader) (map[string]float64, error) {
+	pages := map[string]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) != 2 || fields[1] != "pages" {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		pages[strings.TrimSpace(parts[0])] = value
+	}
+	return pages, scanner.Err()
+}
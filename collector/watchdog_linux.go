@@ -0,0 +1,132 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nowatchdog
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const watchdogSubsystem = "watchdog"
+
+// watchdogSecondsFiles are the sysfs attributes of a watchdog device
+// holding a duration in seconds.
+var watchdogSecondsFiles = []string{"timeout", "pretimeout", "timeleft"}
+
+type watchdogCollector struct {
+	info       *prometheus.Desc
+	active     *prometheus.Desc
+	nowayout   *prometheus.Desc
+	bootstatus *prometheus.Desc
+	seconds    map[string]*prometheus.Desc
+	logger     log.Logger
+}
+
+func init() {
+	registerCollector(watchdogSubsystem, defaultDisabled, NewWatchdogCollector)
+}
+
+// NewWatchdogCollector returns a new Collector exposing watchdog device
+// status from /sys/class/watchdog.
+func NewWatchdogCollector(logger log.Logger) (Collector, error) {
+	seconds := make(map[string]*prometheus.Desc)
+	for _, name := range watchdogSecondsFiles {
+		seconds[name] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, name+"_seconds"),
+			fmt.Sprintf("Watchdog '%s' in seconds.", name),
+			[]string{"watchdog"}, nil,
+		)
+	}
+
+	return &watchdogCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "info"),
+			"Watchdog device present on the system, value is always 1.",
+			[]string{"watchdog", "identity"}, nil,
+		),
+		active: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "active"),
+			"Whether the watchdog is armed.",
+			[]string{"watchdog"}, nil,
+		),
+		nowayout: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "nowayout"),
+			"Whether the watchdog can't be stopped once armed.",
+			[]string{"watchdog"}, nil,
+		),
+		bootstatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, watchdogSubsystem, "bootstatus"),
+			"Watchdog status flags (WDIOF_*) at the last boot, e.g. 32 when the watchdog reset the system.",
+			[]string{"watchdog"}, nil,
+		),
+		seconds: seconds,
+		logger:  logger,
+	}, nil
+}
+
+func (c *watchdogCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("class/watchdog/watchdog[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		level.Debug(c.logger).Log("msg", "no watchdog devices found")
+		return ErrNoData
+	}
+
+	for _, device := range devices {
+		name := filepath.Base(device)
+
+		// Everything but the device number is only available with
+		// CONFIG_WATCHDOG_SYSFS.
+		identity, err := ioutil.ReadFile(filepath.Join(device, "identity"))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read watchdog identity: %w", err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, name, strings.TrimSpace(string(identity)))
+
+		if state, err := ioutil.ReadFile(filepath.Join(device, "state")); err == nil {
+			active := 0.0
+			if strings.TrimSpace(string(state)) == "active" {
+				active = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, active, name)
+		}
+		if value, err := readUintFromFile(filepath.Join(device, "nowayout")); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.nowayout, prometheus.GaugeValue, float64(value), name)
+		}
+		if value, err := readUintFromFile(filepath.Join(device, "bootstatus")); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.bootstatus, prometheus.GaugeValue, float64(value), name)
+		}
+		for _, file := range watchdogSecondsFiles {
+			// timeleft and pretimeout are only present if the driver supports them.
+			value, err := readUintFromFile(filepath.Join(device, file))
+			if err != nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.seconds[file], prometheus.GaugeValue, float64(value), name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nowifi
+
+package collector
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// #cgo CFLAGS: -x objective-c
+// #cgo LDFLAGS: -framework CoreWLAN -framework Foundation
+// #include <stdlib.h>
+// #include "wifi_darwin.h"
+import "C"
+
+// wifiInterfaceModes are the values of CWInterfaceMode.
+var wifiInterfaceModes = []string{"none", "station", "ibss", "ap"}
+
+type wifiCollector struct {
+	interfaceFrequencyHertz *prometheus.Desc
+	stationInfo             *prometheus.Desc
+	stationSignalDBM        *prometheus.Desc
+	stationNoiseDBM         *prometheus.Desc
+	stationTransmitBitrate  *prometheus.Desc
+	logger                  log.Logger
+}
+
+func init() {
+	registerCollector("wifi", defaultDisabled, NewWifiCollector)
+}
+
+// NewWifiCollector returns a new Collector exposing the association of Wi-Fi
+// interfaces from CoreWLAN.
+func NewWifiCollector(logger log.Logger) (Collector, error) {
+	const subsystem = "wifi"
+	labels := []string{"device", "mac_address"}
+	return &wifiCollector{
+		interfaceFrequencyHertz: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "interface_frequency_hertz"),
+			"The current frequency a WiFi interface is operating at, in hertz.",
+			[]string{"device"}, nil,
+		),
+		stationInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "station_info"),
+			"Labeled WiFi interface station information as provided by the operating system.",
+			[]string{"device", "bssid", "ssid", "mode"}, nil,
+		),
+		stationSignalDBM: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "station_signal_dbm"),
+			"The current WiFi signal strength, in decibel-milliwatts (dBm).",
+			labels, nil,
+		),
+		stationNoiseDBM: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "station_noise_dbm"),
+			"The current WiFi noise level, in decibel-milliwatts (dBm).",
+			labels, nil,
+		),
+		stationTransmitBitrate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "station_transmit_bits_per_second"),
+			"The current WiFi transmit bitrate of a station, in bits per second.",
+			labels, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *wifiCollector) Update(ch chan<- prometheus.Metric) error {
+	var ifaces *C.WiFiInterface
+	n := C._wifi_interfaces(&ifaces)
+	if n == -1 {
+		return errors.New("failed to get WiFi interfaces")
+	}
+	defer C.free(unsafe.Pointer(ifaces))
+
+	for _, iface := range (*[1 << 16]C.WiFiInterface)(unsafe.Pointer(ifaces))[:n:n] {
+		device := C.GoString(&iface.name[0])
+		bssid := C.GoString(&iface.bssid[0])
+		mode := "unknown"
+		if int(iface.mode) < len(wifiInterfaceModes) {
+			mode = wifiInterfaceModes[iface.mode]
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.stationInfo, prometheus.GaugeValue, 1, device, bssid, C.GoString(&iface.ssid[0]), mode)
+		ch <- prometheus.MustNewConstMetric(c.stationSignalDBM, prometheus.GaugeValue, float64(iface.rssi), device, bssid)
+		ch <- prometheus.MustNewConstMetric(c.stationNoiseDBM, prometheus.GaugeValue, float64(iface.noise), device, bssid)
+		ch <- prometheus.MustNewConstMetric(c.stationTransmitBitrate, prometheus.GaugeValue, float64(iface.transmitRate)*1e6, device, bssid)
+		if freq := wifiChannelFrequency(int(iface.channel), int(iface.band)); freq > 0 {
+			ch <- prometheus.MustNewConstMetric(c.interfaceFrequencyHertz, prometheus.GaugeValue, freq, device)
+		}
+	}
+	return nil
+}
+
+// wifiChannelFrequency returns the center frequency in hertz of the channel in
+// the band of CWChannelBand, 2.4 GHz, 5 GHz or 6 GHz.
+func wifiChannelFrequency(channel, band int) float64 {
+	switch {
+	case band == 1 && channel == 14:
+		return 2484e6
+	case band == 1:
+		return float64(2407+5*channel) * 1e6
+	case band == 2:
+		return float64(5000+5*channel) * 1e6
+	case band == 3:
+		return float64(5950+5*channel) * 1e6
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include <stdint.h>
+
+typedef struct {
+	char	name[16];
+	char	ssid[33];
+	char	bssid[18];
+	int	mode;
+	int	rssi;
+	int	noise;
+	double	transmitRate;
+	int	channel;
+	int	band;
+} WiFiInterface;
+
+int _wifi_interfaces(WiFiInterface **interfaces);
@@ -0,0 +1,61 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nowifi
+
+#import <CoreWLAN/CoreWLAN.h>
+#include <stdlib.h>
+#include <string.h>
+#include "wifi_darwin.h"
+
+static void _wifi_copy(char *dst, NSString *src, size_t size) {
+	// The SSID and BSSID are nil unless the process may access the location.
+	if (src == nil) {
+		dst[0] = '\0';
+		return;
+	}
+	strlcpy(dst, [src UTF8String], size);
+}
+
+int _wifi_interfaces(WiFiInterface **interfaces) {
+	@autoreleasepool {
+		NSArray<CWInterface *> *ifaces = [[CWWiFiClient sharedWiFiClient] interfaces];
+		if (ifaces == nil) {
+			return -1;
+		}
+		WiFiInterface *p = calloc([ifaces count] + 1, sizeof(WiFiInterface));
+		if (p == NULL) {
+			return -1;
+		}
+		int n = 0;
+		for (CWInterface *iface in ifaces) {
+			// Interfaces which are powered off or not associated report
+			// no signal.
+			if (![iface powerOn] || [iface rssiValue] == 0) {
+				continue;
+			}
+			WiFiInterface *w = &p[n++];
+			_wifi_copy(w->name, [iface interfaceName], sizeof(w->name));
+			_wifi_copy(w->ssid, [iface ssid], sizeof(w->ssid));
+			_wifi_copy(w->bssid, [iface bssid], sizeof(w->bssid));
+			w->mode = (int)[iface interfaceMode];
+			w->rssi = (int)[iface rssiValue];
+			w->noise = (int)[iface noiseMeasurement];
+			w->transmitRate = [iface transmitRate];
+			w->channel = (int)[[iface wlanChannel] channelNumber];
+			w->band = (int)[[iface wlanChannel] channelBand];
+		}
+		*interfaces = p;
+		return n;
+	}
+}
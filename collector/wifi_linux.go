@@ -22,6 +22,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -45,6 +47,13 @@ type wifiCollector struct {
 	stationTransmitFailedTotal   *prometheus.Desc
 	stationBeaconLossTotal       *prometheus.Desc
 
+	surveyInUse           *prometheus.Desc
+	surveyNoiseDBM        *prometheus.Desc
+	surveyActiveSeconds   *prometheus.Desc
+	surveyBusySeconds     *prometheus.Desc
+	surveyReceiveSeconds  *prometheus.Desc
+	surveyTransmitSeconds *prometheus.Desc
+
 	logger log.Logger
 }
 
@@ -56,14 +65,13 @@ func init() {
 	registerCollector("wifi", defaultDisabled, NewWifiCollector)
 }
 
-var _ wifiStater = &wifi.Client{}
-
-// wifiStater is an interface used to swap out a *wifi.Client for end to end tests.
+// wifiStater is an interface used to swap out a *wifiClient for end to end tests.
 type wifiStater interface {
 	BSS(ifi *wifi.Interface) (*wifi.BSS, error)
 	Close() error
 	Interfaces() ([]*wifi.Interface, error)
 	StationInfo(ifi *wifi.Interface) ([]*wifi.StationInfo, error)
+	Survey(ifi *wifi.Interface) ([]*wifiSurvey, error)
 }
 
 // NewWifiCollector returns a new Collector exposing Wifi statistics.
@@ -73,7 +81,8 @@ func NewWifiCollector(logger log.Logger) (Collector, error) {
 	)
 
 	var (
-		labels = []string{"device", "mac_address"}
+		labels       = []string{"device", "mac_address"}
+		surveyLabels = []string{"device", "frequency_mhz"}
 	)
 
 	return &wifiCollector{
@@ -160,6 +169,48 @@ func NewWifiCollector(logger log.Logger) (Collector, error) {
 			labels,
 			nil,
 		),
+
+		surveyInUse: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "survey_channel_in_use"),
+			"Whether the surveyed channel is the one the WiFi interface is operating on.",
+			surveyLabels,
+			nil,
+		),
+
+		surveyNoiseDBM: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "survey_noise_dbm"),
+			"The noise level of the surveyed channel, in decibel-milliwatts (dBm).",
+			surveyLabels,
+			nil,
+		),
+
+		surveyActiveSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "survey_active_seconds_total"),
+			"The total number of seconds the radio has been on the surveyed channel.",
+			surveyLabels,
+			nil,
+		),
+
+		surveyBusySeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "survey_busy_seconds_total"),
+			"The total number of seconds the surveyed channel was sensed busy.",
+			surveyLabels,
+			nil,
+		),
+
+		surveyReceiveSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "survey_receive_seconds_total"),
+			"The total number of seconds the radio spent receiving on the surveyed channel.",
+			surveyLabels,
+			nil,
+		),
+
+		surveyTransmitSeconds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "survey_transmit_seconds_total"),
+			"The total number of seconds the radio spent transmitting on the surveyed channel.",
+			surveyLabels,
+			nil,
+		),
 		logger: logger,
 	}, nil
 }
@@ -228,6 +279,19 @@ func (c *wifiCollector) Update(ch chan<- prometheus.Metric) error {
 			return fmt.Errorf("failed to retrieve station info for device %q: %v",
 				ifi.Name, err)
 		}
+
+		surveys, err := stat.Survey(ifi)
+		switch {
+		case err == nil:
+			for _, survey := range surveys {
+				c.updateSurveyStats(ch, ifi.Name, survey)
+			}
+		case errors.Is(err, os.ErrNotExist):
+			level.Debug(c.logger).Log("msg", "survey information not found for wifi device", "name", ifi.Name)
+		default:
+			return fmt.Errorf("failed to retrieve survey for device %q: %v",
+				ifi.Name, err)
+		}
 	}
 
 	return nil
@@ -328,6 +392,49 @@ func (c *wifiCollector) updateStationStats(ch chan<- prometheus.Metric, device s
 	)
 }
 
+func (c *wifiCollector) updateSurveyStats(ch chan<- prometheus.Metric, device string, survey *wifiSurvey) {
+	frequency := strconv.Itoa(survey.Frequency)
+
+	inUse := 0.0
+	if survey.InUse {
+		inUse = 1
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.surveyInUse,
+		prometheus.GaugeValue,
+		inUse,
+		device,
+		frequency,
+	)
+
+	if survey.Noise != nil {
+		ch <- prometheus.MustNewConstMetric(
+			c.surveyNoiseDBM,
+			prometheus.GaugeValue,
+			float64(*survey.Noise),
+			device,
+			frequency,
+		)
+	}
+
+	for desc, d := range map[*prometheus.Desc]*time.Duration{
+		c.surveyActiveSeconds:   survey.ActiveTime,
+		c.surveyBusySeconds:     survey.BusyTime,
+		c.surveyReceiveSeconds:  survey.ReceiveTime,
+		c.surveyTransmitSeconds: survey.TransmitTime,
+	} {
+		if d != nil {
+			ch <- prometheus.MustNewConstMetric(
+				desc,
+				prometheus.CounterValue,
+				d.Seconds(),
+				device,
+				frequency,
+			)
+		}
+	}
+}
+
 func mHzToHz(mHz int) float64 {
 	return float64(mHz) * 1000 * 1000
 }
@@ -355,7 +462,7 @@ func newWifiStater(fixtures string) (wifiStater, error) {
 		}, nil
 	}
 
-	return wifi.New()
+	return newWifiClient()
 }
 
 var _ wifiStater = &mockWifiStater{}
@@ -405,3 +512,14 @@ func (s *mockWifiStater) StationInfo(ifi *wifi.Interface) ([]*wifi.StationInfo,
 
 	return stations, nil
 }
+
+func (s *mockWifiStater) Survey(ifi *wifi.Interface) ([]*wifiSurvey, error) {
+	p := filepath.Join(ifi.Name, "survey.json")
+
+	var surveys []*wifiSurvey
+	if err := s.unmarshalJSONFile(p, &surveys); err != nil {
+		return nil, err
+	}
+
+	return surveys, nil
+}
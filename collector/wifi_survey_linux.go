@@ -0,0 +1,156 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nowifi
+
+package collector
+
+import (
+	"os"
+	"time"
+
+	"github.com/mdlayher/genetlink"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/wifi"
+)
+
+// nl80211 constants for channel surveys, see include/uapi/linux/nl80211.h.
+// Package wifi doesn't implement surveys yet.
+const (
+	nl80211GenlName       = "nl80211"
+	nl80211CmdGetSurvey   = 50
+	nl80211AttrIfindex    = 3
+	nl80211AttrSurveyInfo = 84
+
+	nl80211SurveyInfoFrequency = 1
+	nl80211SurveyInfoNoise     = 2
+	nl80211SurveyInfoInUse     = 3
+	nl80211SurveyInfoTime      = 4
+	nl80211SurveyInfoTimeBusy  = 5
+	nl80211SurveyInfoTimeRx    = 7
+	nl80211SurveyInfoTimeTx    = 8
+)
+
+// wifiSurvey is the survey result of a channel. Drivers only report some of
+// the fields, the others are nil.
+type wifiSurvey struct {
+	Frequency    int
+	InUse        bool
+	Noise        *int
+	ActiveTime   *time.Duration
+	BusyTime     *time.Duration
+	ReceiveTime  *time.Duration
+	TransmitTime *time.Duration
+}
+
+var _ wifiStater = &wifiClient{}
+
+// wifiClient extends a *wifi.Client with survey results.
+type wifiClient struct {
+	*wifi.Client
+	conn   *genetlink.Conn
+	family genetlink.Family
+}
+
+func newWifiClient() (*wifiClient, error) {
+	client, err := wifi.New()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := genetlink.Dial(nil)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	family, err := conn.GetFamily(nl80211GenlName)
+	if err != nil {
+		conn.Close()
+		client.Close()
+		return nil, err
+	}
+	return &wifiClient{Client: client, conn: conn, family: family}, nil
+}
+
+func (c *wifiClient) Close() error {
+	c.conn.Close()
+	return c.Client.Close()
+}
+
+// Survey dumps the channel survey results of an interface.
+func (c *wifiClient) Survey(ifi *wifi.Interface) ([]*wifiSurvey, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(nl80211AttrIfindex, uint32(ifi.Index))
+	data, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := c.conn.Execute(genetlink.Message{
+		Header: genetlink.Header{
+			Command: nl80211CmdGetSurvey,
+			Version: c.family.Version,
+		},
+		Data: data,
+	}, c.family.ID, netlink.Request|netlink.Dump)
+	if err != nil {
+		return nil, err
+	}
+
+	var surveys []*wifiSurvey
+	for _, msg := range msgs {
+		ad, err := netlink.NewAttributeDecoder(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		for ad.Next() {
+			if ad.Type() != nl80211AttrSurveyInfo {
+				continue
+			}
+			survey := &wifiSurvey{}
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				duration := func() *time.Duration {
+					d := time.Duration(nad.Uint64()) * time.Millisecond
+					return &d
+				}
+				for nad.Next() {
+					switch nad.Type() {
+					case nl80211SurveyInfoFrequency:
+						survey.Frequency = int(nad.Uint32())
+					case nl80211SurveyInfoNoise:
+						noise := int(int8(nad.Uint8()))
+						survey.Noise = &noise
+					case nl80211SurveyInfoInUse:
+						survey.InUse = true
+					case nl80211SurveyInfoTime:
+						survey.ActiveTime = duration()
+					case nl80211SurveyInfoTimeBusy:
+						survey.BusyTime = duration()
+					case nl80211SurveyInfoTimeRx:
+						survey.ReceiveTime = duration()
+					case nl80211SurveyInfoTimeTx:
+						survey.TransmitTime = duration()
+					}
+				}
+				return nil
+			})
+			surveys = append(surveys, survey)
+		}
+		if err := ad.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if len(surveys) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return surveys, nil
+}
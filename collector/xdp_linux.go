@@ -0,0 +1,194 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noxdp
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jsimonetti/rtnetlink"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/safchain/ethtool"
+	"golang.org/x/sys/unix"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	xdpStatsInclude = kingpin.Flag("collector.xdp.stats-include", "Regexp of driver (ethtool) statistics to expose as XDP action counters.").Default("(?i)xdp").String()
+)
+
+// xdpAttachModes maps the IFLA_XDP_ATTACHED values to the names used by ip(8).
+var xdpAttachModes = map[uint8]string{
+	1: "native",
+	2: "generic",
+	3: "offload",
+	4: "multi",
+}
+
+type xdpCollector struct {
+	statsPattern *regexp.Regexp
+	attached     *prometheus.Desc
+	programInfo  *prometheus.Desc
+	driverStat   *prometheus.Desc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector("xdp", defaultDisabled, NewXDPCollector)
+}
+
+// NewXDPCollector returns a new Collector exposing XDP program attachment
+// and driver XDP statistics.
+func NewXDPCollector(logger log.Logger) (Collector, error) {
+	const subsystem = "xdp"
+
+	pattern, err := regexp.Compile(*xdpStatsInclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XDP stats pattern: %w", err)
+	}
+
+	return &xdpCollector{
+		statsPattern: pattern,
+		attached: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "attached"),
+			"Whether an XDP program is attached to the network device.",
+			[]string{"device"}, nil,
+		),
+		programInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "program_info"),
+			"XDP program attached to the network device, value is always 1.",
+			[]string{"device", "mode", "id", "name"}, nil,
+		),
+		driverStat: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "driver_stat_total"),
+			"XDP action counter reported by the network driver.",
+			[]string{"device", "stat"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *xdpCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return fmt.Errorf("couldn't connect rtnetlink: %w", err)
+	}
+	defer conn.Close()
+
+	links, err := conn.Link.List()
+	if err != nil {
+		return fmt.Errorf("couldn't get links: %w", err)
+	}
+
+	e, err := ethtool.NewEthtool()
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to initialize ethtool, skipping XDP driver statistics", "err", err)
+	} else {
+		defer e.Close()
+	}
+
+	for _, link := range links {
+		if link.Attributes == nil {
+			continue
+		}
+		device := link.Attributes.Name
+		xdp := link.Attributes.XDP
+
+		mode, ok := uint8(0), false
+		if xdp != nil {
+			_, ok = xdpAttachModes[xdp.Attached]
+			mode = xdp.Attached
+		}
+		if !ok {
+			ch <- prometheus.MustNewConstMetric(c.attached, prometheus.GaugeValue, 0, device)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.attached, prometheus.GaugeValue, 1, device)
+
+		id, name := "", ""
+		if xdp.ProgID != 0 {
+			id = strconv.FormatUint(uint64(xdp.ProgID), 10)
+			name, err = bpfProgramName(xdp.ProgID)
+			if err != nil {
+				level.Debug(c.logger).Log("msg", "failed to get XDP program name", "device", device, "id", id, "err", err)
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.programInfo, prometheus.GaugeValue, 1, device, xdpAttachModes[mode], id, name)
+
+		if e == nil {
+			continue
+		}
+		stats, err := e.Stats(device)
+		if err != nil {
+			if errno, ok := err.(syscall.Errno); !ok || errno != unix.EOPNOTSUPP {
+				level.Debug(c.logger).Log("msg", "failed to get ethtool statistics", "device", device, "err", err)
+			}
+			continue
+		}
+		for stat, value := range stats {
+			if !c.statsPattern.MatchString(stat) {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.driverStat, prometheus.CounterValue, float64(value), device, stat)
+		}
+	}
+
+	return nil
+}
+
+// bpfProgramName looks up the name of a loaded BPF program by its ID.
+// This requires CAP_SYS_ADMIN.
+func bpfProgramName(id uint32) (string, error) {
+	// union bpf_attr for BPF_PROG_GET_FD_BY_ID, see /usr/include/linux/bpf.h
+	getFD := struct {
+		ID        uint32
+		NextID    uint32
+		OpenFlags uint32
+	}{ID: id}
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_PROG_GET_FD_BY_ID, uintptr(unsafe.Pointer(&getFD)), unsafe.Sizeof(getFD))
+	if errno != 0 {
+		return "", errno
+	}
+	defer unix.Close(int(fd))
+
+	// The name is stored at offset 64 of struct bpf_prog_info.
+	info := make([]byte, 80)
+	getInfo := struct {
+		FD      uint32
+		InfoLen uint32
+		Info    uint64
+	}{
+		FD:      uint32(fd),
+		InfoLen: uint32(len(info)),
+		Info:    uint64(uintptr(unsafe.Pointer(&info[0]))),
+	}
+	_, _, errno = unix.Syscall(unix.SYS_BPF, unix.BPF_OBJ_GET_INFO_BY_FD, uintptr(unsafe.Pointer(&getInfo)), unsafe.Sizeof(getInfo))
+	runtime.KeepAlive(info)
+	if errno != 0 {
+		return "", errno
+	}
+	if getInfo.InfoLen < uint32(len(info)) {
+		return "", errors.New("kernel does not report BPF program names")
+	}
+	return bytesToString(info[64:80]), nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noxen
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const xenSubsystem = "xen"
+
+// Message types of the xenstore protocol.
+const (
+	xenstoreDirectory = 1
+	xenstoreRead      = 2
+	xenstoreError     = 16
+)
+
+type xenCollector struct {
+	info         *prometheus.Desc
+	memoryTarget *prometheus.Desc
+	memoryMax    *prometheus.Desc
+	vcpus        *prometheus.Desc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector(xenSubsystem, defaultDisabled, NewXenCollector)
+}
+
+// NewXenCollector returns a new Collector exposing the domains of a Xen dom0
+// from xenstore. The CPU time of the domains is only available with hypercalls
+// of libxenstat, whose interface changes with each Xen release.
+func NewXenCollector(logger log.Logger) (Collector, error) {
+	domainLabels := []string{"domain", "name"}
+	return &xenCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, xenSubsystem, "hypervisor_info"),
+			"Version of the Xen hypervisor, value is always 1.",
+			[]string{"version"}, nil,
+		),
+		memoryTarget: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, xenSubsystem, "domain_memory_target_bytes"),
+			"Memory the domain is ballooned to.",
+			domainLabels, nil,
+		),
+		memoryMax: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, xenSubsystem, "domain_memory_max_bytes"),
+			"Maximum memory of the domain.",
+			domainLabels, nil,
+		),
+		vcpus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, xenSubsystem, "domain_vcpus"),
+			"Number of vCPUs of the domain by availability.",
+			append(domainLabels, "state"), nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *xenCollector) Update(ch chan<- prometheus.Metric) error {
+	typ, err := ioutil.ReadFile(sysFilePath("hypervisor/type"))
+	if err != nil || strings.TrimSpace(string(typ)) != "xen" {
+		level.Debug(c.logger).Log("msg", "not running on Xen")
+		return ErrNoData
+	}
+	if version, err := xenHypervisorVersion(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, version)
+	} else {
+		level.Debug(c.logger).Log("msg", "failed to read Xen version", "err", err)
+	}
+
+	// Only dom0 has access to xenstore.
+	file, err := os.OpenFile(rootfsFilePath("/dev/xen/xenbus"), os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		file, err = os.OpenFile(procFilePath("xen/xenbus"), os.O_RDWR, 0)
+	}
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "failed to open xenstore", "err", err)
+		return ErrNoData
+	}
+	defer file.Close()
+	xs := &xenstoreClient{rw: file}
+
+	domains, err := xs.directory("/local/domain")
+	if err != nil {
+		return fmt.Errorf("failed to list Xen domains: %w", err)
+	}
+	for _, domain := range domains {
+		dir := "/local/domain/" + domain
+		// Domains being destroyed lose their entries while reading them.
+		name, err := xs.read(dir + "/name")
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "failed to read Xen domain name", "domain", domain, "err", err)
+			continue
+		}
+		for desc, path := range map[*prometheus.Desc]string{
+			c.memoryTarget: "/memory/target",
+			c.memoryMax:    "/memory/static-max",
+		} {
+			value, err := xs.read(dir + path)
+			if err != nil {
+				continue
+			}
+			kib, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s of Xen domain %s: %w", path, domain, err)
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(kib*1024), domain, name)
+		}
+
+		cpus, err := xs.directory(dir + "/cpu")
+		if err != nil {
+			continue
+		}
+		vcpus := map[string]int{"online": 0, "offline": 0}
+		for _, cpu := range cpus {
+			if availability, err := xs.read(dir + "/cpu/" + cpu + "/availability"); err == nil {
+				vcpus[availability]++
+			}
+		}
+		for state, count := range vcpus {
+			ch <- prometheus.MustNewConstMetric(c.vcpus, prometheus.GaugeValue, float64(count), domain, name, state)
+		}
+	}
+	return nil
+}
+
+func xenHypervisorVersion() (string, error) {
+	parts := make([]string, 0, 3)
+	for _, file := range []string{"major", "minor", "extra"} {
+		value, err := ioutil.ReadFile(sysFilePath("hypervisor/version/" + file))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, strings.TrimSpace(string(value)))
+	}
+	return parts[0] + "." + parts[1] + parts[2], nil
+}
+
+// xenstoreClient speaks the xenstore protocol, each message has a header of
+// four little endian 32 bit integers followed by the payload.
+type xenstoreClient struct {
+	rw    io.ReadWriter
+	reqID uint32
+}
+
+type xenstoreHeader struct {
+	Type, ReqID, TxID, Len uint32
+}
+
+func (xs *xenstoreClient) request(typ uint32, path string) ([]byte, error) {
+	xs.reqID++
+	payload := append([]byte(path), 0)
+	var msg bytes.Buffer
+	if err := binary.Write(&msg, binary.LittleEndian, xenstoreHeader{Type: typ, ReqID: xs.reqID, Len: uint32(len(payload))}); err != nil {
+		return nil, err
+	}
+	msg.Write(payload)
+	if _, err := xs.rw.Write(msg.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var header xenstoreHeader
+	if err := binary.Read(xs.rw, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, header.Len)
+	if _, err := io.ReadFull(xs.rw, resp); err != nil {
+		return nil, err
+	}
+	if header.Type == xenstoreError {
+		return nil, errors.New(strings.TrimRight(string(resp), "\x00"))
+	}
+	if header.Type != typ || header.ReqID != xs.reqID {
+		return nil, fmt.Errorf("unexpected xenstore response type %d for request %d", header.Type, header.ReqID)
+	}
+	return resp, nil
+}
+
+func (xs *xenstoreClient) read(path string) (string, error) {
+	resp, err := xs.request(xenstoreRead, path)
+	return string(resp), err
+}
+
+func (xs *xenstoreClient) directory(path string) ([]string, error) {
+	resp, err := xs.request(xenstoreDirectory, path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	for _, entry := range strings.Split(string(resp), "\x00") {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !noxen
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeXenstore answers xenstore requests from a map of paths.
+type fakeXenstore struct {
+	entries map[string]string
+	resp    bytes.Buffer
+}
+
+func (f *fakeXenstore) Write(p []byte) (int, error) {
+	var header xenstoreHeader
+	if err := binary.Read(bytes.NewReader(p), binary.LittleEndian, &header); err != nil {
+		return 0, err
+	}
+	path := strings.TrimRight(string(p[16:16+header.Len]), "\x00")
+	value, ok := f.entries[path]
+	if !ok {
+		header.Type = xenstoreError
+		value = "ENOENT\x00"
+	}
+	header.Len = uint32(len(value))
+	binary.Write(&f.resp, binary.LittleEndian, header)
+	f.resp.WriteString(value)
+	return len(p), nil
+}
+
+func (f *fakeXenstore) Read(p []byte) (int, error) {
+	return f.resp.Read(p)
+}
+
+func TestXenstoreClient(t *testing.T) {
+	xs := &xenstoreClient{rw: &fakeXenstore{entries: map[string]string{
+		"/local/domain":                 "0\x001\x00",
+		"/local/domain/1/name":          "web01",
+		"/local/domain/1/memory/target": "2097152",
+	}}}
+
+	domains, err := xs.directory("/local/domain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"0", "1"}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("want domains %v, got %v", want, domains)
+	}
+
+	name, err := xs.read("/local/domain/1/name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "web01"; name != want {
+		t.Errorf("want name %q, got %q", want, name)
+	}
+
+	if _, err := xs.read("/local/domain/2/name"); err == nil || err.Error() != "ENOENT" {
+		t.Errorf("want ENOENT error, got %v", err)
+	}
+}
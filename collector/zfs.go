@@ -87,7 +87,11 @@ func (c *zfsCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	// Pool stats
-	return c.updatePoolStats(ch)
+	if err := c.updatePoolStats(ch); err != nil {
+		return err
+	}
+
+	return c.updateDatasetSpace(ch)
 }
 
 func (s zfsSysctl) metricName() string {
@@ -143,6 +147,21 @@ func (c *zfsCollector) constPoolObjsetMetric(poolName string, datasetName string
 	)
 }
 
+func (c *zfsCollector) constDatasetSpaceMetric(poolName string, datasetName string, name string, help string, value float64) prometheus.Metric {
+	return prometheus.MustNewConstMetric(
+		prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "zfs_zpool_dataset", name),
+			help,
+			[]string{"zpool", "dataset"},
+			nil,
+		),
+		prometheus.GaugeValue,
+		value,
+		poolName,
+		datasetName,
+	)
+}
+
 func (c *zfsCollector) constPoolStateMetric(poolName string, stateName string, isActive uint64) prometheus.Metric {
 	return prometheus.MustNewConstMetric(
 		prometheus.NewDesc(
@@ -17,9 +17,11 @@ package collector
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
 )
 
 type zfsCollector struct {
@@ -261,5 +263,50 @@ func (c *zfsCollector) Update(ch chan<- prometheus.Metric) error {
 			), m.valueType, v)
 	}
 
+	return c.updateDatasetSpace(ch)
+}
+
+// updateDatasetSpace exports the space of the mounted datasets like on Linux,
+// ZFS reports the referenced and available space of a dataset as size and
+// free space of the mount. The space used by snapshots and the statistics of
+// the vdevs are only available from libzfs.
+func (c *zfsCollector) updateDatasetSpace(ch chan<- prometheus.Metric) error {
+	n, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil {
+		return fmt.Errorf("couldn't get mounts: %w", err)
+	}
+	buf := make([]unix.Statfs_t, n)
+	if n, err = unix.Getfsstat(buf, unix.MNT_NOWAIT); err != nil {
+		return fmt.Errorf("couldn't get mounts: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, fs := range buf[:n] {
+		if bytesToString(fs.Fstypename[:]) != "zfs" {
+			continue
+		}
+		dataset := bytesToString(fs.Mntfromname[:])
+		// Snapshots are mounted below .zfs/snapshot of their dataset.
+		if strings.Contains(dataset, "@") || seen[dataset] {
+			continue
+		}
+		seen[dataset] = true
+
+		pool := strings.SplitN(dataset, "/", 2)[0]
+		for _, m := range []struct {
+			name, help string
+			value      float64
+		}{
+			{"referenced_bytes", "Space referenced by the dataset, shared with its snapshots.", float64(fs.Blocks-fs.Bfree) * float64(fs.Bsize)},
+			{"available_bytes", "Space available to the dataset, limited by its quota and refquota and those of its parents.", float64(fs.Bavail) * float64(fs.Bsize)},
+		} {
+			ch <- prometheus.MustNewConstMetric(
+				prometheus.NewDesc(
+					prometheus.BuildFQName(namespace, "zfs_zpool_dataset", m.name),
+					m.help,
+					[]string{"zpool", "dataset"}, nil,
+				), prometheus.GaugeValue, m.value, pool, dataset)
+		}
+	}
 	return nil
 }
@@ -17,6 +17,7 @@ package collector
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -26,6 +27,7 @@ import (
 
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
 )
 
 // constants from https://github.com/zfsonlinux/zfs/blob/master/lib/libspl/include/sys/kstat.h
@@ -294,3 +296,66 @@ func (c *zfsCollector) parsePoolStateFile(reader io.Reader, zpoolPath string, ha
 
 	return nil
 }
+
+// updateDatasetSpace exports the space of the mounted datasets. ZFS reports
+// the referenced and available space of a dataset, the latter limited by its
+// quotas, as size and free space to statfs. Unmounted datasets and the space
+// used by snapshots and children are only available through libzfs.
+func (c *zfsCollector) updateDatasetSpace(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("1/mounts"))
+	if errors.Is(err, os.ErrNotExist) {
+		// Fallback to `/proc/mounts` if `/proc/1/mounts` is missing due hidepid.
+		file, err = os.Open(procFilePath("mounts"))
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	datasets, err := parseZFSMountedDatasets(file)
+	if err != nil {
+		return err
+	}
+
+	for dataset, mountPoint := range datasets {
+		var buf unix.Statfs_t
+		if err := unix.Statfs(rootfsFilePath(mountPoint), &buf); err != nil {
+			level.Debug(c.logger).Log("msg", "Failed to get space of dataset", "dataset", dataset, "mountpoint", mountPoint, "err", err)
+			continue
+		}
+		poolName := strings.SplitN(dataset, "/", 2)[0]
+		ch <- c.constDatasetSpaceMetric(poolName, dataset, "referenced_bytes", "Space referenced by the dataset, shared with its snapshots.", float64(buf.Blocks-buf.Bfree)*float64(buf.Bsize))
+		ch <- c.constDatasetSpaceMetric(poolName, dataset, "available_bytes", "Space available to the dataset, limited by its quota and refquota and those of its parents.", float64(buf.Bavail)*float64(buf.Bsize))
+	}
+	return nil
+}
+
+// parseZFSMountedDatasets returns a mount point of each mounted ZFS dataset.
+func parseZFSMountedDatasets(r io.Reader) (map[string]string, error) {
+	datasets := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("malformed mount point information: %q", scanner.Text())
+		}
+		if parts[2] != "zfs" {
+			continue
+		}
+		// Ensure we handle the translation of \040 and \011
+		// as per fstab(5).
+		for i := 0; i < 2; i++ {
+			parts[i] = strings.Replace(parts[i], "\\040", " ", -1)
+			parts[i] = strings.Replace(parts[i], "\\011", "\t", -1)
+		}
+
+		// Snapshots are mounted below .zfs/snapshot of their dataset.
+		if strings.Contains(parts[0], "@") {
+			continue
+		}
+		if _, ok := datasets[parts[0]]; !ok {
+			datasets[parts[0]] = rootfsStripPrefix(parts[1])
+		}
+	}
+	return datasets, scanner.Err()
+}
@@ -16,6 +16,7 @@ package collector
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -543,3 +544,30 @@ func TestPoolStateParsing(t *testing.T) {
 	}
 
 }
+
+func TestMountedDatasetsParsing(t *testing.T) {
+	datasets, err := parseZFSMountedDatasets(strings.NewReader(`/dev/sda1 / ext4 rw,relatime 0 0
+pool1 /pool1 zfs rw,xattr,noacl 0 0
+pool1/home /home zfs rw,xattr,noacl 0 0
+pool1/home /srv/home zfs rw,xattr,noacl 0 0
+pool1/home@daily /home/.zfs/snapshot/daily zfs ro,relatime,xattr,noacl 0 0
+poolz1/media\040files /media\040files zfs rw,xattr,noacl 0 0
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"pool1":              "/pool1",
+		"pool1/home":         "/home",
+		"poolz1/media files": "/media files",
+	}
+	if len(datasets) != len(want) {
+		t.Fatalf("want datasets %v, got %v", want, datasets)
+	}
+	for dataset, mountPoint := range want {
+		if datasets[dataset] != mountPoint {
+			t.Errorf("%s: want mount point %q, got %q", dataset, mountPoint, datasets[dataset])
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nozone
+
+package collector
+
+import (
+	"math"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/illumos/go-kstat"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const zoneSubsystem = "zone"
+
+type zoneCollector struct {
+	cpu        *prometheus.Desc
+	waitTime   *prometheus.Desc
+	processes  *prometheus.Desc
+	rss        *prometheus.Desc
+	rssCap     *prometheus.Desc
+	pagedOut   *prometheus.Desc
+	swap       *prometheus.Desc
+	swapCap    *prometheus.Desc
+	cpuCap     *prometheus.Desc
+	cpuCapUsed *prometheus.Desc
+	logger     log.Logger
+}
+
+func init() {
+	registerCollector(zoneSubsystem, defaultDisabled, NewZoneCollector)
+}
+
+// NewZoneCollector returns a new Collector exposing the CPU usage, memory and
+// swap of zones from the zone_misc, zone_memory_cap and zone_caps kstats. Caps
+// are only exposed for zones which have one.
+func NewZoneCollector(logger log.Logger) (Collector, error) {
+	labels := []string{"zone"}
+	return &zoneCollector{
+		cpu: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "cpu_seconds_total"),
+			"Seconds the CPUs spent running the processes of the zone in each mode.",
+			[]string{"zone", "mode"}, nil,
+		),
+		waitTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "cpu_wait_seconds_total"),
+			"Seconds the threads of the zone spent waiting on a run queue.",
+			labels, nil,
+		),
+		processes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "processes"),
+			"Number of processes in the zone.",
+			labels, nil,
+		),
+		rss: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "memory_rss_bytes"),
+			"Resident memory of the processes of the zone.",
+			labels, nil,
+		),
+		rssCap: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "memory_cap_bytes"),
+			"Cap on the resident memory of the zone.",
+			labels, nil,
+		),
+		pagedOut: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "memory_cap_paged_out_bytes_total"),
+			"Memory paged out to enforce the cap on the resident memory of the zone.",
+			labels, nil,
+		),
+		swap: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "swap_reserved_bytes"),
+			"Swap space reserved by the zone.",
+			labels, nil,
+		),
+		swapCap: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "swap_cap_bytes"),
+			"Cap on the swap space reserved by the zone.",
+			labels, nil,
+		),
+		cpuCap: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "cpu_cap"),
+			"Cap on the CPU usage of the zone in CPUs.",
+			labels, nil,
+		),
+		cpuCapUsed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, zoneSubsystem, "cpu_cap_usage"),
+			"CPU usage of the zone counted against its cap in CPUs.",
+			labels, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *zoneCollector) Update(ch chan<- prometheus.Metric) error {
+	tok, err := kstat.Open()
+	if err != nil {
+		return err
+	}
+	defer tok.Close()
+
+	for _, ks := range tok.All() {
+		switch ks.Class {
+		case "zone_misc", "zone_memory_cap", "zone_caps":
+		default:
+			continue
+		}
+		named, err := ks.AllNamed()
+		if err != nil {
+			return err
+		}
+		stats := map[string]*kstat.Named{}
+		for _, n := range named {
+			stats[n.Name] = n
+		}
+		// The kstat names are truncated, zonename has the full name.
+		zone, ok := stats["zonename"]
+		if !ok {
+			continue
+		}
+		name := zone.StringVal
+
+		switch ks.Class {
+		case "zone_misc":
+			c.emit(ch, c.cpu, prometheus.CounterValue, stats["nsec_user"], 1e-9, name, "user")
+			c.emit(ch, c.cpu, prometheus.CounterValue, stats["nsec_sys"], 1e-9, name, "system")
+			c.emit(ch, c.waitTime, prometheus.CounterValue, stats["nsec_waitrq"], 1e-9, name)
+			c.emit(ch, c.processes, prometheus.GaugeValue, stats["nprocs"], 1, name)
+		case "zone_memory_cap":
+			c.emit(ch, c.rss, prometheus.GaugeValue, stats["rss"], 1, name)
+			c.emitCap(ch, c.rssCap, stats["physcap"], 1, name)
+			c.emit(ch, c.pagedOut, prometheus.CounterValue, stats["pagedout"], 1, name)
+			c.emit(ch, c.swap, prometheus.GaugeValue, stats["swap"], 1, name)
+			c.emitCap(ch, c.swapCap, stats["swapcap"], 1, name)
+		case "zone_caps":
+			// The caps of locked memory, swap and processes share the
+			// class, CPU caps are in percent of a CPU.
+			if !strings.HasPrefix(ks.Name, "cpucaps_zone_") {
+				continue
+			}
+			c.emitCap(ch, c.cpuCap, stats["value"], 0.01, name)
+			c.emit(ch, c.cpuCapUsed, prometheus.GaugeValue, stats["usage"], 0.01, name)
+		}
+	}
+	return nil
+}
+
+func (c *zoneCollector) emit(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, stat *kstat.Named, factor float64, labels ...string) {
+	if stat == nil {
+		return
+	}
+	value := float64(stat.UintVal)
+	if stat.Type == kstat.Int32 || stat.Type == kstat.Int64 {
+		value = float64(stat.IntVal)
+	}
+	ch <- prometheus.MustNewConstMetric(desc, valueType, value*factor, labels...)
+}
+
+// emitCap exposes caps, which are 0 or the maximum value if not set.
+func (c *zoneCollector) emitCap(ch chan<- prometheus.Metric, desc *prometheus.Desc, stat *kstat.Named, factor float64, zone string) {
+	if stat == nil || stat.UintVal == 0 || stat.UintVal == math.MaxUint64 {
+		return
+	}
+	c.emit(ch, desc, prometheus.GaugeValue, stat, factor, zone)
+}
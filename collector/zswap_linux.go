@@ -0,0 +1,121 @@
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !nozswap
+
+package collector
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const zswapSubsystem = "zswap"
+
+type zswapCollector struct {
+	enabled        *prometheus.Desc
+	poolSize       *prometheus.Desc
+	storedPages    *prometheus.Desc
+	sameFilled     *prometheus.Desc
+	writtenBack    *prometheus.Desc
+	poolLimitHit   *prometheus.Desc
+	duplicateEntry *prometheus.Desc
+	rejected       *prometheus.Desc
+	logger         log.Logger
+}
+
+func init() {
+	registerCollector(zswapSubsystem, defaultDisabled, NewZswapCollector)
+}
+
+// NewZswapCollector returns a new Collector exposing zswap statistics.
+func NewZswapCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, zswapSubsystem, name), help, labels, nil)
+	}
+	return &zswapCollector{
+		enabled:        desc("enabled", "Whether zswap is enabled."),
+		poolSize:       desc("pool_size_bytes", "Memory used by the compressed pool."),
+		storedPages:    desc("stored_pages", "Number of compressed pages stored in the pool."),
+		sameFilled:     desc("same_filled_pages", "Number of stored pages filled with the same value, which need no pool memory."),
+		writtenBack:    desc("written_back_pages_total", "Number of pages written back from the pool to the swap device."),
+		poolLimitHit:   desc("pool_limit_hit_total", "Number of times the pool limit was reached."),
+		duplicateEntry: desc("duplicate_entries_total", "Number of duplicate store requests."),
+		rejected:       desc("rejected_pages_total", "Number of pages rejected by zswap.", "reason"),
+		logger:         logger,
+	}, nil
+}
+
+func (c *zswapCollector) Update(ch chan<- prometheus.Metric) error {
+	enabled, err := ioutil.ReadFile(sysFilePath("module/zswap/parameters/enabled"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			level.Debug(c.logger).Log("msg", "zswap not supported by the kernel")
+			return ErrNoData
+		}
+		return fmt.Errorf("failed to read zswap state: %w", err)
+	}
+	value := 0.0
+	if strings.TrimSpace(string(enabled)) == "Y" {
+		value = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, value)
+
+	// The statistics are only available in debugfs.
+	dir := sysFilePath("kernel/debug/zswap")
+	if _, err := os.Stat(dir); err != nil {
+		level.Debug(c.logger).Log("msg", "zswap statistics not available, debugfs not mounted or not readable", "err", err)
+		return nil
+	}
+	for name, m := range map[string]struct {
+		desc      *prometheus.Desc
+		valueType prometheus.ValueType
+	}{
+		"pool_total_size":    {c.poolSize, prometheus.GaugeValue},
+		"stored_pages":       {c.storedPages, prometheus.GaugeValue},
+		"same_filled_pages":  {c.sameFilled, prometheus.GaugeValue},
+		"written_back_pages": {c.writtenBack, prometheus.CounterValue},
+		"pool_limit_hit":     {c.poolLimitHit, prometheus.CounterValue},
+		"duplicate_entry":    {c.duplicateEntry, prometheus.CounterValue},
+	} {
+		value, err := readUintFromFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to read zswap statistic %s: %w", name, err)
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, float64(value))
+	}
+
+	rejects, err := filepath.Glob(filepath.Join(dir, "reject_*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range rejects {
+		value, err := readUintFromFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read zswap statistic %s: %w", filepath.Base(path), err)
+		}
+		reason := strings.TrimPrefix(filepath.Base(path), "reject_")
+		ch <- prometheus.MustNewConstMetric(c.rejected, prometheus.CounterValue, float64(value), reason)
+	}
+	return nil
+}
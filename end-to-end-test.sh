@@ -3,49 +3,77 @@
 set -euf -o pipefail
 
 enabled_collectors=$(cat << COLLECTORS
+  acpi
+  alsa
+  apparmor
   arp
+  backlight
   bcache
   btrfs
   buddyinfo
+  certificate
+  cgroup
+  clocksource
+  compaction
   conntrack
   cpu
   cpufreq
   diskstats
+  dmi
   drbd
   edac
   entropy
+  ext4
+  f2fs
   fibrechannel
   filefd
   hwmon
+  hyperv
+  i915
   infiniband
   interrupts
   ipvs
+  kdump
   ksmd
+  kvm
+  lio
   loadavg
   mdadm
   meminfo
   meminfo_numa
+  mounts
   mountstats
   netdev
   netstat
   nfs
   nfsd
+  nvmeof
+  pci
   pressure
   qdisc
   rapl
+  resctrl
   schedstat
+  secureboot
   sockstat
   stat
   thermal_zone
+  thp
+  tpm
   textfile
   bonding
   udp_queues 
+  usb
+  virtio_balloon
+  vmware_balloon
   vmstat
+  watchdog
   wifi
   xfs
   zfs
   processes
   zoneinfo
+  zswap
 COLLECTORS
 )
 disabled_collectors=$(cat << COLLECTORS
@@ -60,7 +88,7 @@ cd "$(dirname $0)"
 port="$((10000 + (RANDOM % 10000)))"
 tmpdir=$(mktemp -d /tmp/node_exporter_e2e_test.XXXXXX)
 
-skip_re="^(go_|node_exporter_build_info|node_scrape_collector_duration_seconds|process_|node_textfile_mtime_seconds)"
+skip_re="^(go_|node_exporter_build_info|node_scrape_collector_duration_seconds|node_scrape_collector_queue_wait_seconds|process_|node_textfile_mtime_seconds|node_kdump_pstore_oldest_entry_timestamp_seconds|node_clocksource_realtime_monotonic_offset_seconds)"
 
 arch="$(uname -m)"
 
@@ -108,12 +136,18 @@ fi
   --collector.textfile.directory="collector/fixtures/textfile/two_metric_files/" \
   --collector.wifi.fixtures="collector/fixtures/wifi" \
   --collector.qdisc.fixtures="collector/fixtures/qdisc/" \
+  --collector.qdisc.classes \
+  --collector.conntrack.per-cpu \
+  --collector.conntrack.entries-by-state \
   --collector.netclass.ignored-devices="(dmz|int)" \
   --collector.netclass.ignore-invalid-speed \
   --collector.bcache.priorityStats \
   --collector.cpu.info \
   --collector.cpu.info.flags-include="^(aes|avx.?|constant_tsc)$" \
   --collector.cpu.info.bugs-include="^(cpu_meltdown|spectre_.*|mds)$" \
+  --collector.certificate.path="collector/fixtures/certificates/*.pem" \
+  --collector.pressure.cgroup-include="system\.slice" \
+  --collector.processes.thread-states \
   --web.listen-address "127.0.0.1:${port}" \
   --log.level="debug" > "${tmpdir}/node_exporter.log" 2>&1 &
 
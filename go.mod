@@ -12,6 +12,8 @@ require (
 	github.com/jsimonetti/rtnetlink v0.0.0-20210713125558-2bfdf1dbdbd6
 	github.com/lufia/iostat v1.1.1
 	github.com/mattn/go-xmlrpc v0.0.3
+	github.com/mdlayher/genetlink v1.0.0
+	github.com/mdlayher/netlink v1.4.1
 	github.com/mdlayher/wifi v0.0.0-20200527114002-84f0b9457fdd
 	github.com/prometheus/client_golang v1.11.0
 	github.com/prometheus/client_model v0.2.0
@@ -23,6 +25,8 @@ require (
 	github.com/soundcloud/go-runit v0.0.0-20150630195641-06ad41a06c4a
 	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
+	gopkg.in/yaml.v2 v2.4.0
+	modernc.org/sqlite v1.11.2
 )
 
 go 1.14
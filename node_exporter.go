@@ -116,9 +116,15 @@ func (h *handler) innerHandler(filters ...string) (http.Handler, error) {
 		}
 	}
 
+	// Filtered requests always collect on the fly.
+	var c prometheus.Collector = nc
+	if len(filters) == 0 {
+		c = collector.BackgroundCollection(nc, h.logger)
+	}
+
 	r := prometheus.NewRegistry()
 	r.MustRegister(version.NewCollector("node_exporter"))
-	if err := r.Register(nc); err != nil {
+	if err := r.Register(c); err != nil {
 		return nil, fmt.Errorf("couldn't register node collector: %s", err)
 	}
 	handler := promhttp.HandlerFor(